@@ -171,7 +171,7 @@ class BenchmarkRunner {
   }
 
   async runGolang(endpoint, iterations) {
-    const command = `cd golang && go mod tidy && go run main.go "${endpoint}" ${iterations}`;
+    const command = `cd golang && go mod tidy && go run ./cmd/solana-rpc-bench "${endpoint}" ${iterations}`;
     const output = execSync(command, { encoding: 'utf8', timeout: this.options.timeout });
     
     // Extract JSON from output
@@ -0,0 +1,70 @@
+// Package alert opens and resolves incidents in PagerDuty or Opsgenie, for
+// endpoints that a monitor finds breaching their SLO.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds each call to an alerting API.
+const requestTimeout = 15 * time.Second
+
+// Severities of an alert.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// Alert is one SLO breach. Key identifies it: triggering the same key
+// again updates the open incident rather than opening another, and
+// resolving it closes the incident.
+type Alert struct {
+	Key      string
+	Summary  string
+	Source   string
+	Severity string
+	Since    time.Time
+	Details  map[string]interface{}
+}
+
+// Alerter opens and resolves incidents.
+type Alerter interface {
+	Trigger(ctx context.Context, alert Alert) error
+	Resolve(ctx context.Context, alert Alert) error
+}
+
+// post sends body as JSON to url with the headers and fails on any status
+// but 2xx.
+func post(ctx context.Context, url string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
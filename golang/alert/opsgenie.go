@@ -0,0 +1,71 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// OpsgenieURL is the Opsgenie API's base URL; accounts in the EU use
+// https://api.eu.opsgenie.com.
+const OpsgenieURL = "https://api.opsgenie.com"
+
+// opsgenieMessageLength is the longest message Opsgenie accepts.
+const opsgenieMessageLength = 130
+
+// Opsgenie creates and closes Opsgenie alerts with an API integration's
+// key. An alert's Key is the Opsgenie alias, which deduplicates it.
+type Opsgenie struct {
+	APIKey string
+	URL    string
+}
+
+// NewOpsgenie returns an alerter for the API integration with apiKey.
+func NewOpsgenie(apiKey string) *Opsgenie {
+	return &Opsgenie{APIKey: apiKey, URL: OpsgenieURL}
+}
+
+func (o *Opsgenie) Trigger(ctx context.Context, alert Alert) error {
+	priority := "P3"
+	if alert.Severity == SeverityCritical {
+		priority = "P1"
+	}
+	details := make(map[string]string, len(alert.Details))
+	for key, value := range alert.Details {
+		details[key] = fmt.Sprint(value)
+	}
+	message := alert.Summary
+	if len(message) > opsgenieMessageLength {
+		message = message[:opsgenieMessageLength-3] + "..."
+	}
+	body := map[string]interface{}{
+		"message":     message,
+		"description": alert.Summary,
+		"alias":       alert.Key,
+		"source":      alert.Source,
+		"priority":    priority,
+		"details":     details,
+	}
+	if err := post(ctx, o.endpoint("/v2/alerts"), o.headers(), body); err != nil {
+		return fmt.Errorf("opsgenie trigger: %w", err)
+	}
+	return nil
+}
+
+func (o *Opsgenie) Resolve(ctx context.Context, alert Alert) error {
+	path := "/v2/alerts/" + url.PathEscape(alert.Key) + "/close?identifierType=alias"
+	body := map[string]interface{}{"source": alert.Source, "note": "resolved: the endpoint meets its SLO again"}
+	if err := post(ctx, o.endpoint(path), o.headers(), body); err != nil {
+		return fmt.Errorf("opsgenie resolve: %w", err)
+	}
+	return nil
+}
+
+func (o *Opsgenie) endpoint(path string) string {
+	return strings.TrimSuffix(o.URL, "/") + path
+}
+
+func (o *Opsgenie) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.APIKey}
+}
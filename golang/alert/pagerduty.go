@@ -0,0 +1,54 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PagerDutyURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty sends alerts as PagerDuty Events API v2 events to the service
+// whose integration has RoutingKey.
+type PagerDuty struct {
+	RoutingKey string
+	URL        string
+}
+
+// NewPagerDuty returns an alerter for the integration with routingKey.
+func NewPagerDuty(routingKey string) *PagerDuty {
+	return &PagerDuty{RoutingKey: routingKey, URL: PagerDutyURL}
+}
+
+func (p *PagerDuty) Trigger(ctx context.Context, alert Alert) error {
+	event := p.event("trigger", alert)
+	event["payload"] = map[string]interface{}{
+		"summary":        alert.Summary,
+		"source":         alert.Source,
+		"severity":       alert.Severity,
+		"timestamp":      alert.Since.UTC().Format(time.RFC3339),
+		"component":      "rpc",
+		"class":          "slo",
+		"custom_details": alert.Details,
+	}
+	if err := post(ctx, p.URL, nil, event); err != nil {
+		return fmt.Errorf("pagerduty trigger: %w", err)
+	}
+	return nil
+}
+
+func (p *PagerDuty) Resolve(ctx context.Context, alert Alert) error {
+	if err := post(ctx, p.URL, nil, p.event("resolve", alert)); err != nil {
+		return fmt.Errorf("pagerduty resolve: %w", err)
+	}
+	return nil
+}
+
+func (p *PagerDuty) event(action string, alert Alert) map[string]interface{} {
+	return map[string]interface{}{
+		"routing_key":  p.RoutingKey,
+		"event_action": action,
+		"dedup_key":    alert.Key,
+	}
+}
@@ -0,0 +1,134 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// LoadAccountList reads one base58 public key per line. Blank lines and
+// lines starting with # are ignored.
+func LoadAccountList(path string) ([]string, error) {
+	return loadKeyList(path, "public keys", func(key string) error {
+		_, err := rpcclient.ParsePublicKey(key)
+		return err
+	})
+}
+
+// loadKeyList reads one key per line, checking each with check. what
+// names the keys in the error for an empty list.
+func loadKeyList(path, what string, check func(string) error) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := check(text); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		keys = append(keys, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s contains no %s", path, what)
+	}
+	return keys, nil
+}
+
+// AccountPicker hands out keys from a list either in order or at random.
+// It is safe for concurrent use.
+type AccountPicker struct {
+	mu     sync.Mutex
+	keys   []string
+	random bool
+	rng    *rand.Rand
+	next   int
+}
+
+// NewAccountPicker returns a picker of keys, each listed once however many
+// times it appears.
+func NewAccountPicker(keys []string, sampling string) (*AccountPicker, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("account list is empty")
+	}
+	unique := make([]string, 0, len(keys))
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !listed[key] {
+			listed[key] = true
+			unique = append(unique, key)
+		}
+	}
+	picker := &AccountPicker{keys: unique}
+	switch sampling {
+	case "round-robin":
+	case "random":
+		picker.random = true
+		picker.rng = newRand(0)
+	default:
+		return nil, fmt.Errorf("unknown account sampling %q (want random or round-robin)", sampling)
+	}
+	return picker, nil
+}
+
+// Seeded returns a picker of the same keys that starts afresh, drawing
+// random keys in an order that follows seed.
+func (p *AccountPicker) Seeded(seed int64) *AccountPicker {
+	picker := &AccountPicker{keys: p.keys, random: p.random}
+	if p.random {
+		picker.rng = newRand(seed)
+	}
+	return picker
+}
+
+func (p *AccountPicker) Len() int {
+	return len(p.keys)
+}
+
+func (p *AccountPicker) Next() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pickLocked()
+}
+
+// NextN returns n keys, without repeats when the list is long enough.
+func (p *AccountPicker) NextN(n int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for len(keys) < n {
+		key := p.pickLocked()
+		if seen[key] && len(seen) < len(p.keys) {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (p *AccountPicker) pickLocked() string {
+	if p.random {
+		return p.keys[p.rng.Intn(len(p.keys))]
+	}
+	key := p.keys[p.next]
+	p.next = (p.next + 1) % len(p.keys)
+	return key
+}
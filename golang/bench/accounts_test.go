@@ -0,0 +1,47 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccountPickerNextN(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		sampling string
+		n        int
+		want     []string
+	}{
+		{name: "in order", keys: []string{"A", "B", "C"}, sampling: "round-robin", n: 2, want: []string{"A", "B"}},
+		{name: "wraps around", keys: []string{"A", "B"}, sampling: "round-robin", n: 3, want: []string{"A", "B", "A"}},
+		{name: "duplicates", keys: []string{"A", "A"}, sampling: "round-robin", n: 2, want: []string{"A", "A"}},
+		{name: "duplicates, no repeats while others are left", keys: []string{"A", "A", "B"}, sampling: "round-robin", n: 2, want: []string{"A", "B"}},
+		{name: "more than the list", keys: []string{"A", "B", "A"}, sampling: "round-robin", n: 10},
+		{name: "random duplicates", keys: []string{"A", "A", "A"}, sampling: "random", n: 10},
+		{name: "random", keys: []string{"A", "B", "C", "B"}, sampling: "random", n: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picker, err := NewAccountPicker(tt.keys, tt.sampling)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := picker.NextN(tt.n)
+			if len(got) != tt.n {
+				t.Fatalf("got %d keys, want %d", len(got), tt.n)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			// Every key comes once before any comes twice.
+			seen := map[string]int{}
+			for i, key := range got {
+				seen[key]++
+				if i < picker.Len() && seen[key] > 1 {
+					t.Errorf("%s repeated in %v before the others were used", key, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bench
+
+import (
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// ApdexStats rates a run on the Apdex scale from 0 (every user frustrated)
+// to 1 (every user satisfied). Requests answered within the target T are
+// satisfied, within 4T tolerating, and slower or failed ones frustrated.
+type ApdexStats struct {
+	TargetMs   int64   `json:"targetMs"`
+	Satisfied  int     `json:"satisfied"`
+	Tolerating int     `json:"tolerating"`
+	Frustrated int     `json:"frustrated"`
+	Score      float64 `json:"score"`
+}
+
+// calculateApdex scores results against target, or returns nil when target
+// is not positive.
+func calculateApdex(results []rpcclient.Result, target time.Duration) *ApdexStats {
+	if target <= 0 {
+		return nil
+	}
+	apdex := &ApdexStats{TargetMs: target.Milliseconds()}
+	for _, result := range results {
+		latency := time.Duration(result.Latency) * time.Millisecond
+		switch {
+		case !result.Success || latency > 4*target:
+			apdex.Frustrated++
+		case latency > target:
+			apdex.Tolerating++
+		default:
+			apdex.Satisfied++
+		}
+	}
+	if len(results) > 0 {
+		apdex.Score = (float64(apdex.Satisfied) + float64(apdex.Tolerating)/2) / float64(len(results))
+	}
+	return apdex
+}
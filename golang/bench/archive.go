@@ -0,0 +1,238 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+const (
+	archiveSkipProbe    = 20
+	archiveRetries      = 3
+	approxSlotDuration  = 400 * time.Millisecond
+	archiveSampleEvery  = 10
+	archiveSampleTarget = 3
+)
+
+var archiveDepths = []uint64{1_000, 100_000, 1_000_000, 10_000_000, 100_000_000}
+
+type ArchiveDepthSample struct {
+	SlotsBehind uint64       `json:"slotsBehind"`
+	ApproxAge   string       `json:"approxAge"`
+	Slot        uint64       `json:"slot"`
+	Available   bool         `json:"available"`
+	Latency     LatencyStats `json:"latency"`
+}
+
+type ArchiveStats struct {
+	CurrentSlot           uint64               `json:"currentSlot"`
+	ClaimedFirstBlock     uint64               `json:"claimedFirstBlock"`
+	FirstAvailableLatency int64                `json:"firstAvailableLatency"`
+	OldestAvailableSlot   uint64               `json:"oldestAvailableSlot"`
+	ClaimAccurate         bool                 `json:"claimAccurate"`
+	SlotSpan              uint64               `json:"slotSpan"`
+	ApproxHistory         string               `json:"approxHistory"`
+	OldestBlockTime       int64                `json:"oldestBlockTime,omitempty"`
+	SearchSteps           int                  `json:"searchSteps"`
+	TransactionSignature  string               `json:"transactionSignature,omitempty"`
+	TransactionAvailable  bool                 `json:"transactionAvailable"`
+	TransactionLatency    int64                `json:"transactionLatency,omitempty"`
+	TransactionError      string               `json:"transactionError,omitempty"`
+	BlockLatency          LatencyStats         `json:"blockLatency"`
+	DepthSamples          []ArchiveDepthSample `json:"depthSamples"`
+	Interrupted           bool                 `json:"interrupted,omitempty"`
+}
+
+type archiveProbe struct {
+	s         *JSONRPCTester
+	latencies []int64
+}
+
+// checkSlot reports whether the endpoint serves the block at slot. Skipped
+// slots say nothing either way, so the following slots are tried instead.
+func (p *archiveProbe) checkSlot(ctx context.Context, slot uint64) (bool, uint64, []string, error) {
+	for k := uint64(0); k < archiveSkipProbe; k++ {
+		var result *rpcclient.Result
+		for attempt := 0; attempt < archiveRetries; attempt++ {
+			var err error
+			result, err = p.s.GetBlock(ctx, slot+k, blockSignaturesConfig())
+			if err != nil {
+				return false, 0, nil, err
+			}
+			if result.Success || result.ErrorCode != 0 {
+				break
+			}
+		}
+
+		switch {
+		case result.Success:
+			p.latencies = append(p.latencies, result.Latency)
+			var block struct {
+				Signatures []string `json:"signatures"`
+			}
+			if err := rpcclient.DecodeResult(result, &block); err != nil {
+				return false, 0, nil, err
+			}
+			return true, slot + k, block.Signatures, nil
+		case rpcclient.IsSkippedSlot(result):
+			p.latencies = append(p.latencies, result.Latency)
+			continue
+		case result.ErrorCode != 0:
+			p.latencies = append(p.latencies, result.Latency)
+			return false, 0, nil, nil
+		default:
+			return false, 0, nil, fmt.Errorf("getBlock %d: %s", slot+k, result.Error)
+		}
+	}
+	return false, 0, nil, nil
+}
+
+// blockSignaturesConfig asks for the lightest useful getBlock response: just
+// the transaction signatures.
+func blockSignaturesConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"encoding":                       "json",
+		"transactionDetails":             "signatures",
+		"rewards":                        false,
+		"maxSupportedTransactionVersion": 0,
+		"commitment":                     "finalized",
+	}
+}
+
+func approxAge(slots uint64) string {
+	return (time.Duration(slots) * approxSlotDuration).Round(time.Minute).String()
+}
+
+// RunArchiveProbe binary-searches for the oldest slot whose block the
+// endpoint still serves, compares it with getFirstAvailableBlock, and times
+// getBlock at increasing depths plus getTransaction at the oldest block.
+func (s *JSONRPCTester) RunArchiveProbe(ctx context.Context) (*ArchiveStats, error) {
+	p := &archiveProbe{s: s}
+	stats := &ArchiveStats{}
+
+	var first uint64
+	result, err := s.GetFirstAvailableBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := rpcclient.DecodeResult(result, &first); err != nil {
+		return nil, err
+	}
+	stats.ClaimedFirstBlock = first
+	stats.FirstAvailableLatency = result.Latency
+
+	var current uint64
+	if _, err := s.CallResult(ctx, "getSlot", []interface{}{map[string]interface{}{"commitment": "finalized"}}, &current); err != nil {
+		return nil, err
+	}
+	stats.CurrentSlot = current
+	s.Log().Info("probing archive depth", "current_slot", current, "claimed_first_block", first)
+
+	// Binary search over [0, current]; the newest finalized block is
+	// assumed to be served. If interrupted, hi is only an upper bound.
+	lo, hi := uint64(0), current
+	for lo < hi && !s.stopped(ctx) {
+		mid := lo + (hi-lo)/2
+		available, _, _, err := p.checkSlot(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		stats.SearchSteps++
+		if available {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	stats.OldestAvailableSlot = hi
+	stats.ClaimAccurate = hi+archiveSkipProbe >= first && hi <= first+archiveSkipProbe
+	stats.SlotSpan = current - hi
+	stats.ApproxHistory = approxAge(stats.SlotSpan)
+	s.Log().Info("found oldest available slot", "slot", hi, "history", stats.ApproxHistory, "search_steps", stats.SearchSteps)
+	if s.stopped(ctx) {
+		stats.Interrupted = true
+		stats.BlockLatency = s.summarize(p.latencies)
+		return stats, nil
+	}
+
+	var blockTime int64
+	if _, err := s.CallResult(ctx, "getBlockTime", []interface{}{hi}, &blockTime); err == nil {
+		stats.OldestBlockTime = blockTime
+	}
+
+	if err := s.probeOldestTransaction(ctx, p, stats); err != nil {
+		return nil, err
+	}
+
+	depths := append([]uint64(nil), archiveDepths...)
+	depths = append(depths, stats.SlotSpan)
+	for _, behind := range depths {
+		if behind > stats.SlotSpan {
+			continue
+		}
+		if s.stopped(ctx) {
+			break
+		}
+		sample := ArchiveDepthSample{
+			SlotsBehind: behind,
+			ApproxAge:   approxAge(behind),
+			Slot:        current - behind,
+		}
+		var latencies []int64
+		for i := 0; i < archiveSampleTarget; i++ {
+			// Spread samples over neighbouring slots so repeated lookups
+			// don't just measure a provider cache.
+			before := len(p.latencies)
+			available, _, _, err := p.checkSlot(ctx, sample.Slot+uint64(i*archiveSampleEvery))
+			if err != nil {
+				return nil, err
+			}
+			sample.Available = sample.Available || available
+			latencies = append(latencies, p.latencies[before:]...)
+		}
+		sample.Latency = s.summarize(latencies)
+		stats.DepthSamples = append(stats.DepthSamples, sample)
+	}
+
+	stats.BlockLatency = s.summarize(p.latencies)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// probeOldestTransaction fetches a transaction from the oldest served block,
+// since some providers keep blocks longer than transaction history.
+func (s *JSONRPCTester) probeOldestTransaction(ctx context.Context, p *archiveProbe, stats *ArchiveStats) error {
+	slot := stats.OldestAvailableSlot
+	for i := 0; i < archiveSkipProbe; i++ {
+		available, served, signatures, err := p.checkSlot(ctx, slot)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return nil
+		}
+		if len(signatures) == 0 {
+			slot = served + 1
+			continue
+		}
+
+		stats.TransactionSignature = signatures[0]
+		result, err := s.GetTransaction(ctx, signatures[0], map[string]interface{}{
+			"encoding":                       "json",
+			"maxSupportedTransactionVersion": 0,
+			"commitment":                     "finalized",
+		})
+		if err != nil {
+			return err
+		}
+		stats.TransactionLatency = result.Latency
+		stats.TransactionAvailable = result.Success && result.Result != nil
+		if !result.Success {
+			stats.TransactionError = result.Error
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/report"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// BatchJob is one tenant's benchmark. Endpoint and header values may
+// reference environment variables (${VAR}) so credentials stay out of the
+// batch file. Timeout and Timeouts are Go durations ("2s") overriding the
+// command-line timeouts for this job.
+type BatchJob struct {
+	Name       string            `json:"name"`
+	Tenant     string            `json:"tenant,omitempty"`
+	Endpoint   string            `json:"endpoint"`
+	Iterations int               `json:"iterations,omitempty"`
+	RateLimit  float64           `json:"rateLimit,omitempty"`
+	Timeout    string            `json:"timeout,omitempty"`
+	Timeouts   map[string]string `json:"timeouts,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Output     string            `json:"output,omitempty"`
+
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+}
+
+type BatchConfig struct {
+	OutputDir   string     `json:"outputDir,omitempty"`
+	Parallel    bool       `json:"parallel,omitempty"`
+	MaxParallel int        `json:"maxParallel,omitempty"`
+	Jobs        []BatchJob `json:"jobs"`
+}
+
+type JobReport struct {
+	Job        string          `json:"job"`
+	Tenant     string          `json:"tenant,omitempty"`
+	Endpoint   string          `json:"endpoint"`
+	Iterations int             `json:"iterations"`
+	RateLimit  float64         `json:"rateLimit,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Stats      *BenchmarkStats `json:"stats,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type BatchIndexEntry struct {
+	Job         string  `json:"job"`
+	Tenant      string  `json:"tenant,omitempty"`
+	Endpoint    string  `json:"endpoint"`
+	Report      string  `json:"report"`
+	Success     bool    `json:"success"`
+	Error       string  `json:"error,omitempty"`
+	SuccessRate float64 `json:"successRate"`
+	AvgLatency  float64 `json:"avgLatency"`
+	P50         int64   `json:"p50"`
+	P95         int64   `json:"p95"`
+	P99         int64   `json:"p99"`
+	// BreakerOpened counts the times the job's circuit breaker opened,
+	// and Skipped the requests it held back.
+	BreakerOpened int `json:"breakerOpened,omitempty"`
+	Skipped       int `json:"skipped,omitempty"`
+}
+
+type BatchIndex struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Parallel    bool              `json:"parallel"`
+	Jobs        []BatchIndexEntry `json:"jobs"`
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func loadBatchConfig(path string) (*BatchConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config BatchConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse batch config %s: %w", path, err)
+	}
+	if len(config.Jobs) == 0 {
+		return nil, fmt.Errorf("batch config %s has no jobs", path)
+	}
+
+	seen := make(map[string]bool)
+	for i, job := range config.Jobs {
+		if job.Name == "" {
+			return nil, fmt.Errorf("batch job %d has no name", i)
+		}
+		if job.Endpoint == "" {
+			return nil, fmt.Errorf("batch job %q has no endpoint", job.Name)
+		}
+		if onlyDots(job.Name) || (job.Tenant != "" && onlyDots(job.Tenant)) {
+			return nil, fmt.Errorf("batch job %q: tenant and name must be more than dots", job.Tenant+"/"+job.Name)
+		}
+		key := job.Tenant + "/" + job.Name
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate batch job %q", key)
+		}
+		seen[key] = true
+
+		if job.Timeout != "" {
+			if config.Jobs[i].timeout, err = parseTimeout(job.Timeout); err != nil {
+				return nil, fmt.Errorf("batch job %q: timeout: %w", job.Name, err)
+			}
+		}
+		config.Jobs[i].timeouts = make(map[string]time.Duration, len(job.Timeouts))
+		for method, value := range job.Timeouts {
+			if config.Jobs[i].timeouts[method], err = parseTimeout(value); err != nil {
+				return nil, fmt.Errorf("batch job %q: timeout for %s: %w", job.Name, method, err)
+			}
+		}
+	}
+
+	if config.OutputDir == "" {
+		config.OutputDir = "reports"
+	}
+
+	// Sanitizing tenants and names can map two jobs to one report, and
+	// the later would overwrite the earlier.
+	paths := map[string]string{filepath.Clean(filepath.Join(config.OutputDir, "index.json")): "the batch index"}
+	for _, job := range config.Jobs {
+		path := filepath.Clean(job.reportPath(config.OutputDir))
+		if !withinDir(config.OutputDir, path) {
+			return nil, fmt.Errorf("batch job %q: output %s is outside %s", job.Name, job.Output, config.OutputDir)
+		}
+		if other, ok := paths[path]; ok {
+			return nil, fmt.Errorf("batch job %q would write its report to %s, as %s does", job.Name, path, other)
+		}
+		paths[path] = fmt.Sprintf("job %q", job.Name)
+	}
+	return &config, nil
+}
+
+// withinDir reports whether path lies within dir.
+func withinDir(dir, path string) bool {
+	dir, dirErr := filepath.Abs(dir)
+	path, pathErr := filepath.Abs(path)
+	if dirErr != nil || pathErr != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
+
+// onlyDots reports whether a tenant or name, once sanitized, is nothing
+// but dots, such as "..", which as a path would leave the output
+// directory.
+func onlyDots(name string) bool {
+	return strings.Trim(unsafePathChars.ReplaceAllString(name, "_"), ".") == ""
+}
+
+func (j BatchJob) reportPath(outputDir string) string {
+	if j.Output != "" {
+		return j.Output
+	}
+	dir := outputDir
+	if j.Tenant != "" {
+		dir = filepath.Join(dir, unsafePathChars.ReplaceAllString(j.Tenant, "_"))
+	}
+	return filepath.Join(dir, unsafePathChars.ReplaceAllString(j.Name, "_")+".json")
+}
+
+func runBatchJob(ctx context.Context, job BatchJob, defaultIterations int, template *JSONRPCTester) *JobReport {
+	iterations := job.Iterations
+	if iterations <= 0 {
+		iterations = defaultIterations
+	}
+
+	jobReport := &JobReport{
+		Job:        job.Name,
+		Tenant:     job.Tenant,
+		Endpoint:   rpcclient.RedactURL(job.Endpoint),
+		Iterations: iterations,
+		RateLimit:  job.RateLimit,
+		StartedAt:  time.Now().UTC(),
+	}
+
+	if template.stopped(ctx) {
+		jobReport.Error = "interrupted before the job started"
+		jobReport.FinishedAt = jobReport.StartedAt
+		return jobReport
+	}
+
+	tester := template.withEndpoint(os.ExpandEnv(job.Endpoint))
+	tester.Label = job.Name
+	if job.Tenant != "" {
+		tester.Label = job.Tenant + "/" + job.Name
+	}
+	if tester.Client.Logger != nil {
+		tester.Client.Logger = tester.Client.Logger.With("job", tester.Label)
+	}
+	tester.Headers = make(map[string]string, len(job.Headers))
+	for key, value := range job.Headers {
+		tester.Headers[key] = os.ExpandEnv(value)
+	}
+	tester.SetRateLimit(job.RateLimit)
+	if job.Timeout != "" {
+		tester.Timeout = job.timeout
+	}
+	tester.Timeouts = mergeTimeouts(tester.Timeouts, job.timeouts)
+
+	stats, err := tester.RunBenchmark(ctx, iterations)
+	if err != nil {
+		jobReport.Error = err.Error()
+	}
+	jobReport.Stats = stats
+	jobReport.FinishedAt = time.Now().UTC()
+	return jobReport
+}
+
+// RunBatch runs every job in the batch file; each job's tester inherits the
+// benchmark settings of template.
+func RunBatch(ctx context.Context, path string, defaultIterations int, forceParallel bool, template *JSONRPCTester) error {
+	config, err := loadBatchConfig(path)
+	if err != nil {
+		return err
+	}
+	// Each job's output is its own; one recording, HAR or body capture
+	// would mix every tenant's requests.
+	if template.Recorder != nil || template.HAR != nil || template.Capture != nil {
+		return fmt.Errorf("-record, -har and -capture-bodies can't be combined with -batch")
+	}
+	parallel := config.Parallel || forceParallel
+
+	reports := make([]*JobReport, len(config.Jobs))
+	limit := 1
+	if parallel {
+		limit = len(config.Jobs)
+		if config.MaxParallel > 0 && config.MaxParallel < limit {
+			limit = config.MaxParallel
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, limit)
+	for i, job := range config.Jobs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, job BatchJob) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			reports[i] = runBatchJob(ctx, job, defaultIterations, template)
+		}(i, job)
+	}
+	wg.Wait()
+
+	index := BatchIndex{
+		GeneratedAt: time.Now().UTC(),
+		Parallel:    parallel,
+	}
+	for i, job := range config.Jobs {
+		jobReport := reports[i]
+		reportPath := job.reportPath(config.OutputDir)
+		if err := report.WriteJSONFile(reportPath, jobReport); err != nil {
+			return fmt.Errorf("write report for %s: %w", job.Name, err)
+		}
+
+		entry := BatchIndexEntry{
+			Job:      jobReport.Job,
+			Tenant:   jobReport.Tenant,
+			Endpoint: jobReport.Endpoint,
+			Report:   reportPath,
+			Success:  jobReport.Error == "",
+			Error:    jobReport.Error,
+		}
+		if jobReport.Stats != nil {
+			entry.SuccessRate = jobReport.Stats.SuccessRate
+			entry.AvgLatency = jobReport.Stats.Latency.Avg
+			entry.P50 = jobReport.Stats.Latency.P50
+			entry.P95 = jobReport.Stats.Latency.P95
+			entry.P99 = jobReport.Stats.Latency.P99
+			if b := jobReport.Stats.Breaker; b != nil {
+				entry.BreakerOpened, entry.Skipped = b.Opened, b.Skipped
+			}
+		}
+		index.Jobs = append(index.Jobs, entry)
+	}
+
+	indexPath := filepath.Join(config.OutputDir, "index.json")
+	if err := report.WriteJSONFile(indexPath, index); err != nil {
+		return err
+	}
+
+	logger := template.Log()
+	for _, entry := range index.Jobs {
+		name := entry.Job
+		if entry.Tenant != "" {
+			name = entry.Tenant + "/" + entry.Job
+		}
+		attrs := []interface{}{"job", name, "success_rate", entry.SuccessRate, "p95_ms", entry.P95, "report", entry.Report}
+		if entry.BreakerOpened > 0 {
+			attrs = append(attrs, "breaker_opened", entry.BreakerOpened, "skipped", entry.Skipped)
+		}
+		if !entry.Success {
+			logger.Warn("batch job failed", append(attrs, "error", entry.Error)...)
+			continue
+		}
+		logger.Info("batch job finished", attrs...)
+	}
+	logger.Info("batch complete", "jobs", len(index.Jobs), "index", indexPath)
+	return nil
+}
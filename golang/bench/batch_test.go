@@ -0,0 +1,120 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"solana-rpc-performance-golang/mockserver"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// writeBatchConfig writes config as a batch file in a directory of its own,
+// returning the file and the directory.
+func writeBatchConfig(t *testing.T, config BatchConfig) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if config.OutputDir == "" {
+		config.OutputDir = filepath.Join(dir, "reports")
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "batch.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, dir
+}
+
+func TestLoadBatchConfigPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		job  BatchJob
+		err  string
+	}{
+		{name: "tenant and name", job: BatchJob{Tenant: "payments", Name: "mainnet"}},
+		{name: "unsafe characters", job: BatchJob{Tenant: "a/../..", Name: "x/y"}},
+		{name: "dots within", job: BatchJob{Tenant: "team.one", Name: "v1..2"}},
+		{name: "tenant of dots", job: BatchJob{Tenant: "..", Name: "mainnet"}, err: "more than dots"},
+		{name: "tenant of one dot", job: BatchJob{Tenant: ".", Name: "mainnet"}, err: "more than dots"},
+		{name: "name of dots", job: BatchJob{Name: ".."}, err: "more than dots"},
+		{name: "output inside", job: BatchJob{Name: "mainnet", Output: "custom/mainnet.json"}},
+		{name: "output outside", job: BatchJob{Name: "mainnet", Output: "../mainnet.json"}, err: "outside"},
+		{name: "output absolute", job: BatchJob{Name: "mainnet", Output: "/etc/cron.d/bench"}, err: "outside"},
+		{name: "output the directory", job: BatchJob{Name: "mainnet", Output: "."}, err: "outside"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.job.Endpoint = "https://rpc.example.com"
+			dir := t.TempDir()
+			if tt.job.Output != "" && !filepath.IsAbs(tt.job.Output) {
+				tt.job.Output = filepath.Join(dir, "reports", tt.job.Output)
+			}
+			path, _ := writeBatchConfig(t, BatchConfig{OutputDir: filepath.Join(dir, "reports"), Jobs: []BatchJob{tt.job}})
+			config, err := loadBatchConfig(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report := config.Jobs[0].reportPath(config.OutputDir); !withinDir(config.OutputDir, report) {
+				t.Errorf("report %s is outside %s", report, config.OutputDir)
+			}
+		})
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	server := httptest.NewServer(mockserver.New(mockserver.Config{Seed: 1}))
+	t.Cleanup(server.Close)
+	path, dir := writeBatchConfig(t, BatchConfig{Parallel: true, Jobs: []BatchJob{
+		{Tenant: "payments", Name: "mainnet", Endpoint: server.URL, Iterations: 2},
+		{Tenant: "trading", Name: "mainnet", Endpoint: server.URL, Iterations: 3},
+	}})
+	template := newMockTester(t, mockserver.Config{Seed: 1}, "getSlot")
+	if err := RunBatch(context.Background(), path, 1, false, template); err != nil {
+		t.Fatal(err)
+	}
+	for tenant, requests := range map[string]int{"payments": 2, "trading": 3} {
+		var report JobReport
+		data, err := os.ReadFile(filepath.Join(dir, "reports", tenant, "mainnet.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Tenant != tenant || report.Stats == nil || report.Stats.TotalRequests != requests {
+			t.Errorf("%s: report %+v, want %d requests", tenant, report, requests)
+		}
+	}
+}
+
+func TestRunBatchRefusesSharedOutputs(t *testing.T) {
+	path, _ := writeBatchConfig(t, BatchConfig{Jobs: []BatchJob{{Name: "mainnet", Endpoint: "https://rpc.example.com"}}})
+	tests := []struct {
+		name string
+		set  func(*JSONRPCTester)
+	}{
+		{name: "har", set: func(s *JSONRPCTester) { s.HAR = &rpcclient.HARLog{} }},
+		{name: "recorder", set: func(s *JSONRPCTester) { s.Recorder = &rpcclient.Recorder{} }},
+		{name: "capture", set: func(s *JSONRPCTester) { s.Capture = &rpcclient.BodyCapture{} }},
+	}
+	for _, tt := range tests {
+		template := NewJSONRPCTester("https://rpc.example.com")
+		tt.set(template)
+		if err := RunBatch(context.Background(), path, 1, false, template); err == nil || !strings.Contains(err.Error(), "-batch") {
+			t.Errorf("%s: RunBatch = %v, want it refused", tt.name, err)
+		}
+	}
+}
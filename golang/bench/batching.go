@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BatchingSide summarizes one way of fetching a round's keys. Latency and
+// bytes are per round; for the individual side that is the sum over all of
+// the round's getAccountInfo calls.
+type BatchingSide struct {
+	Method         string       `json:"method"`
+	CallsPerRound  int          `json:"callsPerRound"`
+	Errors         int          `json:"errors"`
+	RoundLatency   LatencyStats `json:"roundLatency"`
+	CallLatency    LatencyStats `json:"callLatency"`
+	RoundBytes     LatencyStats `json:"roundBytes"`
+	TotalBytes     int64        `json:"totalBytes"`
+	TotalWallClock int64        `json:"totalWallClock"`
+}
+
+type BatchingStats struct {
+	Rounds       int          `json:"rounds"`
+	KeysPerRound int          `json:"keysPerRound"`
+	Individual   BatchingSide `json:"individual"`
+	Batched      BatchingSide `json:"batched"`
+	Speedup      float64      `json:"speedup"`
+	ByteRatio    float64      `json:"byteRatio"`
+	Interrupted  bool         `json:"interrupted,omitempty"`
+}
+
+// RunBatchingComparison fetches the same keys once with sequential
+// getAccountInfo calls and once with a single getMultipleAccounts call,
+// for the given number of rounds.
+func (s *JSONRPCTester) RunBatchingComparison(ctx context.Context, rounds int) (*BatchingStats, error) {
+	if s.Accounts == nil {
+		return nil, fmt.Errorf("batching comparison needs an account list (-accounts)")
+	}
+	keysPerRound := s.MultipleAccountsBatch
+	s.Log().Info("comparing getAccountInfo with getMultipleAccounts", "keys_per_round", keysPerRound, "rounds", rounds)
+
+	var (
+		indivRound, indivCall, indivBytes []int64
+		batchRound, batchBytes            []int64
+	)
+	stats := &BatchingStats{
+		KeysPerRound: keysPerRound,
+		Individual:   BatchingSide{Method: "getAccountInfo", CallsPerRound: keysPerRound},
+		Batched:      BatchingSide{Method: "getMultipleAccounts", CallsPerRound: 1},
+	}
+
+	for i := 0; i < rounds && !s.stopped(ctx); i++ {
+		stats.Rounds++
+		keys := s.Accounts.NextN(keysPerRound)
+
+		start := time.Now()
+		var roundLatency, roundBytes int64
+		failed := false
+		for _, key := range keys {
+			result, err := s.GetAccountInfo(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if !result.Success {
+				failed = true
+				continue
+			}
+			indivCall = append(indivCall, result.Latency)
+			roundLatency += result.Latency
+			roundBytes += int64(result.Size)
+		}
+		stats.Individual.TotalWallClock += time.Since(start).Milliseconds()
+		stats.Individual.TotalBytes += roundBytes
+		if failed {
+			stats.Individual.Errors++
+		} else {
+			indivRound = append(indivRound, roundLatency)
+			indivBytes = append(indivBytes, roundBytes)
+		}
+
+		start = time.Now()
+		result, err := s.GetMultipleAccounts(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		stats.Batched.TotalWallClock += time.Since(start).Milliseconds()
+		stats.Batched.TotalBytes += int64(result.Size)
+		if result.Success {
+			batchRound = append(batchRound, result.Latency)
+			batchBytes = append(batchBytes, int64(result.Size))
+		} else {
+			stats.Batched.Errors++
+		}
+
+		if (i+1)%10 == 0 {
+			s.Log().Info("rounds completed", "done", i+1, "total", rounds)
+		}
+	}
+
+	stats.Individual.RoundLatency = s.summarize(indivRound)
+	stats.Individual.CallLatency = s.summarize(indivCall)
+	stats.Individual.RoundBytes = s.summarize(indivBytes)
+	stats.Batched.RoundLatency = s.summarize(batchRound)
+	stats.Batched.CallLatency = s.summarize(append([]int64(nil), batchRound...))
+	stats.Batched.RoundBytes = s.summarize(batchBytes)
+
+	stats.Interrupted = s.stopped(ctx)
+	if stats.Batched.RoundLatency.Avg > 0 {
+		stats.Speedup = stats.Individual.RoundLatency.Avg / stats.Batched.RoundLatency.Avg
+	}
+	if stats.Batched.RoundBytes.Avg > 0 {
+		stats.ByteRatio = stats.Individual.RoundBytes.Avg / stats.Batched.RoundBytes.Avg
+	}
+	return stats, nil
+}
@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/mockserver"
+)
+
+// newMockTester starts a mock server with cfg and returns a tester for it
+// that sends methods and logs nothing.
+func newMockTester(t *testing.T, cfg mockserver.Config, methods ...string) *JSONRPCTester {
+	t.Helper()
+	server := httptest.NewServer(mockserver.New(cfg))
+	t.Cleanup(server.Close)
+	tester := NewJSONRPCTester(server.URL)
+	tester.Methods = methods
+	tester.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return tester
+}
+
+func TestRunBenchmarkAgainstMock(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         mockserver.Config
+		iterations  int
+		successRate float64
+		minP50      int64
+		check       func(t *testing.T, stats *BenchmarkStats)
+	}{
+		{
+			name:        "healthy",
+			cfg:         mockserver.Config{Seed: 1},
+			iterations:  10,
+			successRate: 100,
+		},
+		{
+			name:        "slow",
+			cfg:         mockserver.Config{Latency: 20 * time.Millisecond, Seed: 1},
+			iterations:  5,
+			successRate: 100,
+			minP50:      20,
+		},
+		{
+			name:        "failing",
+			cfg:         mockserver.Config{ErrorRate: 1, Seed: 1},
+			iterations:  5,
+			successRate: 0,
+		},
+		{
+			name:       "rate limited",
+			cfg:        mockserver.Config{RateLimit: 1, Burst: 4, Seed: 1},
+			iterations: 5,
+			// The burst covers the first four requests; the rest come
+			// faster than one a second.
+			successRate: 40,
+			check: func(t *testing.T, stats *BenchmarkStats) {
+				if stats.Quota == nil {
+					t.Fatal("no quota stats from a rate-limited endpoint")
+				}
+				if stats.Quota.RateLimited != 6 || stats.Quota.Limit != 1 || stats.Quota.MinRemaining != 0 {
+					t.Errorf("got quota %+v, want 6 rate limited, limit 1 and none left", *stats.Quota)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := newMockTester(t, tt.cfg, "getSlot", "getBlockHeight")
+			stats, err := tester.RunBenchmark(context.Background(), tt.iterations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.iterations * 2; stats.TotalRequests != want {
+				t.Errorf("got %d requests, want %d", stats.TotalRequests, want)
+			}
+			if stats.SuccessRate != tt.successRate {
+				t.Errorf("got success rate %v, want %v", stats.SuccessRate, tt.successRate)
+			}
+			if stats.SuccessfulRequests+stats.FailedRequests != stats.TotalRequests {
+				t.Errorf("%d successes and %d failures don't add up to %d requests",
+					stats.SuccessfulRequests, stats.FailedRequests, stats.TotalRequests)
+			}
+			if stats.Latency.P50 < tt.minP50 {
+				t.Errorf("got p50 %dms, want at least %dms", stats.Latency.P50, tt.minP50)
+			}
+			if tt.successRate > 0 && (stats.Latency.Min > stats.Latency.P50 || stats.Latency.P50 > stats.Latency.P99 || stats.Latency.P99 > stats.Latency.Max) {
+				t.Errorf("latency percentiles out of order: %+v", stats.Latency)
+			}
+			if tt.check != nil {
+				tt.check(t, stats)
+			}
+		})
+	}
+}
+
+func TestRunOpenLoopAgainstMock(t *testing.T) {
+	tester := newMockTester(t, mockserver.Config{Latency: 5 * time.Millisecond, Seed: 1}, "getSlot")
+	tester.Percentiles = []float64{99.9}
+	stats, err := tester.RunOpenLoop(context.Background(), LoadConfig{Rate: 200, Duration: 500 * time.Millisecond, Concurrency: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalRequests != 100 || stats.SuccessRate != 100 {
+		t.Errorf("got %d requests at %v%% success, want 100 at 100%%", stats.TotalRequests, stats.SuccessRate)
+	}
+	if stats.CorrectedLatency.P50 < stats.Latency.P50 {
+		t.Errorf("corrected p50 %dms is below the service time p50 %dms", stats.CorrectedLatency.P50, stats.Latency.P50)
+	}
+	for name, latency := range map[string]LatencyStats{"latency": stats.Latency, "corrected": stats.CorrectedLatency, "lag": stats.SchedulingLag} {
+		if _, ok := latency.Percentiles["p99.9"]; !ok {
+			t.Errorf("%s percentiles %v lack the tester's p99.9", name, latency.Percentiles)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+type BlockhashProbe struct {
+	Blockhash            string    `json:"blockhash"`
+	Slot                 uint64    `json:"slot"`
+	LastValidBlockHeight uint64    `json:"lastValidBlockHeight"`
+	FetchedAt            time.Time `json:"fetchedAt"`
+	FetchLatency         int64     `json:"fetchLatency"`
+	Checks               int       `json:"checks"`
+	CheckErrors          int       `json:"checkErrors"`
+	Expired              bool      `json:"expired"`
+	ValidFor             int64     `json:"validFor,omitempty"`
+}
+
+type BlockhashStats struct {
+	Probes       int              `json:"probes"`
+	Expired      int              `json:"expired"`
+	StillValid   int              `json:"stillValid"`
+	FetchErrors  int              `json:"fetchErrors"`
+	Commitment   string           `json:"commitment"`
+	FetchLatency LatencyStats     `json:"fetchLatency"`
+	CheckLatency LatencyStats     `json:"checkLatency"`
+	ValidFor     LatencyStats     `json:"validFor"`
+	Interrupted  bool             `json:"interrupted,omitempty"`
+	Samples      []BlockhashProbe `json:"samples"`
+}
+
+type BlockhashProbeConfig struct {
+	Probes        int
+	Interval      time.Duration
+	CheckInterval time.Duration
+	Timeout       time.Duration
+	Commitment    string
+}
+
+// RunBlockhashProbe fetches a blockhash every cfg.Interval and polls
+// isBlockhashValid on each until the endpoint reports it expired, measuring
+// how long the endpoint considers a fresh blockhash usable.
+func (s *JSONRPCTester) RunBlockhashProbe(ctx context.Context, cfg BlockhashProbeConfig) (*BlockhashStats, error) {
+	s.Log().Info("probing blockhash validity", "probes", cfg.Probes, "commitment", cfg.Commitment)
+
+	var (
+		mu             sync.Mutex
+		wg             sync.WaitGroup
+		probes         []*BlockhashProbe
+		fetchLatencies []int64
+		checkLatencies []int64
+		fetchErrors    int
+	)
+
+	fetched := 0
+	for i := 0; i < cfg.Probes; i++ {
+		if i > 0 {
+			s.sleep(ctx, cfg.Interval)
+		}
+		if s.stopped(ctx) {
+			break
+		}
+		fetched++
+
+		result, err := s.GetLatestBlockhash(ctx, cfg.Commitment)
+		if err != nil {
+			return nil, err
+		}
+		var out latestBlockhash
+		if err := rpcclient.DecodeResult(result, &out); err != nil {
+			fetchErrors++
+			continue
+		}
+		fetchLatencies = append(fetchLatencies, result.Latency)
+
+		probe := &BlockhashProbe{
+			Blockhash:            out.Value.Blockhash,
+			Slot:                 out.Context.Slot,
+			LastValidBlockHeight: out.Value.LastValidBlockHeight,
+			FetchedAt:            time.Now(),
+			FetchLatency:         result.Latency,
+		}
+		probes = append(probes, probe)
+
+		wg.Add(1)
+		go func(probe *BlockhashProbe) {
+			defer wg.Done()
+			for time.Since(probe.FetchedAt) < cfg.Timeout {
+				s.sleep(ctx, cfg.CheckInterval)
+				if s.stopped(ctx) {
+					return
+				}
+
+				var valid struct {
+					Value bool `json:"value"`
+				}
+				result, err := s.IsBlockhashValid(ctx, probe.Blockhash, cfg.Commitment)
+				if err == nil {
+					err = rpcclient.DecodeResult(result, &valid)
+				}
+
+				mu.Lock()
+				probe.Checks++
+				if err != nil {
+					probe.CheckErrors++
+					mu.Unlock()
+					continue
+				}
+				checkLatencies = append(checkLatencies, result.Latency)
+				if !valid.Value {
+					probe.Expired = true
+					probe.ValidFor = time.Since(probe.FetchedAt).Milliseconds()
+					mu.Unlock()
+					return
+				}
+				mu.Unlock()
+			}
+		}(probe)
+
+		if (i+1)%10 == 0 {
+			s.Log().Info("blockhashes fetched", "done", i+1, "total", cfg.Probes)
+		}
+	}
+	s.Log().Info("waiting for blockhashes to expire", "timeout", cfg.Timeout)
+	wg.Wait()
+
+	if len(probes) == 0 {
+		return nil, fmt.Errorf("no blockhash could be fetched (%d errors)", fetchErrors)
+	}
+
+	stats := &BlockhashStats{
+		Probes:       fetched,
+		FetchErrors:  fetchErrors,
+		Commitment:   cfg.Commitment,
+		FetchLatency: s.summarize(fetchLatencies),
+		CheckLatency: s.summarize(checkLatencies),
+	}
+	var validFor []int64
+	for _, probe := range probes {
+		stats.Samples = append(stats.Samples, *probe)
+		if probe.Expired {
+			stats.Expired++
+			validFor = append(validFor, probe.ValidFor)
+		} else {
+			stats.StillValid++
+		}
+	}
+	stats.ValidFor = s.summarize(validFor)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
@@ -0,0 +1,238 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// blockWindow is how many slots each getBlocks call lists.
+const blockWindow = 100
+
+// BlockCompleteness covers the blocks checked when VerifyBlocks is set.
+// A block is incomplete if it fails any check: TransactionMismatch
+// counts blocks whose transactions don't match the block's signature
+// list, fetched separately; MissingMeta blocks with a transaction lacking
+// its status meta; MissingRewards and MissingBlockTime blocks without
+// those fields. Links counts the continuity checks made, of a block's
+// parentSlot against the block getBlocks listed before it and of a
+// parent's blockhash against its child's previousBlockhash, and
+// BrokenLinks those that failed. Unverified counts blocks whose signature
+// list couldn't be fetched.
+type BlockCompleteness struct {
+	Blocks              int      `json:"blocks"`
+	Incomplete          int      `json:"incomplete"`
+	TransactionMismatch int      `json:"transactionMismatch"`
+	MissingMeta         int      `json:"missingMeta"`
+	MissingRewards      int      `json:"missingRewards"`
+	MissingBlockTime    int      `json:"missingBlockTime"`
+	Links               int      `json:"links"`
+	BrokenLinks         int      `json:"brokenLinks"`
+	Unverified          int      `json:"unverified,omitempty"`
+	ErrorSamples        []string `json:"errorSamples,omitempty"`
+}
+
+// blockSlot is a slot handed out for getBlock and the block before it,
+// as getBlocks listed them; parent is 0 when unknown.
+type blockSlot struct {
+	slot, parent uint64
+}
+
+// blockSource hands the getBlock method the confirmed blocks getBlocks
+// lists, walking back from just behind the tip so each request asks for
+// a different block and none is a skipped slot. It also holds what
+// VerifyBlocks has found. It is shared by every copy of the tester.
+type blockSource struct {
+	mu      sync.Mutex
+	pending []blockSlot
+	// next is the last slot of the next getBlocks range, 0 before the
+	// first.
+	next uint64
+	// wantHash maps a block's parent slot to the previousBlockhash it
+	// gave, until the parent is fetched.
+	wantHash map[uint64]string
+	stats    BlockCompleteness
+}
+
+// slot returns the next block to fetch, listing more when it runs out.
+func (b *blockSource) slot(ctx context.Context, s *JSONRPCTester) (blockSlot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.pending) == 0 {
+		if b.next == 0 {
+			var tip uint64
+			if _, err := s.CallResult(ctx, "getSlot", nil, &tip); err != nil {
+				return blockSlot{}, fmt.Errorf("prefetch slot: %w", err)
+			}
+			if tip <= recentSlotRange+blockWindow {
+				return blockSlot{}, fmt.Errorf("prefetch slot: slot %d is too close to genesis", tip)
+			}
+			b.next = tip - recentSlotRange
+		}
+		if b.next < blockWindow {
+			return blockSlot{}, fmt.Errorf("prefetch blocks: reached genesis")
+		}
+		start := b.next - blockWindow + 1
+		var slots []uint64
+		result, err := s.GetBlocks(ctx, start, b.next)
+		if err == nil {
+			err = rpcclient.DecodeResult(result, &slots)
+		}
+		if err != nil {
+			return blockSlot{}, fmt.Errorf("prefetch blocks %d-%d: %w", start, b.next, err)
+		}
+		b.next = start - 1
+		// Handed out newest first, each with the block listed before it.
+		for i := len(slots) - 1; i >= 0; i-- {
+			block := blockSlot{slot: slots[i]}
+			if i > 0 {
+				block.parent = slots[i-1]
+			}
+			b.pending = append(b.pending, block)
+		}
+	}
+	block := b.pending[0]
+	b.pending = b.pending[1:]
+	return block, nil
+}
+
+// blockConfig asks for a whole block, as an indexer would.
+func blockConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"encoding":                       "json",
+		"transactionDetails":             "full",
+		"rewards":                        true,
+		"maxSupportedTransactionVersion": 0,
+		"commitment":                     "finalized",
+	}
+}
+
+type fullBlock struct {
+	Blockhash         string             `json:"blockhash"`
+	PreviousBlockhash string             `json:"previousBlockhash"`
+	ParentSlot        uint64             `json:"parentSlot"`
+	BlockTime         *int64             `json:"blockTime"`
+	Rewards           *[]json.RawMessage `json:"rewards"`
+	Transactions      []struct {
+		Meta        json.RawMessage `json:"meta"`
+		Transaction struct {
+			Signatures []string `json:"signatures"`
+		} `json:"transaction"`
+	} `json:"transactions"`
+}
+
+// verify checks a fetched block for signs of truncation. The signature
+// list it is compared with is fetched here, untimed, so verifying doubles
+// the getBlock calls the endpoint sees.
+func (b *blockSource) verify(ctx context.Context, s *JSONRPCTester, block blockSlot, result *rpcclient.Result) {
+	var full fullBlock
+	if err := rpcclient.DecodeResult(result, &full); err != nil {
+		return
+	}
+	var problems []string
+	var listed struct {
+		Signatures []string `json:"signatures"`
+	}
+	unverified := false
+	listResult, err := s.GetBlock(ctx, block.slot, blockSignaturesConfig())
+	if err == nil {
+		err = rpcclient.DecodeResult(listResult, &listed)
+	}
+	if err != nil {
+		unverified = true
+		problems = append(problems, fmt.Sprintf("signature list: %v", err))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := &b.stats
+	stats.Blocks++
+	incomplete := false
+	flag := func(counter *int, msg string, args ...interface{}) {
+		*counter++
+		incomplete = true
+		problems = append(problems, fmt.Sprintf(msg, args...))
+	}
+	if unverified {
+		stats.Unverified++
+	} else if n := len(full.Transactions); n != len(listed.Signatures) {
+		flag(&stats.TransactionMismatch, "%d transactions but %d signatures", n, len(listed.Signatures))
+	} else {
+		for i, tx := range full.Transactions {
+			if len(tx.Transaction.Signatures) == 0 || tx.Transaction.Signatures[0] != listed.Signatures[i] {
+				flag(&stats.TransactionMismatch, "transaction %d doesn't match signature list", i)
+				break
+			}
+		}
+	}
+	for _, tx := range full.Transactions {
+		if len(tx.Meta) == 0 || string(tx.Meta) == "null" {
+			flag(&stats.MissingMeta, "transaction without meta")
+			break
+		}
+	}
+	if full.Rewards == nil {
+		flag(&stats.MissingRewards, "no rewards")
+	}
+	if full.BlockTime == nil {
+		flag(&stats.MissingBlockTime, "no blockTime")
+	}
+
+	// The block's parent is checked twice: against the block getBlocks
+	// listed before it, and, once the parent is fetched, against its
+	// blockhash.
+	if block.parent != 0 {
+		stats.Links++
+		if full.ParentSlot != block.parent {
+			flag(&stats.BrokenLinks, "parentSlot %d, want %d", full.ParentSlot, block.parent)
+		}
+	}
+	if b.wantHash == nil {
+		b.wantHash = map[uint64]string{}
+	}
+	if want, ok := b.wantHash[block.slot]; ok {
+		delete(b.wantHash, block.slot)
+		stats.Links++
+		if full.Blockhash != want {
+			flag(&stats.BrokenLinks, "blockhash %s, but its child's previousBlockhash is %s", full.Blockhash, want)
+		}
+	}
+	b.wantHash[full.ParentSlot] = full.PreviousBlockhash
+
+	if incomplete {
+		stats.Incomplete++
+	}
+	for _, problem := range problems {
+		stats.addErrorSample(fmt.Sprintf("slot %d: %s", block.slot, problem))
+	}
+	if len(problems) > 0 {
+		s.Log().Debug("incomplete block", "slot", block.slot, "problems", problems)
+	}
+}
+
+// take returns what VerifyBlocks found since the last call, or nil if
+// no blocks were checked.
+func (b *blockSource) take() *BlockCompleteness {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stats.Blocks == 0 {
+		return nil
+	}
+	stats := b.stats
+	b.stats = BlockCompleteness{}
+	return &stats
+}
+
+func (stats *BlockCompleteness) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
@@ -0,0 +1,297 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerConfig sets when a run stops sending requests to a failing
+// endpoint. The breaker opens once more than ErrorRate percent of the
+// last Window requests failed, and stays open for Cooldown. It then lets
+// one trial request through, half-open: if that succeeds the breaker
+// closes, and if it fails the breaker opens again. Requests the method
+// isn't served for don't count as failures; rate-limited ones do.
+type BreakerConfig struct {
+	ErrorRate float64
+	Window    int
+	Cooldown  time.Duration
+}
+
+// enabled reports whether c asks for a breaker.
+func (c BreakerConfig) enabled() bool {
+	return c.ErrorRate > 0
+}
+
+// ParseBreaker checks a circuit breaker's settings from the command line;
+// an errorRate of zero disables the breaker.
+func ParseBreaker(errorRate float64, window int, cooldown time.Duration) (BreakerConfig, error) {
+	cfg := BreakerConfig{ErrorRate: errorRate, Window: window, Cooldown: cooldown}
+	switch {
+	case errorRate < 0 || errorRate >= 100:
+		return cfg, fmt.Errorf("breaker error rate must be from 0 up to 100")
+	case errorRate > 0 && window <= 0:
+		return cfg, fmt.Errorf("breaker window must be positive")
+	case errorRate > 0 && cooldown <= 0:
+		return cfg, fmt.Errorf("breaker cooldown must be positive")
+	}
+	return cfg, nil
+}
+
+// BreakerTransition is a change of the breaker's state, AtMs into the
+// run. ErrorRate is the failure percentage over the window that opened
+// it, when that is what did.
+type BreakerTransition struct {
+	AtMs      int64   `json:"atMs"`
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Reason    string  `json:"reason"`
+	ErrorRate float64 `json:"errorRate,omitempty"`
+}
+
+// BreakerStats is what the circuit breaker did during a run, including
+// what other runs against the endpoint, such as batch jobs, made the
+// breaker they share do. Skipped counts the requests an open-loop run
+// didn't send because it was open, where a closed-loop run waits instead,
+// and OpenMs how long it was open in all. State is its state when the run
+// ended.
+type BreakerStats struct {
+	State       string              `json:"state"`
+	Opened      int                 `json:"opened"`
+	Skipped     int                 `json:"skipped"`
+	OpenMs      int64               `json:"openMs"`
+	Transitions []BreakerTransition `json:"transitions,omitempty"`
+}
+
+// breakerPoll is how often a closed-loop run checks on a half-open
+// breaker whose trial request another run sent.
+const breakerPoll = 50 * time.Millisecond
+
+// breaker is the circuit breaker of an endpoint, shared by every run
+// against it.
+type breaker struct {
+	cfg    BreakerConfig
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// recent holds whether each of the last Window requests failed, as a
+	// ring starting at next.
+	recent   []bool
+	next     int
+	failures int
+	state    string
+	openedAt time.Time
+	// trial is set while the half-open trial request is in flight.
+	trial bool
+	// events are the breaker's transitions, for the runs that saw them.
+	events []breakerEvent
+}
+
+type breakerEvent struct {
+	at         time.Time
+	transition BreakerTransition
+}
+
+// breakerSet holds the breakers of the endpoints a tester and the testers
+// copied from it run against, so that batch jobs and both sides of a
+// comparison hitting one endpoint share its breaker.
+type breakerSet struct {
+	mu         sync.Mutex
+	byEndpoint map[string]*breaker
+}
+
+// breakerRun is one run's use of its endpoint's breaker.
+type breakerRun struct {
+	b     *breaker
+	start time.Time
+	// skipped counts the requests the run didn't send; b.mu guards it.
+	skipped int
+}
+
+// newBreaker returns the run that started at start's use of the breaker
+// of s's endpoint, or nil if the tester has none.
+func (s *JSONRPCTester) newBreaker(start time.Time) *breakerRun {
+	if !s.Breaker.enabled() {
+		return nil
+	}
+	create := func() *breaker {
+		return &breaker{
+			cfg:    s.Breaker,
+			logger: s.Log().With("endpoint", rpcclient.RedactURL(s.Endpoint)),
+			recent: make([]bool, 0, s.Breaker.Window),
+			state:  BreakerClosed,
+		}
+	}
+	if s.breakers == nil {
+		return &breakerRun{b: create(), start: start}
+	}
+	s.breakers.mu.Lock()
+	defer s.breakers.mu.Unlock()
+	b := s.breakers.byEndpoint[s.Endpoint]
+	if b == nil {
+		b = create()
+		if s.breakers.byEndpoint == nil {
+			s.breakers.byEndpoint = make(map[string]*breaker)
+		}
+		s.breakers.byEndpoint[s.Endpoint] = b
+	}
+	return &breakerRun{b: b, start: start}
+}
+
+// allow reports whether a request may be sent now, counting it skipped if
+// not. A nil run always allows it.
+func (r *breakerRun) allow(now time.Time) bool {
+	if r == nil {
+		return true
+	}
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	if r.b.admit(now) > 0 {
+		r.skipped++
+		return false
+	}
+	return true
+}
+
+// wait returns 0 if a request may be sent now, and otherwise how long to
+// wait before asking again. A nil run never waits.
+func (r *breakerRun) wait(now time.Time) time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	return r.b.admit(now)
+}
+
+// admit lets a request through now, returning 0, or returns how long until
+// one may be: the rest of the cooldown, or while another request is the
+// half-open trial, a while. b.mu is held.
+func (b *breaker) admit(now time.Time) time.Duration {
+	switch b.state {
+	case BreakerOpen:
+		if left := b.cfg.Cooldown - now.Sub(b.openedAt); left > 0 {
+			return left
+		}
+		b.transition(now, BreakerHalfOpen, "cooldown over, sending a trial request", 0)
+		b.trial = true
+	case BreakerHalfOpen:
+		if b.trial {
+			return min(breakerPoll, b.cfg.Cooldown)
+		}
+		b.trial = true
+	}
+	return 0
+}
+
+// record counts result, which completed at now.
+func (r *breakerRun) record(now time.Time, result *rpcclient.Result) {
+	if r == nil {
+		return
+	}
+	failed := !result.Success && !result.Unsupported
+	b := r.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.trial = false
+		if failed {
+			b.open(now, "trial request failed", 0)
+			return
+		}
+		b.transition(now, BreakerClosed, "trial request succeeded", 0)
+		b.recent, b.next, b.failures = b.recent[:0], 0, 0
+	case BreakerClosed:
+		if len(b.recent) < b.cfg.Window {
+			b.recent = append(b.recent, failed)
+		} else {
+			if b.recent[b.next] {
+				b.failures--
+			}
+			b.recent[b.next] = failed
+			b.next = (b.next + 1) % b.cfg.Window
+		}
+		if failed {
+			b.failures++
+		}
+		if len(b.recent) == b.cfg.Window {
+			if rate := float64(b.failures) / float64(b.cfg.Window) * 100; rate > b.cfg.ErrorRate {
+				b.open(now, fmt.Sprintf("%d of the last %d requests failed", b.failures, b.cfg.Window), rate)
+			}
+		}
+	}
+	// Requests sent before the breaker opened may still complete while it
+	// is open; they change nothing.
+}
+
+// open opens the breaker; b.mu is held.
+func (b *breaker) open(now time.Time, reason string, errorRate float64) {
+	b.transition(now, BreakerOpen, reason, errorRate)
+	b.openedAt = now
+}
+
+// transition moves the breaker to state; b.mu is held.
+func (b *breaker) transition(now time.Time, state, reason string, errorRate float64) {
+	b.events = append(b.events, breakerEvent{at: now, transition: BreakerTransition{
+		From:      b.state,
+		To:        state,
+		Reason:    reason,
+		ErrorRate: errorRate,
+	}})
+	level := slog.LevelInfo
+	if state == BreakerOpen {
+		level = slog.LevelWarn
+	}
+	b.logger.Log(context.Background(), level, "circuit breaker "+state, "reason", reason, "cooldown", b.cfg.Cooldown)
+	b.state = state
+}
+
+// finish returns what the breaker did during a run that ended at now,
+// including what other runs sharing it made it do, or nil for a nil run.
+func (r *breakerRun) finish(now time.Time) *BreakerStats {
+	if r == nil {
+		return nil
+	}
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	stats := &BreakerStats{State: r.b.state, Skipped: r.skipped}
+	// openFrom is when the breaker last opened, from the run's point of
+	// view, while it is open.
+	var openFrom time.Time
+	for _, event := range r.b.events {
+		if event.at.Before(r.start) {
+			openFrom = time.Time{}
+			if event.transition.To == BreakerOpen {
+				openFrom = r.start
+			}
+			continue
+		}
+		if !openFrom.IsZero() {
+			stats.OpenMs += event.at.Sub(openFrom).Milliseconds()
+			openFrom = time.Time{}
+		}
+		if event.transition.To == BreakerOpen {
+			stats.Opened++
+			openFrom = event.at
+		}
+		transition := event.transition
+		transition.AtMs = event.at.Sub(r.start).Milliseconds()
+		stats.Transitions = append(stats.Transitions, transition)
+	}
+	if !openFrom.IsZero() {
+		stats.OpenMs += now.Sub(openFrom).Milliseconds()
+	}
+	return stats
+}
@@ -0,0 +1,207 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/mockserver"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// breakerStep is something that happens to a breaker, at into the run: a
+// request completes, succeeding, failing or unsupported, or one asks to
+// be sent, and is allowed or not.
+type breakerStep struct {
+	at      time.Duration
+	event   string
+	allowed bool
+	state   string
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	cfg := BreakerConfig{ErrorRate: 50, Window: 4, Cooldown: 10 * time.Second}
+	tests := []struct {
+		name  string
+		steps []breakerStep
+		want  BreakerStats
+	}{
+		{
+			name: "at the error rate stays closed",
+			steps: []breakerStep{
+				{event: "fail", state: BreakerClosed},
+				{event: "fail", state: BreakerClosed},
+				{event: "ok", state: BreakerClosed},
+				{event: "ok", state: BreakerClosed},
+				{event: "allow", allowed: true, state: BreakerClosed},
+			},
+			want: BreakerStats{State: BreakerClosed},
+		},
+		{
+			name: "unsupported methods don't count",
+			steps: []breakerStep{
+				{event: "unsupported"}, {event: "unsupported"}, {event: "unsupported"},
+				{event: "fail", state: BreakerClosed},
+			},
+			want: BreakerStats{State: BreakerClosed},
+		},
+		{
+			name: "opens above the error rate, over a sliding window",
+			steps: []breakerStep{
+				{event: "fail"}, {event: "ok"}, {event: "ok"},
+				{event: "fail", state: BreakerClosed},
+				// The first failure slides out as this one comes in.
+				{event: "fail", state: BreakerClosed},
+				{at: 2 * time.Second, event: "fail", state: BreakerOpen},
+				{at: 5 * time.Second, event: "allow", allowed: false, state: BreakerOpen},
+				// Late results change nothing while it is open.
+				{at: 6 * time.Second, event: "ok", state: BreakerOpen},
+			},
+			want: BreakerStats{State: BreakerOpen, Opened: 1, Skipped: 1, OpenMs: 4000, Transitions: []BreakerTransition{
+				{AtMs: 2000, From: BreakerClosed, To: BreakerOpen, Reason: "3 of the last 4 requests failed", ErrorRate: 75},
+			}},
+		},
+		{
+			name: "a trial that succeeds closes it",
+			steps: []breakerStep{
+				{event: "fail"}, {event: "fail"}, {event: "fail"}, {event: "fail", state: BreakerOpen},
+				{at: 9 * time.Second, event: "allow", allowed: false, state: BreakerOpen},
+				{at: 10 * time.Second, event: "allow", allowed: true, state: BreakerHalfOpen},
+				{at: 10 * time.Second, event: "allow", allowed: false, state: BreakerHalfOpen},
+				{at: 11 * time.Second, event: "ok", state: BreakerClosed},
+				// The window starts over.
+				{at: 12 * time.Second, event: "fail", state: BreakerClosed},
+				{at: 12 * time.Second, event: "allow", allowed: true, state: BreakerClosed},
+			},
+			want: BreakerStats{State: BreakerClosed, Opened: 1, Skipped: 2, OpenMs: 10000, Transitions: []BreakerTransition{
+				{AtMs: 0, From: BreakerClosed, To: BreakerOpen, Reason: "4 of the last 4 requests failed", ErrorRate: 100},
+				{AtMs: 10000, From: BreakerOpen, To: BreakerHalfOpen, Reason: "cooldown over, sending a trial request"},
+				{AtMs: 11000, From: BreakerHalfOpen, To: BreakerClosed, Reason: "trial request succeeded"},
+			}},
+		},
+		{
+			name: "a trial that fails opens it again",
+			steps: []breakerStep{
+				{event: "fail"}, {event: "fail"}, {event: "fail"}, {event: "fail", state: BreakerOpen},
+				{at: 10 * time.Second, event: "allow", allowed: true, state: BreakerHalfOpen},
+				{at: 11 * time.Second, event: "fail", state: BreakerOpen},
+				// The cooldown counts from the trial.
+				{at: 20 * time.Second, event: "allow", allowed: false, state: BreakerOpen},
+				{at: 21 * time.Second, event: "allow", allowed: true, state: BreakerHalfOpen},
+			},
+			want: BreakerStats{State: BreakerHalfOpen, Opened: 2, Skipped: 1, OpenMs: 20000, Transitions: []BreakerTransition{
+				{AtMs: 0, From: BreakerClosed, To: BreakerOpen, Reason: "4 of the last 4 requests failed", ErrorRate: 100},
+				{AtMs: 10000, From: BreakerOpen, To: BreakerHalfOpen, Reason: "cooldown over, sending a trial request"},
+				{AtMs: 11000, From: BreakerHalfOpen, To: BreakerOpen, Reason: "trial request failed"},
+				{AtMs: 21000, From: BreakerOpen, To: BreakerHalfOpen, Reason: "cooldown over, sending a trial request"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := NewJSONRPCTester("http://127.0.0.1:1")
+			tester.Breaker = cfg
+			tester.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+			start := time.Now()
+			run := tester.newBreaker(start)
+			for i, step := range tt.steps {
+				now := start.Add(step.at)
+				switch step.event {
+				case "allow":
+					if got := run.allow(now); got != step.allowed {
+						t.Fatalf("step %d: allow = %v, want %v", i, got, step.allowed)
+					}
+				case "ok":
+					run.record(now, &rpcclient.Result{Success: true})
+				case "fail":
+					run.record(now, &rpcclient.Result{})
+				case "unsupported":
+					run.record(now, &rpcclient.Result{Unsupported: true})
+				}
+				if step.state != "" && run.b.state != step.state {
+					t.Fatalf("step %d: state %s, want %s", i, run.b.state, step.state)
+				}
+			}
+			end := start.Add(tt.steps[len(tt.steps)-1].at)
+			if got := run.finish(end); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerSharedByEndpoint(t *testing.T) {
+	tester := NewJSONRPCTester("http://127.0.0.1:1")
+	tester.Breaker = BreakerConfig{ErrorRate: 50, Window: 2, Cooldown: time.Minute}
+	tester.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	start := time.Now()
+	first := tester.newBreaker(start)
+	same := tester.withEndpoint(tester.Endpoint).newBreaker(start)
+	other := tester.withEndpoint("http://127.0.0.1:2").newBreaker(start)
+	if first.b != same.b {
+		t.Error("runs against the same endpoint have breakers of their own")
+	}
+	if first.b == other.b {
+		t.Error("runs against different endpoints share a breaker")
+	}
+
+	first.record(start, &rpcclient.Result{})
+	first.record(start.Add(time.Second), &rpcclient.Result{})
+	if same.allow(start.Add(2 * time.Second)) {
+		t.Error("a run got past the breaker another run against its endpoint opened")
+	}
+	if !other.allow(start.Add(2 * time.Second)) {
+		t.Error("a breaker opened for another endpoint")
+	}
+
+	// A run starting while the breaker is open sees it open from its
+	// start, but didn't open it.
+	later := tester.newBreaker(start.Add(30 * time.Second))
+	want := BreakerStats{State: BreakerOpen, OpenMs: 10000}
+	if got := later.finish(start.Add(40 * time.Second)); !reflect.DeepEqual(*got, want) {
+		t.Errorf("later run's stats = %+v, want %+v", *got, want)
+	}
+	if got := same.finish(start.Add(40 * time.Second)); got.Opened != 1 || got.Skipped != 1 || got.OpenMs != 39000 {
+		t.Errorf("sharing run's stats = %+v, want it open once for 39s, skipping one request", *got)
+	}
+}
+
+func TestRunBenchmarkWaitsOutBreakerCooldown(t *testing.T) {
+	const cooldown = 100 * time.Millisecond
+	tester := newMockTester(t, mockserver.Config{Seed: 1, ErrorRate: 1}, "getSlot")
+	tester.Breaker = BreakerConfig{ErrorRate: 50, Window: 2, Cooldown: cooldown}
+	start := time.Now()
+	stats, err := tester.RunBenchmark(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two failures open it; each of the other two requests is a trial
+	// sent once a cooldown is over, and fails.
+	if stats.TotalRequests != 4 || stats.Breaker.Skipped != 0 {
+		t.Errorf("sent %d requests, skipped %d; want every request sent", stats.TotalRequests, stats.Breaker.Skipped)
+	}
+	if stats.Breaker.Opened != 3 {
+		t.Errorf("opened %d times, want 3", stats.Breaker.Opened)
+	}
+	if elapsed := time.Since(start); elapsed < 2*cooldown {
+		t.Errorf("ran in %v, less than the two cooldowns it had to wait out", elapsed)
+	}
+}
+
+func TestRunBenchmarkBreakerStopsWithContext(t *testing.T) {
+	tester := newMockTester(t, mockserver.Config{Seed: 1, ErrorRate: 1}, "getSlot")
+	tester.Breaker = BreakerConfig{ErrorRate: 50, Window: 2, Cooldown: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	stats, err := tester.RunBenchmark(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalRequests != 2 || !stats.Interrupted || stats.Breaker.State != BreakerOpen {
+		t.Errorf("sent %d requests, interrupted %v, breaker %s; want 2, interrupted waiting on the open breaker",
+			stats.TotalRequests, stats.Interrupted, stats.Breaker.State)
+	}
+}
@@ -0,0 +1,185 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// CapacityConfig sets the service level a rate must meet and the bounds
+// of the search for the highest such rate.
+type CapacityConfig struct {
+	// TargetP99 is the highest acceptable corrected p99 latency and
+	// MaxErrorRate the highest acceptable error percentage.
+	TargetP99    time.Duration
+	MaxErrorRate float64
+
+	MinRate float64
+	MaxRate float64
+	// ProbeDuration is how long each candidate rate is held.
+	ProbeDuration time.Duration
+	// Precision ends the search once the passing and failing rates are
+	// within this fraction of each other.
+	Precision float64
+
+	Concurrency int
+	Arrival     string
+}
+
+// CapacityProbe is the outcome of holding one rate.
+type CapacityProbe struct {
+	Rate         float64 `json:"rate"`
+	Passed       bool    `json:"passed"`
+	Reason       string  `json:"reason,omitempty"`
+	AchievedRate float64 `json:"achievedRate"`
+	P99          int64   `json:"p99"`
+	ErrorRate    float64 `json:"errorRate"`
+}
+
+type CapacityStats struct {
+	// CapacityRPS is the highest probed rate that met the targets, or
+	// zero if none did.
+	CapacityRPS    float64         `json:"capacityRps"`
+	TargetP99      int64           `json:"targetP99"`
+	MaxErrorRate   float64         `json:"maxErrorRate"`
+	WarmupRequests int             `json:"warmupRequests,omitempty"`
+	Interrupted    bool            `json:"interrupted,omitempty"`
+	Probes         []CapacityProbe `json:"probes"`
+}
+
+// achievedRateFloor is the fraction of the target rate a probe has to
+// deliver; below it the generator, not the endpoint, was the limit.
+const achievedRateFloor = 0.95
+
+// RunCapacitySearch finds the highest request rate at which the endpoint
+// still meets cfg's p99 and error targets. The rate doubles from MinRate
+// until a probe fails, then the search bisects between the last passing
+// and first failing rate.
+func (s *JSONRPCTester) RunCapacitySearch(ctx context.Context, cfg CapacityConfig) (*CapacityStats, error) {
+	switch {
+	case cfg.TargetP99 <= 0:
+		return nil, fmt.Errorf("capacity search needs a positive p99 target")
+	case cfg.MinRate <= 0 || cfg.MaxRate < cfg.MinRate:
+		return nil, fmt.Errorf("capacity search needs 0 < min rate <= max rate")
+	case cfg.ProbeDuration <= 0:
+		return nil, fmt.Errorf("capacity search needs a positive probe duration")
+	case cfg.Concurrency <= 0:
+		return nil, fmt.Errorf("open-loop concurrency must be positive")
+	}
+	if cfg.Precision <= 0 {
+		cfg.Precision = 0.1
+	}
+	if _, err := newArrivals(cfg.Arrival, cfg.MinRate, nil); err != nil {
+		return nil, err
+	}
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, err
+	}
+	stats := &CapacityStats{
+		TargetP99:      cfg.TargetP99.Milliseconds(),
+		MaxErrorRate:   cfg.MaxErrorRate,
+		WarmupRequests: warmupRequests,
+	}
+	s.Log().Info("searching for capacity", "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"target_p99", cfg.TargetP99, "max_error_pct", cfg.MaxErrorRate,
+		"min_rate", cfg.MinRate, "max_rate", cfg.MaxRate, "probe_duration", cfg.ProbeDuration)
+
+	probe := func(rate float64) (bool, error) {
+		result, err := s.probeRate(ctx, cfg, methods, rate)
+		if err != nil {
+			return false, err
+		}
+		if s.stopped(ctx) {
+			return false, nil
+		}
+		stats.Probes = append(stats.Probes, *result)
+		s.Log().Info("capacity probe", "rate", rate, "passed", result.Passed, "reason", result.Reason,
+			"p99_ms", result.P99, "error_pct", result.ErrorRate, "achieved_rate", result.AchievedRate)
+		if result.Passed && rate > stats.CapacityRPS {
+			stats.CapacityRPS = rate
+		}
+		return result.Passed, nil
+	}
+
+	// Grow until a rate fails or the ceiling passes.
+	low, high := 0.0, 0.0
+	for rate := cfg.MinRate; !s.stopped(ctx); rate *= 2 {
+		if rate > cfg.MaxRate {
+			rate = cfg.MaxRate
+		}
+		passed, err := probe(rate)
+		if err != nil {
+			return nil, err
+		}
+		if !passed {
+			high = rate
+			break
+		}
+		low = rate
+		if rate == cfg.MaxRate {
+			break
+		}
+	}
+
+	// Bisect between the last pass and the first failure.
+	for low > 0 && high > 0 && (high-low)/low > cfg.Precision && !s.stopped(ctx) {
+		mid := (low + high) / 2
+		passed, err := probe(mid)
+		if err != nil {
+			return nil, err
+		}
+		if passed {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// probeRate holds rate for cfg.ProbeDuration and judges the result
+// against cfg's targets.
+func (s *JSONRPCTester) probeRate(ctx context.Context, cfg CapacityConfig, methods []string, rate float64) (*CapacityProbe, error) {
+	arr, err := newArrivals(cfg.Arrival, rate, s.newRand())
+	if err != nil {
+		return nil, err
+	}
+	load := LoadConfig{Rate: rate, Duration: cfg.ProbeDuration, Concurrency: cfg.Concurrency, Arrival: cfg.Arrival}
+	samples, elapsed, err := s.runSchedule(ctx, load, methods, arr, int(rate*cfg.ProbeDuration.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.summarizeOpenLoop(samples, elapsed)
+	probe := &CapacityProbe{
+		Rate:         rate,
+		AchievedRate: stats.AchievedRate,
+		P99:          stats.CorrectedLatency.P99,
+		ErrorRate:    100 - stats.SuccessRate,
+	}
+	switch {
+	case len(samples) == 0:
+		probe.Reason = "no requests completed"
+	case stats.SuccessfulRequests == 0:
+		probe.Reason = "every request failed"
+	case probe.ErrorRate > cfg.MaxErrorRate:
+		probe.Reason = fmt.Sprintf("error rate %.2f%% above %.2f%%", probe.ErrorRate, cfg.MaxErrorRate)
+	case probe.P99 > cfg.TargetP99.Milliseconds():
+		probe.Reason = fmt.Sprintf("p99 %dms above %dms", probe.P99, cfg.TargetP99.Milliseconds())
+	case probe.AchievedRate < rate*achievedRateFloor:
+		probe.Reason = fmt.Sprintf("achieved only %.1f rps", probe.AchievedRate)
+	default:
+		probe.Passed = true
+	}
+	return probe, nil
+}
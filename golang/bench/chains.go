@@ -0,0 +1,322 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Chain is a sequence of dependent calls, each of which may use values
+// extracted from the results of the steps before it, the way an
+// application looks up signatures and then fetches their transactions.
+type Chain struct {
+	Name  string      `json:"name"`
+	Steps []ChainStep `json:"steps"`
+}
+
+// ChainStep is one call of a chain. Params may use the placeholders of
+// RequestTemplate as well as {{name}} for any value an earlier step
+// extracted. Extract maps names to dotted paths into the step's result,
+// such as "0.signature" or "context.slot"; an empty path is the whole
+// result.
+type ChainStep struct {
+	Method  string            `json:"method"`
+	Params  json.RawMessage   `json:"params,omitempty"`
+	Extract map[string]string `json:"extract,omitempty"`
+
+	params interface{}
+}
+
+// builtinChains are the chains selectable by name with -chains.
+var builtinChains = `[
+	{
+		"name": "slot-block",
+		"steps": [
+			{"method": "getSlot", "params": [{"commitment": "finalized"}], "extract": {"slot": ""}},
+			{"method": "getBlock", "params": ["{{slot}}", {"commitment": "finalized", "transactionDetails": "signatures", "maxSupportedTransactionVersion": 0, "rewards": false}]}
+		]
+	},
+	{
+		"name": "signatures-transaction",
+		"steps": [
+			{"method": "getSignaturesForAddress", "params": ["` + rpcclient.USDCMint + `", {"limit": 1}], "extract": {"signature": "0.signature"}},
+			{"method": "getTransaction", "params": ["{{signature}}", {"encoding": "json", "maxSupportedTransactionVersion": 0}]}
+		]
+	}
+]`
+
+// BuiltinChains returns the chains named in list, a comma-separated list
+// of built-in chain names.
+func BuiltinChains(list string) ([]Chain, error) {
+	builtin, err := parseChains([]byte(builtinChains), "built-in chains")
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Chain, len(builtin))
+	names := make([]string, 0, len(builtin))
+	for _, chain := range builtin {
+		byName[chain.Name] = chain
+		names = append(names, chain.Name)
+	}
+	sort.Strings(names)
+
+	var chains []Chain
+	for _, name := range SplitList(list) {
+		chain, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown chain %q (known: %s)", name, strings.Join(names, ", "))
+		}
+		chains = append(chains, chain)
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("no chains selected")
+	}
+	return chains, nil
+}
+
+// LoadChains reads a JSON array of chain definitions.
+func LoadChains(path string) ([]Chain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseChains(data, path)
+}
+
+func parseChains(data []byte, source string) ([]Chain, error) {
+	var chains []Chain
+	if err := json.Unmarshal(data, &chains); err != nil {
+		return nil, fmt.Errorf("parse chains %s: %w", source, err)
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("chains %s has no chains", source)
+	}
+	seen := make(map[string]bool)
+	for i := range chains {
+		chain := &chains[i]
+		if chain.Name == "" {
+			return nil, fmt.Errorf("chain %d has no name", i)
+		}
+		if seen[chain.Name] {
+			return nil, fmt.Errorf("duplicate chain %q", chain.Name)
+		}
+		seen[chain.Name] = true
+		if len(chain.Steps) == 0 {
+			return nil, fmt.Errorf("chain %q has no steps", chain.Name)
+		}
+		// A step may only use what the steps before it extracted.
+		extracted := make(map[string]bool)
+		for j := range chain.Steps {
+			step := &chain.Steps[j]
+			if step.Method == "" {
+				return nil, fmt.Errorf("chain %q: step %d has no method", chain.Name, j+1)
+			}
+			if len(step.Params) > 0 {
+				if err := json.Unmarshal(step.Params, &step.params); err != nil {
+					return nil, fmt.Errorf("chain %q: step %d: params: %w", chain.Name, j+1, err)
+				}
+			}
+			for _, match := range placeholderPattern.FindAllStringSubmatch(string(step.Params), -1) {
+				if !placeholders[match[1]] && !extracted[match[1]] {
+					return nil, fmt.Errorf("chain %q: step %d: {{%s}} is neither a placeholder nor extracted by an earlier step", chain.Name, j+1, match[1])
+				}
+			}
+			for name := range step.Extract {
+				extracted[name] = true
+			}
+		}
+	}
+	return chains, nil
+}
+
+// usesAccounts reports whether any step of the chain draws from the
+// account list.
+func (c *Chain) usesAccounts() bool {
+	for _, step := range c.Steps {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(string(step.Params), -1) {
+			if match[1] == "account" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractPath walks a dotted path of object keys and array indexes into
+// a decoded JSON result.
+func extractPath(value interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return value, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			item, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("result has no %q", path)
+			}
+			value = item
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("result has no %q", path)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("result has no %q", path)
+		}
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%q is null", path)
+	}
+	return value, nil
+}
+
+// ChainStepStats covers one step across all runs of its chain. Requests
+// counts the runs that got as far as the step; Errors, those that failed
+// there, either with an RPC error or a result missing what was to be
+// extracted. Error is the first such failure.
+type ChainStepStats struct {
+	Step     int          `json:"step"`
+	Method   string       `json:"method"`
+	Requests int          `json:"requests"`
+	Errors   int          `json:"errors"`
+	Error    string       `json:"error,omitempty"`
+	Latency  LatencyStats `json:"latency"`
+}
+
+// ChainStats summarizes the runs of one chain. A run that fails at a step
+// goes no further; EndToEnd is the wall time of the completed runs, from
+// the first request sent to the last response decoded.
+type ChainStats struct {
+	Chain       string           `json:"chain"`
+	Runs        int              `json:"runs"`
+	Completed   int              `json:"completed"`
+	Failed      int              `json:"failed"`
+	SuccessRate float64          `json:"successRate"`
+	EndToEnd    LatencyStats     `json:"endToEnd"`
+	Steps       []ChainStepStats `json:"steps"`
+}
+
+type ChainsStats struct {
+	Iterations  int          `json:"iterations"`
+	Interrupted bool         `json:"interrupted,omitempty"`
+	Chains      []ChainStats `json:"chains"`
+}
+
+// chainRun accumulates one chain's samples.
+type chainRun struct {
+	stats    ChainStats
+	endToEnd []int64
+	steps    [][]int64
+}
+
+// RunChains runs each chain iterations times, one after another, and
+// reports per-step and end-to-end latency.
+func (s *JSONRPCTester) RunChains(ctx context.Context, chains []Chain, iterations int) (*ChainsStats, error) {
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("no chains selected")
+	}
+	names := make([]string, len(chains))
+	runs := make([]*chainRun, len(chains))
+	for i := range chains {
+		if chains[i].usesAccounts() && s.Accounts == nil {
+			return nil, fmt.Errorf("chain %s uses {{account}} but there is no account list (-accounts)", chains[i].Name)
+		}
+		names[i] = chains[i].Name
+		run := &chainRun{stats: ChainStats{Chain: chains[i].Name}, steps: make([][]int64, len(chains[i].Steps))}
+		for j, step := range chains[i].Steps {
+			run.stats.Steps = append(run.stats.Steps, ChainStepStats{Step: j + 1, Method: step.Method})
+		}
+		runs[i] = run
+	}
+	set := s.templates
+	if set == nil {
+		set = newTemplateSet()
+	}
+	s.Log().Info("running chains", "endpoint", rpcclient.RedactURL(s.Endpoint), "iterations", iterations, "chains", names)
+
+run:
+	for i := 0; i < iterations; i++ {
+		for j := range chains {
+			if s.stopped(ctx) {
+				break run
+			}
+			if err := s.runChain(ctx, set, &chains[j], runs[j]); err != nil {
+				if ctx.Err() != nil {
+					break run
+				}
+				return nil, err
+			}
+		}
+	}
+
+	stats := &ChainsStats{Iterations: iterations, Interrupted: s.stopped(ctx)}
+	for _, run := range runs {
+		run.stats.EndToEnd = s.summarize(run.endToEnd)
+		for j := range run.stats.Steps {
+			run.stats.Steps[j].Latency = s.summarize(run.steps[j])
+		}
+		if run.stats.Runs > 0 {
+			run.stats.SuccessRate = float64(run.stats.Completed) / float64(run.stats.Runs) * 100
+		}
+		stats.Chains = append(stats.Chains, run.stats)
+	}
+	return stats, nil
+}
+
+// runChain sends one run of chain, stopping at the first step that fails.
+func (s *JSONRPCTester) runChain(ctx context.Context, set *templateSet, chain *Chain, run *chainRun) error {
+	vars := make(map[string]interface{})
+	start := time.Now()
+	for i := range chain.Steps {
+		step := &chain.Steps[i]
+		stepStats := &run.stats.Steps[i]
+		params, err := set.fill(ctx, s, step.params, vars)
+		if err != nil {
+			return fmt.Errorf("chain %q: step %d: %w", chain.Name, i+1, err)
+		}
+		result, err := s.Call(ctx, step.Method, params)
+		if err != nil {
+			return err
+		}
+		if !result.Success && s.stopped(ctx) {
+			// Cut off by the interrupt; the run is dropped, not failed.
+			return nil
+		}
+		stepStats.Requests++
+		failure := ""
+		if !result.Success {
+			failure = result.Error
+		}
+		for name, path := range step.Extract {
+			if failure != "" {
+				break
+			}
+			if vars[name], err = extractPath(result.Result, path); err != nil {
+				failure = err.Error()
+			}
+		}
+		if failure != "" {
+			stepStats.Errors++
+			if stepStats.Error == "" {
+				stepStats.Error = failure
+			}
+			run.stats.Runs++
+			run.stats.Failed++
+			return nil
+		}
+		run.steps[i] = append(run.steps[i], result.Latency)
+	}
+	run.stats.Runs++
+	run.stats.Completed++
+	run.endToEnd = append(run.endToEnd, time.Since(start).Milliseconds())
+	return nil
+}
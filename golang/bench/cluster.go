@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+)
+
+// Solana clusters, as named by DetectCluster.
+const (
+	ClusterMainnet = "mainnet-beta"
+	ClusterDevnet  = "devnet"
+	ClusterTestnet = "testnet"
+	// ClusterUnknown is any other network, such as a local validator.
+	ClusterUnknown = "unknown"
+)
+
+const mainnetGenesisHash = "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d"
+
+// clusterGenesisHashes maps each public cluster's genesis hash, which
+// identifies it whatever the endpoint's hostname says, to its name.
+var clusterGenesisHashes = map[string]string{
+	mainnetGenesisHash: ClusterMainnet,
+	"EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG": ClusterDevnet,
+	"4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY": ClusterTestnet,
+}
+
+// DetectCluster names the cluster the endpoint serves from its genesis
+// hash: mainnet-beta, devnet, testnet or unknown.
+func (s *JSONRPCTester) DetectCluster(ctx context.Context) (string, error) {
+	var genesis string
+	if _, err := s.CallResult(ctx, "getGenesisHash", nil, &genesis); err != nil {
+		return "", fmt.Errorf("detect cluster: %w", err)
+	}
+	if cluster, ok := clusterGenesisHashes[genesis]; ok {
+		return cluster, nil
+	}
+	return ClusterUnknown, nil
+}
+
+// VerifyCluster detects the endpoint's cluster and records it in
+// s.Cluster, so benchmark stats report it. If expected is set, "mainnet"
+// standing for mainnet-beta, and the endpoint is on another cluster, it
+// returns an error, or with warnOnly logs a warning and carries on.
+func (s *JSONRPCTester) VerifyCluster(ctx context.Context, expected string, warnOnly bool) error {
+	if expected == "mainnet" {
+		expected = ClusterMainnet
+	}
+	switch expected {
+	case "", ClusterMainnet, ClusterDevnet, ClusterTestnet:
+	default:
+		return fmt.Errorf("unknown cluster %q (want mainnet-beta, devnet or testnet)", expected)
+	}
+
+	cluster, err := s.DetectCluster(ctx)
+	if err != nil {
+		if expected == "" || warnOnly {
+			s.Log().Warn("could not verify cluster", "error", err)
+			return nil
+		}
+		return err
+	}
+	s.Cluster = cluster
+	s.Log().Info("cluster detected", "cluster", cluster)
+	if expected == "" || cluster == expected {
+		return nil
+	}
+	if warnOnly {
+		s.Log().Warn("endpoint is on the wrong cluster", "expected", expected, "cluster", cluster)
+		return nil
+	}
+	return fmt.Errorf("endpoint is on the %s cluster, not %s", cluster, expected)
+}
@@ -0,0 +1,149 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// SignificanceAlpha is the p-value below which a latency difference is
+// reported as significant.
+const SignificanceAlpha = 0.05
+
+// ComparisonStats holds two endpoints benchmarked side by side and whether
+// their latencies differ by more than noise. In Significance, A is
+// Endpoint and B is Other.
+type ComparisonStats struct {
+	Endpoint     string             `json:"endpoint"`
+	Other        string             `json:"other"`
+	Iterations   int                `json:"iterations"`
+	Interrupted  bool               `json:"interrupted,omitempty"`
+	Stats        *BenchmarkStats    `json:"stats"`
+	OtherStats   *BenchmarkStats    `json:"otherStats"`
+	Significance stats.Significance `json:"significance"`
+}
+
+// RunComparison benchmarks s's endpoint and other in lockstep: each request
+// is sent to both, alternating which goes first, so drift in the network
+// or the client affects both sides alike. Their successful latencies are
+// then compared with a Mann-Whitney U test.
+func (s *JSONRPCTester) RunComparison(ctx context.Context, iterations int, other string) (*ComparisonStats, error) {
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+	testers := []*JSONRPCTester{s, s.withEndpoint(other)}
+	testers[1].Label = s.Label
+
+	warmupRequests := make([]int, len(testers))
+	for i, tester := range testers {
+		var err error
+		if warmupRequests[i], err = tester.runWarmup(ctx, methods); err != nil {
+			return nil, err
+		}
+	}
+
+	s.Log().Info("comparing endpoints", "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"other", rpcclient.RedactURL(other), "iterations", iterations, "methods", methods)
+
+	results := make([][]rpcclient.Result, len(testers))
+	done := make([][]time.Duration, len(testers))
+	start := time.Now()
+	breakers := make([]*breakerRun, len(testers))
+	for i, tester := range testers {
+		breakers[i] = tester.newBreaker(start)
+	}
+	completed := 0
+
+run:
+	for i := 0; i < iterations; i++ {
+		for _, method := range methods {
+			for j := range testers {
+				if s.stopped(ctx) {
+					break run
+				}
+				k := (i + j) % len(testers)
+				if !s.awaitBreaker(ctx, breakers[k]) {
+					break run
+				}
+				result, err := testers[k].runner(method)(ctx, testers[k])
+				if err != nil && ctx.Err() != nil {
+					break run
+				}
+				if err != nil {
+					return nil, err
+				}
+				breakers[k].record(time.Now(), result)
+				stampOffset(result, start)
+				results[k] = append(results[k], *result)
+				done[k] = append(done[k], time.Since(start))
+			}
+		}
+		completed++
+	}
+	elapsed := time.Since(start)
+
+	comparison := &ComparisonStats{
+		Endpoint:    rpcclient.RedactURL(s.Endpoint),
+		Other:       rpcclient.RedactURL(other),
+		Iterations:  completed,
+		Interrupted: s.stopped(ctx),
+	}
+	all := make([]*BenchmarkStats, len(testers))
+	for i, tester := range testers {
+		all[i] = s.calculateStats(results[i], elapsed)
+		all[i].WarmupRequests = warmupRequests[i]
+		all[i].Breaker = breakers[i].finish(time.Now())
+		tester.addBreakdowns(ctx, all[i], results[i], start, done[i])
+	}
+	comparison.Stats, comparison.OtherStats = all[0], all[1]
+	comparison.Significance = stats.MannWhitneyU(successfulLatencies(results[0]), successfulLatencies(results[1]), SignificanceAlpha)
+	return comparison, nil
+}
+
+// BaselineComparison tests a run against the latency samples of an earlier
+// one. In Significance, A is the current run and B the baseline.
+type BaselineComparison struct {
+	Baseline     string             `json:"baseline"`
+	Significance stats.Significance `json:"significance"`
+}
+
+// LoadBaselineSamples reads the latency samples from a results file written
+// with KeepSamples: either benchmark stats or a batch job report.
+func LoadBaselineSamples(path string) ([]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		LatencySamples []int64 `json:"latencySamples"`
+		Stats          *struct {
+			LatencySamples []int64 `json:"latencySamples"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse baseline %s: %w", path, err)
+	}
+	samples := file.LatencySamples
+	if samples == nil && file.Stats != nil {
+		samples = file.Stats.LatencySamples
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("baseline %s has no latency samples (write it with -keep-samples)", path)
+	}
+	return samples, nil
+}
+
+// CompareToBaseline tests current, which must have been run with
+// KeepSamples, against baseline samples.
+func CompareToBaseline(current *BenchmarkStats, path string, baseline []int64) *BaselineComparison {
+	return &BaselineComparison{
+		Baseline:     path,
+		Significance: stats.MannWhitneyU(current.LatencySamples, baseline, SignificanceAlpha),
+	}
+}
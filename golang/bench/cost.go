@@ -0,0 +1,133 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// CostModel prices requests the way a provider bills them: each request
+// of a method costs Credits[method] credits, or DefaultCredits for methods
+// not listed, and a million credits cost USDPerMillionCredits dollars.
+// Rate-limited requests cost nothing; other failures cost as much as
+// successes if ChargeFailures is set, and nothing otherwise.
+type CostModel struct {
+	Provider string `json:"provider"`
+	// Match selects the model for endpoints whose host contains it, such
+	// as "helius-rpc.com".
+	Match                string             `json:"match,omitempty"`
+	DefaultCredits       float64            `json:"defaultCredits"`
+	Credits              map[string]float64 `json:"credits,omitempty"`
+	USDPerMillionCredits float64            `json:"usdPerMillionCredits,omitempty"`
+	ChargeFailures       bool               `json:"chargeFailures,omitempty"`
+}
+
+// CostStats is what a run cost under a CostModel. The per-million figures
+// are per million of the run's requests, charged or not, so they price
+// the run's method mix at scale. USD figures are set when the model has a
+// price.
+type CostStats struct {
+	Provider          string             `json:"provider"`
+	ChargedRequests   int                `json:"chargedRequests"`
+	Credits           float64            `json:"credits"`
+	MethodCredits     map[string]float64 `json:"methodCredits,omitempty"`
+	CreditsPerMillion float64            `json:"creditsPerMillion"`
+	USD               float64            `json:"usd,omitempty"`
+	USDPerMillion     float64            `json:"usdPerMillion,omitempty"`
+}
+
+// LoadCostModels reads a cost model file: a JSON object whose "providers"
+// lists CostModels.
+func LoadCostModels(path string) ([]CostModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Providers []CostModel `json:"providers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse cost models %s: %w", path, err)
+	}
+	if len(file.Providers) == 0 {
+		return nil, fmt.Errorf("cost models %s list no providers", path)
+	}
+	seen := make(map[string]bool)
+	for i, model := range file.Providers {
+		if model.Provider == "" {
+			return nil, fmt.Errorf("cost model %d has no provider", i)
+		}
+		if seen[model.Provider] {
+			return nil, fmt.Errorf("duplicate cost model %q", model.Provider)
+		}
+		seen[model.Provider] = true
+		if model.DefaultCredits < 0 || model.USDPerMillionCredits < 0 {
+			return nil, fmt.Errorf("cost model %q has a negative price", model.Provider)
+		}
+		for method, credits := range model.Credits {
+			if credits < 0 {
+				return nil, fmt.Errorf("cost model %q: %s has negative credits", model.Provider, method)
+			}
+		}
+	}
+	return file.Providers, nil
+}
+
+// CostModelFor returns the model named provider, if it isn't empty.
+// Otherwise it returns the first model whose Match is in endpoint's host,
+// else the only model if there is just one and it has no Match, else nil.
+func CostModelFor(models []CostModel, provider, endpoint string) (*CostModel, error) {
+	if provider != "" {
+		for i := range models {
+			if models[i].Provider == provider {
+				return &models[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no cost model for provider %q", provider)
+	}
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	for i := range models {
+		if models[i].Match != "" && strings.Contains(host, models[i].Match) {
+			return &models[i], nil
+		}
+	}
+	if len(models) == 1 && models[0].Match == "" {
+		return &models[0], nil
+	}
+	return nil, nil
+}
+
+// Cost prices results, of which there were total requests in all.
+func (m *CostModel) Cost(results []rpcclient.Result, total int) *CostStats {
+	cost := &CostStats{Provider: m.Provider, MethodCredits: map[string]float64{}}
+	for _, result := range results {
+		if result.RateLimited || (!result.Success && !m.ChargeFailures) {
+			continue
+		}
+		credits, ok := m.Credits[result.Method]
+		if !ok {
+			credits = m.DefaultCredits
+		}
+		cost.ChargedRequests++
+		cost.Credits += credits
+		cost.MethodCredits[result.Method] += credits
+	}
+	cost.USD = cost.Credits * m.USDPerMillionCredits / 1e6
+	cost.perMillion(total)
+	return cost
+}
+
+// perMillion sets the per-million figures of a run of total requests.
+func (c *CostStats) perMillion(total int) {
+	if total > 0 {
+		c.CreditsPerMillion = c.Credits / float64(total) * 1e6
+		c.USDPerMillion = c.USD / float64(total) * 1e6
+	}
+}
@@ -0,0 +1,163 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+func TestCostModelFor(t *testing.T) {
+	models := []CostModel{
+		{Provider: "helius", Match: "helius-rpc.com"},
+		{Provider: "quicknode", Match: "quiknode.pro"},
+	}
+	tests := []struct {
+		name               string
+		models             []CostModel
+		provider, endpoint string
+		want               string
+		err                string
+	}{
+		{name: "by host", models: models, endpoint: "https://mainnet.helius-rpc.com/?api-key=k", want: "helius"},
+		{name: "by host, not path", models: models, endpoint: "https://rpc.example.com/helius-rpc.com"},
+		{name: "by name", models: models, provider: "quicknode", endpoint: "https://mainnet.helius-rpc.com", want: "quicknode"},
+		{name: "unknown provider", models: models, provider: "alchemy", err: `no cost model for provider "alchemy"`},
+		{name: "no match", models: models, endpoint: "https://api.mainnet-beta.solana.com"},
+		{name: "the only model", models: []CostModel{{Provider: "flat"}}, endpoint: "https://api.mainnet-beta.solana.com", want: "flat"},
+		{name: "the only model, matching elsewhere", models: models[:1], endpoint: "https://api.mainnet-beta.solana.com"},
+		{name: "no models", endpoint: "https://api.mainnet-beta.solana.com"},
+	}
+	for _, tt := range tests {
+		model, err := CostModelFor(tt.models, tt.provider, tt.endpoint)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		got := ""
+		if model != nil {
+			got = model.Provider
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCostModelCost(t *testing.T) {
+	ok := func(method string) rpcclient.Result { return rpcclient.Result{Method: method, Success: true} }
+	failed := rpcclient.Result{Method: "getSlot", Error: "internal error"}
+	limited := rpcclient.Result{Method: "getSlot", RateLimited: true, StatusCode: 429}
+	model := CostModel{
+		Provider:             "helius",
+		DefaultCredits:       1,
+		Credits:              map[string]float64{"getProgramAccounts": 10, "getBlock": 0.5},
+		USDPerMillionCredits: 5,
+	}
+	tests := []struct {
+		name           string
+		chargeFailures bool
+		noPrice        bool
+		results        []rpcclient.Result
+		total          int
+		want           *CostStats
+	}{
+		{
+			name:    "listed and default prices",
+			results: []rpcclient.Result{ok("getSlot"), ok("getProgramAccounts"), ok("getBlock"), ok("getBlock")},
+			total:   4,
+			want: &CostStats{Provider: "helius", ChargedRequests: 4, Credits: 12,
+				MethodCredits:     map[string]float64{"getSlot": 1, "getProgramAccounts": 10, "getBlock": 1},
+				CreditsPerMillion: 3_000_000, USD: 0.00006, USDPerMillion: 15},
+		},
+		{
+			name:    "failures and rate limits are free",
+			results: []rpcclient.Result{ok("getSlot"), failed, limited},
+			total:   3,
+			want: &CostStats{Provider: "helius", ChargedRequests: 1, Credits: 1, MethodCredits: map[string]float64{"getSlot": 1},
+				CreditsPerMillion: 1e6 / 3.0, USD: 0.000005, USDPerMillion: 5 / 3.0},
+		},
+		{
+			name:           "failures charged, rate limits still free",
+			chargeFailures: true,
+			results:        []rpcclient.Result{ok("getSlot"), failed, limited},
+			total:          3,
+			want: &CostStats{Provider: "helius", ChargedRequests: 2, Credits: 2, MethodCredits: map[string]float64{"getSlot": 2},
+				CreditsPerMillion: 2e6 / 3.0, USD: 0.00001, USDPerMillion: 10 / 3.0},
+		},
+		{
+			// Per million of the run's requests, including any the
+			// results don't hold, such as skipped ones.
+			name:    "per million of the total",
+			results: []rpcclient.Result{ok("getBlock")},
+			total:   2,
+			want: &CostStats{Provider: "helius", ChargedRequests: 1, Credits: 0.5, MethodCredits: map[string]float64{"getBlock": 0.5},
+				CreditsPerMillion: 250_000, USD: 0.0000025, USDPerMillion: 1.25},
+		},
+		{
+			name:    "no price",
+			noPrice: true,
+			results: []rpcclient.Result{ok("getSlot")},
+			total:   1,
+			want:    &CostStats{Provider: "helius", ChargedRequests: 1, Credits: 1, MethodCredits: map[string]float64{"getSlot": 1}, CreditsPerMillion: 1e6},
+		},
+		{name: "no requests", want: &CostStats{Provider: "helius", MethodCredits: map[string]float64{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := model
+			m.ChargeFailures = tt.chargeFailures
+			if tt.noPrice {
+				m.USDPerMillionCredits = 0
+			}
+			got := m.Cost(tt.results, tt.total)
+			// Rounded fields are compared within a tolerance.
+			if !closeTo(got.USD, tt.want.USD, 1e-12) || !closeTo(got.USDPerMillion, tt.want.USDPerMillion, 1e-9) ||
+				!closeTo(got.CreditsPerMillion, tt.want.CreditsPerMillion, 1e-6) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			got.USD, got.USDPerMillion, got.CreditsPerMillion = tt.want.USD, tt.want.USDPerMillion, tt.want.CreditsPerMillion
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func closeTo(a, b, tolerance float64) bool {
+	return a-b <= tolerance && b-a <= tolerance
+}
+
+func TestLoadCostModels(t *testing.T) {
+	tests := []struct {
+		name, file string
+		err        string
+	}{
+		{name: "valid", file: `{"providers": [{"provider": "helius", "match": "helius-rpc.com", "defaultCredits": 1, "credits": {"getProgramAccounts": 10}}]}`},
+		{name: "no providers", file: `{"providers": []}`, err: "list no providers"},
+		{name: "unnamed", file: `{"providers": [{"defaultCredits": 1}]}`, err: "has no provider"},
+		{name: "duplicate", file: `{"providers": [{"provider": "a"}, {"provider": "a"}]}`, err: `duplicate cost model "a"`},
+		{name: "negative default", file: `{"providers": [{"provider": "a", "defaultCredits": -1}]}`, err: "negative price"},
+		{name: "negative price", file: `{"providers": [{"provider": "a", "usdPerMillionCredits": -1}]}`, err: "negative price"},
+		{name: "negative method", file: `{"providers": [{"provider": "a", "credits": {"getSlot": -2}}]}`, err: "getSlot has negative credits"},
+		{name: "malformed", file: `{"providers": [`, err: "parse cost models"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "costs.json")
+		if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadCostModels(path)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
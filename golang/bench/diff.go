@@ -0,0 +1,222 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"solana-rpc-performance-golang/stats"
+)
+
+// Verdicts of a MetricDelta, from B's point of view.
+const (
+	DeltaBetter = "better"
+	DeltaWorse  = "worse"
+	DeltaSame   = "same"
+)
+
+// ResultsDelta is how run B differs from run A, both read back from
+// results files. Success rate and error mix are percentages of each
+// run's requests, so runs of different sizes compare; their Change is in
+// percentage points. Latencies are in milliseconds.
+type ResultsDelta struct {
+	A           string        `json:"a"`
+	B           string        `json:"b"`
+	Requests    MetricDelta   `json:"requests"`
+	SuccessRate MetricDelta   `json:"successRate"`
+	Throughput  MetricDelta   `json:"throughput"`
+	Latency     []MetricDelta `json:"latency"`
+	Errors      []MetricDelta `json:"errors"`
+	// Cost compares what a million requests cost, when both runs were
+	// priced.
+	Cost []MetricDelta `json:"cost,omitempty"`
+	// Significance tests the latencies, when both runs kept their
+	// samples with -keep-samples.
+	Significance *stats.Significance `json:"significance,omitempty"`
+}
+
+// MetricDelta is one metric of two runs. Percent is Change relative to
+// A, when A isn't zero.
+type MetricDelta struct {
+	Name    string   `json:"name"`
+	A       float64  `json:"a"`
+	B       float64  `json:"b"`
+	Change  float64  `json:"change"`
+	Percent *float64 `json:"percent,omitempty"`
+	Verdict string   `json:"verdict,omitempty"`
+}
+
+// metricDelta compares a metric, which is better higher if higherBetter
+// is 1, better lower if it is -1, and neither if it is 0.
+func metricDelta(name string, a, b float64, higherBetter int) MetricDelta {
+	delta := MetricDelta{Name: name, A: a, B: b, Change: b - a}
+	if a != 0 {
+		percent := delta.Change / a * 100
+		delta.Percent = &percent
+	}
+	switch {
+	case higherBetter == 0:
+	case delta.Change == 0:
+		delta.Verdict = DeltaSame
+	case (delta.Change > 0) == (higherBetter > 0):
+		delta.Verdict = DeltaBetter
+	default:
+		delta.Verdict = DeltaWorse
+	}
+	return delta
+}
+
+// DiffResults compares run b with run a.
+func DiffResults(a, b *SavedResults) *ResultsDelta {
+	sa, sb := a.Stats, b.Stats
+	delta := &ResultsDelta{
+		A:           a.Path,
+		B:           b.Path,
+		Requests:    metricDelta("requests", float64(sa.TotalRequests), float64(sb.TotalRequests), 0),
+		SuccessRate: metricDelta("success rate", sa.SuccessRate, sb.SuccessRate, 1),
+		Throughput:  metricDelta("throughput", requestRate(sa), requestRate(sb), 1),
+	}
+
+	la, lb := sa.Latency, sb.Latency
+	delta.Latency = []MetricDelta{
+		metricDelta("avg", la.Avg, lb.Avg, -1),
+		metricDelta("min", float64(la.Min), float64(lb.Min), -1),
+		metricDelta("p50", float64(la.P50), float64(lb.P50), -1),
+		metricDelta("p95", float64(la.P95), float64(lb.P95), -1),
+		metricDelta("p99", float64(la.P99), float64(lb.P99), -1),
+	}
+	// Extra percentiles, from -percentiles, if both runs asked for them.
+	var extra []string
+	for key := range la.Percentiles {
+		if _, ok := lb.Percentiles[key]; ok && key != "p50" && key != "p95" && key != "p99" {
+			extra = append(extra, key)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return percentileOf(extra[i]) < percentileOf(extra[j]) })
+	for _, key := range extra {
+		delta.Latency = append(delta.Latency, metricDelta(key, la.Percentiles[key], lb.Percentiles[key], -1))
+	}
+	delta.Latency = append(delta.Latency, metricDelta("max", float64(la.Max), float64(lb.Max), -1))
+
+	ea, eb := errorMix(sa), errorMix(sb)
+	for i, kind := range errorKinds {
+		delta.Errors = append(delta.Errors, metricDelta(kind, ea[i], eb[i], -1))
+	}
+
+	if ca, cb := sa.Cost, sb.Cost; ca != nil && cb != nil {
+		delta.Cost = []MetricDelta{
+			metricDelta("credits per million", ca.CreditsPerMillion, cb.CreditsPerMillion, -1),
+			metricDelta("usd per million", ca.USDPerMillion, cb.USDPerMillion, -1),
+		}
+	}
+
+	if len(sa.LatencySamples) > 0 && len(sb.LatencySamples) > 0 {
+		significance := stats.MannWhitneyU(sa.LatencySamples, sb.LatencySamples, SignificanceAlpha)
+		delta.Significance = &significance
+	}
+	return delta
+}
+
+// requestRate is a run's requests per second over its measured duration.
+func requestRate(s *BenchmarkStats) float64 {
+	if s.Bandwidth.DurationMs <= 0 {
+		return 0
+	}
+	return float64(s.TotalRequests) * 1000 / float64(s.Bandwidth.DurationMs)
+}
+
+// percentileOf parses a LatencyStats.Percentiles key, such as "p99.9".
+func percentileOf(key string) float64 {
+	var p float64
+	fmt.Sscanf(strings.TrimPrefix(key, "p"), "%g", &p)
+	return p
+}
+
+// errorKinds names the failures errorMix tells apart.
+var errorKinds = []string{"timeout", "unsupported", "schema", "other"}
+
+// errorMix returns the percentage of a run's requests failing each way in
+// errorKinds. The failures not timed out, unsupported or malformed are
+// other.
+func errorMix(s *BenchmarkStats) []float64 {
+	mix := make([]float64, len(errorKinds))
+	if s.TotalRequests == 0 {
+		return mix
+	}
+	other := s.FailedRequests - s.TimedOutRequests - s.UnsupportedRequests - s.SchemaViolations
+	for i, n := range []int{s.TimedOutRequests, s.UnsupportedRequests, s.SchemaViolations, max(other, 0)} {
+		mix[i] = float64(n) / float64(s.TotalRequests) * 100
+	}
+	return mix
+}
+
+// ANSI colors of WriteResultsDelta's changes.
+const (
+	ansiGreen = "\x1b[32m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// WriteResultsDelta writes a table of delta's metrics, with each run's
+// value and the change from A to B. If color is set, changes for the
+// better are green and those for the worse red.
+func WriteResultsDelta(w io.Writer, delta *ResultsDelta, color bool) error {
+	fmt.Fprintf(w, "A: %s\nB: %s\n\n", delta.A, delta.B)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tA\tB\tCHANGE")
+	row := func(m MetricDelta, format, unit string) {
+		value := func(v float64) string { return fmt.Sprintf(format, v) + unit }
+		change := fmt.Sprintf("%+"+format[1:], m.Change) + unit
+		if unit == "%" {
+			change = fmt.Sprintf("%+.2fpp", m.Change)
+		} else if m.Percent != nil {
+			change += fmt.Sprintf(" (%+.1f%%)", *m.Percent)
+		}
+		if color && m.Verdict == DeltaBetter {
+			change = ansiGreen + change + ansiReset
+		} else if color && m.Verdict == DeltaWorse {
+			change = ansiRed + change + ansiReset
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Name, value(m.A), value(m.B), change)
+	}
+	row(delta.Requests, "%.0f", "")
+	row(delta.SuccessRate, "%.2f", "%")
+	if delta.Throughput.A > 0 || delta.Throughput.B > 0 {
+		row(delta.Throughput, "%.1f", " req/s")
+	}
+	for _, m := range delta.Latency {
+		format := "%.0f"
+		if m.Name == "avg" || strings.Contains(m.Name, ".") {
+			format = "%.1f"
+		}
+		row(m, format, "ms")
+	}
+	for _, m := range delta.Errors {
+		m.Name += " errors"
+		row(m, "%.2f", "%")
+	}
+	for _, m := range delta.Cost {
+		if strings.HasPrefix(m.Name, "usd") {
+			row(m, "%.2f", " USD")
+		} else {
+			row(m, "%.0f", "")
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if s := delta.Significance; s != nil {
+		verdict := "no significant latency change"
+		if s.Significant && s.CliffsDelta < 0 {
+			verdict = fmt.Sprintf("B is significantly slower (%s effect)", s.Effect)
+		} else if s.Significant {
+			verdict = fmt.Sprintf("B is significantly faster (%s effect)", s.Effect)
+		}
+		_, err := fmt.Fprintf(w, "\n%s: median %dms vs. %dms, p=%.3g\n", verdict, s.MedianA, s.MedianB, s.PValue)
+		return err
+	}
+	return nil
+}
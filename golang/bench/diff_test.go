@@ -0,0 +1,206 @@
+package bench
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetricDelta(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         float64
+		higherBetter int
+		verdict      string
+		percent      float64
+		noPercent    bool
+	}{
+		{name: "lower latency", a: 100, b: 80, higherBetter: -1, verdict: DeltaBetter, percent: -20},
+		{name: "higher latency", a: 100, b: 150, higherBetter: -1, verdict: DeltaWorse, percent: 50},
+		{name: "higher success", a: 90, b: 99, higherBetter: 1, verdict: DeltaBetter, percent: 10},
+		{name: "lower success", a: 100, b: 95, higherBetter: 1, verdict: DeltaWorse, percent: -5},
+		{name: "unchanged", a: 42, b: 42, higherBetter: -1, verdict: DeltaSame, percent: 0},
+		{name: "neither better nor worse", a: 100, b: 200, higherBetter: 0, percent: 100},
+		{name: "from zero", a: 0, b: 3, higherBetter: -1, verdict: DeltaWorse, noPercent: true},
+	}
+	for _, tt := range tests {
+		got := metricDelta(tt.name, tt.a, tt.b, tt.higherBetter)
+		if got.Verdict != tt.verdict || got.Change != tt.b-tt.a {
+			t.Errorf("%s: verdict %q, change %v; want %q, %v", tt.name, got.Verdict, got.Change, tt.verdict, tt.b-tt.a)
+		}
+		switch {
+		case tt.noPercent && got.Percent != nil:
+			t.Errorf("%s: percent %v of a zero A", tt.name, *got.Percent)
+		case !tt.noPercent && (got.Percent == nil || *got.Percent != tt.percent):
+			t.Errorf("%s: percent %v, want %v", tt.name, got.Percent, tt.percent)
+		}
+	}
+}
+
+// diffStats returns stats of a run of 1000 requests over 10s.
+func diffStats(p99 int64, failed int) *BenchmarkStats {
+	return &BenchmarkStats{
+		TotalRequests:  1000,
+		FailedRequests: failed,
+		SuccessRate:    float64(1000-failed) / 10,
+		Latency:        LatencyStats{Avg: 50, Min: 10, P50: 40, P95: 80, P99: p99, Max: 400},
+		Bandwidth:      BandwidthStats{DurationMs: 10_000},
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  func() *BenchmarkStats
+		check func(t *testing.T, delta *ResultsDelta)
+	}{
+		{
+			name: "headline metrics",
+			a:    func() *BenchmarkStats { return diffStats(100, 0) },
+			b: func() *BenchmarkStats {
+				s := diffStats(150, 20)
+				s.TotalRequests, s.Bandwidth.DurationMs = 2000, 10_000
+				return s
+			},
+			check: func(t *testing.T, delta *ResultsDelta) {
+				if delta.Requests.Verdict != "" || delta.Requests.Change != 1000 {
+					t.Errorf("requests %+v, want +1000 with no verdict", delta.Requests)
+				}
+				if delta.Throughput.A != 100 || delta.Throughput.B != 200 || delta.Throughput.Verdict != DeltaBetter {
+					t.Errorf("throughput %+v, want 100 to 200 req/s, better", delta.Throughput)
+				}
+				if delta.SuccessRate.Change != -2 || delta.SuccessRate.Verdict != DeltaWorse {
+					t.Errorf("success rate %+v, want down 2pp, worse", delta.SuccessRate)
+				}
+				names := []string{}
+				for _, m := range delta.Latency {
+					names = append(names, m.Name)
+					if m.Name == "p99" && (m.Verdict != DeltaWorse || *m.Percent != 50) {
+						t.Errorf("p99 %+v, want up 50%%, worse", m)
+					}
+				}
+				if got := strings.Join(names, " "); got != "avg min p50 p95 p99 max" {
+					t.Errorf("latency metrics %s", got)
+				}
+			},
+		},
+		{
+			name: "error mix",
+			a: func() *BenchmarkStats {
+				s := diffStats(100, 10)
+				s.TimedOutRequests = 10
+				return s
+			},
+			b: func() *BenchmarkStats {
+				s := diffStats(100, 40)
+				s.UnsupportedRequests, s.SchemaViolations = 20, 5
+				return s
+			},
+			check: func(t *testing.T, delta *ResultsDelta) {
+				want := map[string][2]float64{"timeout": {1, 0}, "unsupported": {0, 2}, "schema": {0, 0.5}, "other": {0, 1.5}}
+				for _, m := range delta.Errors {
+					if w := want[m.Name]; m.A != w[0] || m.B != w[1] {
+						t.Errorf("%s errors %v%% to %v%%, want %v%% to %v%%", m.Name, m.A, m.B, w[0], w[1])
+					}
+				}
+			},
+		},
+		{
+			// Percentiles, costs and samples only one run has are left out.
+			name: "metrics missing from one run",
+			a: func() *BenchmarkStats {
+				s := diffStats(100, 0)
+				s.Latency.Percentiles = map[string]float64{"p99.9": 300, "p90": 70}
+				s.Cost = &CostStats{CreditsPerMillion: 1_000_000}
+				s.LatencySamples = []int64{10, 20, 30}
+				return s
+			},
+			b: func() *BenchmarkStats {
+				s := diffStats(100, 0)
+				s.Latency.Percentiles = map[string]float64{"p99.9": 250, "p99.99": 390}
+				return s
+			},
+			check: func(t *testing.T, delta *ResultsDelta) {
+				names := []string{}
+				for _, m := range delta.Latency {
+					names = append(names, m.Name)
+				}
+				if got := strings.Join(names, " "); got != "avg min p50 p95 p99 p99.9 max" {
+					t.Errorf("latency metrics %s, want p99.9 alone of the extra percentiles", got)
+				}
+				if delta.Cost != nil || delta.Significance != nil {
+					t.Errorf("cost %+v, significance %+v; want neither", delta.Cost, delta.Significance)
+				}
+			},
+		},
+		{
+			name: "priced runs with samples",
+			a: func() *BenchmarkStats {
+				s := diffStats(100, 0)
+				s.Cost = &CostStats{CreditsPerMillion: 1_000_000, USDPerMillion: 2}
+				s.LatencySamples = []int64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19}
+				return s
+			},
+			b: func() *BenchmarkStats {
+				s := diffStats(100, 0)
+				s.Cost = &CostStats{CreditsPerMillion: 500_000, USDPerMillion: 1}
+				s.LatencySamples = []int64{50, 51, 52, 53, 54, 55, 56, 57, 58, 59}
+				return s
+			},
+			check: func(t *testing.T, delta *ResultsDelta) {
+				if len(delta.Cost) != 2 || delta.Cost[1].Verdict != DeltaBetter || *delta.Cost[1].Percent != -50 {
+					t.Errorf("cost %+v, want USD per million down 50%%, better", delta.Cost)
+				}
+				if s := delta.Significance; s == nil || !s.Significant || s.CliffsDelta >= 0 {
+					t.Errorf("significance %+v, want B significantly slower", s)
+				}
+			},
+		},
+		{
+			name: "no duration",
+			a:    func() *BenchmarkStats { return &BenchmarkStats{} },
+			b:    func() *BenchmarkStats { return &BenchmarkStats{} },
+			check: func(t *testing.T, delta *ResultsDelta) {
+				if delta.Throughput.A != 0 || delta.Throughput.B != 0 || delta.Throughput.Verdict != DeltaSame {
+					t.Errorf("throughput %+v, want none", delta.Throughput)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, DiffResults(&SavedResults{Path: "a.json", Stats: tt.a()}, &SavedResults{Path: "b.json", Stats: tt.b()}))
+		})
+	}
+}
+
+func TestWriteResultsDelta(t *testing.T) {
+	delta := DiffResults(&SavedResults{Path: "a.json", Stats: diffStats(100, 0)}, &SavedResults{Path: "b.json", Stats: diffStats(150, 10)})
+	tests := []struct {
+		color   bool
+		want    []string
+		notWant []string
+	}{
+		{
+			want:    []string{"A: a.json", "B: b.json", "+50ms (+50.0%)", "-1.00pp", "100.0 req/s"},
+			notWant: []string{ansiRed, ansiGreen},
+		},
+		{color: true, want: []string{ansiRed + "+50ms (+50.0%)" + ansiReset, ansiRed + "-1.00pp" + ansiReset}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := WriteResultsDelta(&out, delta, tt.color); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("color %v: output lacks %q:\n%s", tt.color, want, out.String())
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(out.String(), notWant) {
+				t.Errorf("color %v: output has %q:\n%s", tt.color, notWant, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+var encodingTargets = map[string][]string{
+	"getAccountInfo": {"base58", "base64", "base64+zstd", "jsonParsed"},
+	"getBlock":       {"json", "jsonParsed", "base58", "base64"},
+	"getTransaction": {"json", "jsonParsed", "base58", "base64"},
+}
+
+var EncodingMethods = []string{"getAccountInfo", "getBlock", "getTransaction"}
+
+type EncodingResult struct {
+	Method   string       `json:"method"`
+	Encoding string       `json:"encoding"`
+	Requests int          `json:"requests"`
+	Errors   int          `json:"errors"`
+	Latency  LatencyStats `json:"latency"`
+	Size     LatencyStats `json:"size"`
+	// WireSize is the compressed size, when -compression requested one.
+	WireSize *LatencyStats `json:"wireSize,omitempty"`
+	LastErr  string        `json:"lastError,omitempty"`
+}
+
+type EncodingStats struct {
+	Iterations  int              `json:"iterations"`
+	Account     string           `json:"account,omitempty"`
+	Interrupted bool             `json:"interrupted,omitempty"`
+	Results     []EncodingResult `json:"results"`
+}
+
+type encodingSamples struct {
+	result    EncodingResult
+	latencies []int64
+	sizes     []int64
+	wireSizes []int64
+}
+
+// RunEncodingComparison issues the same query in every supported encoding
+// per iteration, rotating which encoding goes first so none of them
+// consistently benefits from a warm provider cache.
+func (s *JSONRPCTester) RunEncodingComparison(ctx context.Context, iterations int, methods []string) (*EncodingStats, error) {
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no methods selected for encoding comparison")
+	}
+	needsBlock := false
+	for _, method := range methods {
+		if _, ok := encodingTargets[method]; !ok {
+			return nil, fmt.Errorf("encoding comparison does not support %s", method)
+		}
+		needsBlock = needsBlock || method != "getAccountInfo"
+	}
+
+	// Without an account list every iteration reads the USDC mint.
+	stats := &EncodingStats{}
+	if s.Accounts == nil {
+		stats.Account = rpcclient.USDCMint
+	}
+
+	var slot uint64
+	if _, err := s.CallResult(ctx, "getSlot", []interface{}{map[string]interface{}{"commitment": "finalized"}}, &slot); err != nil {
+		return nil, err
+	}
+
+	samples := make(map[string]*encodingSamples)
+	record := func(method, encoding string, result *rpcclient.Result) {
+		key := method + "/" + encoding
+		sample, ok := samples[key]
+		if !ok {
+			sample = &encodingSamples{result: EncodingResult{Method: method, Encoding: encoding}}
+			samples[key] = sample
+		}
+		sample.result.Requests++
+		if !result.Success {
+			sample.result.Errors++
+			sample.result.LastErr = result.Error
+			return
+		}
+		sample.latencies = append(sample.latencies, result.Latency)
+		sample.sizes = append(sample.sizes, int64(result.Size))
+		if result.WireSize > 0 {
+			sample.wireSizes = append(sample.wireSizes, int64(result.WireSize))
+		}
+	}
+
+	s.Log().Info("comparing encodings", "methods", methods, "iterations", iterations)
+	for i := 0; i < iterations && !s.stopped(ctx); i++ {
+		stats.Iterations++
+		key := stats.Account
+		if s.Accounts != nil {
+			key = s.Accounts.Next()
+		}
+		// Step back through recent blocks so each iteration reads a
+		// different one, skipping empty or skipped slots.
+		var blockSlot uint64
+		var signature string
+		if needsBlock {
+			var err error
+			if blockSlot, signature, err = s.blockSignature(ctx, slot-100-uint64(i)*archiveSkipProbe); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, method := range methods {
+			encodings := encodingTargets[method]
+			for j := range encodings {
+				encoding := encodings[(i+j)%len(encodings)]
+
+				var result *rpcclient.Result
+				var err error
+				switch method {
+				case "getAccountInfo":
+					result, err = s.GetAccountInfoEncoded(ctx, key, encoding)
+				case "getBlock":
+					result, err = s.GetBlock(ctx, blockSlot, map[string]interface{}{
+						"encoding":                       encoding,
+						"transactionDetails":             "full",
+						"rewards":                        false,
+						"maxSupportedTransactionVersion": 0,
+					})
+				case "getTransaction":
+					result, err = s.GetTransaction(ctx, signature, map[string]interface{}{
+						"encoding":                       encoding,
+						"maxSupportedTransactionVersion": 0,
+					})
+				}
+				if err != nil {
+					return nil, err
+				}
+				record(method, encoding, result)
+			}
+		}
+
+		if (i+1)%10 == 0 {
+			s.Log().Info("iterations completed", "done", i+1, "total", iterations)
+		}
+	}
+
+	stats.Interrupted = s.stopped(ctx)
+	for _, method := range EncodingMethods {
+		for _, encoding := range encodingTargets[method] {
+			sample, ok := samples[method+"/"+encoding]
+			if !ok {
+				continue
+			}
+			sample.result.Latency = s.summarize(sample.latencies)
+			sample.result.Size = s.summarize(sample.sizes)
+			if len(sample.wireSizes) > 0 {
+				wireSize := s.summarize(sample.wireSizes)
+				sample.result.WireSize = &wireSize
+			}
+			stats.Results = append(stats.Results, sample.result)
+		}
+	}
+	return stats, nil
+}
+
+// blockSignature returns the first block at or after slot that contains
+// transactions, along with its first transaction signature.
+func (s *JSONRPCTester) blockSignature(ctx context.Context, slot uint64) (uint64, string, error) {
+	for k := uint64(0); k < archiveSkipProbe; k++ {
+		result, err := s.GetBlock(ctx, slot+k, blockSignaturesConfig())
+		if err != nil {
+			return 0, "", err
+		}
+		if rpcclient.IsSkippedSlot(result) {
+			continue
+		}
+		var block struct {
+			Signatures []string `json:"signatures"`
+		}
+		if err := rpcclient.DecodeResult(result, &block); err != nil {
+			return 0, "", err
+		}
+		if len(block.Signatures) > 0 {
+			return slot + k, block.Signatures[0], nil
+		}
+	}
+	return 0, "", fmt.Errorf("no transactions found in slots %d-%d", slot, slot+archiveSkipProbe)
+}
@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// ChainEVM is Ethereum and the chains that share its JSON-RPC API.
+const ChainEVM = "evm"
+
+// DefaultEVMMethods are run on EVM endpoints when no methods are selected.
+var DefaultEVMMethods = []string{"eth_chainId", "eth_blockNumber"}
+
+func init() {
+	RegisterChain(ChainEVM, Catalog{
+		Methods:  evmMethods,
+		Defaults: DefaultEVMMethods,
+		Scenarios: map[string][]string{
+			"accounts": {"eth_getBalance", "eth_getTransactionCount"},
+			"blocks":   {"eth_blockNumber", "eth_getBlockByNumber"},
+			"logs":     {"eth_getLogs"},
+		},
+		use: func(s *JSONRPCTester) { s.evm = &evmHead{} },
+	})
+}
+
+var evmMethods = map[string]Method{
+	"eth_chainId": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, "eth_chainId", nil)
+	}},
+	"eth_blockNumber": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, "eth_blockNumber", nil)
+	}},
+	"eth_gasPrice": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, "eth_gasPrice", nil)
+	}},
+	"eth_getBalance": {Check: needsAccounts, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, "eth_getBalance", []interface{}{s.Accounts.Next(), "latest"})
+	}},
+	"eth_getTransactionCount": {Check: needsAccounts, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, "eth_getTransactionCount", []interface{}{s.Accounts.Next(), "latest"})
+	}},
+	"eth_getBlockByNumber": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, "eth_getBlockByNumber", []interface{}{"latest", false})
+	}},
+	"eth_getLogs": {Check: needsLogsRange, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		head, err := s.evm.block(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		from := uint64(0)
+		if head >= uint64(s.EVMLogsRange) {
+			from = head - uint64(s.EVMLogsRange) + 1
+		}
+		filter := map[string]interface{}{
+			"fromBlock": quantity(from),
+			"toBlock":   quantity(head),
+		}
+		if s.EVMLogAddresses != nil {
+			filter["address"] = s.EVMLogAddresses.Next()
+		}
+		return s.Call(ctx, "eth_getLogs", []interface{}{filter})
+	}},
+}
+
+func needsLogsRange(s *JSONRPCTester) error {
+	if s.EVMLogsRange < 1 {
+		return fmt.Errorf("needs a block range of at least 1 (-evm-logs-range)")
+	}
+	return nil
+}
+
+// evmHead caches the chain head eth_getLogs ranges end at, shared by every
+// copy of the tester. Fetching it for every request would double the load
+// and time eth_blockNumber alongside the method measured.
+type evmHead struct {
+	mu        sync.Mutex
+	number    uint64
+	fetchedAt time.Time
+}
+
+// block returns the head block number, refetched when it is older than
+// templateRefresh.
+func (h *evmHead) block(ctx context.Context, s *JSONRPCTester) (uint64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Since(h.fetchedAt) <= templateRefresh {
+		return h.number, nil
+	}
+	var head string
+	if _, err := s.CallResult(ctx, "eth_blockNumber", nil, &head); err != nil {
+		return 0, fmt.Errorf("prefetch block number: %w", err)
+	}
+	number, err := parseQuantity(head)
+	if err != nil {
+		return 0, fmt.Errorf("prefetch block number: %w", err)
+	}
+	h.number, h.fetchedAt = number, time.Now()
+	return number, nil
+}
+
+// quantity encodes n as an EVM JSON-RPC quantity, hex without leading
+// zeros.
+func quantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
+
+// parseQuantity decodes an EVM JSON-RPC quantity such as "0x1b4".
+func parseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) == 2 {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	n, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
+
+// LoadEVMAddressList reads one 0x-prefixed, 20-byte hex address per line.
+// Blank lines and lines starting with # are ignored.
+func LoadEVMAddressList(path string) ([]string, error) {
+	return loadKeyList(path, "addresses", checkEVMAddress)
+}
+
+func checkEVMAddress(address string) error {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return fmt.Errorf("invalid address %q: want 0x and 40 hex digits", address)
+	}
+	if _, err := hex.DecodeString(address[2:]); err != nil {
+		return fmt.Errorf("invalid address %q: want 0x and 40 hex digits", address)
+	}
+	return nil
+}
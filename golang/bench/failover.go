@@ -0,0 +1,321 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Failover moves load from the tester's endpoint, the primary, to Backup
+// once Threshold of the primary's requests in a row have failed. If
+// FailAfter is positive the primary's failure is simulated: from that far
+// into the run its requests fail at once, as if the connection were
+// refused. Otherwise the run waits for it to fail for real.
+type Failover struct {
+	Backup    string
+	FailAfter time.Duration
+	Threshold int
+}
+
+// FailoverStats is what a client saw while failing over. Times are in
+// milliseconds from the start of the run. FailureAtMs is when the primary
+// failed: FailAfter when simulated, else when the first of the failures
+// that triggered the switch was sent. SwitchedAtMs is when the last of them
+// came back and load moved to the backup, and DetectionMs the time between
+// the two. GapMs runs from the primary's last success to the backup's
+// first, the longest the client went without an answer; FailedRequests
+// counts the failures from the primary's failure to that first answer.
+type FailoverStats struct {
+	Primary     string  `json:"primary"`
+	Backup      string  `json:"backup"`
+	Arrival     string  `json:"arrival"`
+	Rate        float64 `json:"rate"`
+	Concurrency int     `json:"concurrency"`
+	Threshold   int     `json:"threshold"`
+	Simulated   bool    `json:"simulated"`
+	// FailedOver reports whether load moved to the backup before the run
+	// ended.
+	FailedOver             bool  `json:"failedOver"`
+	FailureAtMs            int64 `json:"failureAtMs,omitempty"`
+	SwitchedAtMs           int64 `json:"switchedAtMs,omitempty"`
+	FirstBackupSuccessAtMs int64 `json:"firstBackupSuccessAtMs,omitempty"`
+	DetectionMs            int64 `json:"detectionMs,omitempty"`
+	GapMs                  int64 `json:"gapMs,omitempty"`
+	FailedRequests         int   `json:"failedRequests"`
+	// RecoveryThreshold is the corrected p99 the backup has to get back
+	// under, in milliseconds: recoveryTolerance times the primary's before
+	// it failed. Recovered reports whether it did before the run ended,
+	// and RecoveryMs how long after the primary's failure.
+	RecoveryThreshold int64        `json:"recoveryThreshold,omitempty"`
+	Recovered         bool         `json:"recovered"`
+	RecoveryMs        int64        `json:"recoveryMs,omitempty"`
+	Interrupted       bool         `json:"interrupted,omitempty"`
+	Phases            []PhaseStats `json:"phases"`
+}
+
+var failoverPhases = []string{"primary", "failover", "backup"}
+
+// failoverCall is one request of a failover run, by when it was sent and
+// when it came back.
+type failoverCall struct {
+	backup     bool
+	sent, done time.Time
+	failed     bool
+	success    bool
+}
+
+// failoverSwitch routes a failover run's requests and moves them to the
+// backup once the primary fails.
+type failoverSwitch struct {
+	failover        Failover
+	primary, backup *JSONRPCTester
+	start           time.Time
+	// failAt is when the simulated failure starts, or zero.
+	failAt time.Time
+
+	mu sync.Mutex
+	// switchedAt is when load moved to the backup, or zero while it is on
+	// the primary, and failedAt when the primary failed.
+	switchedAt, failedAt time.Time
+	// streak counts the primary's latest failures in a row, the first of
+	// them sent at streakStartedAt.
+	streak          int
+	streakStartedAt time.Time
+	calls           []failoverCall
+}
+
+// target returns the tester the next request goes to, and whether it is the
+// backup.
+func (f *failoverSwitch) target() (*JSONRPCTester, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.switchedAt.IsZero() {
+		return f.primary, false
+	}
+	return f.backup, true
+}
+
+// wrap sends run to whichever endpoint currently takes the load.
+func (f *failoverSwitch) wrap(method string, run methodRunner) methodRunner {
+	return func(ctx context.Context, _ *JSONRPCTester) (*rpcclient.Result, error) {
+		tester, backup := f.target()
+		sent := time.Now()
+		var result *rpcclient.Result
+		if !backup && !f.failAt.IsZero() && !sent.Before(f.failAt) {
+			result = &rpcclient.Result{Method: method, Error: "simulated primary failure", StartedAt: sent}
+		} else {
+			var err error
+			if result, err = run(ctx, tester); err != nil {
+				return result, err
+			}
+		}
+		f.record(failoverCall{
+			backup:  backup,
+			sent:    sent,
+			done:    time.Now(),
+			failed:  !result.Success && !result.Unsupported,
+			success: result.Success,
+		})
+		return result, nil
+	}
+}
+
+// record counts call, and switches to the backup once the primary has
+// failed Threshold times in a row. Primary requests still in flight when
+// it switches change nothing.
+func (f *failoverSwitch) record(call failoverCall) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+	if call.backup || !f.switchedAt.IsZero() {
+		return
+	}
+	if !call.failed {
+		// A request sent before the failures started says nothing of the
+		// endpoint since.
+		if call.sent.After(f.streakStartedAt) {
+			f.streak = 0
+		}
+		return
+	}
+	if f.streak == 0 || call.sent.Before(f.streakStartedAt) {
+		f.streakStartedAt = call.sent
+	}
+	f.streak++
+	if f.streak < f.failover.Threshold {
+		return
+	}
+	f.switchedAt = call.done
+	f.failedAt = f.streakStartedAt
+	if !f.failAt.IsZero() && f.failAt.Before(f.failedAt) {
+		f.failedAt = f.failAt
+	}
+	f.primary.Log().Warn("primary failed, switching to the backup",
+		"failures", f.streak, "backup", rpcclient.RedactURL(f.failover.Backup),
+		"detection", f.switchedAt.Sub(f.failedAt).Round(time.Millisecond))
+}
+
+// RunFailover sends the configured methods at cfg.Rate for cfg.Duration,
+// first to the tester's endpoint and then, once it fails, to
+// failover.Backup. It reports how long the failure took to detect, how long
+// the client went without a successful response, and how long after the
+// failure the backup's corrected p99 and error rate settled back to the
+// primary's, alongside open-loop stats for the time before the failure,
+// during the switch and after it. The backup isn't warmed up, so its first
+// requests open new connections as a real failover would.
+func (s *JSONRPCTester) RunFailover(ctx context.Context, failover Failover, cfg LoadConfig) (*FailoverStats, error) {
+	if cfg.Rate <= 0 || cfg.Duration <= 0 {
+		return nil, fmt.Errorf("failover test needs a positive rate and duration")
+	}
+	if cfg.Concurrency <= 0 {
+		return nil, fmt.Errorf("failover concurrency must be positive")
+	}
+	if failover.Backup == "" {
+		return nil, fmt.Errorf("failover test needs a backup endpoint")
+	}
+	if failover.Threshold <= 0 {
+		return nil, fmt.Errorf("failover threshold must be positive")
+	}
+	if failover.FailAfter < 0 || failover.FailAfter >= cfg.Duration {
+		return nil, fmt.Errorf("simulated failure must come within the run's %s", cfg.Duration)
+	}
+	arr, err := newArrivals(cfg.Arrival, cfg.Rate, s.newRand())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Arrival == "" {
+		cfg.Arrival = ArrivalConstant
+	}
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, err
+	}
+	backup := s.withEndpoint(failover.Backup)
+	backup.Label = s.Label
+
+	s.Log().Info("running failover test", "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"backup", rpcclient.RedactURL(failover.Backup), "rate", cfg.Rate, "arrival", cfg.Arrival,
+		"duration", cfg.Duration, "fail_after", failover.FailAfter, "threshold", failover.Threshold,
+		"concurrency", cfg.Concurrency, "methods", methods)
+
+	f := &failoverSwitch{failover: failover, primary: s, backup: backup, start: time.Now()}
+	if failover.FailAfter > 0 {
+		f.failAt = f.start.Add(failover.FailAfter)
+	}
+	request := func(i int) methodRunner {
+		method := methods[i%len(methods)]
+		return f.wrap(method, s.runner(method))
+	}
+	var samples []openLoopSample
+	expected := int(cfg.Rate * cfg.Duration.Seconds())
+	if _, err := s.streamSchedule(ctx, cfg, request, arr, expected, func(sample openLoopSample) {
+		samples = append(samples, sample)
+	}); err != nil {
+		return nil, err
+	}
+
+	stats := &FailoverStats{
+		Primary:     rpcclient.RedactURL(s.Endpoint),
+		Backup:      rpcclient.RedactURL(failover.Backup),
+		Arrival:     cfg.Arrival,
+		Rate:        cfg.Rate,
+		Concurrency: cfg.Concurrency,
+		Threshold:   failover.Threshold,
+		Simulated:   failover.FailAfter > 0,
+		FailedOver:  !f.switchedAt.IsZero(),
+		Interrupted: s.stopped(ctx),
+	}
+	if !stats.FailedOver {
+		s.Log().Warn("primary never failed, so the run stayed on it")
+		steps := []LoadStep{{Rate: cfg.Rate, Duration: cfg.Duration}}
+		for _, step := range s.splitSteps(samples, steps, cfg, warmupRequests, stats.Interrupted) {
+			stats.Phases = append(stats.Phases, PhaseStats{Phase: failoverPhases[0], StepStats: step})
+		}
+		return stats, nil
+	}
+
+	failedAt := f.failedAt.Sub(f.start).Round(time.Millisecond)
+	switchedAt := f.switchedAt.Sub(f.start).Round(time.Millisecond)
+	stats.FailureAtMs = failedAt.Milliseconds()
+	stats.SwitchedAtMs = switchedAt.Milliseconds()
+	stats.DetectionMs = (switchedAt - failedAt).Milliseconds()
+
+	var lastSuccess, firstBackupSuccess time.Time
+	for _, call := range f.calls {
+		switch {
+		case call.success && !call.backup && call.done.After(lastSuccess):
+			lastSuccess = call.done
+		case call.success && call.backup && (firstBackupSuccess.IsZero() || call.done.Before(firstBackupSuccess)):
+			firstBackupSuccess = call.done
+		}
+	}
+	if !firstBackupSuccess.IsZero() {
+		if lastSuccess.IsZero() {
+			lastSuccess = f.start
+		}
+		stats.FirstBackupSuccessAtMs = firstBackupSuccess.Sub(f.start).Milliseconds()
+		stats.GapMs = firstBackupSuccess.Sub(lastSuccess).Milliseconds()
+		for _, call := range f.calls {
+			if call.failed && !call.done.Before(f.failedAt) && !call.done.After(firstBackupSuccess) {
+				stats.FailedRequests++
+			}
+		}
+	}
+
+	// The phases split the run by when requests were scheduled, leaving out
+	// any too short to measure, such as the primary's when it was down from
+	// the start.
+	var steps []LoadStep
+	var phases []string
+	for i, d := range []time.Duration{failedAt, switchedAt - failedAt, cfg.Duration - switchedAt} {
+		if d > 0 {
+			steps = append(steps, LoadStep{Rate: cfg.Rate, Duration: d})
+			phases = append(phases, failoverPhases[i])
+		}
+	}
+	for i, step := range s.splitSteps(samples, steps, cfg, warmupRequests, stats.Interrupted) {
+		stats.Phases = append(stats.Phases, PhaseStats{Phase: phases[i], StepStats: step})
+	}
+	backupPhase := cfg.Duration - switchedAt
+	if len(stats.Phases) < len(steps) || phases[0] != failoverPhases[0] || stats.Phases[0].SuccessfulRequests == 0 || backupPhase <= 0 {
+		return stats, nil
+	}
+
+	primary := stats.Phases[0]
+	stats.RecoveryThreshold = int64(float64(primary.CorrectedLatency.P99) * recoveryTolerance)
+	maxErrorRate := 100 - primary.SuccessRate + recoveryErrorSlack
+
+	// Bucket the backup phase by when requests were scheduled; the client
+	// has recovered after the last window that was degraded.
+	windows := int((backupPhase + recoveryWindow - 1) / recoveryWindow)
+	buckets := make([][]openLoopSample, windows)
+	for _, sample := range samples {
+		if i := int((sample.offset - switchedAt) / recoveryWindow); sample.offset >= switchedAt && i < windows {
+			buckets[i] = append(buckets[i], sample)
+		}
+	}
+	lastDegraded, lastSeen := -1, -1
+	for i, bucket := range buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+		lastSeen = i
+		window := s.summarizeOpenLoop(bucket, recoveryWindow)
+		if window.CorrectedLatency.P99 > stats.RecoveryThreshold || 100-window.SuccessRate > maxErrorRate {
+			lastDegraded = i
+		}
+	}
+	if lastSeen >= 0 && lastDegraded < lastSeen {
+		stats.Recovered = true
+		stats.RecoveryMs = (switchedAt - failedAt + time.Duration(lastDegraded+1)*recoveryWindow).Milliseconds()
+	}
+	return stats, nil
+}
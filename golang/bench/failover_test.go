@@ -0,0 +1,136 @@
+package bench
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/mockserver"
+)
+
+func TestRunFailover(t *testing.T) {
+	healthy := mockserver.Config{Latency: 20 * time.Millisecond, Seed: 1}
+	tests := []struct {
+		name             string
+		primary          mockserver.Config
+		failAfter        time.Duration
+		duration         time.Duration
+		failedOver       bool
+		failureAtMs      int64
+		phases           []string
+		recovered        bool
+		recoveryMeasured bool
+	}{
+		{
+			name:             "simulated failure",
+			primary:          healthy,
+			failAfter:        300 * time.Millisecond,
+			duration:         1500 * time.Millisecond,
+			failedOver:       true,
+			failureAtMs:      300,
+			phases:           []string{"primary", "failover", "backup"},
+			recovered:        true,
+			recoveryMeasured: true,
+		},
+		{
+			// With no primary phase to compare with, recovery isn't measured.
+			name:       "primary down from the start",
+			primary:    mockserver.Config{ErrorRate: 1, Seed: 1},
+			duration:   300 * time.Millisecond,
+			failedOver: true,
+			phases:     []string{"failover", "backup"},
+		},
+		{
+			name:     "primary never fails",
+			primary:  healthy,
+			duration: 300 * time.Millisecond,
+			phases:   []string{"primary"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := newMockTester(t, tt.primary, "getSlot")
+			backup := newMockTester(t, healthy).Endpoint
+			failover := Failover{Backup: backup, FailAfter: tt.failAfter, Threshold: 3}
+			stats, err := tester.RunFailover(context.Background(), failover, LoadConfig{Rate: 50, Duration: tt.duration, Concurrency: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.FailedOver != tt.failedOver || stats.Simulated != (tt.failAfter > 0) {
+				t.Fatalf("failed over %v, simulated %v; want %v, %v", stats.FailedOver, stats.Simulated, tt.failedOver, tt.failAfter > 0)
+			}
+			var phases []string
+			for _, phase := range stats.Phases {
+				phases = append(phases, phase.Phase)
+			}
+			if !reflect.DeepEqual(phases, tt.phases) {
+				t.Errorf("phases %v, want %v", phases, tt.phases)
+			}
+			if !tt.failedOver {
+				if stats.FailedRequests != 0 || stats.SwitchedAtMs != 0 {
+					t.Errorf("stayed on the primary with %d failed requests, switched at %dms", stats.FailedRequests, stats.SwitchedAtMs)
+				}
+				return
+			}
+			if stats.FailureAtMs != tt.failureAtMs {
+				t.Errorf("failed at %dms, want %dms", stats.FailureAtMs, tt.failureAtMs)
+			}
+			// Three failures at 50/s take at least two intervals to send.
+			if stats.DetectionMs < 40 || stats.SwitchedAtMs != stats.FailureAtMs+stats.DetectionMs {
+				t.Errorf("failed at %dms, switched at %dms after %dms; want the switch at least 40ms after the failure",
+					stats.FailureAtMs, stats.SwitchedAtMs, stats.DetectionMs)
+			}
+			if stats.FailedRequests < 3 {
+				t.Errorf("%d failed requests, want at least the 3 that triggered the switch", stats.FailedRequests)
+			}
+			if stats.FirstBackupSuccessAtMs < stats.SwitchedAtMs || stats.GapMs < stats.DetectionMs {
+				t.Errorf("backup answered at %dms after a %dms gap; want after the switch at %dms, the gap spanning detection",
+					stats.FirstBackupSuccessAtMs, stats.GapMs, stats.SwitchedAtMs)
+			}
+			if (stats.RecoveryThreshold > 0) != tt.recoveryMeasured || stats.Recovered != tt.recovered {
+				t.Errorf("recovery threshold %dms, recovered %v; want measured %v, recovered %v",
+					stats.RecoveryThreshold, stats.Recovered, tt.recoveryMeasured, tt.recovered)
+			}
+			if stats.Recovered && stats.RecoveryMs < stats.DetectionMs {
+				t.Errorf("recovered %dms after the failure, before it was detected at %dms", stats.RecoveryMs, stats.DetectionMs)
+			}
+		})
+	}
+}
+
+func TestRunFailoverChecksConfig(t *testing.T) {
+	tester := newMockTester(t, mockserver.Config{Seed: 1}, "getSlot")
+	cfg := LoadConfig{Rate: 10, Duration: time.Second, Concurrency: 1}
+	failover := Failover{Backup: "http://127.0.0.1:1", Threshold: 3}
+	tests := []struct {
+		name     string
+		failover func(*Failover)
+		cfg      func(*LoadConfig)
+		err      string
+	}{
+		{name: "no rate", cfg: func(c *LoadConfig) { c.Rate = 0 }, err: "positive rate and duration"},
+		{name: "no duration", cfg: func(c *LoadConfig) { c.Duration = 0 }, err: "positive rate and duration"},
+		{name: "no concurrency", cfg: func(c *LoadConfig) { c.Concurrency = 0 }, err: "concurrency must be positive"},
+		{name: "no backup", failover: func(f *Failover) { f.Backup = "" }, err: "needs a backup"},
+		{name: "no threshold", failover: func(f *Failover) { f.Threshold = 0 }, err: "threshold must be positive"},
+		{name: "failure after the run", failover: func(f *Failover) { f.FailAfter = time.Second }, err: "within the run"},
+		{name: "failure before the run", failover: func(f *Failover) { f.FailAfter = -time.Second }, err: "within the run"},
+		{name: "unknown arrival", cfg: func(c *LoadConfig) { c.Arrival = "bursty" }, err: "bursty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, c := failover, cfg
+			if tt.failover != nil {
+				tt.failover(&f)
+			}
+			if tt.cfg != nil {
+				tt.cfg(&c)
+			}
+			if _, err := tester.RunFailover(context.Background(), f, c); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("RunFailover = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
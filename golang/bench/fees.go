@@ -0,0 +1,160 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// FeeTrackerConfig configures RunFeeTracker: getRecentPrioritizationFees
+// is called every Interval for Duration, for transactions writing all of
+// Accounts, or any transaction if there are none.
+type FeeTrackerConfig struct {
+	Duration time.Duration
+	Interval time.Duration
+	Accounts []string
+}
+
+// FeeSample is one getRecentPrioritizationFees call. Offset is when it was
+// made, in milliseconds since the start of the run. Slots is how many
+// slots it covered and NonZero how many of them had a fee; the levels are
+// percentiles of the per-slot fees, in micro-lamports per compute unit.
+type FeeSample struct {
+	Offset  int64  `json:"offset"`
+	Latency int64  `json:"latency"`
+	Slots   int    `json:"slots"`
+	NonZero int    `json:"nonZero"`
+	Median  int64  `json:"median"`
+	P75     int64  `json:"p75"`
+	P90     int64  `json:"p90"`
+	Max     int64  `json:"max"`
+	Error   string `json:"error,omitempty"`
+}
+
+// FeeTrackerStats covers a fee tracking run. Latency is of successful
+// calls. Fees summarizes the fee of every distinct slot the calls covered,
+// which overlap from one call to the next, and Medians the median of each
+// call, showing how the going rate moved during the run.
+type FeeTrackerStats struct {
+	Duration     string       `json:"duration"`
+	Interval     string       `json:"interval"`
+	Accounts     []string     `json:"accounts,omitempty"`
+	Requests     int          `json:"requests"`
+	Errors       int          `json:"errors"`
+	Latency      LatencyStats `json:"latency"`
+	Slots        int          `json:"slots"`
+	Fees         LatencyStats `json:"fees"`
+	Medians      LatencyStats `json:"medians"`
+	Interrupted  bool         `json:"interrupted,omitempty"`
+	ErrorSamples []string     `json:"errorSamples,omitempty"`
+	Samples      []FeeSample  `json:"samples"`
+}
+
+type prioritizationFee struct {
+	Slot              uint64 `json:"slot"`
+	PrioritizationFee int64  `json:"prioritizationFee"`
+}
+
+// RunFeeTracker samples getRecentPrioritizationFees over time, timing each
+// call and recording the fee levels it reports. Fee estimation is on the
+// hot path of trading clients, so both matter: how fast the answer comes
+// and what it says.
+func (s *JSONRPCTester) RunFeeTracker(ctx context.Context, cfg FeeTrackerConfig) (*FeeTrackerStats, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("fee sampling interval must be positive")
+	}
+	for _, account := range cfg.Accounts {
+		if _, err := rpcclient.ParsePublicKey(account); err != nil {
+			return nil, err
+		}
+	}
+	stats := &FeeTrackerStats{
+		Duration: cfg.Duration.String(),
+		Interval: cfg.Interval.String(),
+		Accounts: cfg.Accounts,
+		Samples:  []FeeSample{},
+	}
+	s.Log().Info("tracking prioritization fees", "accounts", len(cfg.Accounts),
+		"interval", cfg.Interval, "duration", cfg.Duration)
+
+	var latencies, medians []int64
+	slotFees := map[uint64]int64{}
+	start := time.Now()
+	for next := start; time.Since(start) < cfg.Duration; next = next.Add(cfg.Interval) {
+		s.sleep(ctx, time.Until(next))
+		if s.stopped(ctx) {
+			break
+		}
+		sample := FeeSample{Offset: time.Since(start).Milliseconds()}
+		result, err := s.GetRecentPrioritizationFees(ctx, cfg.Accounts)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		stats.Requests++
+		sample.Latency = result.Latency
+		var fees []prioritizationFee
+		if err := rpcclient.DecodeResult(result, &fees); err != nil {
+			stats.Errors++
+			sample.Error = err.Error()
+			stats.addErrorSample(sample.Error)
+			stats.Samples = append(stats.Samples, sample)
+			continue
+		}
+		latencies = append(latencies, result.Latency)
+
+		levels := make([]int64, len(fees))
+		for i, fee := range fees {
+			levels[i] = fee.PrioritizationFee
+			if fee.PrioritizationFee > 0 {
+				sample.NonZero++
+			}
+			slotFees[fee.Slot] = fee.PrioritizationFee
+		}
+		sample.Slots = len(fees)
+		if len(levels) > 0 {
+			sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+			sample.Median = nearestRank(levels, 0.5)
+			sample.P75 = nearestRank(levels, 0.75)
+			sample.P90 = nearestRank(levels, 0.9)
+			sample.Max = levels[len(levels)-1]
+			medians = append(medians, sample.Median)
+		}
+		stats.Samples = append(stats.Samples, sample)
+		s.Log().Debug("prioritization fees", "latency_ms", sample.Latency,
+			"median", sample.Median, "p90", sample.P90, "max", sample.Max)
+	}
+
+	all := make([]int64, 0, len(slotFees))
+	for _, fee := range slotFees {
+		all = append(all, fee)
+	}
+	stats.Slots = len(all)
+	stats.Fees = s.summarize(all)
+	stats.Medians = s.summarize(medians)
+	stats.Latency = s.summarize(latencies)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// nearestRank returns the q-quantile of sorted, a sample rather than an
+// interpolation, since fee levels are what was actually paid.
+func nearestRank(sorted []int64, q float64) int64 {
+	return sorted[int(q*float64(len(sorted)-1)+0.5)]
+}
+
+func (stats *FeeTrackerStats) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
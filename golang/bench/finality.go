@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// finalityLevels are the commitments RunFinalityProbe follows, in order.
+var finalityLevels = []string{"processed", "confirmed", "finalized"}
+
+// FinalityConfig configures RunFinalityProbe: getSlot is polled at each
+// commitment every PollInterval for Duration.
+type FinalityConfig struct {
+	Duration     time.Duration
+	PollInterval time.Duration
+}
+
+// FinalityStats covers a finality probe. ToConfirmed is how long after a
+// slot was first seen processed it was seen confirmed, ToFinalized how long
+// until it was seen finalized, and ConfirmedToFinalized the time between
+// the two. ConfirmedLag and FinalizedLag are how many slots the confirmed
+// and finalized slots trailed the processed one when polled. Slots still
+// unfinalized when the run ended are left out.
+type FinalityStats struct {
+	Duration             string         `json:"duration"`
+	PollInterval         string         `json:"pollInterval"`
+	Polls                map[string]int `json:"polls"`
+	PollErrors           int            `json:"pollErrors"`
+	PollLatency          LatencyStats   `json:"pollLatency"`
+	Slots                int            `json:"slots"`
+	Confirmed            int            `json:"confirmed"`
+	Finalized            int            `json:"finalized"`
+	ToConfirmed          LatencyStats   `json:"toConfirmed"`
+	ToFinalized          LatencyStats   `json:"toFinalized"`
+	ConfirmedToFinalized LatencyStats   `json:"confirmedToFinalized"`
+	ConfirmedLag         LatencyStats   `json:"confirmedLag"`
+	FinalizedLag         LatencyStats   `json:"finalizedLag"`
+	Interrupted          bool           `json:"interrupted,omitempty"`
+	ErrorSamples         []string       `json:"errorSamples,omitempty"`
+}
+
+// slotSighting is when a commitment's slot was first seen at a new high.
+type slotSighting struct {
+	slot uint64
+	at   time.Time
+}
+
+// RunFinalityProbe measures the endpoint's data pipeline rather than its
+// HTTP round trips: how long slots take to move from processed through
+// confirmed to finalized, as the endpoint reports them. Each commitment is
+// polled on its own so a slow answer at one level doesn't delay the
+// others; resolution is the poll interval plus the request latency.
+func (s *JSONRPCTester) RunFinalityProbe(ctx context.Context, cfg FinalityConfig) (*FinalityStats, error) {
+	if cfg.PollInterval <= 0 {
+		return nil, fmt.Errorf("finality poll interval must be positive")
+	}
+	stats := &FinalityStats{
+		Duration:     cfg.Duration.String(),
+		PollInterval: cfg.PollInterval.String(),
+		Polls:        map[string]int{},
+	}
+	s.Log().Info("probing finality", "duration", cfg.Duration, "poll_interval", cfg.PollInterval)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []int64
+		tips      = map[string]uint64{}
+		lags      = map[string][]int64{}
+		sightings = map[string][]slotSighting{}
+		failure   error
+	)
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	for _, level := range finalityLevels {
+		wg.Add(1)
+		go func(level string) {
+			defer wg.Done()
+			for next := time.Now(); ; next = next.Add(cfg.PollInterval) {
+				s.sleep(runCtx, time.Until(next))
+				if s.stopped(runCtx) {
+					return
+				}
+				var slot uint64
+				result, err := s.GetSlotWithCommitment(runCtx, level)
+				if err != nil {
+					if runCtx.Err() == nil {
+						mu.Lock()
+						failure = err
+						mu.Unlock()
+					}
+					return
+				}
+				seen := time.Now()
+				decodeErr := rpcclient.DecodeResult(result, &slot)
+
+				mu.Lock()
+				stats.Polls[level]++
+				if decodeErr != nil {
+					stats.PollErrors++
+					stats.addErrorSample(decodeErr.Error())
+					mu.Unlock()
+					continue
+				}
+				latencies = append(latencies, result.Latency)
+				if slot > tips[level] {
+					tips[level] = slot
+					sightings[level] = append(sightings[level], slotSighting{slot, seen})
+				}
+				if level != "processed" && tips["processed"] >= slot {
+					lags[level] = append(lags[level], int64(tips["processed"]-slot))
+				}
+				mu.Unlock()
+			}
+		}(level)
+	}
+	wg.Wait()
+	if failure != nil {
+		return nil, failure
+	}
+
+	// The first sighting at each level is of a slot that got there before
+	// the probe started, so only later ones are timed.
+	processed, confirmed, finalized := trimFirst(sightings["processed"]), trimFirst(sightings["confirmed"]), trimFirst(sightings["finalized"])
+	var toConfirmed, toFinalized, confirmedToFinalized []int64
+	for _, sighting := range processed {
+		if at, ok := reachedAt(confirmed, sighting.slot); ok {
+			toConfirmed = append(toConfirmed, at.Sub(sighting.at).Milliseconds())
+		}
+		if at, ok := reachedAt(finalized, sighting.slot); ok {
+			toFinalized = append(toFinalized, at.Sub(sighting.at).Milliseconds())
+		}
+	}
+	for _, sighting := range confirmed {
+		if at, ok := reachedAt(finalized, sighting.slot); ok {
+			confirmedToFinalized = append(confirmedToFinalized, at.Sub(sighting.at).Milliseconds())
+		}
+	}
+
+	stats.Slots = len(processed)
+	stats.Confirmed = len(toConfirmed)
+	stats.Finalized = len(toFinalized)
+	stats.PollLatency = s.summarize(latencies)
+	stats.ToConfirmed = s.summarize(toConfirmed)
+	stats.ToFinalized = s.summarize(toFinalized)
+	stats.ConfirmedToFinalized = s.summarize(confirmedToFinalized)
+	stats.ConfirmedLag = s.summarize(lags["confirmed"])
+	stats.FinalizedLag = s.summarize(lags["finalized"])
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+func trimFirst(sightings []slotSighting) []slotSighting {
+	if len(sightings) == 0 {
+		return nil
+	}
+	return sightings[1:]
+}
+
+// reachedAt returns when sightings, ascending by slot, first reached slot.
+func reachedAt(sightings []slotSighting, slot uint64) (time.Time, bool) {
+	i := sort.Search(len(sightings), func(i int) bool { return sightings[i].slot >= slot })
+	if i == len(sightings) {
+		return time.Time{}, false
+	}
+	return sightings[i].at, true
+}
+
+func (stats *FinalityStats) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
@@ -0,0 +1,98 @@
+package bench
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Fingerprint identifies the software and edge behind an endpoint, so
+// results can be attributed to a node version and CDN location rather than
+// just a URL. SolanaCore and FeatureSet come from getVersion, and
+// ClientVersion from web3_clientVersion on EVM endpoints. Edge is the CDN
+// point of presence that answered, where the headers name one, such as
+// Cloudflare's colo code from cf-ray.
+type Fingerprint struct {
+	SolanaCore    string            `json:"solanaCore,omitempty"`
+	FeatureSet    uint32            `json:"featureSet,omitempty"`
+	ClientVersion string            `json:"clientVersion,omitempty"`
+	Protocol      string            `json:"protocol,omitempty"`
+	TLSVersion    string            `json:"tlsVersion,omitempty"`
+	IPFamily      string            `json:"ipFamily,omitempty"`
+	Server        string            `json:"server,omitempty"`
+	Via           string            `json:"via,omitempty"`
+	CFRay         string            `json:"cfRay,omitempty"`
+	Edge          string            `json:"edge,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// fingerprintHeaders are further response headers that tell CDNs, proxies
+// and providers apart.
+var fingerprintHeaders = []string{
+	"X-Powered-By", "X-Served-By", "X-Cache", "X-Amz-Cf-Pop", "X-Amz-Cf-Id",
+	"Fly-Request-Id", "X-Envoy-Upstream-Service-Time", "Alt-Svc",
+}
+
+// TakeFingerprint asks the endpoint for its version and records what the
+// response reveals in s.Fingerprint, which benchmark stats report. A
+// failed version call is noted in the fingerprint rather than returned,
+// since the headers may still be telling; only ctx ending is an error.
+func (s *JSONRPCTester) TakeFingerprint(ctx context.Context) error {
+	method := "getVersion"
+	if s.Chain() == ChainEVM {
+		method = "web3_clientVersion"
+	}
+	result, header, err := s.CallWithHeader(ctx, method, nil)
+	if err != nil {
+		return err
+	}
+	fp := &Fingerprint{
+		Protocol:   result.Protocol,
+		TLSVersion: result.TLSVersion,
+		IPFamily:   result.IPFamily,
+		Server:     header.Get("Server"),
+		Via:        header.Get("Via"),
+		CFRay:      header.Get("Cf-Ray"),
+		Edge:       edgeLocation(header),
+	}
+	for _, name := range fingerprintHeaders {
+		if value := header.Get(name); value != "" {
+			if fp.Headers == nil {
+				fp.Headers = map[string]string{}
+			}
+			fp.Headers[strings.ToLower(name)] = value
+		}
+	}
+
+	if method == "getVersion" {
+		var version struct {
+			SolanaCore string `json:"solana-core"`
+			FeatureSet uint32 `json:"feature-set"`
+		}
+		if err := rpcclient.DecodeResult(result, &version); err != nil {
+			fp.Error = err.Error()
+		}
+		fp.SolanaCore, fp.FeatureSet = version.SolanaCore, version.FeatureSet
+	} else if err := rpcclient.DecodeResult(result, &fp.ClientVersion); err != nil {
+		fp.Error = err.Error()
+	}
+	s.Fingerprint = fp
+	s.Log().Info("endpoint fingerprint", "version", fp.SolanaCore+fp.ClientVersion,
+		"protocol", fp.Protocol, "server", fp.Server, "edge", fp.Edge)
+	return nil
+}
+
+// edgeLocation returns the CDN point of presence named in header:
+// Cloudflare's cf-ray ends in the colo code, e.g. "8a1f2c3d4e5f6a7b-FRA",
+// and CloudFront sends x-amz-cf-pop, e.g. "FRA56-P1".
+func edgeLocation(header http.Header) string {
+	if ray := header.Get("Cf-Ray"); ray != "" {
+		if i := strings.LastIndex(ray, "-"); i >= 0 {
+			return ray[i+1:]
+		}
+	}
+	return header.Get("X-Amz-Cf-Pop")
+}
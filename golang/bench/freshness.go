@@ -0,0 +1,218 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// FreshnessConfig configures RunFreshnessComparison: slots are followed
+// over slotSubscribe on WSURL, or the URL derived from the endpoint if it
+// is empty, and by polling getSlot every PollInterval, for Duration.
+type FreshnessConfig struct {
+	WSURL        string
+	Duration     time.Duration
+	PollInterval time.Duration
+}
+
+// FreshnessStats covers a freshness comparison. SubscribeTime runs from
+// dialing to the subscription being confirmed. Delta is, for every slot
+// notified over the websocket, how much later polling first returned that
+// slot or a higher one: positive when the websocket was ahead, negative
+// when polling was. WSFirst and PollFirst count the slots each side saw
+// first. Slots polling had not reached when the run ended are left out.
+type FreshnessStats struct {
+	WSEndpoint    string       `json:"wsEndpoint"`
+	Duration      string       `json:"duration"`
+	PollInterval  string       `json:"pollInterval"`
+	SubscribeTime int64        `json:"subscribeTime"`
+	Notifications int          `json:"notifications"`
+	Polls         int          `json:"polls"`
+	PollErrors    int          `json:"pollErrors"`
+	PollLatency   LatencyStats `json:"pollLatency"`
+	WSSlots       int          `json:"wsSlots"`
+	PolledSlots   int          `json:"polledSlots"`
+	Compared      int          `json:"compared"`
+	WSFirst       int          `json:"wsFirst"`
+	PollFirst     int          `json:"pollFirst"`
+	Delta         LatencyStats `json:"delta"`
+	Error         string       `json:"error,omitempty"`
+	Interrupted   bool         `json:"interrupted,omitempty"`
+	ErrorSamples  []string     `json:"errorSamples,omitempty"`
+}
+
+// RunFreshnessComparison measures how much sooner new slots are visible
+// over a websocket subscription than by polling getSlot as fast as
+// PollInterval allows on the same endpoint, to tell whether a latency
+// budget needs websockets. Polling starts once the subscription is
+// confirmed, so both sides start from the same moment. A subscription
+// that breaks mid-run ends the comparison, reported in Error.
+func (s *JSONRPCTester) RunFreshnessComparison(ctx context.Context, cfg FreshnessConfig) (*FreshnessStats, error) {
+	if cfg.PollInterval <= 0 {
+		return nil, fmt.Errorf("freshness poll interval must be positive")
+	}
+	wsURL := cfg.WSURL
+	if wsURL == "" {
+		var err error
+		if wsURL, err = rpcclient.WSURL(s.Client.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+	stats := &FreshnessStats{
+		WSEndpoint:   rpcclient.RedactURL(wsURL),
+		Duration:     cfg.Duration.String(),
+		PollInterval: cfg.PollInterval.String(),
+	}
+	s.Log().Info("comparing slot freshness", "ws_endpoint", stats.WSEndpoint,
+		"duration", cfg.Duration, "poll_interval", cfg.PollInterval)
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.Stop:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	start := time.Now()
+	conn, err := s.Client.DialWS(runCtx, wsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.Subscribe(runCtx, "slotSubscribe", nil); err != nil {
+		return nil, err
+	}
+	stats.SubscribeTime = time.Since(start).Milliseconds()
+	s.Log().Info("subscribed to slots", "subscribe_ms", stats.SubscribeTime)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []int64
+		tip       uint64
+		polled    []slotSighting
+		notified  []slotSighting
+		failure   error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var last uint64
+		for {
+			n, err := conn.Next(runCtx)
+			if err != nil {
+				if runCtx.Err() == nil {
+					mu.Lock()
+					stats.Error = fmt.Sprintf("slot subscription: %v", err)
+					mu.Unlock()
+					cancel()
+				}
+				return
+			}
+			var update struct {
+				Slot uint64 `json:"slot"`
+			}
+			decodeErr := json.Unmarshal(n.Result, &update)
+
+			mu.Lock()
+			stats.Notifications++
+			if decodeErr != nil {
+				stats.addErrorSample(fmt.Sprintf("decode slot notification: %v", decodeErr))
+			} else if update.Slot > last {
+				last = update.Slot
+				notified = append(notified, slotSighting{update.Slot, n.ReceivedAt})
+			}
+			mu.Unlock()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for next := time.Now(); ; next = next.Add(cfg.PollInterval) {
+			s.sleep(runCtx, time.Until(next))
+			if s.stopped(runCtx) {
+				return
+			}
+			var slot uint64
+			result, err := s.GetSlotWithCommitment(runCtx, "processed")
+			if err != nil {
+				if runCtx.Err() == nil {
+					mu.Lock()
+					failure = err
+					mu.Unlock()
+					cancel()
+				}
+				return
+			}
+			seen := time.Now()
+			decodeErr := rpcclient.DecodeResult(result, &slot)
+
+			mu.Lock()
+			stats.Polls++
+			if decodeErr != nil {
+				stats.PollErrors++
+				stats.addErrorSample(decodeErr.Error())
+			} else {
+				latencies = append(latencies, result.Latency)
+				if slot > tip {
+					tip = slot
+					polled = append(polled, slotSighting{slot, seen})
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+	wg.Wait()
+	if failure != nil {
+		return nil, failure
+	}
+
+	// Slots polling found at its first call, or the subscription in its
+	// first notification, were produced before the comparison started.
+	var deltas []int64
+	notified = trimFirst(notified)
+	if len(polled) > 0 {
+		for _, sighting := range notified {
+			if sighting.slot <= polled[0].slot {
+				continue
+			}
+			at, ok := reachedAt(polled, sighting.slot)
+			if !ok {
+				continue
+			}
+			delta := at.Sub(sighting.at).Milliseconds()
+			deltas = append(deltas, delta)
+			switch {
+			case delta > 0:
+				stats.WSFirst++
+			case delta < 0:
+				stats.PollFirst++
+			}
+		}
+	}
+
+	stats.WSSlots = len(notified)
+	stats.PolledSlots = len(trimFirst(polled))
+	stats.Compared = len(deltas)
+	stats.PollLatency = s.summarize(latencies)
+	stats.Delta = s.summarize(deltas)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+func (stats *FreshnessStats) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
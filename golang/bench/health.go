@@ -0,0 +1,246 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/alert"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// HealthConfig configures RunHealthMonitor: getHealth is polled every
+// Interval for Duration.
+type HealthConfig struct {
+	Interval time.Duration
+	Duration time.Duration
+
+	// Alerter, if set, is sent an alert when the endpoint stays
+	// unhealthy, or its healthy checks slower than LatencySLO, for
+	// AlertAfter, and a resolution at the first check that meets the SLO
+	// again. A zero LatencySLO alerts on health alone.
+	Alerter    alert.Alerter
+	AlertAfter time.Duration
+	LatencySLO time.Duration
+}
+
+// HealthOutage is one unhealthy period, from the first failed check to the
+// first healthy one after it. Reason is what the first failed check
+// reported, e.g. "Node is behind by 42 slots (code -32005)".
+type HealthOutage struct {
+	Start    time.Time `json:"start"`
+	Duration int64     `json:"duration"`
+	Checks   int       `json:"checks"`
+	Reason   string    `json:"reason"`
+	// Ongoing marks an outage still in progress when the run ended; its
+	// Duration runs to the end.
+	Ongoing bool `json:"ongoing,omitempty"`
+}
+
+// HealthStats summarizes a health monitoring run. Availability is the
+// percentage of the window spent outside outages; Flaps counts changes of
+// state in either direction, so an endpoint that went down and recovered
+// once has flapped twice. Latency covers healthy checks.
+type HealthStats struct {
+	Endpoint      string         `json:"endpoint"`
+	Interval      string         `json:"interval"`
+	Window        int64          `json:"window"`
+	Checks        int            `json:"checks"`
+	Healthy       int            `json:"healthy"`
+	Unhealthy     int            `json:"unhealthy"`
+	Availability  float64        `json:"availability"`
+	Flaps         int            `json:"flaps"`
+	Downtime      int64          `json:"downtime"`
+	LongestOutage int64          `json:"longestOutage"`
+	Latency       LatencyStats   `json:"latency"`
+	Interrupted   bool           `json:"interrupted,omitempty"`
+	Outages       []HealthOutage `json:"outages"`
+	// Alerts counts the alerts triggered with HealthConfig.Alerter.
+	Alerts int `json:"alerts,omitempty"`
+}
+
+// RunHealthMonitor polls getHealth every cfg.Interval until cfg.Duration
+// has passed, recording every unhealthy period. A check is unhealthy if
+// getHealth fails for any reason, a timeout or a dropped connection
+// included, or answers anything but "ok".
+func (s *JSONRPCTester) RunHealthMonitor(ctx context.Context, cfg HealthConfig) (*HealthStats, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("health check interval must be positive")
+	}
+	stats := &HealthStats{
+		Endpoint: rpcclient.RedactURL(s.Endpoint),
+		Interval: cfg.Interval.String(),
+		Outages:  []HealthOutage{},
+	}
+	s.Log().Info("monitoring health", "endpoint", stats.Endpoint, "interval", cfg.Interval, "duration", cfg.Duration)
+
+	var latencies []int64
+	var outage *HealthOutage
+	healthy := true
+	// Alerts still go out when the run is being interrupted.
+	alertCtx := context.WithoutCancel(ctx)
+	availability := &healthBreach{slo: "availability", severity: alert.SeverityCritical}
+	slowness := &healthBreach{slo: "latency", severity: alert.SeverityWarning}
+	start := time.Now()
+	for next := start; time.Since(start) < cfg.Duration; next = next.Add(cfg.Interval) {
+		s.sleep(ctx, time.Until(next))
+		if s.stopped(ctx) {
+			break
+		}
+		checkedAt := time.Now()
+		result, err := s.GetHealth(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		stats.Checks++
+
+		reason := healthProblem(result)
+		if reason == "" {
+			stats.Healthy++
+			latencies = append(latencies, result.Latency)
+		} else {
+			stats.Unhealthy++
+		}
+		switch {
+		case reason != "" && healthy:
+			// Starting out unhealthy is not a change of state.
+			if stats.Checks > 1 {
+				stats.Flaps++
+			}
+			outage = &HealthOutage{Start: checkedAt, Reason: reason}
+			s.Log().Warn("endpoint unhealthy", "reason", reason)
+		case reason == "" && !healthy:
+			stats.Flaps++
+			outage.Duration = checkedAt.Sub(outage.Start).Milliseconds()
+			stats.addOutage(*outage)
+			s.Log().Info("endpoint healthy again", "outage_ms", outage.Duration)
+			outage = nil
+		}
+		if outage != nil {
+			outage.Checks++
+		}
+		healthy = reason == ""
+		if cfg.Alerter != nil {
+			s.trackBreach(alertCtx, cfg, stats, availability, checkedAt, reason)
+			if healthy {
+				s.trackBreach(alertCtx, cfg, stats, slowness, checkedAt, latencyProblem(result, cfg.LatencySLO))
+			}
+		}
+	}
+
+	end := time.Now()
+	if outage != nil {
+		outage.Duration = end.Sub(outage.Start).Milliseconds()
+		outage.Ongoing = true
+		stats.addOutage(*outage)
+	}
+	stats.Window = end.Sub(start).Milliseconds()
+	if stats.Window > 0 {
+		stats.Availability = 100 * float64(stats.Window-stats.Downtime) / float64(stats.Window)
+	}
+	stats.Latency = s.summarize(latencies)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// healthProblem returns why a getHealth result is unhealthy, or "" if it
+// is healthy.
+func healthProblem(result *rpcclient.Result) string {
+	if !result.Success {
+		return result.Error
+	}
+	var status string
+	if err := rpcclient.DecodeResult(result, &status); err != nil {
+		return err.Error()
+	}
+	if status != "ok" {
+		return fmt.Sprintf("unexpected status %q", status)
+	}
+	return ""
+}
+
+// latencyProblem returns why a healthy getHealth result breaches slo, or
+// "" if it doesn't or slo is zero.
+func latencyProblem(result *rpcclient.Result, slo time.Duration) string {
+	if slo > 0 && result.Latency > slo.Milliseconds() {
+		return fmt.Sprintf("getHealth took %dms, above %dms", result.Latency, slo.Milliseconds())
+	}
+	return ""
+}
+
+// healthBreach is the state of one of a health monitor's SLOs: since when
+// it has been breached, if it is, and whether an alert is open for it.
+type healthBreach struct {
+	slo      string
+	severity string
+	since    time.Time
+	reason   string
+	alerted  bool
+}
+
+// trackBreach updates b with a check at checkedAt that found problem, or
+// "" if it met the SLO. It triggers an alert once the breach has lasted
+// cfg.AlertAfter and resolves it when the breach ends. An alert that
+// could not be sent is retried at the next check.
+func (s *JSONRPCTester) trackBreach(ctx context.Context, cfg HealthConfig, stats *HealthStats, b *healthBreach, checkedAt time.Time, problem string) {
+	if problem == "" {
+		b.since, b.reason = time.Time{}, ""
+		if !b.alerted {
+			return
+		}
+		if err := cfg.Alerter.Resolve(ctx, b.alert(stats.Endpoint, checkedAt)); err != nil {
+			s.Log().Warn("could not resolve alert", "slo", b.slo, "error", err)
+			return
+		}
+		b.alerted = false
+		s.Log().Info("alert resolved", "slo", b.slo)
+		return
+	}
+	if b.since.IsZero() {
+		b.since, b.reason = checkedAt, problem
+	}
+	if b.alerted || checkedAt.Sub(b.since) < cfg.AlertAfter {
+		return
+	}
+	if err := cfg.Alerter.Trigger(ctx, b.alert(stats.Endpoint, checkedAt)); err != nil {
+		s.Log().Warn("could not send alert", "slo", b.slo, "error", err)
+		return
+	}
+	b.alerted = true
+	stats.Alerts++
+	s.Log().Warn("alert triggered", "slo", b.slo, "reason", b.reason)
+}
+
+// alert describes b's breach of endpoint's SLO as of now.
+func (b *healthBreach) alert(endpoint string, now time.Time) alert.Alert {
+	summary := endpoint + " unhealthy"
+	if b.slo == "latency" {
+		summary = endpoint + " slow to answer getHealth"
+	}
+	if !b.since.IsZero() {
+		summary += fmt.Sprintf(" for %s: %s", now.Sub(b.since).Round(time.Second), b.reason)
+	}
+	return alert.Alert{
+		Key:      "solana-rpc-bench/" + b.slo + "/" + endpoint,
+		Summary:  summary,
+		Source:   endpoint,
+		Severity: b.severity,
+		Since:    b.since,
+		Details: map[string]interface{}{
+			"endpoint": endpoint,
+			"slo":      b.slo,
+			"reason":   b.reason,
+		},
+	}
+}
+
+func (stats *HealthStats) addOutage(outage HealthOutage) {
+	stats.Outages = append(stats.Outages, outage)
+	stats.Downtime += outage.Duration
+	if outage.Duration > stats.LongestOutage {
+		stats.LongestOutage = outage.Duration
+	}
+}
@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"fmt"
+	"strconv"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// ParseHistogramBuckets parses ascending millisecond bounds such as
+// "10,50,100,500".
+func ParseHistogramBuckets(list string) ([]int64, error) {
+	var bounds []int64
+	for _, item := range SplitList(list) {
+		bound, err := strconv.ParseInt(item, 10, 64)
+		if err != nil || bound <= 0 {
+			return nil, fmt.Errorf("histogram bucket %q must be a positive number of milliseconds", item)
+		}
+		if len(bounds) > 0 && bound <= bounds[len(bounds)-1] {
+			return nil, fmt.Errorf("histogram buckets must be ascending, got %d after %d", bound, bounds[len(bounds)-1])
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds, nil
+}
+
+// ParsePercentiles parses a list of percentiles such as "50,90,99,99.9".
+func ParsePercentiles(list string) ([]float64, error) {
+	var percentiles []float64
+	for _, item := range SplitList(list) {
+		p, err := strconv.ParseFloat(item, 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("percentile %q must be a number between 0 and 100", item)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// latencyDistribution records the latencies of successful results, or
+// returns nil if there are none.
+func latencyDistribution(results []rpcclient.Result) *stats.Histogram {
+	var h stats.Histogram
+	for _, result := range results {
+		if result.Success {
+			h.Record(result.Latency)
+		}
+	}
+	if h.Count() == 0 {
+		return nil
+	}
+	return &h
+}
+
+// latencyHistogram counts the latencies of successful results into the
+// buckets split at bounds.
+func latencyHistogram(results []rpcclient.Result, bounds []int64) []stats.Bucket {
+	return stats.Bucketize(successfulLatencies(results), bounds)
+}
@@ -0,0 +1,53 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InfluxMeasurement is the measurement InfluxLines writes points to.
+const InfluxMeasurement = "solana_rpc_bench"
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// InfluxLines renders the time series of stats as InfluxDB line protocol,
+// a point per interval timestamped in nanoseconds at the interval's start
+// and tagged with tags. Intervals without a successful request have no
+// latency fields. It returns nil when stats has no time series.
+func InfluxLines(stats *BenchmarkStats, tags map[string]string) []byte {
+	if len(stats.TimeSeries) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		if tags[key] != "" {
+			keys = append(keys, key)
+		}
+	}
+	// Sorted tags are what Influx stores most efficiently.
+	sort.Strings(keys)
+	var series strings.Builder
+	series.WriteString(influxMeasurementEscaper.Replace(InfluxMeasurement))
+	for _, key := range keys {
+		fmt.Fprintf(&series, ",%s=%s", influxTagEscaper.Replace(key), influxTagEscaper.Replace(tags[key]))
+	}
+
+	var b strings.Builder
+	for _, bucket := range stats.TimeSeries {
+		fmt.Fprintf(&b, "%s requests=%di,errors=%di,timed_out=%di,throughput=%g", series.String(),
+			bucket.Requests, bucket.Errors, bucket.TimedOut, bucket.Throughput)
+		if bucket.Requests > bucket.Errors {
+			latency := bucket.Latency
+			fmt.Fprintf(&b, ",latency_avg=%g,latency_min=%di,latency_p50=%di,latency_p95=%di,latency_p99=%di,latency_max=%di",
+				latency.Avg, latency.Min, latency.P50, latency.P95, latency.P99, latency.Max)
+		}
+		at := stats.StartedAt.Add(time.Duration(bucket.StartMs) * time.Millisecond)
+		fmt.Fprintf(&b, " %d\n", at.UnixNano())
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,258 @@
+package bench
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// JitoConfig configures RunJitoBenchmark. TipRequests getTipAccounts calls
+// are timed against the block engine; then Bundles bundles are sent to it,
+// or simulated on the tester's endpoint when Simulate is set. Each bundle
+// is one transaction: a transfer to Recipient and a tip of Tip lamports to
+// a random tip account.
+type JitoConfig struct {
+	BlockEngine  string
+	TipRequests  int
+	Bundles      int
+	Simulate     bool
+	Keypair      ed25519.PrivateKey
+	Recipient    rpcclient.PublicKey
+	Lamports     uint64
+	Tip          uint64
+	Interval     time.Duration
+	AllowMainnet bool
+}
+
+// JitoBundle is one bundle sent. Latency is how long the block engine
+// took to accept or reject it.
+type JitoBundle struct {
+	BundleID string `json:"bundleId,omitempty"`
+	Latency  int64  `json:"latency"`
+	Error    string `json:"error,omitempty"`
+}
+
+// JitoStats summarizes a Jito run. Accepted bundles were taken by the
+// block engine (or simulated without error); acceptance says nothing of
+// whether they landed.
+type JitoStats struct {
+	BlockEngine        string       `json:"blockEngine"`
+	TipAccounts        []string     `json:"tipAccounts,omitempty"`
+	TipRequests        int          `json:"tipRequests"`
+	TipErrors          int          `json:"tipErrors"`
+	TipAccountsLatency LatencyStats `json:"tipAccountsLatency"`
+	Simulated          bool         `json:"simulated,omitempty"`
+	Bundles            int          `json:"bundles"`
+	Accepted           int          `json:"accepted"`
+	Rejected           int          `json:"rejected"`
+	AcceptanceLatency  LatencyStats `json:"acceptanceLatency"`
+	BlockhashLatency   LatencyStats `json:"blockhashLatency"`
+	Interrupted        bool         `json:"interrupted,omitempty"`
+	ErrorSamples       []string     `json:"errorSamples,omitempty"`
+	BundleSamples      []JitoBundle `json:"bundleSamples,omitempty"`
+}
+
+// jitoMinTip is the smallest tip block engines accept.
+const jitoMinTip = 1000
+
+// checkBlockEngine refuses, unless allow is set, to send bundles to a
+// block engine other than a testnet or devnet one, such as
+// testnet.block-engine.jito.wtf, or one on this machine. The endpoint's
+// genesis hash says nothing of where the block engine lands bundles, and
+// a custom block engine URL may well be mainnet.
+func checkBlockEngine(blockEngine string, allow bool) error {
+	if allow {
+		return nil
+	}
+	u, err := url.Parse(blockEngine)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid block engine URL %q", rpcclient.RedactURL(blockEngine))
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	labels := strings.Split(host, ".")
+	if slices.Contains(labels, "testnet") || slices.Contains(labels, "devnet") {
+		return nil
+	}
+	return fmt.Errorf("block engine %s is not a testnet or devnet one; pass -allow-mainnet to send it real bundles", host)
+}
+
+// RunJitoBenchmark measures a Jito block engine: getTipAccounts latency,
+// and how quickly bundles are accepted. Sending bundles spends real
+// lamports, so unless simulating it refuses, without cfg.AllowMainnet, a
+// mainnet endpoint or a block engine not known to be off mainnet.
+func (s *JSONRPCTester) RunJitoBenchmark(ctx context.Context, cfg JitoConfig) (*JitoStats, error) {
+	if cfg.Bundles > 0 && cfg.Tip < jitoMinTip {
+		return nil, fmt.Errorf("tip must be at least %d lamports", jitoMinTip)
+	}
+	if cfg.Bundles > 0 && !cfg.Simulate {
+		if err := checkBlockEngine(cfg.BlockEngine, cfg.AllowMainnet); err != nil {
+			return nil, err
+		}
+		if err := s.checkNotMainnet(ctx, cfg.AllowMainnet); err != nil {
+			return nil, err
+		}
+	}
+	engine := s.Client.Clone(rpcclient.JitoBundlesURL(cfg.BlockEngine))
+	stats := &JitoStats{BlockEngine: rpcclient.RedactURL(cfg.BlockEngine), Simulated: cfg.Simulate}
+	s.Log().Info("running jito benchmark", "block_engine", stats.BlockEngine,
+		"tip_requests", cfg.TipRequests, "bundles", cfg.Bundles, "simulate", cfg.Simulate)
+
+	// Block engines rate-limit per IP, by default to one request a
+	// second, so every request is paced by cfg.Interval.
+	first := true
+	pace := func() {
+		if !first && cfg.Interval > 0 {
+			s.sleep(ctx, cfg.Interval)
+		}
+		first = false
+	}
+
+	var tipLatencies []int64
+	for i := 0; i < cfg.TipRequests; i++ {
+		pace()
+		if s.stopped(ctx) {
+			break
+		}
+		result, err := engine.GetTipAccounts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stats.TipRequests++
+		var accounts []string
+		if err := rpcclient.DecodeResult(result, &accounts); err != nil {
+			stats.TipErrors++
+			stats.addErrorSample(err.Error())
+			continue
+		}
+		tipLatencies = append(tipLatencies, result.Latency)
+		stats.TipAccounts = accounts
+	}
+	stats.TipAccountsLatency = s.summarize(tipLatencies)
+	if cfg.Bundles > 0 && len(stats.TipAccounts) == 0 && !s.stopped(ctx) {
+		pace()
+		var accounts []string
+		if _, err := engine.CallResult(ctx, "getTipAccounts", []interface{}{}, &accounts); err != nil {
+			return nil, fmt.Errorf("fetch tip accounts: %w", err)
+		}
+		stats.TipAccounts = accounts
+	}
+
+	payer := rpcclient.PublicKeyOf(cfg.Keypair)
+	var tipAccounts []rpcclient.PublicKey
+	for _, account := range stats.TipAccounts {
+		key, err := rpcclient.ParsePublicKey(account)
+		if err != nil {
+			return nil, fmt.Errorf("tip account: %w", err)
+		}
+		tipAccounts = append(tipAccounts, key)
+	}
+	if cfg.Bundles > 0 && len(tipAccounts) == 0 && !s.stopped(ctx) {
+		return nil, fmt.Errorf("%s returned no tip accounts", stats.BlockEngine)
+	}
+
+	rng := s.newRand()
+	var acceptLatencies, bhLatencies []int64
+	var blockhash rpcclient.PublicKey
+	var fetchedAt time.Time
+	for i := 0; i < cfg.Bundles; i++ {
+		pace()
+		if s.stopped(ctx) {
+			break
+		}
+		// A simulation gets a recent blockhash swapped in; a real bundle
+		// needs one, refreshed well within its validity.
+		if !cfg.Simulate && (fetchedAt.IsZero() || time.Since(fetchedAt) > 30*time.Second) {
+			hash, result, err := s.getLatestBlockhash(ctx, "confirmed")
+			if err != nil {
+				return nil, fmt.Errorf("fetch blockhash: %w", err)
+			}
+			blockhash, fetchedAt = hash, time.Now()
+			bhLatencies = append(bhLatencies, result.Latency)
+		}
+
+		// Vary the amount so every bundle has a distinct signature.
+		instructions := []rpcclient.Instruction{
+			rpcclient.TransferInstruction(payer, cfg.Recipient, cfg.Lamports+uint64(i)),
+			rpcclient.TransferInstruction(payer, tipAccounts[rng.Intn(len(tipAccounts))], cfg.Tip),
+		}
+		tx, _, err := rpcclient.BuildTransaction(cfg.Keypair, instructions, blockhash)
+		if err != nil {
+			return nil, err
+		}
+
+		var result *rpcclient.Result
+		if cfg.Simulate {
+			result, err = s.SimulateBundle(ctx, [][]byte{tx})
+		} else {
+			result, err = engine.SendBundle(ctx, [][]byte{tx})
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats.Bundles++
+		bundle := JitoBundle{Latency: result.Latency}
+		if err := decodeBundleResult(result, cfg.Simulate, &bundle); err != nil {
+			bundle.Error = err.Error()
+			stats.Rejected++
+			stats.addErrorSample(bundle.Error)
+		} else {
+			stats.Accepted++
+			acceptLatencies = append(acceptLatencies, result.Latency)
+		}
+		stats.BundleSamples = append(stats.BundleSamples, bundle)
+		if (i+1)%10 == 0 {
+			s.Log().Info("bundles sent", "done", i+1, "total", cfg.Bundles)
+		}
+	}
+
+	stats.AcceptanceLatency = s.summarize(acceptLatencies)
+	stats.BlockhashLatency = s.summarize(bhLatencies)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// decodeBundleResult reads a sendBundle result's bundle ID, or checks a
+// simulateBundle result for a failed transaction.
+func decodeBundleResult(result *rpcclient.Result, simulated bool, bundle *JitoBundle) error {
+	if !simulated {
+		return rpcclient.DecodeResult(result, &bundle.BundleID)
+	}
+	var out struct {
+		Value struct {
+			Summary interface{} `json:"summary"`
+		} `json:"value"`
+	}
+	if err := rpcclient.DecodeResult(result, &out); err != nil {
+		return err
+	}
+	// The summary is the string "succeeded" or an object describing the
+	// failure.
+	if summary, ok := out.Value.Summary.(string); !ok || summary != "succeeded" {
+		return fmt.Errorf("simulation failed: %v", out.Value.Summary)
+	}
+	return nil
+}
+
+func (stats *JitoStats) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"solana-rpc-performance-golang/mockserver"
+)
+
+func TestCheckBlockEngine(t *testing.T) {
+	tests := []struct {
+		blockEngine string
+		allow       bool
+		ok          bool
+	}{
+		{blockEngine: "https://testnet.block-engine.jito.wtf", ok: true},
+		{blockEngine: "https://dallas.testnet.block-engine.jito.wtf", ok: true},
+		{blockEngine: "https://block-engine.devnet.example.com", ok: true},
+		{blockEngine: "http://localhost:8080", ok: true},
+		{blockEngine: "http://127.0.0.1:8080", ok: true},
+		{blockEngine: "http://[::1]:8080", ok: true},
+		{blockEngine: "https://mainnet.block-engine.jito.wtf"},
+		{blockEngine: "https://ny.mainnet.block-engine.jito.wtf"},
+		{blockEngine: "https://mytestnet-engine.example.com"},
+		{blockEngine: "https://block-engine.example.com"},
+		{blockEngine: "not a url"},
+		{blockEngine: "https://mainnet.block-engine.jito.wtf", allow: true, ok: true},
+	}
+	for _, tt := range tests {
+		err := checkBlockEngine(tt.blockEngine, tt.allow)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkBlockEngine(%q, %v) = %v, want ok %v", tt.blockEngine, tt.allow, err, tt.ok)
+		}
+	}
+}
+
+func TestRunJitoBenchmarkRefusesMainnetBlockEngine(t *testing.T) {
+	tester := newMockTester(t, mockserver.Config{Seed: 1})
+	_, err := tester.RunJitoBenchmark(context.Background(), JitoConfig{
+		BlockEngine: "https://mainnet.block-engine.jito.wtf",
+		Bundles:     1,
+		Tip:         jitoMinTip,
+	})
+	if err == nil || !strings.Contains(err.Error(), "-allow-mainnet") {
+		t.Errorf("got %v, want a refusal to send bundles to a mainnet block engine", err)
+	}
+}
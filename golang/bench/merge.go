@@ -0,0 +1,205 @@
+package bench
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/stats"
+)
+
+// MergeResults combines runs, such as the same benchmark from several
+// regions, into the stats of one run that made all their requests.
+// Counts are summed and latencies merged from each run's
+// LatencyDistribution, or from its LatencySamples if every run kept them,
+// never averaged from the runs' summaries. The duration is the wall-clock
+// span the runs cover, as they usually run at the same time.
+//
+// Parts that can't be combined exactly are left out: the time series,
+// outliers, rate-limit quota, per-method sizes and connection latencies,
+// the histogram and Apdex score unless every run used the same buckets or
+// target, the cost unless every run was priced by the same provider, and
+// the cluster unless every run saw the same one. The merged latency
+// reports the extra percentiles the first run was saved with.
+func MergeResults(runs []*SavedResults) (*BenchmarkStats, error) {
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no results to merge")
+	}
+	merged := &BenchmarkStats{
+		Cluster:   runs[0].Stats.Cluster,
+		Histogram: cloneBuckets(runs[0].Stats.Histogram),
+		Connections: ConnectionStats{
+			Protocols: make(map[string]int),
+			Families:  make(map[string]int),
+		},
+	}
+	if apdex := runs[0].Stats.Apdex; apdex != nil {
+		merged.Apdex = &ApdexStats{TargetMs: apdex.TargetMs}
+	}
+	if cost := runs[0].Stats.Cost; cost != nil {
+		merged.Cost = &CostStats{Provider: cost.Provider, MethodCredits: map[string]float64{}}
+	}
+	var percentiles []float64
+	for key := range runs[0].Stats.Latency.Percentiles {
+		percentiles = append(percentiles, percentileOf(key))
+	}
+	sort.Float64s(percentiles)
+	var distribution stats.Histogram
+	var samples []int64
+	keptSamples := true
+	unsupported := map[string]bool{}
+	var longest int64
+	var end time.Time
+	allStarted := true
+	for _, run := range runs {
+		s := run.Stats
+		if s.SuccessfulRequests > 0 && s.LatencyDistribution == nil && len(s.LatencySamples) == 0 {
+			return nil, fmt.Errorf("results %s hold no latency distribution to merge; save them again with this version or -keep-samples", run.Path)
+		}
+
+		merged.TotalRequests += s.TotalRequests
+		merged.SuccessfulRequests += s.SuccessfulRequests
+		merged.FailedRequests += s.FailedRequests
+		merged.TimedOutRequests += s.TimedOutRequests
+		merged.UnsupportedRequests += s.UnsupportedRequests
+		merged.SchemaViolations += s.SchemaViolations
+		merged.WarmupRequests += s.WarmupRequests
+		merged.Interrupted = merged.Interrupted || s.Interrupted
+		for _, method := range s.UnsupportedMethods {
+			unsupported[method] = true
+		}
+		for method, n := range s.SchemaViolationMethods {
+			if merged.SchemaViolationMethods == nil {
+				merged.SchemaViolationMethods = make(map[string]int)
+			}
+			merged.SchemaViolationMethods[method] += n
+		}
+		if s.Cluster != merged.Cluster {
+			merged.Cluster = ""
+		}
+
+		if s.LatencyDistribution != nil {
+			distribution.Merge(s.LatencyDistribution)
+		} else {
+			for _, latency := range s.LatencySamples {
+				distribution.Record(latency)
+			}
+		}
+		if len(s.LatencySamples) == 0 && s.SuccessfulRequests > 0 {
+			keptSamples = false
+		}
+		samples = append(samples, s.LatencySamples...)
+
+		merged.Histogram = mergeBuckets(merged.Histogram, s.Histogram)
+		merged.Apdex = mergeApdex(merged.Apdex, s.Apdex)
+		merged.Cost = mergeCost(merged.Cost, s.Cost)
+
+		merged.Bandwidth.TotalBytes += s.Bandwidth.TotalBytes
+		merged.Bandwidth.WireBytes += s.Bandwidth.WireBytes
+		longest = max(longest, s.Bandwidth.DurationMs)
+		if s.StartedAt.IsZero() {
+			allStarted = false
+		} else {
+			if merged.StartedAt.IsZero() || s.StartedAt.Before(merged.StartedAt) {
+				merged.StartedAt = s.StartedAt
+			}
+			if runEnd := s.StartedAt.Add(time.Duration(s.Bandwidth.DurationMs) * time.Millisecond); runEnd.After(end) {
+				end = runEnd
+			}
+		}
+
+		c := s.Connections
+		merged.Connections.New += c.New
+		merged.Connections.Reused += c.Reused
+		merged.Connections.DNSLookups += c.DNSLookups
+		for protocol, n := range c.Protocols {
+			merged.Connections.Protocols[protocol] += n
+		}
+		for family, n := range c.Families {
+			merged.Connections.Families[family] += n
+		}
+	}
+
+	for method := range unsupported {
+		merged.UnsupportedMethods = append(merged.UnsupportedMethods, method)
+	}
+	sort.Strings(merged.UnsupportedMethods)
+	if merged.SuccessfulRequests > 0 {
+		merged.SuccessRate = float64(merged.SuccessfulRequests) / float64(merged.TotalRequests) * 100
+	}
+	if distribution.Count() > 0 {
+		merged.LatencyDistribution = &distribution
+		merged.Latency = distribution.Summary(percentiles...)
+	}
+	if keptSamples && len(samples) > 0 {
+		merged.LatencySamples = samples
+		merged.Latency = stats.Summarize(slices.Clone(samples), percentiles...)
+	}
+	merged.Bandwidth.DurationMs = longest
+	if allStarted {
+		merged.Bandwidth.DurationMs = max(longest, end.Sub(merged.StartedAt).Milliseconds())
+	}
+	if merged.Bandwidth.DurationMs > 0 {
+		merged.Bandwidth.ThroughputMBps = float64(merged.Bandwidth.TotalBytes) / 1e6 / (float64(merged.Bandwidth.DurationMs) / 1000)
+	}
+	if total := merged.Connections.New + merged.Connections.Reused; total > 0 {
+		merged.Connections.ReuseRatio = float64(merged.Connections.Reused) / float64(total)
+	}
+	if merged.Apdex != nil && merged.TotalRequests > 0 {
+		merged.Apdex.Score = (float64(merged.Apdex.Satisfied) + float64(merged.Apdex.Tolerating)/2) / float64(merged.TotalRequests)
+	}
+	if merged.Cost != nil {
+		merged.Cost.perMillion(merged.TotalRequests)
+	}
+	return merged, nil
+}
+
+func cloneBuckets(buckets []stats.Bucket) []stats.Bucket {
+	cloned := slices.Clone(buckets)
+	for i := range cloned {
+		cloned[i].Count = 0
+	}
+	return cloned
+}
+
+// mergeBuckets adds the counts of buckets to merged, or returns nil if
+// their bounds differ.
+func mergeBuckets(merged, buckets []stats.Bucket) []stats.Bucket {
+	if len(merged) != len(buckets) {
+		return nil
+	}
+	for i, bucket := range buckets {
+		if bucket.From != merged[i].From || bucket.To != merged[i].To {
+			return nil
+		}
+		merged[i].Count += bucket.Count
+	}
+	return merged
+}
+
+// mergeApdex adds the counts of apdex to merged, or returns nil if their
+// targets differ.
+func mergeApdex(merged, apdex *ApdexStats) *ApdexStats {
+	if merged == nil || apdex == nil || apdex.TargetMs != merged.TargetMs {
+		return nil
+	}
+	merged.Satisfied += apdex.Satisfied
+	merged.Tolerating += apdex.Tolerating
+	merged.Frustrated += apdex.Frustrated
+	return merged
+}
+
+// mergeCost adds cost to merged, or returns nil if their providers differ.
+func mergeCost(merged, cost *CostStats) *CostStats {
+	if merged == nil || cost == nil || cost.Provider != merged.Provider {
+		return nil
+	}
+	merged.ChargedRequests += cost.ChargedRequests
+	merged.Credits += cost.Credits
+	merged.USD += cost.USD
+	for method, credits := range cost.MethodCredits {
+		merged.MethodCredits[method] += credits
+	}
+	return merged
+}
@@ -0,0 +1,209 @@
+package bench
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/stats"
+)
+
+// mergeRun returns a saved run of the given successful latencies and
+// failures, its distribution recorded and, if keep, its samples kept.
+func mergeRun(latencies []int64, failed int, keep bool) *BenchmarkStats {
+	var distribution stats.Histogram
+	for _, latency := range latencies {
+		distribution.Record(latency)
+	}
+	s := &BenchmarkStats{
+		TotalRequests:       len(latencies) + failed,
+		SuccessfulRequests:  len(latencies),
+		FailedRequests:      failed,
+		LatencyDistribution: &distribution,
+	}
+	if keep {
+		s.LatencySamples = latencies
+	}
+	return s
+}
+
+func TestMergeResults(t *testing.T) {
+	start := time.Date(2026, 3, 11, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		runs  []*BenchmarkStats
+		check func(t *testing.T, merged *BenchmarkStats)
+	}{
+		{
+			name: "counts and samples",
+			runs: []*BenchmarkStats{mergeRun([]int64{10, 20, 30}, 1, true), mergeRun([]int64{400, 500}, 3, true)},
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if merged.TotalRequests != 9 || merged.SuccessfulRequests != 5 || merged.FailedRequests != 4 {
+					t.Errorf("requests %d, %d successful, %d failed; want 9, 5, 4", merged.TotalRequests, merged.SuccessfulRequests, merged.FailedRequests)
+				}
+				if want := float64(5) / 9 * 100; merged.SuccessRate != want {
+					t.Errorf("success rate %v, want %v", merged.SuccessRate, want)
+				}
+				// The median of all requests, not of the runs' medians.
+				if want := stats.Summarize([]int64{10, 20, 30, 400, 500}); !reflect.DeepEqual(merged.Latency, want) {
+					t.Errorf("latency %+v, want %+v", merged.Latency, want)
+				}
+				if len(merged.LatencySamples) != 5 {
+					t.Errorf("kept %d samples, want 5", len(merged.LatencySamples))
+				}
+			},
+		},
+		{
+			name: "distributions only",
+			runs: []*BenchmarkStats{mergeRun([]int64{10, 20, 30}, 0, false), mergeRun([]int64{400, 500}, 0, true)},
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				var all stats.Histogram
+				for _, latency := range []int64{10, 20, 30, 400, 500} {
+					all.Record(latency)
+				}
+				if want := all.Summary(); !reflect.DeepEqual(merged.Latency, want) {
+					t.Errorf("latency %+v, want %+v", merged.Latency, want)
+				}
+				if merged.LatencySamples != nil {
+					t.Errorf("kept samples %v that one run didn't", merged.LatencySamples)
+				}
+			},
+		},
+		{
+			name: "extra percentiles of the first run",
+			runs: func() []*BenchmarkStats {
+				a := mergeRun([]int64{10, 20, 30, 40}, 0, true)
+				a.Latency.Percentiles = map[string]float64{"p99.9": 40, "p90": 37}
+				return []*BenchmarkStats{a, mergeRun([]int64{50}, 0, true)}
+			}(),
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if _, ok := merged.Latency.Percentiles["p99.9"]; !ok || len(merged.Latency.Percentiles) != 2 {
+					t.Errorf("percentiles %v, want p90 and p99.9", merged.Latency.Percentiles)
+				}
+			},
+		},
+		{
+			name: "different method sets",
+			runs: func() []*BenchmarkStats {
+				a, b := mergeRun([]int64{10}, 2, true), mergeRun([]int64{20}, 3, true)
+				a.UnsupportedMethods, a.SchemaViolationMethods = []string{"getBlock"}, map[string]int{"getSlot": 1}
+				b.UnsupportedMethods, b.SchemaViolationMethods = []string{"getTokenSupply", "getBlock"}, map[string]int{"getBalance": 2, "getSlot": 1}
+				a.Cost = &CostStats{Provider: "helius", ChargedRequests: 1, Credits: 10, MethodCredits: map[string]float64{"getSlot": 10}}
+				b.Cost = &CostStats{Provider: "helius", ChargedRequests: 2, Credits: 30, MethodCredits: map[string]float64{"getBalance": 20, "getSlot": 10}}
+				return []*BenchmarkStats{a, b}
+			}(),
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if want := []string{"getBlock", "getTokenSupply"}; !reflect.DeepEqual(merged.UnsupportedMethods, want) {
+					t.Errorf("unsupported %v, want %v", merged.UnsupportedMethods, want)
+				}
+				if want := map[string]int{"getSlot": 2, "getBalance": 2}; !reflect.DeepEqual(merged.SchemaViolationMethods, want) {
+					t.Errorf("schema violations %v, want %v", merged.SchemaViolationMethods, want)
+				}
+				want := &CostStats{Provider: "helius", ChargedRequests: 3, Credits: 40,
+					MethodCredits: map[string]float64{"getSlot": 20, "getBalance": 20}, CreditsPerMillion: 40.0 / 7 * 1e6}
+				if !reflect.DeepEqual(merged.Cost, want) {
+					t.Errorf("cost %+v, want %+v", merged.Cost, want)
+				}
+			},
+		},
+		{
+			name: "parts that differ are left out",
+			runs: func() []*BenchmarkStats {
+				a, b := mergeRun([]int64{10}, 0, true), mergeRun([]int64{20}, 0, true)
+				a.Cluster, b.Cluster = "mainnet-beta", "devnet"
+				a.Histogram = []stats.Bucket{{From: 0, To: 50, Count: 1}, {From: 50}}
+				b.Histogram = []stats.Bucket{{From: 0, To: 100, Count: 1}, {From: 100}}
+				a.Apdex, b.Apdex = &ApdexStats{TargetMs: 100, Satisfied: 1}, &ApdexStats{TargetMs: 200, Satisfied: 1}
+				a.Cost, b.Cost = &CostStats{Provider: "helius"}, &CostStats{Provider: "quicknode"}
+				return []*BenchmarkStats{a, b}
+			}(),
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if merged.Cluster != "" || merged.Histogram != nil || merged.Apdex != nil || merged.Cost != nil {
+					t.Errorf("cluster %q, histogram %v, apdex %+v, cost %+v; want none", merged.Cluster, merged.Histogram, merged.Apdex, merged.Cost)
+				}
+			},
+		},
+		{
+			name: "parts alike are combined",
+			runs: func() []*BenchmarkStats {
+				a, b := mergeRun([]int64{10, 60}, 0, true), mergeRun([]int64{20, 30}, 0, true)
+				a.Cluster, b.Cluster = "mainnet-beta", "mainnet-beta"
+				a.Histogram = []stats.Bucket{{From: 0, To: 50, Count: 1}, {From: 50, Count: 1}}
+				b.Histogram = []stats.Bucket{{From: 0, To: 50, Count: 2}, {From: 50}}
+				a.Apdex, b.Apdex = &ApdexStats{TargetMs: 50, Satisfied: 1, Tolerating: 1}, &ApdexStats{TargetMs: 50, Satisfied: 2}
+				return []*BenchmarkStats{a, b}
+			}(),
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if want := []stats.Bucket{{From: 0, To: 50, Count: 3}, {From: 50, Count: 1}}; merged.Cluster != "mainnet-beta" || !reflect.DeepEqual(merged.Histogram, want) {
+					t.Errorf("cluster %q, histogram %v; want mainnet-beta, %v", merged.Cluster, merged.Histogram, want)
+				}
+				if want := (&ApdexStats{TargetMs: 50, Satisfied: 3, Tolerating: 1, Score: 3.5 / 4}); !reflect.DeepEqual(merged.Apdex, want) {
+					t.Errorf("apdex %+v, want %+v", merged.Apdex, want)
+				}
+			},
+		},
+		{
+			name: "overlapping runs span their wall-clock time",
+			runs: func() []*BenchmarkStats {
+				a, b := mergeRun([]int64{10}, 0, true), mergeRun([]int64{20}, 0, true)
+				a.StartedAt, a.Bandwidth = start, BandwidthStats{TotalBytes: 1e6, DurationMs: 10_000}
+				b.StartedAt, b.Bandwidth = start.Add(5*time.Second), BandwidthStats{TotalBytes: 2e6, DurationMs: 10_000}
+				return []*BenchmarkStats{a, b}
+			}(),
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if !merged.StartedAt.Equal(start) || merged.Bandwidth.DurationMs != 15_000 || merged.Bandwidth.ThroughputMBps != 0.2 {
+					t.Errorf("started %v, ran %dms at %v MB/s; want %v, 15000ms, 0.2", merged.StartedAt, merged.Bandwidth.DurationMs, merged.Bandwidth.ThroughputMBps, start)
+				}
+			},
+		},
+		{
+			name: "runs without a start take the longest",
+			runs: func() []*BenchmarkStats {
+				a, b := mergeRun([]int64{10}, 0, true), mergeRun([]int64{20}, 0, true)
+				a.StartedAt, a.Bandwidth.DurationMs = start, 10_000
+				b.Bandwidth.DurationMs = 12_000
+				return []*BenchmarkStats{a, b}
+			}(),
+			check: func(t *testing.T, merged *BenchmarkStats) {
+				if merged.Bandwidth.DurationMs != 12_000 {
+					t.Errorf("ran %dms, want 12000", merged.Bandwidth.DurationMs)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs []*SavedResults
+			for _, s := range tt.runs {
+				runs = append(runs, &SavedResults{Path: "run.json", Stats: s})
+			}
+			merged, err := MergeResults(runs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, merged)
+		})
+	}
+}
+
+func TestMergeResultsErrors(t *testing.T) {
+	summaryOnly := &BenchmarkStats{TotalRequests: 1, SuccessfulRequests: 1, Latency: LatencyStats{P50: 10}}
+	tests := []struct {
+		name string
+		runs []*SavedResults
+		err  string
+	}{
+		{name: "none", err: "no results"},
+		{
+			name: "summaries only",
+			runs: []*SavedResults{{Path: "a.json", Stats: mergeRun([]int64{10}, 0, false)}, {Path: "old.json", Stats: summaryOnly}},
+			err:  "old.json hold no latency distribution",
+		},
+	}
+	for _, tt := range tests {
+		if _, err := MergeResults(tt.runs); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+type methodRunner func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error)
+
+type methodSpec struct {
+	run   methodRunner
+	check func(s *JSONRPCTester) error
+}
+
+// Catalog is the methods the method benchmark can send to one chain's
+// endpoints. A new protocol is supported by registering its catalog with
+// RegisterChain; the client, run modes and stats are shared.
+type Catalog struct {
+	// Methods are the chain's methods by name, as given to -methods.
+	Methods map[string]Method
+	// Defaults are run when no methods are selected.
+	Defaults []string
+	// Scenarios are named method lists, selectable with -scenario.
+	Scenarios map[string][]string
+
+	// use, if set, is called by UseChain to set up state the chain's
+	// methods share.
+	use func(s *JSONRPCTester)
+}
+
+// chainCatalog is a registered Catalog, its methods ready to run.
+type chainCatalog struct {
+	methods   map[string]methodSpec
+	defaults  []string
+	scenarios map[string][]string
+	use       func(s *JSONRPCTester)
+}
+
+// catalogs maps each registered chain to its catalog.
+var catalogs = map[string]*chainCatalog{}
+
+// RegisterChain makes c the method catalog of chain, selectable with
+// UseChain. It is meant to be called from init functions, and panics if
+// chain is already registered, a method has no Run, or a default or
+// scenario names a method c lacks.
+func RegisterChain(chain string, c Catalog) {
+	if _, ok := catalogs[chain]; ok {
+		panic(fmt.Sprintf("bench: chain %q registered twice", chain))
+	}
+	catalog := &chainCatalog{
+		methods:   make(map[string]methodSpec, len(c.Methods)),
+		defaults:  c.Defaults,
+		scenarios: make(map[string][]string, len(c.Scenarios)+1),
+		use:       c.use,
+	}
+	for name, method := range c.Methods {
+		if method.Run == nil {
+			panic(fmt.Sprintf("bench: chain %q: method %s has no Run", chain, name))
+		}
+		catalog.methods[name] = method.spec(name)
+	}
+	if len(c.Defaults) == 0 {
+		panic(fmt.Sprintf("bench: chain %q has no default methods", chain))
+	}
+	catalog.scenarios["default"] = c.Defaults
+	for scenario, methods := range c.Scenarios {
+		catalog.scenarios[scenario] = methods
+	}
+	for scenario, methods := range catalog.scenarios {
+		for _, method := range methods {
+			if _, ok := catalog.methods[method]; !ok {
+				panic(fmt.Sprintf("bench: chain %q: scenario %s uses unknown method %s", chain, scenario, method))
+			}
+		}
+	}
+	catalogs[chain] = catalog
+}
+
+// Chains returns the names of the registered chains, sorted.
+func Chains() []string {
+	names := make([]string, 0, len(catalogs))
+	for name := range catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckChain reports whether chain has a registered catalog.
+func CheckChain(chain string) error {
+	if _, ok := catalogs[chain]; !ok {
+		return fmt.Errorf("unknown chain %q (known: %s)", chain, strings.Join(Chains(), ", "))
+	}
+	return nil
+}
+
+// ChainDefaultMethods returns the methods run on chain when none are
+// selected.
+func ChainDefaultMethods(chain string) []string {
+	if catalog, ok := catalogs[chain]; ok {
+		return catalog.defaults
+	}
+	return DefaultMethods
+}
+
+func catalogMethodNames(catalog map[string]methodSpec) []string {
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseMethods turns a comma-separated method list into Solana catalog
+// names, rejecting unknown methods.
+func ParseMethods(list string) ([]string, error) {
+	return ParseChainMethods(ChainSolana, list)
+}
+
+// ParseChainMethods is ParseMethods for chain's catalog.
+func ParseChainMethods(chain, list string) ([]string, error) {
+	if err := CheckChain(chain); err != nil {
+		return nil, err
+	}
+	catalog := catalogs[chain].methods
+	var methods []string
+	for _, name := range SplitList(list) {
+		if _, ok := catalog[name]; !ok {
+			return nil, fmt.Errorf("unknown %s method %q (known: %s)", chain, name, strings.Join(catalogMethodNames(catalog), ", "))
+		}
+		methods = append(methods, name)
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no methods selected")
+	}
+	return methods, nil
+}
+
+// ScenarioMethods returns the method list of a named Solana scenario.
+func ScenarioMethods(name string) ([]string, error) {
+	return ChainScenarioMethods(ChainSolana, name)
+}
+
+// ChainScenarioMethods is ScenarioMethods for chain's scenarios.
+func ChainScenarioMethods(chain, name string) ([]string, error) {
+	if err := CheckChain(chain); err != nil {
+		return nil, err
+	}
+	scenarios := catalogs[chain].scenarios
+	methods, ok := scenarios[name]
+	if !ok {
+		names := make([]string, 0, len(scenarios))
+		for scenario := range scenarios {
+			names = append(names, scenario)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown %s scenario %q (known: %s)", chain, name, strings.Join(names, ", "))
+	}
+	return methods, nil
+}
+
+func (s *JSONRPCTester) validateMethods() error {
+	for _, name := range s.methods() {
+		if name == ScriptMethod && s.script != nil && s.script.request != nil {
+			continue
+		}
+		if template := s.templateNamed(name); template != nil {
+			if template.accounts && s.Accounts == nil {
+				return fmt.Errorf("template %s uses {{account}} but there is no account list (-accounts)", name)
+			}
+			continue
+		}
+		spec, ok := s.catalog().methods[name]
+		if !ok {
+			return fmt.Errorf("unknown %s method %q", s.Chain(), name)
+		}
+		if spec.check != nil {
+			if err := spec.check(s); err != nil {
+				return fmt.Errorf("method %s %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *JSONRPCTester) methods() []string {
+	if len(s.Methods) == 0 {
+		return s.catalog().defaults
+	}
+	return s.Methods
+}
+
+// catalog returns the catalog of the chain s speaks.
+func (s *JSONRPCTester) catalog() *chainCatalog {
+	return catalogs[s.Chain()]
+}
+
+// Chain returns the chain s speaks, set with UseChain.
+func (s *JSONRPCTester) Chain() string {
+	if s.chain == "" {
+		return ChainSolana
+	}
+	return s.chain
+}
+
+// UseChain switches s to chain's method catalog. Templates, scripts and
+// chains send whatever methods they name regardless.
+func (s *JSONRPCTester) UseChain(chain string) error {
+	if err := CheckChain(chain); err != nil {
+		return err
+	}
+	s.chain = chain
+	if use := catalogs[chain].use; use != nil {
+		use(s)
+	}
+	return nil
+}
+
+// SplitList splits a comma-separated list, dropping blanks.
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,221 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// NodesConfig configures RunNodesBenchmark. Nodes are RPC URLs to
+// benchmark; if empty, they are discovered with getClusterNodes on the
+// tester's endpoint. Each node gets one getVersion with ProbeTimeout
+// first, so the many firewalled RPC ports are skipped quickly, and then
+// the main benchmark of Iterations iterations. Parallel nodes are
+// benchmarked at once.
+type NodesConfig struct {
+	Nodes        []string
+	Limit        int
+	Iterations   int
+	Parallel     int
+	ProbeTimeout time.Duration
+}
+
+// NodeResult is the benchmark of one node. Rank orders the nodes that
+// were benchmarked, fastest first.
+type NodeResult struct {
+	Rank    int             `json:"rank,omitempty"`
+	RPC     string          `json:"rpc"`
+	Pubkey  string          `json:"pubkey,omitempty"`
+	Version string          `json:"version,omitempty"`
+	Stats   *BenchmarkStats `json:"stats,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// NodesStats covers a per-node run. Discovered counts the nodes
+// getClusterNodes returned and WithRPC those advertising an RPC port;
+// both are left out for a given node list.
+type NodesStats struct {
+	Discovered  int          `json:"discovered,omitempty"`
+	WithRPC     int          `json:"withRpc,omitempty"`
+	Probed      int          `json:"probed"`
+	Reachable   int          `json:"reachable"`
+	Interrupted bool         `json:"interrupted,omitempty"`
+	Results     []NodeResult `json:"results"`
+}
+
+type clusterNode struct {
+	Pubkey  string  `json:"pubkey"`
+	RPC     *string `json:"rpc"`
+	Version *string `json:"version"`
+}
+
+// RunNodesBenchmark benchmarks each node's RPC port individually and ranks
+// them by success rate, then median and p99 latency. Nodes that could not
+// be reached or benchmarked are listed after the ranked ones.
+func (s *JSONRPCTester) RunNodesBenchmark(ctx context.Context, cfg NodesConfig) (*NodesStats, error) {
+	stats := &NodesStats{}
+	var nodes []NodeResult
+	if len(cfg.Nodes) > 0 {
+		for _, node := range cfg.Nodes {
+			nodes = append(nodes, NodeResult{RPC: nodeURL(node)})
+		}
+	} else {
+		var cluster []clusterNode
+		if _, err := s.CallResult(ctx, "getClusterNodes", nil, &cluster); err != nil {
+			return nil, fmt.Errorf("discover nodes: %w", err)
+		}
+		stats.Discovered = len(cluster)
+		for _, node := range cluster {
+			if node.RPC == nil {
+				continue
+			}
+			result := NodeResult{RPC: nodeURL(*node.RPC), Pubkey: node.Pubkey}
+			if node.Version != nil {
+				result.Version = *node.Version
+			}
+			nodes = append(nodes, result)
+		}
+		stats.WithRPC = len(nodes)
+		// Order is arbitrary; sort so -nodes-limit picks the same nodes
+		// from run to run.
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Pubkey < nodes[j].Pubkey })
+	}
+	if cfg.Limit > 0 && len(nodes) > cfg.Limit {
+		nodes = nodes[:cfg.Limit]
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes with an RPC address to benchmark")
+	}
+	parallel := max(cfg.Parallel, 1)
+	s.Log().Info("benchmarking nodes", "nodes", len(nodes), "parallel", parallel, "iterations", cfg.Iterations)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for i := range nodes {
+		if s.stopped(ctx) {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(node *NodeResult) {
+			defer func() { <-sem; wg.Done() }()
+			reachable := s.benchmarkNode(ctx, node, cfg)
+			mu.Lock()
+			defer mu.Unlock()
+			stats.Probed++
+			if reachable {
+				stats.Reachable++
+			}
+			if stats.Probed%10 == 0 {
+				s.Log().Info("nodes done", "done", stats.Probed, "total", len(nodes), "reachable", stats.Reachable)
+			}
+		}(&nodes[i])
+	}
+	wg.Wait()
+	for i := range nodes {
+		if nodes[i].Stats == nil && nodes[i].Error == "" {
+			nodes[i].Error = "not benchmarked: interrupted"
+		}
+	}
+
+	rankNodes(nodes)
+	stats.Results = nodes
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// benchmarkNode probes node and, if it answers, benchmarks it, reporting
+// whether it was reachable.
+func (s *JSONRPCTester) benchmarkNode(ctx context.Context, node *NodeResult, cfg NodesConfig) bool {
+	tester := s.withEndpoint(node.RPC)
+	tester.Label = node.RPC
+	if s.Label != "" {
+		tester.Label = s.Label + "/" + node.RPC
+	}
+
+	probe := tester.Client.Clone(node.RPC)
+	probe.Timeout = cfg.ProbeTimeout
+	var version struct {
+		SolanaCore string `json:"solana-core"`
+	}
+	if _, err := probe.CallResult(ctx, "getVersion", nil, &version); err != nil {
+		node.Error = "unreachable: " + err.Error()
+		return false
+	}
+	if node.Version == "" {
+		node.Version = version.SolanaCore
+	}
+
+	nodeStats, err := tester.RunBenchmark(ctx, cfg.Iterations)
+	if err != nil {
+		node.Error = err.Error()
+	}
+	node.Stats = nodeStats
+	return true
+}
+
+// LoadNodeList reads one node RPC address per line, as a URL or host:port.
+// Blank lines and lines starting with # are ignored.
+func LoadNodeList(path string) ([]string, error) {
+	return loadKeyList(path, "node addresses", func(node string) error {
+		u, err := url.Parse(nodeURL(node))
+		if err != nil {
+			return err
+		}
+		if u.Host == "" {
+			return fmt.Errorf("invalid node address %q", node)
+		}
+		return nil
+	})
+}
+
+// nodeURL turns a getClusterNodes rpc address, host:port, into a URL.
+func nodeURL(address string) string {
+	if strings.Contains(address, "://") {
+		return address
+	}
+	return "http://" + address
+}
+
+// rankNodes sorts benchmarked nodes by success rate, then median and p99
+// latency, and numbers them; the rest follow unranked.
+func rankNodes(nodes []NodeResult) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i].Stats, nodes[j].Stats
+		switch {
+		case a == nil || b == nil:
+			return a != nil
+		case a.SuccessRate != b.SuccessRate:
+			return a.SuccessRate > b.SuccessRate
+		case a.Latency.P50 != b.Latency.P50:
+			return a.Latency.P50 < b.Latency.P50
+		}
+		return a.Latency.P99 < b.Latency.P99
+	})
+	for i := range nodes {
+		if nodes[i].Stats != nil {
+			nodes[i].Rank = i + 1
+		}
+	}
+}
+
+// WriteTable writes the ranked nodes as an aligned text table.
+func (stats *NodesStats) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tRPC\tVERSION\tSUCCESS\tP50\tP99\tPUBKEY")
+	for _, node := range stats.Results {
+		if node.Stats == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.2f%%\t%dms\t%dms\t%s\n", node.Rank, node.RPC, node.Version,
+			node.Stats.SuccessRate, node.Stats.Latency.P50, node.Stats.Latency.P99, node.Pubkey)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,296 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Arrival processes for LoadConfig.Arrival.
+const (
+	// ArrivalConstant spaces requests evenly.
+	ArrivalConstant = "constant"
+	// ArrivalPoisson draws exponentially distributed gaps with the same
+	// mean, giving the bursts of independent clients.
+	ArrivalPoisson = "poisson"
+)
+
+// LoadConfig describes an open-loop run: requests are sent on a fixed
+// schedule whether or not earlier ones have completed, up to Concurrency in
+// flight. The run ends after Duration or Requests, whichever comes first.
+type LoadConfig struct {
+	Rate        float64
+	Duration    time.Duration
+	Requests    int
+	Concurrency int
+	// Arrival is one of the Arrival constants; empty means
+	// ArrivalConstant.
+	Arrival string
+
+	// breaker, if set, holds back requests while it is open.
+	breaker *breakerRun
+}
+
+// OpenLoopStats adds schedule accounting to the usual benchmark stats.
+// Latency is service time, measured from when a request was actually sent;
+// CorrectedLatency is measured from when it was meant to be sent, so time
+// spent waiting behind a slow endpoint is not silently dropped (coordinated
+// omission). SchedulingLag is the difference between the two send times.
+type OpenLoopStats struct {
+	BenchmarkStats
+	Arrival          string       `json:"arrival"`
+	TargetRate       float64      `json:"targetRate"`
+	AchievedRate     float64      `json:"achievedRate"`
+	Concurrency      int          `json:"concurrency"`
+	CorrectedLatency LatencyStats `json:"correctedLatency"`
+	SchedulingLag    LatencyStats `json:"schedulingLag"`
+}
+
+// arrivals yields the intended send time of each request as an offset from
+// the start of the run.
+type arrivals interface {
+	next() time.Duration
+}
+
+// constantArrivals spaces requests evenly at a fixed rate.
+type constantArrivals struct {
+	rate float64
+	n    int
+}
+
+func (a *constantArrivals) next() time.Duration {
+	offset := time.Duration(float64(a.n) / a.rate * float64(time.Second))
+	a.n++
+	return offset
+}
+
+// poissonArrivals draws exponentially distributed gaps averaging 1/rate.
+type poissonArrivals struct {
+	rate   float64
+	rng    *rand.Rand
+	offset float64
+}
+
+func (a *poissonArrivals) next() time.Duration {
+	offset := a.offset
+	a.offset += a.rng.ExpFloat64() / a.rate
+	return time.Duration(offset * float64(time.Second))
+}
+
+// newArrivals returns the arrival process named process at rate, drawing
+// from rng if it is random.
+func newArrivals(process string, rate float64, rng *rand.Rand) (arrivals, error) {
+	switch process {
+	case "", ArrivalConstant:
+		return &constantArrivals{rate: rate}, nil
+	case ArrivalPoisson:
+		return &poissonArrivals{rate: rate, rng: rng}, nil
+	}
+	return nil, fmt.Errorf("unknown arrival process %q (want %s or %s)", process, ArrivalConstant, ArrivalPoisson)
+}
+
+// openLoopSample is one request of an open-loop run; offset is its
+// scheduled send time relative to the start of the run.
+type openLoopSample struct {
+	result    rpcclient.Result
+	offset    time.Duration
+	corrected time.Duration
+	lag       time.Duration
+}
+
+// RunOpenLoop sends the configured methods in rotation at cfg.Rate.
+func (s *JSONRPCTester) RunOpenLoop(ctx context.Context, cfg LoadConfig) (*OpenLoopStats, error) {
+	if cfg.Rate <= 0 {
+		return nil, fmt.Errorf("open-loop rate must be positive")
+	}
+	if cfg.Duration <= 0 && cfg.Requests <= 0 {
+		return nil, fmt.Errorf("open-loop run needs a duration or a request count")
+	}
+	if cfg.Concurrency <= 0 {
+		return nil, fmt.Errorf("open-loop concurrency must be positive")
+	}
+	arr, err := newArrivals(cfg.Arrival, cfg.Rate, s.newRand())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Arrival == "" {
+		cfg.Arrival = ArrivalConstant
+	}
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := cfg.Requests
+	if cfg.Duration > 0 {
+		if byDuration := int(cfg.Rate * cfg.Duration.Seconds()); expected <= 0 || byDuration < expected {
+			expected = byDuration
+		}
+	}
+	s.Log().Info("running open-loop benchmark", "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"rate", cfg.Rate, "arrival", cfg.Arrival, "duration", cfg.Duration, "requests", cfg.Requests,
+		"concurrency", cfg.Concurrency, "methods", methods)
+
+	cfg.breaker = s.newBreaker(time.Now())
+	samples, elapsed, err := s.runSchedule(ctx, cfg, methods, arr, expected)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.summarizeOpenLoop(samples, elapsed)
+	stats.Breaker = cfg.breaker.finish(time.Now())
+	results := make([]rpcclient.Result, len(samples))
+	done := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		results[i], done[i] = sample.result, sample.offset+sample.corrected
+	}
+	s.addBreakdowns(ctx, &stats.BenchmarkStats, results, time.Now().Add(-elapsed), done)
+	stats.WarmupRequests = warmupRequests
+	stats.Interrupted = s.stopped(ctx)
+	stats.Arrival = cfg.Arrival
+	stats.TargetRate = cfg.Rate
+	stats.Concurrency = cfg.Concurrency
+	return stats, nil
+}
+
+// runSchedule sends requests at the offsets arr yields until cfg's
+// duration or request count is reached, and returns every completed
+// request along with the time the run took.
+func (s *JSONRPCTester) runSchedule(ctx context.Context, cfg LoadConfig, methods []string, arr arrivals, expected int) ([]openLoopSample, time.Duration, error) {
+	var samples []openLoopSample
+	elapsed, err := s.streamSchedule(ctx, cfg, s.rotation(methods), arr, expected, func(sample openLoopSample) {
+		samples = append(samples, sample)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return samples, elapsed, nil
+}
+
+// rotation sends methods in turn.
+func (s *JSONRPCTester) rotation(methods []string) func(i int) methodRunner {
+	return func(i int) methodRunner {
+		return s.runner(methods[i%len(methods)])
+	}
+}
+
+// streamSchedule is runSchedule handing each completed request to observe
+// instead of keeping it, with request choosing what the i-th request
+// sends. Calls to observe are serialized.
+func (s *JSONRPCTester) streamSchedule(ctx context.Context, cfg LoadConfig, request func(i int) methodRunner, arr arrivals, expected int, observe func(openLoopSample)) (time.Duration, error) {
+	var sinks []ResultSink
+	if s.NewSinks != nil {
+		sinks = s.NewSinks(s)
+	}
+	for _, sink := range sinks {
+		sink.Start(expected)
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, cfg.Concurrency)
+	start := time.Now()
+
+schedule:
+	for i := 0; cfg.Requests <= 0 || i < cfg.Requests; i++ {
+		offset := arr.next()
+		if cfg.Duration > 0 && offset >= cfg.Duration {
+			break
+		}
+		intended := start.Add(offset)
+		s.sleep(ctx, time.Until(intended))
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed || s.stopped(ctx) {
+			break
+		}
+		if !cfg.breaker.allow(time.Now()) {
+			continue
+		}
+
+		// With every slot busy the request waits here, and the wait
+		// shows up as scheduling lag rather than vanishing.
+		select {
+		case slots <- struct{}{}:
+		case <-s.Stop:
+			break schedule
+		case <-ctx.Done():
+			break schedule
+		}
+
+		run := request(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			sent := time.Now()
+			result, err := run(ctx, s)
+			done := time.Now()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if ctx.Err() == nil && firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			cfg.breaker.record(done, result)
+			stampOffset(result, start)
+			observe(openLoopSample{
+				result:    *result,
+				offset:    offset,
+				corrected: done.Sub(intended),
+				lag:       sent.Sub(intended),
+			})
+			for _, sink := range sinks {
+				sink.Observe(*result)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for _, sink := range sinks {
+		sink.Stop()
+	}
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return elapsed, nil
+}
+
+func (s *JSONRPCTester) summarizeOpenLoop(samples []openLoopSample, elapsed time.Duration) *OpenLoopStats {
+	results := make([]rpcclient.Result, len(samples))
+	var corrected, lag []int64
+	for i, sample := range samples {
+		results[i] = sample.result
+		if sample.result.Success {
+			corrected = append(corrected, sample.corrected.Milliseconds())
+		}
+		lag = append(lag, sample.lag.Milliseconds())
+	}
+
+	stats := &OpenLoopStats{
+		BenchmarkStats:   *s.calculateStats(results, elapsed),
+		CorrectedLatency: s.summarize(corrected),
+		SchedulingLag:    s.summarize(lag),
+	}
+	if elapsed > 0 {
+		stats.AchievedRate = float64(len(samples)) / elapsed.Seconds()
+	}
+	return stats
+}
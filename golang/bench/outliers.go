@@ -0,0 +1,127 @@
+package bench
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+const (
+	// maxListedOutliers caps how many outliers are listed individually;
+	// the count and clusters still cover all of them.
+	maxListedOutliers = 100
+	// outlierClusterGap is the longest gap between two outliers of one
+	// cluster.
+	outlierClusterGap = 2 * time.Second
+)
+
+// Outlier is one request whose latency stood out from the rest of the run.
+type Outlier struct {
+	At         time.Time `json:"at"`
+	OffsetMs   int64     `json:"offsetMs"`
+	Method     string    `json:"method"`
+	Latency    int64     `json:"latency"`
+	Connection string    `json:"connection,omitempty"`
+}
+
+// OutlierCluster is a run of outliers no more than outlierClusterGap
+// apart; a cluster of many usually marks a provider incident rather than
+// chance.
+type OutlierCluster struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Count      int       `json:"count"`
+	MaxLatency int64     `json:"maxLatency"`
+}
+
+// OutlierStats flags successful requests whose modified z-score, based on
+// the median absolute deviation (MAD), exceeds MaxZ. Unlike a standard
+// deviation the MAD is not inflated by the outliers themselves. ThresholdMs
+// is the latency that score corresponds to.
+type OutlierStats struct {
+	MaxZ        float64          `json:"maxZ"`
+	MedianMs    int64            `json:"medianMs"`
+	MADMs       float64          `json:"madMs"`
+	ThresholdMs int64            `json:"thresholdMs"`
+	Count       int              `json:"count"`
+	Outliers    []Outlier        `json:"outliers,omitempty"`
+	Clusters    []OutlierCluster `json:"clusters,omitempty"`
+}
+
+// detectOutliers finds the outliers among results, which completed at start
+// plus done. It returns nil when maxZ is not positive.
+func detectOutliers(results []rpcclient.Result, start time.Time, done []time.Duration, maxZ float64) *OutlierStats {
+	if maxZ <= 0 {
+		return nil
+	}
+	stats := &OutlierStats{MaxZ: maxZ}
+	latencies := successfulLatencies(results)
+	if len(latencies) == 0 {
+		return stats
+	}
+	median := medianOf(latencies)
+	deviations := make([]float64, len(latencies))
+	var meanDeviation float64
+	for i, latency := range latencies {
+		deviations[i] = math.Abs(float64(latency) - median)
+		meanDeviation += deviations[i]
+	}
+	meanDeviation /= float64(len(deviations))
+	sort.Float64s(deviations)
+	mad := deviations[len(deviations)/2]
+
+	// 1.4826 scales the MAD to a standard deviation for normal data. When
+	// over half the latencies are identical the MAD is zero; fall back on
+	// the mean absolute deviation, scaled likewise by 1.2533.
+	scale := 1.4826 * mad
+	if mad == 0 {
+		scale = 1.2533 * meanDeviation
+	}
+	stats.MedianMs = int64(median)
+	stats.MADMs = mad
+	if scale == 0 {
+		// Every latency is the same.
+		stats.ThresholdMs = stats.MedianMs
+		return stats
+	}
+	threshold := median + maxZ*scale
+	stats.ThresholdMs = int64(math.Ceil(threshold))
+
+	var cluster *OutlierCluster
+	for i, result := range results {
+		if !result.Success || float64(result.Latency) <= threshold {
+			continue
+		}
+		at := start.Add(done[i])
+		stats.Count++
+		if len(stats.Outliers) < maxListedOutliers {
+			stats.Outliers = append(stats.Outliers, Outlier{
+				At:         at.UTC(),
+				OffsetMs:   done[i].Milliseconds(),
+				Method:     result.Method,
+				Latency:    result.Latency,
+				Connection: result.Connection,
+			})
+		}
+		if cluster == nil || at.Sub(cluster.End) > outlierClusterGap {
+			stats.Clusters = append(stats.Clusters, OutlierCluster{Start: at.UTC()})
+			cluster = &stats.Clusters[len(stats.Clusters)-1]
+		}
+		cluster.End = at.UTC()
+		cluster.Count++
+		cluster.MaxLatency = max(cluster.MaxLatency, result.Latency)
+	}
+	return stats
+}
+
+func medianOf(samples []int64) float64 {
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 0 {
+		return float64(sorted[n/2-1]+sorted[n/2]) / 2
+	}
+	return float64(sorted[n/2])
+}
@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+type SignaturePage struct {
+	Page       int    `json:"page"`
+	Latency    int64  `json:"latency"`
+	Size       int    `json:"size"`
+	Signatures int    `json:"signatures"`
+	NewestSlot uint64 `json:"newestSlot,omitempty"`
+	OldestSlot uint64 `json:"oldestSlot,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type PaginationStats struct {
+	Address         string          `json:"address"`
+	PageLimit       int             `json:"pageLimit"`
+	Pages           int             `json:"pages"`
+	Signatures      int             `json:"signatures"`
+	ReachedEnd      bool            `json:"reachedEnd"`
+	TotalTime       int64           `json:"totalTime"`
+	TotalBytes      int64           `json:"totalBytes"`
+	PageLatency     LatencyStats    `json:"pageLatency"`
+	OldestSlot      uint64          `json:"oldestSlot,omitempty"`
+	OldestBlockTime int64           `json:"oldestBlockTime,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	Interrupted     bool            `json:"interrupted,omitempty"`
+	PageSamples     []SignaturePage `json:"pageSamples"`
+}
+
+type signatureInfo struct {
+	Signature string `json:"signature"`
+	Slot      uint64 `json:"slot"`
+	BlockTime *int64 `json:"blockTime"`
+}
+
+// RunPaginationBenchmark walks an address's history backwards with
+// `before` cursors for up to depth pages, timing every page.
+func (s *JSONRPCTester) RunPaginationBenchmark(ctx context.Context, address string, depth, limit int) (*PaginationStats, error) {
+	if _, err := rpcclient.ParsePublicKey(address); err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > 1000 {
+		return nil, fmt.Errorf("page limit must be between 1 and 1000, got %d", limit)
+	}
+	s.Log().Info("paginating getSignaturesForAddress", "address", address, "pages", depth, "limit", limit)
+
+	stats := &PaginationStats{Address: address, PageLimit: limit}
+	var latencies []int64
+	before := ""
+	start := time.Now()
+
+	for page := 1; page <= depth && !s.stopped(ctx); page++ {
+		result, err := s.GetSignaturesForAddress(ctx, address, limit, before)
+		if err != nil {
+			return nil, err
+		}
+
+		sample := SignaturePage{Page: page, Latency: result.Latency, Size: result.Size}
+		stats.TotalBytes += int64(result.Size)
+
+		var signatures []signatureInfo
+		if err := rpcclient.DecodeResult(result, &signatures); err != nil {
+			sample.Error = err.Error()
+			stats.PageSamples = append(stats.PageSamples, sample)
+			stats.Error = fmt.Sprintf("page %d: %v", page, err)
+			break
+		}
+
+		latencies = append(latencies, result.Latency)
+		stats.Pages++
+		stats.Signatures += len(signatures)
+		sample.Signatures = len(signatures)
+		if len(signatures) > 0 {
+			oldest := signatures[len(signatures)-1]
+			sample.NewestSlot = signatures[0].Slot
+			sample.OldestSlot = oldest.Slot
+			stats.OldestSlot = oldest.Slot
+			if oldest.BlockTime != nil {
+				stats.OldestBlockTime = *oldest.BlockTime
+			}
+			before = oldest.Signature
+		}
+		stats.PageSamples = append(stats.PageSamples, sample)
+
+		if len(signatures) < limit {
+			stats.ReachedEnd = true
+			break
+		}
+		if page%10 == 0 {
+			s.Log().Info("pages fetched", "done", page, "total", depth, "signatures", stats.Signatures)
+		}
+	}
+
+	stats.TotalTime = time.Since(start).Milliseconds()
+	stats.PageLatency = s.summarize(latencies)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
@@ -0,0 +1,109 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// performanceHistory is how many samples, of about a minute each,
+// ClusterContext compares the run against.
+const performanceHistory = 60
+
+// ClusterPerformance is what the cluster was doing during a run, from
+// getRecentPerformanceSamples. TPS, NonVoteTPS and SlotTime, in
+// milliseconds, cover the samples overlapping the run, which are
+// minute-long, so short runs get the minute around them; the Baseline
+// fields cover the last hour. A latency regression that comes with TPS
+// well above its baseline or slot times well over 400ms is likely
+// cluster-wide congestion rather than the endpoint.
+type ClusterPerformance struct {
+	Samples            int     `json:"samples"`
+	TPS                float64 `json:"tps"`
+	NonVoteTPS         float64 `json:"nonVoteTps,omitempty"`
+	SlotTime           float64 `json:"slotTime"`
+	BaselineSamples    int     `json:"baselineSamples"`
+	BaselineTPS        float64 `json:"baselineTps"`
+	BaselineNonVoteTPS float64 `json:"baselineNonVoteTps,omitempty"`
+	BaselineSlotTime   float64 `json:"baselineSlotTime"`
+	Error              string  `json:"error,omitempty"`
+}
+
+type performanceSample struct {
+	Slot                   uint64  `json:"slot"`
+	NumSlots               uint64  `json:"numSlots"`
+	NumTransactions        uint64  `json:"numTransactions"`
+	NumNonVoteTransactions *uint64 `json:"numNonVoteTransactions"`
+	SamplePeriodSecs       uint64  `json:"samplePeriodSecs"`
+}
+
+// clusterPerformance fetches the cluster's recent performance samples
+// after a run that took elapsed. A failure is reported in the result's
+// Error rather than failing the run.
+func (s *JSONRPCTester) clusterPerformance(ctx context.Context, elapsed time.Duration) *ClusterPerformance {
+	// The run may have been interrupted, which shouldn't stop the fetch.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rpcclient.DefaultTimeout)
+	defer cancel()
+	perf := &ClusterPerformance{}
+	var samples []performanceSample
+	result, err := s.GetRecentPerformanceSamples(ctx, performanceHistory)
+	if err == nil {
+		err = rpcclient.DecodeResult(result, &samples)
+	}
+	if err != nil {
+		perf.Error = err.Error()
+		return perf
+	}
+	if len(samples) == 0 {
+		perf.Error = "no performance samples"
+		return perf
+	}
+
+	// Samples are newest first; the run overlaps the newest ones.
+	recent := 1
+	if samples[0].SamplePeriodSecs > 0 {
+		period := time.Duration(samples[0].SamplePeriodSecs) * time.Second
+		recent = int((elapsed+period-1)/period) + 1
+	}
+	if recent > len(samples) {
+		recent = len(samples)
+	}
+	perf.Samples = recent
+	perf.TPS, perf.NonVoteTPS, perf.SlotTime = summarizePerformance(samples[:recent])
+	perf.BaselineSamples = len(samples)
+	perf.BaselineTPS, perf.BaselineNonVoteTPS, perf.BaselineSlotTime = summarizePerformance(samples)
+	s.Log().Info("cluster performance", "tps", fmt.Sprintf("%.0f", perf.TPS),
+		"baseline_tps", fmt.Sprintf("%.0f", perf.BaselineTPS), "slot_ms", fmt.Sprintf("%.0f", perf.SlotTime))
+	return perf
+}
+
+// summarizePerformance returns the transactions and non-vote transactions
+// per second, and milliseconds per slot, across samples. Non-vote TPS is
+// zero if any sample lacks it, as older nodes' samples do.
+func summarizePerformance(samples []performanceSample) (tps, nonVoteTPS, slotTime float64) {
+	var secs, slots, txs, nonVote uint64
+	haveNonVote := true
+	for _, sample := range samples {
+		secs += sample.SamplePeriodSecs
+		slots += sample.NumSlots
+		txs += sample.NumTransactions
+		if sample.NumNonVoteTransactions == nil {
+			haveNonVote = false
+		} else {
+			nonVote += *sample.NumNonVoteTransactions
+		}
+	}
+	if secs == 0 {
+		return 0, 0, 0
+	}
+	tps = float64(txs) / float64(secs)
+	if haveNonVote {
+		nonVoteTPS = float64(nonVote) / float64(secs)
+	}
+	if slots > 0 {
+		slotTime = float64(secs) * 1000 / float64(slots)
+	}
+	return tps, nonVoteTPS, slotTime
+}
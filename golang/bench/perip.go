@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// IPResult is the benchmark of one address behind the endpoint's hostname.
+type IPResult struct {
+	IP     string          `json:"ip"`
+	Family string          `json:"family"`
+	Stats  *BenchmarkStats `json:"stats,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type PerIPStats struct {
+	Host        string     `json:"host"`
+	Addresses   []string   `json:"addresses"`
+	Interrupted bool       `json:"interrupted,omitempty"`
+	Results     []IPResult `json:"results"`
+}
+
+// RunPerIPBenchmark resolves the endpoint's hostname and runs the main
+// benchmark against each address in turn, keeping the hostname for SNI and
+// the Host header. Anycast and load-balanced providers can hide very
+// different backends behind one name.
+func (s *JSONRPCTester) RunPerIPBenchmark(ctx context.Context, iterations int) (*PerIPStats, error) {
+	host, ips, err := rpcclient.ResolveEndpoint(ctx, s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no addresses", host)
+	}
+
+	stats := &PerIPStats{Host: host}
+	for _, ip := range ips {
+		stats.Addresses = append(stats.Addresses, ip.String())
+	}
+	s.Log().Info("benchmarking each address", "host", host, "addresses", stats.Addresses)
+
+	for _, ip := range ips {
+		if s.stopped(ctx) {
+			break
+		}
+		ipResult := IPResult{IP: ip.String(), Family: "ipv6"}
+		if ip.To4() != nil {
+			ipResult.Family = "ipv4"
+		}
+
+		client, err := s.Client.Pinned(ip)
+		if err != nil {
+			return nil, err
+		}
+		tester := *s
+		tester.Client = client
+		// Each address is an endpoint of its own to the breaker.
+		tester.breakers = &breakerSet{}
+		tester.Label = ip.String()
+		if s.Label != "" {
+			tester.Label = s.Label + "/" + ip.String()
+		}
+
+		ipStats, err := tester.RunBenchmark(ctx, iterations)
+		if err != nil {
+			ipResult.Error = err.Error()
+		}
+		ipResult.Stats = ipStats
+		stats.Results = append(stats.Results, ipResult)
+	}
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
@@ -0,0 +1,181 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Run modes a WorkloadPlan describes.
+const (
+	PlanClosedLoop = "closed-loop"
+	PlanCompare    = "compare"
+	PlanOpenLoop   = "open-loop"
+	PlanRamp       = "ramp"
+	PlanSpike      = "spike"
+	PlanSoak       = "soak"
+)
+
+// WorkloadPlan is what a method benchmark would send, worked out without
+// sending anything. A closed-loop or compare run sends Iterations rounds
+// of the methods; the open-loop modes send them at the rate of each of
+// their Phases in turn. Requests, the measured total, is an estimate for
+// open-loop runs, counts the requests to each endpoint, and excludes the
+// warmup.
+type WorkloadPlan struct {
+	Endpoints      []string        `json:"endpoints"`
+	Chain          string          `json:"chain"`
+	Mode           string          `json:"mode"`
+	Iterations     int             `json:"iterations,omitempty"`
+	Requests       int             `json:"requests"`
+	Duration       string          `json:"duration,omitempty"`
+	Concurrency    int             `json:"concurrency,omitempty"`
+	Arrival        string          `json:"arrival,omitempty"`
+	Phases         []PlannedPhase  `json:"phases,omitempty"`
+	WarmupRequests int             `json:"warmupRequests,omitempty"`
+	WarmupDuration string          `json:"warmupDuration,omitempty"`
+	Timeout        string          `json:"timeout,omitempty"`
+	Seed           int64           `json:"seed,omitempty"`
+	Methods        []PlannedMethod `json:"methods"`
+}
+
+// PlannedPhase is one stretch of an open-loop run at a fixed rate.
+type PlannedPhase struct {
+	Rate     float64 `json:"rate"`
+	Duration string  `json:"duration"`
+	Requests int     `json:"requests"`
+}
+
+// PlannedMethod is one of the methods a run rotates through. Share is the
+// fraction of requests it makes up, more than one method's worth if it is
+// listed more than once. Params are those of the first request it would
+// send, as its placeholders, account list and seed resolve them, and
+// Template a template's params before they are. A method whose params
+// depend on a prefetched value, such as the current slot, has them
+// resolved only when the run starts; ResolvedAfter names the call it
+// makes first.
+type PlannedMethod struct {
+	Name          string          `json:"name"`
+	Method        string          `json:"method,omitempty"`
+	Share         float64         `json:"share"`
+	Template      json.RawMessage `json:"template,omitempty"`
+	Params        interface{}     `json:"params,omitempty"`
+	ResolvedAfter string          `json:"resolvedAfter,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// errDryRun stops each planned call before it is sent.
+var errDryRun = errors.New("dry run")
+
+// Plan works out the requests a closed-loop run of iterations rounds
+// would send, without sending any. Each method is run once against a
+// client that records the request instead of sending it, so params are
+// resolved exactly as the run would resolve them; doing so draws from the
+// account lists, so s shouldn't be run afterwards.
+func (s *JSONRPCTester) Plan(ctx context.Context, iterations int) (*WorkloadPlan, error) {
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+	plan := &WorkloadPlan{
+		Endpoints:      []string{rpcclient.RedactURL(s.Endpoint)},
+		Chain:          s.Chain(),
+		Mode:           PlanClosedLoop,
+		Iterations:     iterations,
+		Requests:       iterations * len(methods),
+		WarmupRequests: s.Warmup.Requests,
+		Seed:           s.seed,
+	}
+	if s.Warmup.Duration > 0 {
+		plan.WarmupDuration = s.Warmup.Duration.String()
+	}
+	if s.Timeout > 0 {
+		plan.Timeout = s.Timeout.String()
+	}
+
+	type call struct {
+		method string
+		params interface{}
+	}
+	var calls []call
+	dry := *s
+	dry.Client = s.Client.Clone(s.Endpoint)
+	transform := dry.Params
+	dry.Params = func(method string, params interface{}) (interface{}, error) {
+		if transform != nil {
+			var err error
+			if params, err = transform(method, params); err != nil {
+				return nil, err
+			}
+		}
+		calls = append(calls, call{method, params})
+		return nil, errDryRun
+	}
+
+	counts := map[string]int{}
+	for _, name := range methods {
+		counts[name]++
+	}
+	for _, name := range methods {
+		if counts[name] == 0 {
+			continue
+		}
+		planned := PlannedMethod{Name: name, Share: float64(counts[name]) / float64(len(methods))}
+		counts[name] = 0
+		if template := s.templateNamed(name); template != nil {
+			planned.Template = template.Params
+		}
+		calls = calls[:0]
+		_, err := dry.runner(name)(ctx, &dry)
+		switch {
+		case len(calls) == 0 && err != nil:
+			planned.Error = err.Error()
+		case len(calls) == 0:
+			// Nothing to send, e.g. a script that skipped its turn.
+		case err != nil:
+			// The call was a prefetch the method's params depend on.
+			planned.ResolvedAfter = calls[0].method
+		default:
+			planned.Method, planned.Params = calls[0].method, calls[0].params
+		}
+		plan.Methods = append(plan.Methods, planned)
+	}
+	return plan, nil
+}
+
+// SetLoad makes the plan an open-loop run of mode, sending at the rate of
+// each of steps in turn with cfg's concurrency and arrival process. A
+// single step without a duration sends cfg.Requests.
+func (plan *WorkloadPlan) SetLoad(mode string, steps []LoadStep, cfg LoadConfig) {
+	plan.Mode = mode
+	plan.Iterations = 0
+	plan.Requests = 0
+	plan.Concurrency = cfg.Concurrency
+	plan.Arrival = cfg.Arrival
+	if plan.Arrival == "" {
+		plan.Arrival = ArrivalConstant
+	}
+	var total time.Duration
+	for _, step := range steps {
+		phase := PlannedPhase{Rate: step.Rate, Requests: int(step.Rate * step.Duration.Seconds())}
+		if step.Duration <= 0 && step.Rate > 0 {
+			phase.Requests = cfg.Requests
+			step.Duration = time.Duration(float64(cfg.Requests) / step.Rate * float64(time.Second))
+		}
+		phase.Duration = step.Duration.String()
+		total += step.Duration
+		plan.Requests += phase.Requests
+		plan.Phases = append(plan.Phases, phase)
+	}
+	plan.Duration = total.String()
+}
+
+// SetCompare makes the plan a comparison, sending every request to other
+// as well.
+func (plan *WorkloadPlan) SetCompare(other string) {
+	plan.Mode = PlanCompare
+	plan.Endpoints = append(plan.Endpoints, rpcclient.RedactURL(other))
+}
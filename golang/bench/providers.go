@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// MethodProvider adds benchmarkable methods beyond a chain's standard
+// catalog, such as one provider's own APIs. Providers register at compile time,
+// from an init function, so importing a provider's package is all it takes
+// to make its methods selectable with -methods:
+//
+//	func init() { bench.RegisterProvider(helius{}) }
+type MethodProvider interface {
+	// Name identifies the provider, e.g. "helius".
+	Name() string
+	// Methods returns the provider's methods by name, as they are given
+	// to -methods.
+	Methods() map[string]Method
+}
+
+// ScenarioProvider is a MethodProvider that also contributes named method
+// lists, selectable with -scenario.
+type ScenarioProvider interface {
+	MethodProvider
+	Scenarios() map[string][]string
+}
+
+// ChainProvider is a MethodProvider whose methods extend the catalog of a
+// chain other than Solana, such as ChainEVM.
+type ChainProvider interface {
+	MethodProvider
+	Chain() string
+}
+
+// Method is one benchmarkable method. Run sends a single request,
+// drawing any keys it needs from the tester; its result is reported under
+// the method's registered name, whatever RPC method it called. Check, if
+// set, is called before a run and reports what the tester lacks to send
+// the method, e.g. "needs an account list (-accounts)".
+type Method struct {
+	Run   func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error)
+	Check func(s *JSONRPCTester) error
+}
+
+// spec returns m as the catalog runs it, its results named name.
+func (m Method) spec(name string) methodSpec {
+	return methodSpec{check: m.Check, run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		result, err := m.Run(ctx, s)
+		if result != nil {
+			result.Method = name
+		}
+		return result, err
+	}}
+}
+
+// providers maps each registered provider's name to the methods it added.
+var providers = map[string][]string{}
+
+// RegisterProvider adds p's methods to the Solana method catalog, or its
+// chain's if it is a ChainProvider, and its scenarios if it is a
+// ScenarioProvider. It is meant to be called from init functions, and
+// panics if p is already registered, its chain is unknown, or a method or
+// scenario is missing or already taken.
+func RegisterProvider(p MethodProvider) {
+	name := p.Name()
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("bench: provider %q registered twice", name))
+	}
+	chain := ChainSolana
+	if cp, ok := p.(ChainProvider); ok {
+		chain = cp.Chain()
+	}
+	catalog, ok := catalogs[chain]
+	if !ok {
+		panic(fmt.Sprintf("bench: provider %q: unknown chain %q", name, chain))
+	}
+	methods := p.Methods()
+	names := make([]string, 0, len(methods))
+	for method, spec := range methods {
+		if spec.Run == nil {
+			panic(fmt.Sprintf("bench: provider %q: method %s has no Run", name, method))
+		}
+		if _, ok := catalog.methods[method]; ok {
+			panic(fmt.Sprintf("bench: provider %q: method %s is already registered", name, method))
+		}
+		names = append(names, method)
+	}
+	for method, spec := range methods {
+		catalog.methods[method] = spec.spec(method)
+	}
+	if sp, ok := p.(ScenarioProvider); ok {
+		for scenario, methods := range sp.Scenarios() {
+			if _, ok := catalog.scenarios[scenario]; ok {
+				panic(fmt.Sprintf("bench: provider %q: scenario %s is already registered", name, scenario))
+			}
+			for _, method := range methods {
+				if _, ok := catalog.methods[method]; !ok {
+					panic(fmt.Sprintf("bench: provider %q: scenario %s uses unknown method %s", name, scenario, method))
+				}
+			}
+			catalog.scenarios[scenario] = methods
+		}
+	}
+	sort.Strings(names)
+	providers[name] = names
+}
+
+// Providers returns the names of the registered providers, sorted.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProviderMethods returns the sorted names of the methods the named
+// provider registered.
+func ProviderMethods(name string) []string {
+	return providers[name]
+}
@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// QuotaStats follows the allowance the endpoint's rate-limit headers
+// reported during a run. Responses counts those whose headers gave the
+// allowance left; a refusal with no more than Retry-After isn't one.
+// FirstRemaining and LastRemaining are the allowance left after the first
+// and last of them to complete, and MinRemaining the least seen; Limit and
+// Window are those of the last. RateLimited counts the requests refused
+// for going too fast, with or without headers, and MaxRetryAfterMs is the
+// longest a refusal asked the client to wait.
+type QuotaStats struct {
+	Responses       int    `json:"responses"`
+	Limit           int64  `json:"limit,omitempty"`
+	Window          string `json:"window,omitempty"`
+	FirstRemaining  int64  `json:"firstRemaining"`
+	LastRemaining   int64  `json:"lastRemaining"`
+	MinRemaining    int64  `json:"minRemaining"`
+	RateLimited     int    `json:"rateLimited"`
+	MaxRetryAfterMs int64  `json:"maxRetryAfterMs,omitempty"`
+}
+
+// trackQuota follows the rate-limit headers of results, which completed
+// at the offsets in done, or nil if none carried any and none was rate
+// limited.
+func trackQuota(results []rpcclient.Result, done []time.Duration) *QuotaStats {
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	if len(done) == len(results) {
+		sort.SliceStable(order, func(i, j int) bool { return done[order[i]] < done[order[j]] })
+	}
+	stats := &QuotaStats{}
+	for _, i := range order {
+		result := results[i]
+		if result.RateLimited {
+			stats.RateLimited++
+		}
+		quota := rpcclient.ParseQuota(result.LimitHeaders, completedAt(result))
+		if quota == nil {
+			continue
+		}
+		stats.MaxRetryAfterMs = max(stats.MaxRetryAfterMs, quota.RetryAfter.Milliseconds())
+		if !quota.HasRemaining {
+			continue
+		}
+		if stats.Responses == 0 {
+			stats.FirstRemaining, stats.MinRemaining = quota.Remaining, quota.Remaining
+		}
+		stats.Responses++
+		stats.Limit, stats.Window = quota.Limit, quota.Window
+		stats.LastRemaining = quota.Remaining
+		stats.MinRemaining = min(stats.MinRemaining, quota.Remaining)
+	}
+	if stats.Responses == 0 && stats.RateLimited == 0 {
+		return nil
+	}
+	return stats
+}
+
+// completedAt is when result's response arrived, as near as it records.
+func completedAt(result rpcclient.Result) time.Time {
+	if result.StartedAt.IsZero() {
+		return time.Now()
+	}
+	return result.StartedAt.Add(time.Duration(result.Latency) * time.Millisecond)
+}
@@ -0,0 +1,56 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+func TestTrackQuota(t *testing.T) {
+	allowance := func(remaining string) rpcclient.Result {
+		return rpcclient.Result{Success: true, LimitHeaders: map[string]string{
+			"x-ratelimit-limit-second": "50", "x-ratelimit-remaining-second": remaining,
+		}}
+	}
+	refused := func(retryAfter string) rpcclient.Result {
+		return rpcclient.Result{RateLimited: true, StatusCode: 429, LimitHeaders: map[string]string{"retry-after": retryAfter}}
+	}
+	tests := []struct {
+		name    string
+		results []rpcclient.Result
+		want    *QuotaStats
+	}{
+		{name: "no headers", results: []rpcclient.Result{{Success: true}}},
+		{
+			name:    "allowance",
+			results: []rpcclient.Result{allowance("40"), allowance("12"), allowance("30")},
+			want:    &QuotaStats{Responses: 3, Limit: 50, Window: "second", FirstRemaining: 40, LastRemaining: 30, MinRemaining: 12},
+		},
+		{
+			// A refusal giving only Retry-After says nothing of the
+			// allowance left.
+			name:    "retry-after only",
+			results: []rpcclient.Result{allowance("40"), refused("2"), allowance("30"), refused("1")},
+			want: &QuotaStats{Responses: 2, Limit: 50, Window: "second", FirstRemaining: 40, LastRemaining: 30, MinRemaining: 30,
+				RateLimited: 2, MaxRetryAfterMs: 2000},
+		},
+		{
+			name:    "only refusals",
+			results: []rpcclient.Result{refused("1.5"), {RateLimited: true, StatusCode: 429}},
+			want:    &QuotaStats{RateLimited: 2, MaxRetryAfterMs: 1500},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make([]time.Duration, len(tt.results))
+			for i := range done {
+				done[i] = time.Duration(i) * time.Millisecond
+			}
+			if got := trackQuota(tt.results, done); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// LoadStep is one stage of a ramp: Rate requests per second for Duration.
+type LoadStep struct {
+	Rate     float64
+	Duration time.Duration
+}
+
+// ParseRamp parses a schedule such as "100rps:2m,500rps:2m,1000rps:2m".
+// The "rps" suffix is optional.
+func ParseRamp(schedule string) ([]LoadStep, error) {
+	var steps []LoadStep
+	for _, item := range SplitList(schedule) {
+		rate, duration, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("ramp step %q is not of the form rate:duration", item)
+		}
+		var step LoadStep
+		var err error
+		if step.Rate, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rate), "rps"), 64); err != nil || step.Rate <= 0 {
+			return nil, fmt.Errorf("ramp step %q: rate must be a positive number", item)
+		}
+		if step.Duration, err = time.ParseDuration(strings.TrimSpace(duration)); err != nil || step.Duration <= 0 {
+			return nil, fmt.Errorf("ramp step %q: duration must be a positive duration", item)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("ramp has no steps")
+	}
+	return steps, nil
+}
+
+// StepStats are the open-loop stats of the requests scheduled during one
+// ramp step. AchievedRate counts requests completed during the step.
+type StepStats struct {
+	Step     int    `json:"step"`
+	Duration string `json:"duration"`
+	OpenLoopStats
+}
+
+type RampStats struct {
+	Arrival     string      `json:"arrival"`
+	Concurrency int         `json:"concurrency"`
+	Interrupted bool        `json:"interrupted,omitempty"`
+	Steps       []StepStats `json:"steps"`
+}
+
+// stepArrivals runs one arrival process per step, back to back.
+type stepArrivals struct {
+	steps     []LoadStep
+	process   string
+	rng       *rand.Rand
+	index     int
+	stepStart time.Duration
+	current   arrivals
+}
+
+func (a *stepArrivals) next() time.Duration {
+	for a.index < len(a.steps) {
+		if offset := a.current.next(); offset < a.steps[a.index].Duration {
+			return a.stepStart + offset
+		}
+		a.stepStart += a.steps[a.index].Duration
+		a.index++
+		if a.index < len(a.steps) {
+			a.current, _ = newArrivals(a.process, a.steps[a.index].Rate, a.rng)
+		}
+	}
+	// Past the last step; the schedule's duration ends the run.
+	return a.stepStart
+}
+
+// RunRamp steps the open-loop request rate through steps without pausing
+// between them and reports stats per step, so the knee of the latency curve
+// shows up as the step where latency or scheduling lag takes off. cfg
+// supplies concurrency and arrival process; its rate and length are
+// ignored.
+func (s *JSONRPCTester) RunRamp(ctx context.Context, steps []LoadStep, cfg LoadConfig) (*RampStats, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("ramp has no steps")
+	}
+	if cfg.Arrival == "" {
+		cfg.Arrival = ArrivalConstant
+	}
+	samples, warmupRequests, err := s.runSteps(ctx, "running ramp", steps, cfg)
+	if err != nil {
+		return nil, err
+	}
+	stats := &RampStats{
+		Arrival:     cfg.Arrival,
+		Concurrency: cfg.Concurrency,
+		Interrupted: s.stopped(ctx),
+	}
+	stats.Steps = s.splitSteps(samples, steps, cfg, warmupRequests, stats.Interrupted)
+	return stats, nil
+}
+
+// runSteps warms up and then runs steps as one open-loop schedule.
+func (s *JSONRPCTester) runSteps(ctx context.Context, msg string, steps []LoadStep, cfg LoadConfig) ([]openLoopSample, int, error) {
+	if cfg.Concurrency <= 0 {
+		return nil, 0, fmt.Errorf("open-loop concurrency must be positive")
+	}
+	rng := s.newRand()
+	first, err := newArrivals(cfg.Arrival, steps[0].Rate, rng)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.validateMethods(); err != nil {
+		return nil, 0, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total time.Duration
+	expected := 0
+	for _, step := range steps {
+		total += step.Duration
+		expected += int(step.Rate * step.Duration.Seconds())
+	}
+	cfg.Duration, cfg.Requests = total, 0
+	s.Log().Info(msg, "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"steps", len(steps), "duration", total, "arrival", cfg.Arrival,
+		"concurrency", cfg.Concurrency, "methods", methods)
+
+	arr := &stepArrivals{steps: steps, process: cfg.Arrival, rng: rng, current: first}
+	samples, _, err := s.runSchedule(ctx, cfg, methods, arr, expected)
+	if err != nil {
+		return nil, 0, err
+	}
+	return samples, warmupRequests, nil
+}
+
+// splitSteps summarizes samples per step, by when each was scheduled.
+func (s *JSONRPCTester) splitSteps(samples []openLoopSample, steps []LoadStep, cfg LoadConfig, warmupRequests int, interrupted bool) []StepStats {
+	var stats []StepStats
+	var stepStart time.Duration
+	for i, step := range steps {
+		stepEnd := stepStart + step.Duration
+		var stepSamples []openLoopSample
+		// Throughput is what completed during the step, whichever step
+		// scheduled it; a saturated endpoint falls short of the target.
+		completed := 0
+		for _, sample := range samples {
+			if sample.offset >= stepStart && sample.offset < stepEnd {
+				stepSamples = append(stepSamples, sample)
+			}
+			if done := sample.offset + sample.corrected; done >= stepStart && done < stepEnd {
+				completed++
+			}
+		}
+		stepStart = stepEnd
+		if len(stepSamples) == 0 && interrupted {
+			break
+		}
+
+		stepStats := StepStats{
+			Step:          i + 1,
+			Duration:      step.Duration.String(),
+			OpenLoopStats: *s.summarizeOpenLoop(stepSamples, step.Duration),
+		}
+		stepStats.AchievedRate = float64(completed) / step.Duration.Seconds()
+		stepStats.Arrival = cfg.Arrival
+		stepStats.TargetRate = step.Rate
+		stepStats.Concurrency = cfg.Concurrency
+		if i == 0 {
+			stepStats.WarmupRequests = warmupRequests
+		}
+		stats = append(stats, stepStats)
+	}
+	return stats
+}
@@ -0,0 +1,327 @@
+package bench
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// RateLimitConfig bounds the search for the rate at which an endpoint
+// starts refusing requests.
+type RateLimitConfig struct {
+	MinRate float64
+	MaxRate float64
+	// ProbeDuration is how long each candidate rate is held, and Cooldown
+	// how long to wait before each probe, so the limit window or token
+	// bucket the last one drained has refilled.
+	ProbeDuration time.Duration
+	Cooldown      time.Duration
+	// MaxErrorRate is the percentage of requests that may fail, rate
+	// limited or otherwise, before a rate counts as over the limit.
+	MaxErrorRate float64
+	// Precision ends the search once the rates under and over the limit
+	// are within this fraction of each other.
+	Precision float64
+	// BurstRequests, if positive, caps the burst test that follows the
+	// search: requests sent at MaxRate until one is rate limited.
+	BurstRequests int
+
+	Concurrency int
+	Arrival     string
+}
+
+// RateLimitProbe is the outcome of holding one rate. AcceptedRate is the
+// rate of successful requests over the probe's second half, after any
+// burst allowance has run out.
+type RateLimitProbe struct {
+	Rate         float64 `json:"rate"`
+	Limited      bool    `json:"limited"`
+	Reason       string  `json:"reason,omitempty"`
+	AchievedRate float64 `json:"achievedRate"`
+	AcceptedRate float64 `json:"acceptedRate"`
+	Requests     int     `json:"requests"`
+	RateLimited  int     `json:"rateLimited"`
+	Failed       int     `json:"failed"`
+}
+
+// BurstStats is how many requests the endpoint took at once. Accepted
+// counts the successful requests sent before the first rate-limited one,
+// which was sent LimitedAfterMs into the burst. Allowance is how many of
+// those the sustained limit doesn't account for: the provider's burst
+// allowance, or bucket size.
+type BurstStats struct {
+	Rate           float64 `json:"rate"`
+	Requests       int     `json:"requests"`
+	Limited        bool    `json:"limited"`
+	Accepted       int     `json:"accepted"`
+	LimitedAfterMs int64   `json:"limitedAfterMs,omitempty"`
+	Allowance      int     `json:"allowance,omitempty"`
+}
+
+// RateLimitStats is the rate limit found for an endpoint and key. KeyID
+// is a hash of the endpoint URL and headers, which tells runs with
+// different API keys apart without revealing them.
+//
+// LimitRPS is the highest rate the endpoint accepted requests at while
+// refusing others, or zero if no probe went over the limit, and
+// SustainedRPS the highest probed rate it took without refusals. Headers
+// are the rate-limit headers of the latest successful response, and
+// LimitedHeaders those of the first rate-limited one.
+type RateLimitStats struct {
+	Endpoint       string            `json:"endpoint"`
+	KeyID          string            `json:"keyId"`
+	LimitRPS       float64           `json:"limitRps"`
+	SustainedRPS   float64           `json:"sustainedRps"`
+	Burst          *BurstStats       `json:"burst,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	LimitedHeaders map[string]string `json:"limitedHeaders,omitempty"`
+	MaxErrorRate   float64           `json:"maxErrorRate"`
+	WarmupRequests int               `json:"warmupRequests,omitempty"`
+	Interrupted    bool              `json:"interrupted,omitempty"`
+	Probes         []RateLimitProbe  `json:"probes"`
+}
+
+// RunRateLimitDiscovery finds the rate at which the endpoint starts
+// refusing requests. The rate doubles from MinRate until a probe fails
+// more than cfg.MaxErrorRate of its requests, then the search bisects
+// between the last rate under the limit and the first over it. A burst
+// test then measures how many requests the endpoint takes at once.
+func (s *JSONRPCTester) RunRateLimitDiscovery(ctx context.Context, cfg RateLimitConfig) (*RateLimitStats, error) {
+	switch {
+	case cfg.MinRate <= 0 || cfg.MaxRate < cfg.MinRate:
+		return nil, fmt.Errorf("rate limit discovery needs 0 < min rate <= max rate")
+	case cfg.ProbeDuration <= 0:
+		return nil, fmt.Errorf("rate limit discovery needs a positive probe duration")
+	case cfg.Cooldown < 0 || cfg.BurstRequests < 0:
+		return nil, fmt.Errorf("rate limit discovery needs a cooldown and burst size of zero or more")
+	case cfg.Concurrency <= 0:
+		return nil, fmt.Errorf("open-loop concurrency must be positive")
+	}
+	if cfg.Precision <= 0 {
+		cfg.Precision = 0.1
+	}
+	if _, err := newArrivals(cfg.Arrival, cfg.MinRate, nil); err != nil {
+		return nil, err
+	}
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, err
+	}
+	stats := &RateLimitStats{
+		Endpoint:       rpcclient.RedactURL(s.Endpoint),
+		KeyID:          keyID(s.Endpoint, s.Headers),
+		MaxErrorRate:   cfg.MaxErrorRate,
+		WarmupRequests: warmupRequests,
+	}
+	s.Log().Info("searching for rate limit", "endpoint", stats.Endpoint, "key_id", stats.KeyID,
+		"max_error_pct", cfg.MaxErrorRate, "min_rate", cfg.MinRate, "max_rate", cfg.MaxRate,
+		"probe_duration", cfg.ProbeDuration, "cooldown", cfg.Cooldown)
+
+	probe := func(rate float64) (bool, error) {
+		s.sleep(ctx, cfg.Cooldown)
+		if s.stopped(ctx) {
+			return false, nil
+		}
+		result, err := s.probeLimit(ctx, cfg, methods, rate, stats)
+		if err != nil {
+			return false, err
+		}
+		if s.stopped(ctx) {
+			return false, nil
+		}
+		stats.Probes = append(stats.Probes, *result)
+		s.Log().Info("rate limit probe", "rate", rate, "limited", result.Limited, "reason", result.Reason,
+			"accepted_rate", result.AcceptedRate, "rate_limited", result.RateLimited, "failed", result.Failed)
+		under := !result.Limited && result.Reason == ""
+		if under && rate > stats.SustainedRPS {
+			stats.SustainedRPS = rate
+		}
+		if result.Limited && result.AcceptedRate > stats.LimitRPS {
+			stats.LimitRPS = result.AcceptedRate
+		}
+		return under, nil
+	}
+
+	// Grow until a rate goes over the limit or the ceiling passes.
+	low, high := 0.0, 0.0
+	for rate := cfg.MinRate; !s.stopped(ctx); rate *= 2 {
+		if rate > cfg.MaxRate {
+			rate = cfg.MaxRate
+		}
+		under, err := probe(rate)
+		if err != nil {
+			return nil, err
+		}
+		if !under {
+			high = rate
+			break
+		}
+		low = rate
+		if rate == cfg.MaxRate {
+			break
+		}
+	}
+
+	// Bisect between the last rate under the limit and the first over.
+	for low > 0 && high > 0 && (high-low)/low > cfg.Precision && !s.stopped(ctx) {
+		mid := (low + high) / 2
+		under, err := probe(mid)
+		if err != nil {
+			return nil, err
+		}
+		if under {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	if cfg.BurstRequests > 0 && !s.stopped(ctx) {
+		s.sleep(ctx, cfg.Cooldown)
+		if !s.stopped(ctx) {
+			if stats.Burst, err = s.probeBurst(ctx, cfg, methods, stats); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	stats.Interrupted = s.stopped(ctx)
+	if stats.LimitRPS > 0 {
+		s.Log().Info("rate limit found", "limit_rps", stats.LimitRPS, "sustained_rps", stats.SustainedRPS)
+	} else {
+		s.Log().Info("no rate limit found", "sustained_rps", stats.SustainedRPS)
+	}
+	return stats, nil
+}
+
+// probeLimit holds rate for cfg.ProbeDuration and judges whether the
+// endpoint refused too many of its requests. It records the limit headers
+// the responses carried in stats.
+func (s *JSONRPCTester) probeLimit(ctx context.Context, cfg RateLimitConfig, methods []string, rate float64, stats *RateLimitStats) (*RateLimitProbe, error) {
+	arr, err := newArrivals(cfg.Arrival, rate, s.newRand())
+	if err != nil {
+		return nil, err
+	}
+	load := LoadConfig{Rate: rate, Duration: cfg.ProbeDuration, Concurrency: cfg.Concurrency, Arrival: cfg.Arrival}
+	samples, elapsed, err := s.runSchedule(ctx, load, methods, arr, int(rate*cfg.ProbeDuration.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &RateLimitProbe{Rate: rate, Requests: len(samples)}
+	half := elapsed / 2
+	accepted := 0
+	for _, sample := range samples {
+		stats.observeHeaders(sample.result)
+		switch {
+		case sample.result.Success:
+			if sample.offset >= half {
+				accepted++
+			}
+		case sample.result.RateLimited:
+			probe.RateLimited++
+			probe.Failed++
+		default:
+			probe.Failed++
+		}
+	}
+	if elapsed > 0 {
+		probe.AchievedRate = float64(len(samples)) / elapsed.Seconds()
+		probe.AcceptedRate = float64(accepted) / (elapsed - half).Seconds()
+	}
+	errorRate := 0.0
+	if len(samples) > 0 {
+		errorRate = float64(probe.Failed) / float64(len(samples)) * 100
+	}
+	switch {
+	case len(samples) == 0:
+		probe.Reason = "no requests completed"
+	case errorRate > cfg.MaxErrorRate && probe.RateLimited > 0:
+		probe.Limited = true
+		probe.Reason = fmt.Sprintf("%.2f%% rate limited, %.2f%% failed", float64(probe.RateLimited)/float64(len(samples))*100, errorRate)
+	case errorRate > cfg.MaxErrorRate:
+		probe.Limited = true
+		probe.Reason = fmt.Sprintf("%.2f%% failed", errorRate)
+	case probe.AchievedRate < rate*achievedRateFloor:
+		probe.Reason = fmt.Sprintf("achieved only %.1f rps", probe.AchievedRate)
+	}
+	return probe, nil
+}
+
+// probeBurst sends requests at cfg.MaxRate, up to cfg.BurstRequests, until
+// one is rate limited.
+func (s *JSONRPCTester) probeBurst(ctx context.Context, cfg RateLimitConfig, methods []string, stats *RateLimitStats) (*BurstStats, error) {
+	burstCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	load := LoadConfig{Rate: cfg.MaxRate, Duration: cfg.ProbeDuration, Requests: cfg.BurstRequests, Concurrency: cfg.Concurrency}
+	var samples []openLoopSample
+	_, err := s.streamSchedule(burstCtx, load, s.rotation(methods), &constantArrivals{rate: cfg.MaxRate}, cfg.BurstRequests, func(sample openLoopSample) {
+		samples = append(samples, sample)
+		if sample.result.RateLimited {
+			cancel()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	burst := &BurstStats{Rate: cfg.MaxRate, Requests: len(samples)}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].offset < samples[j].offset })
+	for _, sample := range samples {
+		stats.observeHeaders(sample.result)
+		if sample.result.RateLimited {
+			burst.Limited = true
+			burst.LimitedAfterMs = sample.offset.Milliseconds()
+			break
+		}
+		if sample.result.Success {
+			burst.Accepted++
+		}
+	}
+	if burst.Limited && stats.LimitRPS > 0 {
+		burst.Allowance = max(0, burst.Accepted-int(stats.LimitRPS*float64(burst.LimitedAfterMs)/1000))
+	}
+	s.Log().Info("burst probe", "rate", burst.Rate, "accepted", burst.Accepted, "limited", burst.Limited,
+		"limited_after_ms", burst.LimitedAfterMs, "allowance", burst.Allowance)
+	return burst, nil
+}
+
+// observeHeaders keeps the limit headers of result: always those of a
+// success, and those of the first rate-limited response.
+func (r *RateLimitStats) observeHeaders(result rpcclient.Result) {
+	if len(result.LimitHeaders) == 0 {
+		return
+	}
+	if result.Success {
+		r.Headers = result.LimitHeaders
+	} else if result.RateLimited && r.LimitedHeaders == nil {
+		r.LimitedHeaders = result.LimitHeaders
+	}
+}
+
+// keyID is a short hash of endpoint and headers, which differ between API
+// keys.
+func keyID(endpoint string, headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	io.WriteString(h, endpoint)
+	for _, name := range names {
+		fmt.Fprintf(h, "\n%s: %s", name, headers[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
@@ -0,0 +1,161 @@
+package bench
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/mockserver"
+)
+
+// searchRates replays the search over the verdicts of probes, returning
+// the rates it should have probed and the bracket it ended with.
+func searchRates(cfg RateLimitConfig, probes []RateLimitProbe) (rates []float64, low, high float64) {
+	under := func(i int) bool { return !probes[i].Limited && probes[i].Reason == "" }
+	for rate := cfg.MinRate; len(rates) < len(probes); rate *= 2 {
+		rate = min(rate, cfg.MaxRate)
+		rates = append(rates, rate)
+		if !under(len(rates) - 1) {
+			high = rate
+			break
+		}
+		low = rate
+		if rate == cfg.MaxRate {
+			break
+		}
+	}
+	for low > 0 && high > 0 && (high-low)/low > cfg.Precision && len(rates) < len(probes) {
+		mid := (low + high) / 2
+		rates = append(rates, mid)
+		if under(len(rates) - 1) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return rates, low, high
+}
+
+func TestRunRateLimitDiscovery(t *testing.T) {
+	tests := []struct {
+		name   string
+		server mockserver.Config
+		cfg    RateLimitConfig
+		// The limit found, if any, and the highest rate the endpoint may
+		// take without refusals.
+		limit, maxSustained float64
+		probes              int
+		burst               func(t *testing.T, burst *BurstStats)
+	}{
+		{
+			name:         "converges on the limit",
+			server:       mockserver.Config{RateLimit: 50, Burst: 2, Seed: 1},
+			cfg:          RateLimitConfig{MinRate: 10, MaxRate: 200, Precision: 0.25},
+			limit:        50,
+			maxSustained: 60,
+		},
+		{
+			name:         "no limit under the ceiling",
+			server:       mockserver.Config{Seed: 1},
+			cfg:          RateLimitConfig{MinRate: 10, MaxRate: 30},
+			maxSustained: 30,
+			probes:       3,
+		},
+		{
+			// With no rate under the limit there is nothing to bisect.
+			name:   "over the limit from the start",
+			server: mockserver.Config{RateLimit: 20, Burst: 10, Seed: 1},
+			cfg:    RateLimitConfig{MinRate: 80, MaxRate: 80, Cooldown: 500 * time.Millisecond, BurstRequests: 40},
+			limit:  20,
+			probes: 1,
+			burst: func(t *testing.T, burst *BurstStats) {
+				// The refilled bucket's ten, and those refilled while they
+				// went.
+				if !burst.Limited || burst.Accepted < 10 || burst.Accepted > 20 || burst.Allowance < 6 || burst.Allowance > 14 {
+					t.Errorf("burst %+v, want about 10 allowed before a refusal", burst)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := newMockTester(t, tt.server, "getSlot")
+			cfg := tt.cfg
+			cfg.ProbeDuration, cfg.MaxErrorRate, cfg.Concurrency = 400*time.Millisecond, 2, 10
+			if cfg.Cooldown == 0 {
+				cfg.Cooldown = 100 * time.Millisecond
+			}
+			stats, err := tester.RunRateLimitDiscovery(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Precision == 0 {
+				cfg.Precision = 0.1
+			}
+			rates, low, high := searchRates(cfg, stats.Probes)
+			var probed []float64
+			for _, probe := range stats.Probes {
+				probed = append(probed, probe.Rate)
+			}
+			if !reflect.DeepEqual(probed, rates) {
+				t.Fatalf("probed %v, want %v given their verdicts", probed, rates)
+			}
+			if tt.probes > 0 && len(probed) != tt.probes {
+				t.Errorf("probed %v, want %d rates", probed, tt.probes)
+			}
+			for _, rate := range probed {
+				if rate < cfg.MinRate || rate > cfg.MaxRate {
+					t.Errorf("probed %g, outside %g-%g", rate, cfg.MinRate, cfg.MaxRate)
+				}
+			}
+			if low > 0 && high > 0 && (high-low)/low > cfg.Precision {
+				t.Errorf("ended between %g and %g, wider than %g", low, high, cfg.Precision)
+			}
+			if stats.SustainedRPS != low || stats.SustainedRPS > tt.maxSustained {
+				t.Errorf("sustained %g rps, want the last rate under the limit, %g, at most %g", stats.SustainedRPS, low, tt.maxSustained)
+			}
+			if tt.limit == 0 && stats.LimitRPS != 0 {
+				t.Errorf("found a limit of %g rps, want none", stats.LimitRPS)
+			}
+			if tt.limit > 0 && (stats.LimitRPS < tt.limit*0.7 || stats.LimitRPS > tt.limit*1.3) {
+				t.Errorf("found a limit of %g rps, want about %g", stats.LimitRPS, tt.limit)
+			}
+			if tt.limit > 0 && (stats.Headers["x-ratelimit-limit"] == "" || stats.LimitedHeaders["retry-after"] == "") {
+				t.Errorf("headers %v and %v, want the limit and Retry-After kept", stats.Headers, stats.LimitedHeaders)
+			}
+			if tt.burst != nil {
+				tt.burst(t, stats.Burst)
+			} else if stats.Burst != nil {
+				t.Errorf("burst %+v, want none", stats.Burst)
+			}
+		})
+	}
+}
+
+func TestRunRateLimitDiscoveryChecksBounds(t *testing.T) {
+	tester := newMockTester(t, mockserver.Config{Seed: 1}, "getSlot")
+	cfg := RateLimitConfig{MinRate: 10, MaxRate: 100, ProbeDuration: time.Second, Concurrency: 1}
+	tests := []struct {
+		name string
+		cfg  func(*RateLimitConfig)
+		err  string
+	}{
+		{name: "no min rate", cfg: func(c *RateLimitConfig) { c.MinRate = 0 }, err: "0 < min rate <= max rate"},
+		{name: "max under min", cfg: func(c *RateLimitConfig) { c.MaxRate = 5 }, err: "0 < min rate <= max rate"},
+		{name: "no probe duration", cfg: func(c *RateLimitConfig) { c.ProbeDuration = 0 }, err: "positive probe duration"},
+		{name: "negative cooldown", cfg: func(c *RateLimitConfig) { c.Cooldown = -time.Second }, err: "zero or more"},
+		{name: "negative burst", cfg: func(c *RateLimitConfig) { c.BurstRequests = -1 }, err: "zero or more"},
+		{name: "no concurrency", cfg: func(c *RateLimitConfig) { c.Concurrency = 0 }, err: "concurrency must be positive"},
+		{name: "unknown arrival", cfg: func(c *RateLimitConfig) { c.Arrival = "bursty" }, err: "bursty"},
+	}
+	for _, tt := range tests {
+		c := cfg
+		tt.cfg(&c)
+		if _, err := tester.RunRateLimitDiscovery(context.Background(), c); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
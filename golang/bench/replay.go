@@ -0,0 +1,132 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// ReplayStats summarizes a replayed recording. When it was paced (Speed
+// above zero) the open-loop fields are set as well.
+type ReplayStats struct {
+	Recording string  `json:"recording"`
+	Speed     float64 `json:"speed"`
+	BenchmarkStats
+	Concurrency      int           `json:"concurrency,omitempty"`
+	CorrectedLatency *LatencyStats `json:"correctedLatency,omitempty"`
+	SchedulingLag    *LatencyStats `json:"schedulingLag,omitempty"`
+}
+
+// replayArrivals sends each call at its recorded offset divided by speed.
+type replayArrivals struct {
+	calls []rpcclient.RecordedCall
+	speed float64
+	n     int
+}
+
+func (a *replayArrivals) next() time.Duration {
+	if a.n >= len(a.calls) {
+		return 0
+	}
+	offset := time.Duration(float64(a.calls[a.n].OffsetMicros) / a.speed * float64(time.Microsecond))
+	a.n++
+	return offset
+}
+
+func replayRunner(call rpcclient.RecordedCall) methodRunner {
+	var params interface{}
+	if len(call.Params) > 0 {
+		params = call.Params
+	}
+	return func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.Call(ctx, call.Method, params)
+	}
+}
+
+// RunReplay re-sends the requests recorded in path, with the same methods
+// and params, to s's endpoint. With speed above zero the original timing
+// is reproduced, compressed by speed (2 replays twice as fast), with up to
+// concurrency requests in flight; with speed zero the calls go one after
+// another as fast as the endpoint answers. No warmup is sent, so the
+// sequence stays exactly as recorded.
+func (s *JSONRPCTester) RunReplay(ctx context.Context, path string, speed float64, concurrency int) (*ReplayStats, error) {
+	if speed < 0 {
+		return nil, fmt.Errorf("replay speed must not be negative")
+	}
+	calls, err := rpcclient.ReadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].OffsetMicros < calls[j].OffsetMicros })
+	stats := &ReplayStats{Recording: path, Speed: speed}
+	s.Log().Info("replaying recording", "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"recording", path, "requests", len(calls), "speed", speed)
+
+	if speed > 0 {
+		if concurrency <= 0 {
+			return nil, fmt.Errorf("open-loop concurrency must be positive")
+		}
+		cfg := LoadConfig{Requests: len(calls), Concurrency: concurrency}
+		arr := &replayArrivals{calls: calls, speed: speed}
+		request := func(i int) methodRunner { return replayRunner(calls[i]) }
+		var samples []openLoopSample
+		elapsed, err := s.streamSchedule(ctx, cfg, request, arr, len(calls), func(sample openLoopSample) {
+			samples = append(samples, sample)
+		})
+		if err != nil {
+			return nil, err
+		}
+		loop := s.summarizeOpenLoop(samples, elapsed)
+		results := make([]rpcclient.Result, len(samples))
+		done := make([]time.Duration, len(samples))
+		for i, sample := range samples {
+			results[i], done[i] = sample.result, sample.offset+sample.corrected
+		}
+		stats.BenchmarkStats = loop.BenchmarkStats
+		s.addBreakdowns(ctx, &stats.BenchmarkStats, results, time.Now().Add(-elapsed), done)
+		stats.Concurrency = concurrency
+		stats.CorrectedLatency = &loop.CorrectedLatency
+		stats.SchedulingLag = &loop.SchedulingLag
+		stats.Interrupted = s.stopped(ctx)
+		return stats, nil
+	}
+
+	var sinks []ResultSink
+	if s.NewSinks != nil {
+		sinks = s.NewSinks(s)
+	}
+	for _, sink := range sinks {
+		sink.Start(len(calls))
+	}
+	defer func() {
+		for _, sink := range sinks {
+			sink.Stop()
+		}
+	}()
+
+	var results []rpcclient.Result
+	var done []time.Duration
+	start := time.Now()
+	for _, call := range calls {
+		if s.stopped(ctx) {
+			break
+		}
+		result, err := replayRunner(call)(ctx, s)
+		if err != nil {
+			break
+		}
+		stampOffset(result, start)
+		results = append(results, *result)
+		done = append(done, time.Since(start))
+		for _, sink := range sinks {
+			sink.Observe(*result)
+		}
+	}
+	stats.BenchmarkStats = *s.calculateStats(results, time.Since(start))
+	s.addBreakdowns(ctx, &stats.BenchmarkStats, results, start, done)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
@@ -0,0 +1,64 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// SavedResults is the stats of one run, read back from a results file.
+type SavedResults struct {
+	Path string
+	// Endpoint is the job's endpoint, known only for batch job reports.
+	Endpoint string
+	Stats    *BenchmarkStats
+}
+
+// LoadResults reads a results file holding benchmark stats, as written
+// by the method benchmark and its open-loop variants, or a batch job
+// report.
+func LoadResults(path string) (*SavedResults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Endpoint      string          `json:"endpoint"`
+		Stats         *BenchmarkStats `json:"stats"`
+		TotalRequests *int            `json:"totalRequests"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse results %s: %w", path, err)
+	}
+	saved := &SavedResults{Path: path, Endpoint: file.Endpoint, Stats: file.Stats}
+	if saved.Stats == nil {
+		if file.TotalRequests == nil {
+			return nil, fmt.Errorf("results %s holds no benchmark stats", path)
+		}
+		saved.Stats = &BenchmarkStats{}
+		if err := json.Unmarshal(data, saved.Stats); err != nil {
+			return nil, fmt.Errorf("parse results %s: %w", path, err)
+		}
+	}
+	return saved, nil
+}
+
+// WriteResultsTable writes a row of headline numbers per run, latencies
+// in milliseconds.
+func WriteResultsTable(w io.Writer, runs []*SavedResults) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"RESULTS", "ENDPOINT", "REQUESTS", "SUCCESS", "AVG", "P50", "P95", "P99", "MAX"}, "\t"))
+	for _, run := range runs {
+		endpoint := run.Endpoint
+		if endpoint == "" {
+			endpoint = "-"
+		}
+		latency := run.Stats.Latency
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f%%\t%.1f\t%d\t%d\t%d\t%d\n", run.Path, endpoint, run.Stats.TotalRequests,
+			run.Stats.SuccessRate, latency.Avg, latency.P50, latency.P95, latency.P99, latency.Max)
+	}
+	return tw.Flush()
+}
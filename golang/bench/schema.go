@@ -0,0 +1,124 @@
+package bench
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// schemaFiles holds a JSON schema of the result of each known method,
+// named after the method, and definitions.json, the types they share.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemaBase is the URL the embedded schemas are compiled under, so they
+// can refer to each other by file name.
+const schemaBase = "file:///schemas/"
+
+var (
+	schemasOnce sync.Once
+	schemas     map[string]*jsonschema.Schema
+	schemasErr  error
+)
+
+// loadSchemas compiles the embedded schemas, once, by method.
+func loadSchemas() (map[string]*jsonschema.Schema, error) {
+	schemasOnce.Do(func() {
+		entries, err := schemaFiles.ReadDir("schemas")
+		if err != nil {
+			schemasErr = err
+			return
+		}
+		compiler := jsonschema.NewCompiler()
+		for _, entry := range entries {
+			data, err := schemaFiles.ReadFile(path.Join("schemas", entry.Name()))
+			if err != nil {
+				schemasErr = err
+				return
+			}
+			if err := compiler.AddResource(schemaBase+entry.Name(), bytes.NewReader(data)); err != nil {
+				schemasErr = fmt.Errorf("schema %s: %w", entry.Name(), err)
+				return
+			}
+		}
+		compiled := make(map[string]*jsonschema.Schema, len(entries))
+		for _, entry := range entries {
+			method := strings.TrimSuffix(entry.Name(), ".json")
+			if method == "definitions" {
+				continue
+			}
+			if compiled[method], err = compiler.Compile(schemaBase + entry.Name()); err != nil {
+				schemasErr = fmt.Errorf("schema %s: %w", entry.Name(), err)
+				return
+			}
+		}
+		schemas = compiled
+	})
+	return schemas, schemasErr
+}
+
+// SchemaMethods returns the methods whose results ValidateSchemas checks,
+// sorted.
+func SchemaMethods() []string {
+	compiled, err := loadSchemas()
+	if err != nil {
+		return nil
+	}
+	methods := make([]string, 0, len(compiled))
+	for method := range compiled {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// checkSchema fails a successful result of method whose result doesn't
+// match the method's schema, marking it as a schema violation. Methods
+// without a schema pass.
+func (s *JSONRPCTester) checkSchema(method string, result *rpcclient.Result) {
+	if result == nil || !result.Success {
+		return
+	}
+	compiled, err := loadSchemas()
+	if err != nil {
+		// The schemas are embedded, so this is a bug, not bad input.
+		panic(fmt.Sprintf("bench: %v", err))
+	}
+	schema, ok := compiled[method]
+	if !ok {
+		return
+	}
+	if err := schema.Validate(result.Result); err != nil {
+		result.Success = false
+		result.SchemaViolation = true
+		result.Error = fmt.Sprintf("schema violation: %s", schemaError(err))
+		s.Log().Debug("schema violation", "method", method, "error", result.Error)
+	}
+}
+
+// schemaError describes the innermost cause of a validation error, which
+// says what was wrong and where, rather than the whole tree.
+func schemaError(err error) string {
+	var validation *jsonschema.ValidationError
+	if !errors.As(err, &validation) {
+		return err.Error()
+	}
+	for len(validation.Causes) > 0 {
+		validation = validation.Causes[0]
+	}
+	location := validation.InstanceLocation
+	if location == "" {
+		location = "/"
+	}
+	return fmt.Sprintf("%s: %s", location, validation.Message)
+}
@@ -0,0 +1,248 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// ScriptMethod is the name under which a script's request function is
+// listed in JSONRPCTester.Methods. Results carry the name of each
+// request the script generated.
+const ScriptMethod = "script"
+
+// Script is a Starlark program that customizes a run. It may define any
+// of three functions:
+//
+//	request(i)                returns the i-th request to send, a dict with
+//	                          "method" and optional "params" and "name"
+//	transform(method, params) returns the params to send in place of params
+//	check(method, result)     asserts on a successful response; returning
+//	                          False or a string, or calling fail(), marks
+//	                          the request failed
+//
+// Besides the Starlark built-ins, scripts can use json.encode and
+// json.decode, and placeholder(name), the value of a template placeholder
+// such as "latestSlot" or "recentSignature".
+type Script struct {
+	Path string
+
+	request   starlark.Callable
+	transform starlark.Callable
+	check     starlark.Callable
+
+	requests atomic.Int64
+	tester   *JSONRPCTester
+}
+
+// scriptContextKey is the thread-local holding the context of the call a
+// script runs for, so placeholder() can fetch what it needs.
+const scriptContextKey = "context"
+
+// LoadScript runs the Starlark file at path and picks up the functions it
+// defines. It fails if it defines none of request, transform and check.
+func LoadScript(path string) (*Script, error) {
+	script := &Script{Path: path}
+	predeclared := starlark.StringDict{
+		"json":        starlarkjson.Module,
+		"placeholder": starlark.NewBuiltin("placeholder", script.placeholder),
+	}
+	globals, err := starlark.ExecFile(script.thread(context.Background()), path, nil, predeclared)
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	for name, fn := range map[string]*starlark.Callable{
+		"request":   &script.request,
+		"transform": &script.transform,
+		"check":     &script.check,
+	} {
+		value, ok := globals[name]
+		if !ok {
+			continue
+		}
+		if *fn, ok = value.(starlark.Callable); !ok {
+			return nil, fmt.Errorf("script %s: %s is a %s, not a function", path, name, value.Type())
+		}
+	}
+	if script.request == nil && script.transform == nil && script.check == nil {
+		return nil, fmt.Errorf("script %s defines none of request, transform and check", path)
+	}
+	return script, nil
+}
+
+// UseScript applies script to s's runs. If it defines request, its
+// requests replace catalog methods and templates.
+func (s *JSONRPCTester) UseScript(script *Script) {
+	script.tester = s
+	s.script = script
+	if s.templates == nil {
+		s.templates = newTemplateSet()
+	}
+	if script.request != nil {
+		s.Methods = []string{ScriptMethod}
+	}
+	if script.transform != nil {
+		s.Params = script.transformParams
+	}
+}
+
+// thread returns a Starlark thread for one call into the script. Threads
+// are cheap and not safe for concurrent use, so each call gets its own.
+func (script *Script) thread(ctx context.Context) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: script.Path,
+		Print: func(_ *starlark.Thread, msg string) {
+			logger := slog.Default()
+			if script.tester != nil {
+				logger = script.tester.Log()
+			}
+			logger.Info("script", "msg", msg)
+		},
+	}
+	thread.SetLocal(scriptContextKey, ctx)
+	return thread
+}
+
+func (script *Script) call(ctx context.Context, fn starlark.Callable, args ...starlark.Value) (starlark.Value, error) {
+	return starlark.Call(script.thread(ctx), fn, args, nil)
+}
+
+// scriptError includes the Starlark backtrace, which says where in the
+// script an error came from.
+func scriptError(err error) error {
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		return fmt.Errorf("%s", evalErr.Backtrace())
+	}
+	return err
+}
+
+func (script *Script) placeholder(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &name); err != nil {
+		return nil, err
+	}
+	if !placeholders[name] {
+		return nil, fmt.Errorf("%s: unknown placeholder %q", fn.Name(), name)
+	}
+	s := script.tester
+	if s == nil {
+		return nil, fmt.Errorf("%s: only available while a run is in progress", fn.Name())
+	}
+	if name == "account" && s.Accounts == nil {
+		return nil, fmt.Errorf("%s: there is no account list (-accounts)", fn.Name())
+	}
+	ctx, _ := thread.Local(scriptContextKey).(context.Context)
+	value, err := s.templates.value(ctx, s, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toStarlark(value)
+}
+
+// run sends the script's next request.
+func (script *Script) run(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+	i := script.requests.Add(1) - 1
+	value, err := script.call(ctx, script.request, starlark.MakeInt64(i))
+	if err != nil {
+		return nil, fmt.Errorf("script request(%d): %w", i, scriptError(err))
+	}
+	var request struct {
+		Name   string      `json:"name"`
+		Method string      `json:"method"`
+		Params interface{} `json:"params"`
+	}
+	if err := fromStarlark(value, &request); err != nil {
+		return nil, fmt.Errorf("script request(%d): %w", i, err)
+	}
+	if request.Method == "" {
+		return nil, fmt.Errorf("script request(%d) returned no method", i)
+	}
+	result, err := s.Call(ctx, request.Method, request.Params)
+	if result != nil && request.Name != "" {
+		result.Method = request.Name
+	}
+	return result, err
+}
+
+// transformParams is the client's Params hook when the script defines
+// transform.
+func (script *Script) transformParams(method string, params interface{}) (interface{}, error) {
+	in, err := toStarlark(params)
+	if err != nil {
+		return nil, err
+	}
+	value, err := script.call(context.Background(), script.transform, starlark.String(method), in)
+	if err != nil {
+		return nil, fmt.Errorf("script transform: %w", scriptError(err))
+	}
+	var out interface{}
+	if err := fromStarlark(value, &out); err != nil {
+		return nil, fmt.Errorf("script transform: %w", err)
+	}
+	return out, nil
+}
+
+// assert runs the script's check on a successful result, failing the
+// result if the check doesn't pass.
+func (script *Script) assert(ctx context.Context, result *rpcclient.Result) {
+	if script.check == nil || result == nil || !result.Success {
+		return
+	}
+	in, err := toStarlark(result.Result)
+	if err == nil {
+		var value starlark.Value
+		if value, err = script.call(ctx, script.check, starlark.String(result.Method), in); err == nil {
+			switch v := value.(type) {
+			case starlark.String:
+				err = fmt.Errorf("%s", string(v))
+			case starlark.Bool:
+				if !v {
+					err = fmt.Errorf("check returned False")
+				}
+			}
+		}
+	}
+	// The backtrace is left out; the message alone reads better as the
+	// request's error.
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		err = errors.New(evalErr.Msg)
+	}
+	if err != nil {
+		result.Success = false
+		result.Error = fmt.Sprintf("assertion failed: %v", err)
+		script.tester.Log().Debug("assertion failed", "method", result.Method, "error", err)
+	}
+}
+
+// toStarlark converts a Go value, as sent or received in JSON, to the
+// equivalent Starlark value.
+func toStarlark(v interface{}) (starlark.Value, error) {
+	if v == nil {
+		return starlark.None, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decode := starlarkjson.Module.Members["decode"]
+	return starlark.Call(&starlark.Thread{}, decode, starlark.Tuple{starlark.String(data)}, nil)
+}
+
+// fromStarlark converts a Starlark value to JSON and decodes it into out.
+func fromStarlark(v starlark.Value, out interface{}) error {
+	encode := starlarkjson.Module.Members["encode"]
+	data, err := starlark.Call(&starlark.Thread{}, encode, starlark.Tuple{v}, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data.(starlark.String)), out)
+}
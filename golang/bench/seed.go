@@ -0,0 +1,51 @@
+package bench
+
+import (
+	"math/rand"
+	"time"
+)
+
+// UseSeed makes every random choice s makes follow seed: the accounts
+// pickers hand out, the values templates fill in, Poisson arrival times
+// and Jito tip accounts. Two runs with the same seed, against any
+// endpoints, then send the same requests in the same order, though
+// concurrent workers may still interleave them differently. A seed of 0
+// leaves the choices random.
+//
+// Call it after the pickers and templates are set.
+func (s *JSONRPCTester) UseSeed(seed int64) {
+	s.seed = seed
+	s.reseed()
+}
+
+// reseed restarts s's pickers and templates from s.seed, so each copy of
+// the tester, such as each endpoint of a comparison, draws the same
+// sequence.
+func (s *JSONRPCTester) reseed() {
+	if s.seed == 0 {
+		return
+	}
+	for _, picker := range []**AccountPicker{&s.Accounts, &s.TokenOwners, &s.TokenMints, &s.EVMLogAddresses, &s.DASAssets} {
+		if *picker != nil {
+			*picker = (*picker).Seeded(s.seed)
+		}
+	}
+	if s.templates != nil {
+		s.templates = s.templates.seeded(s.seed)
+	}
+}
+
+// newRand returns a source for one run's random choices, following s's
+// seed if it has one.
+func (s *JSONRPCTester) newRand() *rand.Rand {
+	return newRand(s.seed)
+}
+
+// newRand returns a source seeded with seed, or with the time if seed is
+// 0.
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,309 @@
+package bench
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+var commitmentLevels = map[string]int{"processed": 0, "confirmed": 1, "finalized": 2}
+
+type TxBenchmarkConfig struct {
+	Submissions      int
+	Keypair          ed25519.PrivateKey
+	Recipient        rpcclient.PublicKey
+	Lamports         uint64
+	Interval         time.Duration
+	Commitment       string
+	ConfirmTimeout   time.Duration
+	PollInterval     time.Duration
+	BlockhashRefresh time.Duration
+	SkipPreflight    bool
+	AllowMainnet     bool
+}
+
+type TxSubmission struct {
+	Signature      string    `json:"signature,omitempty"`
+	SentAt         time.Time `json:"sentAt"`
+	SendLatency    int64     `json:"sendLatency"`
+	SendError      string    `json:"sendError,omitempty"`
+	Landed         bool      `json:"landed"`
+	ConfirmLatency int64     `json:"confirmLatency,omitempty"`
+	Slot           uint64    `json:"slot,omitempty"`
+	TxError        string    `json:"txError,omitempty"`
+
+	done bool
+}
+
+type TxBenchmarkStats struct {
+	Submissions      int            `json:"submissions"`
+	Sent             int            `json:"sent"`
+	SendErrors       int            `json:"sendErrors"`
+	Landed           int            `json:"landed"`
+	FailedOnChain    int            `json:"failedOnChain"`
+	Dropped          int            `json:"dropped"`
+	LandingRate      float64        `json:"landingRate"`
+	Commitment       string         `json:"commitment"`
+	SendLatency      LatencyStats   `json:"sendLatency"`
+	ConfirmLatency   LatencyStats   `json:"confirmLatency"`
+	BlockhashLatency LatencyStats   `json:"blockhashLatency"`
+	Interrupted      bool           `json:"interrupted,omitempty"`
+	Transactions     []TxSubmission `json:"transactions"`
+}
+
+type latestBlockhash struct {
+	Context struct {
+		Slot uint64 `json:"slot"`
+	} `json:"context"`
+	Value struct {
+		Blockhash            string `json:"blockhash"`
+		LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
+	} `json:"value"`
+}
+
+type signatureStatus struct {
+	Slot               uint64      `json:"slot"`
+	Err                interface{} `json:"err"`
+	ConfirmationStatus string      `json:"confirmationStatus"`
+}
+
+func (s *JSONRPCTester) getLatestBlockhash(ctx context.Context, commitment string) (rpcclient.PublicKey, *rpcclient.Result, error) {
+	result, err := s.GetLatestBlockhash(ctx, commitment)
+	if err != nil {
+		return rpcclient.PublicKey{}, result, err
+	}
+	var out latestBlockhash
+	if err := rpcclient.DecodeResult(result, &out); err != nil {
+		return rpcclient.PublicKey{}, result, err
+	}
+	hash, err := rpcclient.ParsePublicKey(out.Value.Blockhash)
+	return hash, result, err
+}
+
+func (s *JSONRPCTester) getSignatureStatuses(ctx context.Context, signatures []string) ([]*signatureStatus, error) {
+	var out struct {
+		Value []*signatureStatus `json:"value"`
+	}
+	params := []interface{}{signatures, map[string]interface{}{"searchTransactionHistory": false}}
+	if _, err := s.CallResult(ctx, "getSignatureStatuses", params, &out); err != nil {
+		return nil, err
+	}
+	return out.Value, nil
+}
+
+// checkNotMainnet refuses to run transaction benchmarks against mainnet
+// unless explicitly allowed, since they spend real lamports.
+func (s *JSONRPCTester) checkNotMainnet(ctx context.Context, allow bool) error {
+	cluster, err := s.DetectCluster(ctx)
+	if err != nil {
+		return err
+	}
+	if cluster == ClusterMainnet && !allow {
+		return fmt.Errorf("endpoint is on mainnet-beta; pass -allow-mainnet to submit real transactions")
+	}
+	return nil
+}
+
+func (s *JSONRPCTester) RunSendTransactionBenchmark(ctx context.Context, cfg TxBenchmarkConfig) (*TxBenchmarkStats, error) {
+	if _, ok := commitmentLevels[cfg.Commitment]; !ok {
+		return nil, fmt.Errorf("unknown commitment %q", cfg.Commitment)
+	}
+	if err := s.checkNotMainnet(ctx, cfg.AllowMainnet); err != nil {
+		return nil, err
+	}
+
+	payer := rpcclient.PublicKeyOf(cfg.Keypair)
+	s.Log().Info("submitting transfers", "count", cfg.Submissions, "from", payer.String(), "to", cfg.Recipient.String())
+
+	var (
+		mu          sync.Mutex
+		submissions []*TxSubmission
+		blockhash   rpcclient.PublicKey
+		fetchedAt   time.Time
+		bhLatencies []int64
+	)
+
+	sendingDone := make(chan struct{})
+	pollerDone := make(chan struct{})
+	go func() {
+		defer close(pollerDone)
+		s.pollConfirmations(ctx, cfg, &mu, &submissions, sendingDone)
+	}()
+
+	err := func() error {
+		for i := 0; i < cfg.Submissions; i++ {
+			if i > 0 && cfg.Interval > 0 {
+				s.sleep(ctx, cfg.Interval)
+			}
+			if s.stopped(ctx) {
+				s.Log().Info("stopped submitting, waiting for pending confirmations", "submitted", i)
+				return nil
+			}
+
+			if fetchedAt.IsZero() || time.Since(fetchedAt) > cfg.BlockhashRefresh {
+				hash, result, err := s.getLatestBlockhash(ctx, cfg.Commitment)
+				if err != nil {
+					return fmt.Errorf("fetch blockhash: %w", err)
+				}
+				blockhash, fetchedAt = hash, time.Now()
+				bhLatencies = append(bhLatencies, result.Latency)
+			}
+
+			// Vary the amount so every transaction has a distinct signature
+			// even when several share a blockhash.
+			ix := rpcclient.TransferInstruction(payer, cfg.Recipient, cfg.Lamports+uint64(i))
+			tx, signature, err := rpcclient.BuildTransaction(cfg.Keypair, []rpcclient.Instruction{ix}, blockhash)
+			if err != nil {
+				return err
+			}
+
+			sub := &TxSubmission{Signature: signature, SentAt: time.Now()}
+			result, err := s.SendTransaction(ctx, tx, cfg.SkipPreflight, cfg.Commitment)
+			if err != nil {
+				return err
+			}
+			sub.SendLatency = result.Latency
+			if !result.Success {
+				sub.SendError = result.Error
+				sub.done = true
+			}
+
+			mu.Lock()
+			submissions = append(submissions, sub)
+			mu.Unlock()
+
+			if (i+1)%10 == 0 {
+				s.Log().Info("transactions submitted", "done", i+1, "total", cfg.Submissions)
+			}
+		}
+		return nil
+	}()
+	close(sendingDone)
+	<-pollerDone
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.summarizeSubmissions(cfg, submissions, bhLatencies)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// pollConfirmations batches getSignatureStatuses over every pending
+// submission until each one reaches the target commitment or times out.
+func (s *JSONRPCTester) pollConfirmations(ctx context.Context, cfg TxBenchmarkConfig, mu *sync.Mutex, submissions *[]*TxSubmission, sendingDone <-chan struct{}) {
+	target := commitmentLevels[cfg.Commitment]
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	finished := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sendingDone:
+			finished = true
+		case <-ticker.C:
+		}
+
+		mu.Lock()
+		var pending []*TxSubmission
+		for _, sub := range *submissions {
+			if !sub.done {
+				pending = append(pending, sub)
+			}
+		}
+		mu.Unlock()
+
+		if finished && len(pending) == 0 {
+			return
+		}
+
+		// getSignatureStatuses accepts at most 256 signatures per call.
+		for start := 0; start < len(pending); start += 256 {
+			end := start + 256
+			if end > len(pending) {
+				end = len(pending)
+			}
+			batch := pending[start:end]
+			signatures := make([]string, len(batch))
+			for i, sub := range batch {
+				signatures[i] = sub.Signature
+			}
+
+			statuses, err := s.getSignatureStatuses(ctx, signatures)
+			observedAt := time.Now()
+			if err != nil {
+				s.Log().Warn("getSignatureStatuses failed", "error", err)
+				continue
+			}
+
+			mu.Lock()
+			for i, sub := range batch {
+				if i < len(statuses) && statuses[i] != nil {
+					status := statuses[i]
+					level, ok := commitmentLevels[status.ConfirmationStatus]
+					if ok && level >= target {
+						sub.Landed = true
+						sub.done = true
+						sub.Slot = status.Slot
+						sub.ConfirmLatency = observedAt.Sub(sub.SentAt).Milliseconds()
+						if status.Err != nil {
+							sub.TxError = fmt.Sprintf("%v", status.Err)
+						}
+						continue
+					}
+				}
+				if observedAt.Sub(sub.SentAt) > cfg.ConfirmTimeout {
+					sub.done = true
+				}
+			}
+			mu.Unlock()
+		}
+
+		if finished {
+			time.Sleep(cfg.PollInterval)
+		}
+	}
+}
+
+func (s *JSONRPCTester) summarizeSubmissions(cfg TxBenchmarkConfig, submissions []*TxSubmission, bhLatencies []int64) *TxBenchmarkStats {
+	stats := &TxBenchmarkStats{
+		Submissions:      len(submissions),
+		Commitment:       cfg.Commitment,
+		BlockhashLatency: s.summarize(bhLatencies),
+	}
+
+	var sendLatencies, confirmLatencies []int64
+	for _, sub := range submissions {
+		stats.Transactions = append(stats.Transactions, *sub)
+		if sub.SendError != "" {
+			stats.SendErrors++
+			continue
+		}
+		stats.Sent++
+		sendLatencies = append(sendLatencies, sub.SendLatency)
+		switch {
+		case sub.Landed && sub.TxError != "":
+			stats.Landed++
+			stats.FailedOnChain++
+			confirmLatencies = append(confirmLatencies, sub.ConfirmLatency)
+		case sub.Landed:
+			stats.Landed++
+			confirmLatencies = append(confirmLatencies, sub.ConfirmLatency)
+		default:
+			stats.Dropped++
+		}
+	}
+
+	if stats.Submissions > 0 {
+		stats.LandingRate = float64(stats.Landed) / float64(stats.Submissions) * 100
+	}
+	stats.SendLatency = s.summarize(sendLatencies)
+	stats.ConfirmLatency = s.summarize(confirmLatencies)
+	return stats
+}
@@ -0,0 +1,101 @@
+package bench
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+type SimulationStats struct {
+	Simulations      int          `json:"simulations"`
+	Succeeded        int          `json:"succeeded"`
+	SimulationErrors int          `json:"simulationErrors"`
+	RPCErrors        int          `json:"rpcErrors"`
+	Latency          LatencyStats `json:"latency"`
+	ComputeUnits     LatencyStats `json:"computeUnits"`
+	Interrupted      bool         `json:"interrupted,omitempty"`
+	ErrorSamples     []string     `json:"errorSamples,omitempty"`
+}
+
+type simulationValue struct {
+	Err           interface{} `json:"err"`
+	UnitsConsumed uint64      `json:"unitsConsumed"`
+}
+
+// LoadSerializedTransaction reads a transaction stored as base64 or base58
+// text, the two encodings simulateTransaction accepts.
+func LoadSerializedTransaction(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(string(data))
+	if tx, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return tx, nil
+	}
+	if tx, err := rpcclient.Base58Decode(text); err == nil {
+		return tx, nil
+	}
+	return nil, fmt.Errorf("%s is neither base64 nor base58", path)
+}
+
+// RunSimulationBenchmark simulates tx repeatedly. A nil tx is replaced by a
+// 1-lamport self-transfer from payer; the blockhash is swapped in by the
+// node, so no signing or blockhash fetch is needed.
+func (s *JSONRPCTester) RunSimulationBenchmark(ctx context.Context, iterations int, tx []byte, payer rpcclient.PublicKey) (*SimulationStats, error) {
+	if tx == nil {
+		tx = rpcclient.BuildUnsignedTransaction(payer, []rpcclient.Instruction{rpcclient.TransferInstruction(payer, payer, 1)}, rpcclient.PublicKey{})
+	}
+	s.Log().Info("running simulateTransaction benchmark", "iterations", iterations)
+
+	stats := &SimulationStats{}
+	var latencies, units []int64
+	for i := 0; i < iterations && !s.stopped(ctx); i++ {
+		stats.Simulations++
+		result, err := s.SimulateTransaction(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		var out struct {
+			Value simulationValue `json:"value"`
+		}
+		if err := rpcclient.DecodeResult(result, &out); err != nil {
+			stats.RPCErrors++
+			stats.addErrorSample(err.Error())
+			continue
+		}
+
+		latencies = append(latencies, result.Latency)
+		units = append(units, int64(out.Value.UnitsConsumed))
+		if out.Value.Err != nil {
+			stats.SimulationErrors++
+			stats.addErrorSample(fmt.Sprintf("%v", out.Value.Err))
+		} else {
+			stats.Succeeded++
+		}
+
+		if (i+1)%10 == 0 {
+			s.Log().Info("simulations completed", "done", i+1, "total", iterations)
+		}
+	}
+
+	stats.Latency = s.summarize(latencies)
+	stats.ComputeUnits = s.summarize(units)
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+func (stats *SimulationStats) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
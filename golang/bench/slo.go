@@ -0,0 +1,24 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLOBreaches returns how a run misses a p99 target and an error
+// percentage ceiling, or nil if it meets both. latency is the summary
+// whose p99 is checked: stats.Latency, or an open-loop run's corrected
+// latency. A zero target or a negative ceiling is not checked.
+func SLOBreaches(stats *BenchmarkStats, latency LatencyStats, targetP99 time.Duration, maxErrorRate float64) []string {
+	var breaches []string
+	if stats.TotalRequests == 0 {
+		return []string{"no requests completed"}
+	}
+	if errorRate := 100 - stats.SuccessRate; maxErrorRate >= 0 && errorRate > maxErrorRate {
+		breaches = append(breaches, fmt.Sprintf("error rate %.2f%% above %.2f%%", errorRate, maxErrorRate))
+	}
+	if targetP99 > 0 && stats.SuccessfulRequests > 0 && latency.P99 > targetP99.Milliseconds() {
+		breaches = append(breaches, fmt.Sprintf("p99 %dms above %dms", latency.P99, targetP99.Milliseconds()))
+	}
+	return breaches
+}
@@ -0,0 +1,229 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// SoakWindow summarizes the requests that completed during one window of
+// a soak run.
+type SoakWindow struct {
+	Window           int          `json:"window"`
+	Start            time.Time    `json:"start"`
+	End              time.Time    `json:"end"`
+	Requests         int64        `json:"requests"`
+	Errors           int64        `json:"errors"`
+	TimedOut         int64        `json:"timedOut"`
+	SuccessRate      float64      `json:"successRate"`
+	AchievedRate     float64      `json:"achievedRate"`
+	Latency          LatencyStats `json:"latency"`
+	CorrectedLatency LatencyStats `json:"correctedLatency"`
+	SchedulingLag    LatencyStats `json:"schedulingLag"`
+	// Partial marks a last window cut short by the end of the run.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// SoakStats is the whole-run summary of a soak. Its percentiles come from
+// streaming histograms, so they are accurate to about 1%.
+type SoakStats struct {
+	Arrival          string       `json:"arrival"`
+	TargetRate       float64      `json:"targetRate"`
+	Concurrency      int          `json:"concurrency"`
+	Window           string       `json:"window"`
+	Windows          int          `json:"windows"`
+	DurationMs       int64        `json:"durationMs"`
+	TotalRequests    int64        `json:"totalRequests"`
+	FailedRequests   int64        `json:"failedRequests"`
+	TimedOutRequests int64        `json:"timedOutRequests"`
+	SuccessRate      float64      `json:"successRate"`
+	TotalBytes       int64        `json:"totalBytes"`
+	WarmupRequests   int          `json:"warmupRequests,omitempty"`
+	Interrupted      bool         `json:"interrupted,omitempty"`
+	Latency          LatencyStats `json:"latency"`
+	CorrectedLatency LatencyStats `json:"correctedLatency"`
+	SchedulingLag    LatencyStats `json:"schedulingLag"`
+	// WorstWindow is the window with the highest corrected p99.
+	WorstWindow *SoakWindow `json:"worstWindow,omitempty"`
+}
+
+// soakCounts is what a soak accumulates per window and for the whole run.
+type soakCounts struct {
+	requests, errors, timedOut, bytes int64
+	latency, corrected, lag           stats.Histogram
+}
+
+func (c *soakCounts) observe(sample openLoopSample) {
+	c.requests++
+	c.bytes += int64(sample.result.Size)
+	if !sample.result.Success {
+		c.errors++
+		if sample.result.TimedOut {
+			c.timedOut++
+		}
+	} else {
+		c.latency.Record(sample.result.Latency)
+		c.corrected.Record(sample.corrected.Milliseconds())
+	}
+	c.lag.Record(sample.lag.Milliseconds())
+}
+
+func (c *soakCounts) merge(other *soakCounts) {
+	c.requests += other.requests
+	c.errors += other.errors
+	c.timedOut += other.timedOut
+	c.bytes += other.bytes
+	c.latency.Merge(&other.latency)
+	c.corrected.Merge(&other.corrected)
+	c.lag.Merge(&other.lag)
+}
+
+func (c *soakCounts) successRate() float64 {
+	if c.requests == 0 {
+		return 0
+	}
+	return float64(c.requests-c.errors) / float64(c.requests) * 100
+}
+
+// RunSoak runs cfg open-loop like RunOpenLoop, but instead of keeping every
+// result it summarizes each window of completed requests as it closes and
+// writes it to w as one line of JSON, so memory stays flat however long
+// the run is. The returned stats cover the whole run.
+func (s *JSONRPCTester) RunSoak(ctx context.Context, cfg LoadConfig, window time.Duration, w io.Writer) (*SoakStats, error) {
+	switch {
+	case cfg.Rate <= 0:
+		return nil, fmt.Errorf("open-loop rate must be positive")
+	case cfg.Duration <= 0:
+		return nil, fmt.Errorf("soak run needs a duration")
+	case cfg.Concurrency <= 0:
+		return nil, fmt.Errorf("open-loop concurrency must be positive")
+	case window <= 0:
+		return nil, fmt.Errorf("soak window must be positive")
+	}
+	arr, err := newArrivals(cfg.Arrival, cfg.Rate, s.newRand())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Arrival == "" {
+		cfg.Arrival = ArrivalConstant
+	}
+	cfg.Requests = 0
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, err
+	}
+	s.Log().Info("running soak", "endpoint", rpcclient.RedactURL(s.Endpoint),
+		"rate", cfg.Rate, "arrival", cfg.Arrival, "duration", cfg.Duration, "window", window,
+		"concurrency", cfg.Concurrency, "methods", methods)
+
+	var (
+		mu       sync.Mutex
+		current  = &soakCounts{}
+		total    soakCounts
+		windows  int
+		worst    *SoakWindow
+		writeErr error
+		start    = time.Now()
+		opened   = start
+	)
+	encoder := json.NewEncoder(w)
+	// flush closes the current window; the caller holds mu.
+	flush := func(end time.Time, partial bool) {
+		counts := current
+		current = &soakCounts{}
+		if counts.requests == 0 {
+			opened = end
+			return
+		}
+		windows++
+		summary := &SoakWindow{
+			Window:           windows,
+			Start:            opened,
+			End:              end,
+			Requests:         counts.requests,
+			Errors:           counts.errors,
+			TimedOut:         counts.timedOut,
+			SuccessRate:      counts.successRate(),
+			AchievedRate:     float64(counts.requests) / end.Sub(opened).Seconds(),
+			Latency:          counts.latency.Summary(s.Percentiles...),
+			CorrectedLatency: counts.corrected.Summary(s.Percentiles...),
+			SchedulingLag:    counts.lag.Summary(s.Percentiles...),
+			Partial:          partial,
+		}
+		opened = end
+		total.merge(counts)
+		if worst == nil || summary.CorrectedLatency.P99 > worst.CorrectedLatency.P99 {
+			worst = summary
+		}
+		if err := encoder.Encode(summary); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		s.Log().Info("soak window", "window", windows, "requests", summary.Requests,
+			"errors", summary.Errors, "p50_ms", summary.CorrectedLatency.P50, "p99_ms", summary.CorrectedLatency.P99)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				mu.Lock()
+				flush(now, false)
+				mu.Unlock()
+			}
+		}
+	}()
+
+	elapsed, err := s.streamSchedule(ctx, cfg, s.rotation(methods), arr, int(cfg.Rate*cfg.Duration.Seconds()), func(sample openLoopSample) {
+		mu.Lock()
+		current.observe(sample)
+		mu.Unlock()
+	})
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	flush(time.Now(), true)
+	if writeErr != nil {
+		return nil, fmt.Errorf("write soak window: %w", writeErr)
+	}
+
+	return &SoakStats{
+		Arrival:          cfg.Arrival,
+		TargetRate:       cfg.Rate,
+		Concurrency:      cfg.Concurrency,
+		Window:           window.String(),
+		Windows:          windows,
+		DurationMs:       elapsed.Milliseconds(),
+		TotalRequests:    total.requests,
+		FailedRequests:   total.errors,
+		TimedOutRequests: total.timedOut,
+		SuccessRate:      total.successRate(),
+		TotalBytes:       total.bytes,
+		WarmupRequests:   warmupRequests,
+		Interrupted:      s.stopped(ctx),
+		Latency:          total.latency.Summary(s.Percentiles...),
+		CorrectedLatency: total.corrected.Summary(s.Percentiles...),
+		SchedulingLag:    total.lag.Summary(s.Percentiles...),
+		WorstWindow:      worst,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// ChainSolana is the chain testers speak unless UseChain selects another.
+const ChainSolana = "solana"
+
+// DefaultMethods are run on Solana endpoints when no methods are selected.
+var DefaultMethods = []string{"getVersion", "getSlot"}
+
+func init() {
+	RegisterChain(ChainSolana, Catalog{
+		Methods:  solanaMethods,
+		Defaults: DefaultMethods,
+		Scenarios: map[string][]string{
+			"accounts": {"getBalance", "getAccountInfo", "getMultipleAccounts"},
+			"tokens":   {"getTokenAccountsByOwner", "getTokenSupply"},
+			"cluster":  {"getEpochInfo", "getBlockHeight", "getBlockProduction", "getVoteAccounts", "getLeaderSchedule"},
+			"supply":   {"getSupply", "getInflationRate", "getLargestAccounts"},
+		},
+		use: func(s *JSONRPCTester) { s.blocks = &blockSource{} },
+	})
+}
+
+func needsAccounts(s *JSONRPCTester) error {
+	if s.Accounts == nil {
+		return fmt.Errorf("needs an account list (-accounts)")
+	}
+	return nil
+}
+
+func needsTokenOwners(s *JSONRPCTester) error {
+	if s.TokenOwners == nil && s.Accounts == nil {
+		return fmt.Errorf("needs token owners (-token-owners or -accounts)")
+	}
+	return nil
+}
+
+func needsTokenMints(s *JSONRPCTester) error {
+	if s.TokenMints == nil {
+		return fmt.Errorf("needs token mints (-token-mints)")
+	}
+	return nil
+}
+
+var solanaMethods = map[string]Method{
+	"getVersion": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetVersion(ctx)
+	}},
+	"getSlot": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetSlot(ctx)
+	}},
+	"getBalance": {Check: needsAccounts, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetBalance(ctx, s.Accounts.Next())
+	}},
+	"getAccountInfo": {Check: needsAccounts, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetAccountInfo(ctx, s.Accounts.Next())
+	}},
+	"getMultipleAccounts": {Check: needsAccounts, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetMultipleAccounts(ctx, s.Accounts.NextN(s.MultipleAccountsBatch))
+	}},
+	"getTokenAccountsByOwner": {Check: needsTokenOwners, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		owners := s.TokenOwners
+		if owners == nil {
+			owners = s.Accounts
+		}
+		return s.GetTokenAccountsByOwner(ctx, owners.Next(), s.TokenProgram)
+	}},
+	"getTokenSupply": {Check: needsTokenMints, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetTokenSupply(ctx, s.TokenMints.Next())
+	}},
+	// Each request fetches a different recent block, newest first.
+	"getBlock": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		block, err := s.blocks.slot(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		result, err := s.GetBlock(ctx, block.slot, blockConfig())
+		if err == nil && result.Success && s.VerifyBlocks {
+			s.blocks.verify(ctx, s, block, result)
+		}
+		return result, err
+	}},
+	"getEpochInfo": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetEpochInfo(ctx)
+	}},
+	"getBlockHeight": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetBlockHeight(ctx)
+	}},
+	"getBlockProduction": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetBlockProduction(ctx)
+	}},
+	"getVoteAccounts": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetVoteAccounts(ctx)
+	}},
+	"getLeaderSchedule": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetLeaderSchedule(ctx)
+	}},
+	"getSupply": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetSupply(ctx)
+	}},
+	"getInflationRate": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetInflationRate(ctx)
+	}},
+	"getInflationReward": {Check: needsAccounts, Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetInflationReward(ctx, s.Accounts.NextN(s.MultipleAccountsBatch))
+	}},
+	"getLargestAccounts": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		return s.GetLargestAccounts(ctx)
+	}},
+	// With an account list, fees are for transactions writing one of the
+	// accounts; without, for all transactions.
+	"getRecentPrioritizationFees": {Run: func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		var accounts []string
+		if s.Accounts != nil {
+			accounts = []string{s.Accounts.Next()}
+		}
+		return s.GetRecentPrioritizationFees(ctx, accounts)
+	}},
+}
@@ -0,0 +1,142 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spike is a burst of Multiplier times the baseline rate lasting Duration.
+type Spike struct {
+	Multiplier float64
+	Duration   time.Duration
+}
+
+// ParseSpike parses a burst such as "5x:30s".
+func ParseSpike(spec string) (Spike, error) {
+	multiplier, duration, ok := strings.Cut(spec, ":")
+	if !ok {
+		return Spike{}, fmt.Errorf("spike %q is not of the form multiplier:duration", spec)
+	}
+	var spike Spike
+	var err error
+	if spike.Multiplier, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(multiplier), "x"), 64); err != nil || spike.Multiplier <= 1 {
+		return Spike{}, fmt.Errorf("spike %q: multiplier must be a number above 1", spec)
+	}
+	if spike.Duration, err = time.ParseDuration(strings.TrimSpace(duration)); err != nil || spike.Duration <= 0 {
+		return Spike{}, fmt.Errorf("spike %q: duration must be a positive duration", spec)
+	}
+	return spike, nil
+}
+
+// PhaseStats are the open-loop stats of one spike phase: baseline, spike
+// or recovery.
+type PhaseStats struct {
+	Phase string `json:"phase"`
+	StepStats
+}
+
+type SpikeStats struct {
+	Arrival      string  `json:"arrival"`
+	Concurrency  int     `json:"concurrency"`
+	BaselineRate float64 `json:"baselineRate"`
+	SpikeRate    float64 `json:"spikeRate"`
+	// RecoveryThreshold is the corrected p99 a recovery window has to get
+	// back under, in milliseconds.
+	RecoveryThreshold int64 `json:"recoveryThreshold"`
+	// Recovered reports whether the endpoint got back to baseline before
+	// the run ended. TimeToRecover is measured from the end of the spike.
+	Recovered     bool         `json:"recovered"`
+	TimeToRecover string       `json:"timeToRecover,omitempty"`
+	Interrupted   bool         `json:"interrupted,omitempty"`
+	Phases        []PhaseStats `json:"phases"`
+}
+
+var spikePhases = []string{"baseline", "spike", "recovery"}
+
+const (
+	// recoveryWindow is the granularity of the time-to-recover search.
+	recoveryWindow = time.Second
+	// recoveryTolerance is how far above the baseline p99 a window may be
+	// and still count as recovered.
+	recoveryTolerance = 1.5
+	// recoveryErrorSlack is how many error percentage points above the
+	// baseline a window may have and still count as recovered.
+	recoveryErrorSlack = 1.0
+)
+
+// Steps returns the spike's baseline, burst and recovery as ramp steps,
+// their rates based on cfg.Rate.
+func (spike Spike) Steps(cfg LoadConfig) []LoadStep {
+	return []LoadStep{
+		{Rate: cfg.Rate, Duration: cfg.Duration},
+		{Rate: cfg.Rate * spike.Multiplier, Duration: spike.Duration},
+		{Rate: cfg.Rate, Duration: cfg.Duration},
+	}
+}
+
+// RunSpike holds cfg.Rate for cfg.Duration, multiplies it by
+// spike.Multiplier for spike.Duration, then drops back to cfg.Rate for
+// another cfg.Duration. Alongside per-phase stats it reports how long
+// after the burst the endpoint's p99 and error rate took to return to
+// their baseline levels.
+func (s *JSONRPCTester) RunSpike(ctx context.Context, spike Spike, cfg LoadConfig) (*SpikeStats, error) {
+	if cfg.Rate <= 0 || cfg.Duration <= 0 {
+		return nil, fmt.Errorf("spike test needs a positive baseline rate and duration")
+	}
+	if cfg.Arrival == "" {
+		cfg.Arrival = ArrivalConstant
+	}
+	steps := spike.Steps(cfg)
+	samples, warmupRequests, err := s.runSteps(ctx, "running spike test", steps, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SpikeStats{
+		Arrival:      cfg.Arrival,
+		Concurrency:  cfg.Concurrency,
+		BaselineRate: steps[0].Rate,
+		SpikeRate:    steps[1].Rate,
+		Interrupted:  s.stopped(ctx),
+	}
+	for i, step := range s.splitSteps(samples, steps, cfg, warmupRequests, stats.Interrupted) {
+		stats.Phases = append(stats.Phases, PhaseStats{Phase: spikePhases[i], StepStats: step})
+	}
+	if len(stats.Phases) < len(steps) {
+		return stats, nil
+	}
+
+	baseline := stats.Phases[0]
+	stats.RecoveryThreshold = int64(float64(baseline.CorrectedLatency.P99) * recoveryTolerance)
+	maxErrorRate := 100 - baseline.SuccessRate + recoveryErrorSlack
+	spikeEnd := steps[0].Duration + steps[1].Duration
+
+	// Bucket the recovery phase by when requests were scheduled; the
+	// endpoint has recovered after the last window that was degraded.
+	windows := int((steps[2].Duration + recoveryWindow - 1) / recoveryWindow)
+	buckets := make([][]openLoopSample, windows)
+	for _, sample := range samples {
+		if i := int((sample.offset - spikeEnd) / recoveryWindow); sample.offset >= spikeEnd && i < windows {
+			buckets[i] = append(buckets[i], sample)
+		}
+	}
+	lastDegraded, lastSeen := -1, -1
+	for i, bucket := range buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+		lastSeen = i
+		window := s.summarizeOpenLoop(bucket, recoveryWindow)
+		if window.CorrectedLatency.P99 > stats.RecoveryThreshold || 100-window.SuccessRate > maxErrorRate {
+			lastDegraded = i
+		}
+	}
+	if lastSeen >= 0 && lastDegraded < lastSeen {
+		stats.Recovered = true
+		stats.TimeToRecover = (time.Duration(lastDegraded+1) * recoveryWindow).String()
+	}
+	return stats, nil
+}
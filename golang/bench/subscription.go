@@ -0,0 +1,339 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Subscription methods RunSubscriptionBenchmark supports.
+const (
+	SubscribeLogs    = "logsSubscribe"
+	SubscribeProgram = "programSubscribe"
+)
+
+// subscriptionQueue is how many notifications may wait to be processed
+// before reading from the connection stalls.
+const subscriptionQueue = 1 << 16
+
+// SubscriptionConfig configures RunSubscriptionBenchmark. Method is
+// logsSubscribe or programSubscribe; Target is the program ID for
+// programSubscribe, and for logsSubscribe the address logs must mention,
+// or empty for all non-vote transactions. Up to Reconnects attempts in all
+// are made to re-establish connections that drop unexpectedly.
+type SubscriptionConfig struct {
+	WSURL      string
+	Method     string
+	Target     string
+	Commitment string
+	Duration   time.Duration
+	Reconnects int
+	// DropEvery, if set, closes each connection after this long, to see
+	// how the endpoint handles clients reconnecting.
+	DropEvery time.Duration
+}
+
+// SubscriptionStats covers a subscription benchmark. PerSecond is the
+// number of notifications that arrived in each whole second of the run, so
+// its minimum is the rate the subscription sustained. Backlog is the
+// number of notifications received but not yet processed, sampled every
+// second; a backlog that keeps growing means the client can't keep up.
+//
+// Slots counts the distinct slots notifications were for. SlotGaps counts
+// jumps of more than one slot between consecutive notifications and
+// MissedSlots the slots jumped over; skipped slots and quiet stretches of
+// the program count too, so they are meaningful for busy programs and
+// when compared across providers. OutOfOrder counts notifications for an
+// older slot than the one before.
+//
+// Reconnects counts connections re-established after a drop, whether
+// deliberate, counted in Drops, or not. ReconnectTime runs from the drop
+// to the subscription being confirmed again, failed attempts included;
+// DialTime and ResubscribeTime split the successful attempt into its two
+// steps. MissedWindow runs from the last notification before a drop to
+// the first after it, and ReconnectMissedSlots counts the slots jumped
+// over across them.
+type SubscriptionStats struct {
+	WSEndpoint           string       `json:"wsEndpoint"`
+	Method               string       `json:"method"`
+	Target               string       `json:"target,omitempty"`
+	Commitment           string       `json:"commitment"`
+	Duration             string       `json:"duration"`
+	SubscribeTime        int64        `json:"subscribeTime"`
+	Notifications        int          `json:"notifications"`
+	Bytes                int64        `json:"bytes"`
+	Throughput           float64      `json:"throughput"`
+	PerSecond            LatencyStats `json:"perSecond"`
+	MaxBacklog           int          `json:"maxBacklog"`
+	Backlog              LatencyStats `json:"backlog"`
+	Slots                int          `json:"slots"`
+	SlotGaps             int          `json:"slotGaps"`
+	MissedSlots          int          `json:"missedSlots"`
+	OutOfOrder           int          `json:"outOfOrder"`
+	Reconnects           int          `json:"reconnects"`
+	ReconnectTime        LatencyStats `json:"reconnectTime"`
+	ReconnectMissedSlots int          `json:"reconnectMissedSlots"`
+	Drops                int          `json:"drops,omitempty"`
+	DialTime             LatencyStats `json:"dialTime"`
+	ResubscribeTime      LatencyStats `json:"resubscribeTime"`
+	MissedWindow         LatencyStats `json:"missedWindow"`
+	Error                string       `json:"error,omitempty"`
+	Interrupted          bool         `json:"interrupted,omitempty"`
+	ErrorSamples         []string     `json:"errorSamples,omitempty"`
+}
+
+// subscriptionEvent is a notification queued for processing. resumed
+// marks the first one after a reconnect.
+type subscriptionEvent struct {
+	notification rpcclient.Notification
+	resumed      bool
+}
+
+// subscribeParams returns cfg's subscription parameters.
+func (cfg SubscriptionConfig) subscribeParams() ([]interface{}, error) {
+	options := map[string]interface{}{"commitment": cfg.Commitment}
+	switch cfg.Method {
+	case SubscribeLogs:
+		var filter interface{} = "all"
+		if cfg.Target != "" {
+			filter = map[string]interface{}{"mentions": []string{cfg.Target}}
+		}
+		return []interface{}{filter, options}, nil
+	case SubscribeProgram:
+		if cfg.Target == "" {
+			return nil, fmt.Errorf("%s needs a program ID", SubscribeProgram)
+		}
+		options["encoding"] = "base64"
+		return []interface{}{cfg.Target, options}, nil
+	}
+	return nil, fmt.Errorf("cannot benchmark %q (want %s or %s)", cfg.Method, SubscribeLogs, SubscribeProgram)
+}
+
+// RunSubscriptionBenchmark holds a logsSubscribe or programSubscribe
+// subscription open for cfg.Duration and measures how much it delivers,
+// whether the client keeps up, and whether slots go missing. Point it at
+// a busy program: gaps only stand out against steady activity.
+func (s *JSONRPCTester) RunSubscriptionBenchmark(ctx context.Context, cfg SubscriptionConfig) (*SubscriptionStats, error) {
+	params, err := cfg.subscribeParams()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Target != "" {
+		if _, err := rpcclient.ParsePublicKey(cfg.Target); err != nil {
+			return nil, err
+		}
+	}
+	wsURL := cfg.WSURL
+	if wsURL == "" {
+		if wsURL, err = rpcclient.WSURL(s.Client.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+	stats := &SubscriptionStats{
+		WSEndpoint: rpcclient.RedactURL(wsURL),
+		Method:     cfg.Method,
+		Target:     cfg.Target,
+		Commitment: cfg.Commitment,
+		Duration:   cfg.Duration.String(),
+	}
+	s.Log().Info("benchmarking subscription", "ws_endpoint", stats.WSEndpoint, "method", cfg.Method,
+		"target", cfg.Target, "commitment", cfg.Commitment, "duration", cfg.Duration)
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.Stop:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	// connect dials and subscribes, timing each step.
+	connect := func() (*rpcclient.WSConn, time.Duration, time.Duration, error) {
+		start := time.Now()
+		conn, err := s.Client.DialWS(runCtx, wsURL)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		dialed := time.Now()
+		if _, err := conn.Subscribe(runCtx, cfg.Method, params); err != nil {
+			conn.Close()
+			return nil, 0, 0, err
+		}
+		return conn, dialed.Sub(start), time.Since(dialed), nil
+	}
+	conn, dial, subscribe, err := connect()
+	if err != nil {
+		return nil, err
+	}
+	stats.SubscribeTime = (dial + subscribe).Milliseconds()
+	s.Log().Info("subscribed", "method", cfg.Method, "subscribe_ms", stats.SubscribeTime)
+	start := time.Now()
+
+	var (
+		mu                        sync.Mutex
+		reconnectTimes, dialTimes []int64
+		resubscribeTimes, windows []int64
+		arrived                   atomic.Int64
+		events                    = make(chan subscriptionEvent, subscriptionQueue)
+	)
+	go func() {
+		defer close(events)
+		resumed, attempts := false, 0
+		var lastAt time.Time
+		for {
+			// With cfg.DropEvery, each connection lives only that long.
+			connCtx, connCancel := runCtx, context.CancelFunc(func() {})
+			if cfg.DropEvery > 0 {
+				connCtx, connCancel = context.WithTimeout(runCtx, cfg.DropEvery)
+			}
+			var err error
+			for {
+				var n rpcclient.Notification
+				if n, err = conn.Next(connCtx); err != nil {
+					break
+				}
+				arrived.Add(1)
+				if resumed && !lastAt.IsZero() {
+					mu.Lock()
+					windows = append(windows, n.ReceivedAt.Sub(lastAt).Milliseconds())
+					mu.Unlock()
+				}
+				lastAt = n.ReceivedAt
+				events <- subscriptionEvent{n, resumed}
+				resumed = false
+			}
+			connCancel()
+			conn.Close()
+			if runCtx.Err() != nil {
+				return
+			}
+			// Deliberate drops don't use up reconnect attempts.
+			dropped, free := err, connCtx.Err() != nil
+			if free {
+				mu.Lock()
+				stats.Drops++
+				mu.Unlock()
+				s.Log().Debug("dropping connection", "after", cfg.DropEvery)
+			} else {
+				s.Log().Warn("subscription dropped", "error", err)
+			}
+			droppedAt := time.Now()
+			for {
+				if !free {
+					if attempts >= cfg.Reconnects {
+						mu.Lock()
+						stats.Error = fmt.Sprintf("subscription dropped: %v", dropped)
+						mu.Unlock()
+						return
+					}
+					attempts++
+				}
+				free = false
+				if conn, dial, subscribe, err = connect(); err == nil {
+					took := time.Since(droppedAt)
+					mu.Lock()
+					stats.Reconnects++
+					reconnectTimes = append(reconnectTimes, took.Milliseconds())
+					dialTimes = append(dialTimes, dial.Milliseconds())
+					resubscribeTimes = append(resubscribeTimes, subscribe.Milliseconds())
+					mu.Unlock()
+					s.Log().Info("resubscribed", "reconnect_ms", took.Milliseconds())
+					resumed = true
+					break
+				}
+				if runCtx.Err() != nil {
+					return
+				}
+				s.Log().Warn("reconnect failed", "error", err)
+				s.sleep(runCtx, time.Second)
+			}
+		}
+	}()
+
+	// Every second, note how many notifications arrived and how many are
+	// still waiting.
+	var perSecond, backlog []int64
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				perSecond = append(perSecond, arrived.Swap(0))
+				backlog = append(backlog, int64(len(events)))
+			}
+		}
+	}()
+
+	slots := map[uint64]bool{}
+	var last uint64
+	for event := range events {
+		n := event.notification
+		stats.Notifications++
+		stats.Bytes += int64(n.Size)
+		var update struct {
+			Context struct {
+				Slot uint64 `json:"slot"`
+			} `json:"context"`
+		}
+		if err := json.Unmarshal(n.Result, &update); err != nil || update.Context.Slot == 0 {
+			stats.addErrorSample(fmt.Sprintf("notification without a slot: %.200s", n.Result))
+			continue
+		}
+		slot := update.Context.Slot
+		slots[slot] = true
+		switch {
+		case last == 0:
+		case slot < last:
+			stats.OutOfOrder++
+			continue
+		case slot > last+1 && event.resumed:
+			stats.ReconnectMissedSlots += int(slot - last - 1)
+		case slot > last+1:
+			stats.SlotGaps++
+			stats.MissedSlots += int(slot - last - 1)
+		}
+		last = slot
+	}
+	// The subscription may have ended before the run, having run out of
+	// reconnects.
+	cancel()
+	<-sampled
+
+	elapsed := time.Since(start)
+	stats.Throughput = float64(stats.Notifications) / elapsed.Seconds()
+	stats.Slots = len(slots)
+	stats.PerSecond = s.summarize(perSecond)
+	stats.Backlog = s.summarize(backlog)
+	stats.MaxBacklog = int(stats.Backlog.Max)
+	mu.Lock()
+	stats.ReconnectTime = s.summarize(reconnectTimes)
+	stats.DialTime = s.summarize(dialTimes)
+	stats.ResubscribeTime = s.summarize(resubscribeTimes)
+	stats.MissedWindow = s.summarize(windows)
+	mu.Unlock()
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+func (stats *SubscriptionStats) addErrorSample(msg string) {
+	for _, existing := range stats.ErrorSamples {
+		if existing == msg {
+			return
+		}
+	}
+	if len(stats.ErrorSamples) < 5 {
+		stats.ErrorSamples = append(stats.ErrorSamples, msg)
+	}
+}
@@ -0,0 +1,278 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Support statuses of a method in a SupportMatrix. A method is supported
+// if it answered, even with an error about the request itself, since that
+// shows it ran; error covers failures that say nothing either way, such
+// as timeouts and internal errors.
+const (
+	SupportSupported   = "supported"
+	SupportDisabled    = "disabled"
+	SupportRateLimited = "rate-limited"
+	SupportError       = "error"
+)
+
+// Addresses the support probes ask about; any cluster has them.
+const (
+	sysvarClock    = "SysvarC1ock11111111111111111111111111111111"
+	configProgram  = "Config1111111111111111111111111111111111111"
+	tokenProgram   = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	wrappedSOLMint = "So11111111111111111111111111111111111111112"
+	// zeroSignature and zeroBlockhash are all zero bytes; no transaction
+	// or block has them.
+	zeroSignature = "1111111111111111111111111111111111111111111111111111111111111111"
+	zeroBlockhash = "11111111111111111111111111111111"
+	// invalidMessage is a base64 message too short to deserialize.
+	invalidMessage = "AQABAgMEBQ=="
+)
+
+// supportProbe is one method RunSupportMatrix tries, with params built
+// from a recent slot that an endpoint serving the method should accept.
+// Expensive methods are asked narrow questions, so probing one doesn't
+// cost the endpoint, or the run, much.
+type supportProbe struct {
+	method string
+	params func(slot uint64) interface{}
+}
+
+func noParams(uint64) interface{} { return nil }
+
+// supportProbes are the Solana HTTP methods, except requestAirdrop, which
+// would spend faucet funds on devnet and testnet.
+var supportProbes = []supportProbe{
+	{"getAccountInfo", func(uint64) interface{} {
+		return []interface{}{sysvarClock, map[string]interface{}{"encoding": "base64"}}
+	}},
+	{"getBalance", func(uint64) interface{} { return []interface{}{sysvarClock} }},
+	{"getBlock", func(slot uint64) interface{} {
+		return []interface{}{slot, map[string]interface{}{
+			"transactionDetails": "none", "rewards": false, "maxSupportedTransactionVersion": 0,
+		}}
+	}},
+	{"getBlockCommitment", func(slot uint64) interface{} { return []interface{}{slot} }},
+	{"getBlockHeight", noParams},
+	{"getBlockProduction", noParams},
+	{"getBlockTime", func(slot uint64) interface{} { return []interface{}{slot} }},
+	{"getBlocks", func(slot uint64) interface{} { return []interface{}{slot - 10, slot} }},
+	{"getBlocksWithLimit", func(slot uint64) interface{} { return []interface{}{slot - 10, 10} }},
+	{"getClusterNodes", noParams},
+	{"getEpochInfo", noParams},
+	{"getEpochSchedule", noParams},
+	{"getFeeForMessage", func(uint64) interface{} { return []interface{}{invalidMessage} }},
+	{"getFirstAvailableBlock", noParams},
+	{"getGenesisHash", noParams},
+	{"getHealth", noParams},
+	{"getHighestSnapshotSlot", noParams},
+	{"getIdentity", noParams},
+	{"getInflationGovernor", noParams},
+	{"getInflationRate", noParams},
+	{"getInflationReward", func(uint64) interface{} { return []interface{}{[]string{sysvarClock}} }},
+	{"getLargestAccounts", noParams},
+	{"getLatestBlockhash", noParams},
+	{"getLeaderSchedule", func(uint64) interface{} {
+		return []interface{}{nil, map[string]interface{}{"identity": sysvarClock}}
+	}},
+	{"getMaxRetransmitSlot", noParams},
+	{"getMaxShredInsertSlot", noParams},
+	{"getMinimumBalanceForRentExemption", func(uint64) interface{} { return []interface{}{0} }},
+	{"getMultipleAccounts", func(uint64) interface{} {
+		return []interface{}{[]string{sysvarClock}, map[string]interface{}{"encoding": "base64"}}
+	}},
+	{"getProgramAccounts", func(uint64) interface{} {
+		return []interface{}{configProgram, map[string]interface{}{
+			"encoding": "base64", "dataSlice": map[string]interface{}{"offset": 0, "length": 0},
+		}}
+	}},
+	{"getRecentPerformanceSamples", func(uint64) interface{} { return []interface{}{1} }},
+	{"getRecentPrioritizationFees", noParams},
+	{"getSignatureStatuses", func(uint64) interface{} { return []interface{}{[]string{zeroSignature}} }},
+	{"getSignaturesForAddress", func(uint64) interface{} {
+		return []interface{}{sysvarClock, map[string]interface{}{"limit": 1}}
+	}},
+	{"getSlot", noParams},
+	{"getSlotLeader", noParams},
+	{"getSlotLeaders", func(slot uint64) interface{} { return []interface{}{slot, 1} }},
+	{"getStakeMinimumDelegation", noParams},
+	{"getSupply", func(uint64) interface{} {
+		return []interface{}{map[string]interface{}{"excludeNonCirculatingAccountsList": true}}
+	}},
+	{"getTokenAccountBalance", func(uint64) interface{} { return []interface{}{wrappedSOLMint} }},
+	{"getTokenAccountsByDelegate", func(uint64) interface{} {
+		return []interface{}{sysvarClock, map[string]interface{}{"programId": tokenProgram}, map[string]interface{}{"encoding": "base64"}}
+	}},
+	{"getTokenAccountsByOwner", func(uint64) interface{} {
+		return []interface{}{sysvarClock, map[string]interface{}{"programId": tokenProgram}, map[string]interface{}{"encoding": "base64"}}
+	}},
+	{"getTokenLargestAccounts", func(uint64) interface{} { return []interface{}{wrappedSOLMint} }},
+	{"getTokenSupply", func(uint64) interface{} { return []interface{}{wrappedSOLMint} }},
+	{"getTransaction", func(uint64) interface{} {
+		return []interface{}{zeroSignature, map[string]interface{}{"maxSupportedTransactionVersion": 0}}
+	}},
+	{"getTransactionCount", noParams},
+	{"getVersion", noParams},
+	{"getVoteAccounts", func(uint64) interface{} {
+		return []interface{}{map[string]interface{}{"votePubkey": sysvarClock}}
+	}},
+	{"isBlockhashValid", func(uint64) interface{} { return []interface{}{zeroBlockhash} }},
+	{"minimumLedgerSlot", noParams},
+	// Both are sent a transaction that can't be deserialized, so nothing
+	// is ever submitted.
+	{"sendTransaction", func(uint64) interface{} {
+		return []interface{}{invalidMessage, map[string]interface{}{"encoding": "base64"}}
+	}},
+	{"simulateTransaction", func(uint64) interface{} {
+		return []interface{}{invalidMessage, map[string]interface{}{"encoding": "base64"}}
+	}},
+}
+
+// SupportMethods returns the methods RunSupportMatrix probes, sorted.
+func SupportMethods() []string {
+	methods := make([]string, len(supportProbes))
+	for i, probe := range supportProbes {
+		methods[i] = probe.method
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// SupportCell is one method's outcome on one endpoint.
+type SupportCell struct {
+	Status  string `json:"status"`
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// EndpointSupport is every method's outcome on one endpoint, and how many
+// methods ended with each status.
+type EndpointSupport struct {
+	Endpoint string                 `json:"endpoint"`
+	Counts   map[string]int         `json:"counts"`
+	Methods  map[string]SupportCell `json:"methods"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// SupportMatrix is the outcome of every probed method on every endpoint,
+// in the order given.
+type SupportMatrix struct {
+	Methods     []string          `json:"methods"`
+	Endpoints   []EndpointSupport `json:"endpoints"`
+	Interrupted bool              `json:"interrupted,omitempty"`
+}
+
+// RunSupportMatrix sends every known Solana method once to each endpoint,
+// waiting interval between calls, and records whether the endpoint
+// serves it. Providers quietly switch off expensive methods such as
+// getProgramAccounts or getLargestAccounts; this shows which. Calls that
+// were rate limited are best tried again with a longer interval.
+func (s *JSONRPCTester) RunSupportMatrix(ctx context.Context, endpoints []string, interval time.Duration) (*SupportMatrix, error) {
+	matrix := &SupportMatrix{Methods: SupportMethods()}
+	for _, endpoint := range endpoints {
+		if s.stopped(ctx) {
+			break
+		}
+		tester := s.withEndpoint(endpoint)
+		support := EndpointSupport{
+			Endpoint: rpcclient.RedactURL(endpoint),
+			Counts:   map[string]int{},
+			Methods:  map[string]SupportCell{},
+		}
+		tester.Log().Info("probing method support", "endpoint", support.Endpoint, "methods", len(supportProbes))
+
+		// Slot-based probes ask about a slot far enough back to be
+		// confirmed everywhere, but recent enough that any node has it.
+		var slot uint64
+		if _, err := tester.CallResult(ctx, "getSlot", nil, &slot); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			support.Error = fmt.Sprintf("fetch slot: %v", err)
+		}
+		if slot > 100 {
+			slot -= 100
+		}
+
+		for i, probe := range supportProbes {
+			if i > 0 {
+				s.sleep(ctx, interval)
+			}
+			if s.stopped(ctx) {
+				break
+			}
+			result, err := tester.Call(ctx, probe.method, probe.params(slot))
+			if err != nil {
+				break
+			}
+			cell := SupportCell{Status: supportStatus(result), Latency: result.Latency}
+			if !result.Success {
+				cell.Error = result.Error
+			}
+			support.Methods[probe.method] = cell
+			support.Counts[cell.Status]++
+		}
+		tester.Log().Info("method support", "endpoint", support.Endpoint, "supported", support.Counts[SupportSupported],
+			"disabled", support.Counts[SupportDisabled], "rate_limited", support.Counts[SupportRateLimited],
+			"errors", support.Counts[SupportError])
+		matrix.Endpoints = append(matrix.Endpoints, support)
+	}
+	matrix.Interrupted = s.stopped(ctx)
+	return matrix, nil
+}
+
+// supportStatus classifies a probe's result. Errors in the JSON-RPC
+// server range, -32000 to -32099, and invalid params are the method
+// rejecting the request, so it is served.
+func supportStatus(result *rpcclient.Result) string {
+	switch {
+	case result.Success:
+		return SupportSupported
+	case result.Unsupported:
+		return SupportDisabled
+	case result.RateLimited:
+		return SupportRateLimited
+	case result.ErrorCode == -32602, result.ErrorCode <= -32000 && result.ErrorCode >= -32099:
+		return SupportSupported
+	}
+	return SupportError
+}
+
+// WriteTable writes the matrix with a row per method and a column per
+// endpoint, numbered in the order given.
+func (matrix *SupportMatrix) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"METHOD"}
+	for i := range matrix.Endpoints {
+		header = append(header, fmt.Sprintf("#%d", i+1))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, method := range matrix.Methods {
+		row := []string{method}
+		for _, endpoint := range matrix.Endpoints {
+			status := "-"
+			if cell, ok := endpoint.Methods[method]; ok {
+				status = cell.Status
+			}
+			row = append(row, status)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for i, endpoint := range matrix.Endpoints {
+		if _, err := fmt.Fprintf(w, "#%d %s\n", i+1, endpoint.Endpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
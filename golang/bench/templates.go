@@ -0,0 +1,406 @@
+package bench
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// RequestTemplate is a request whose params may contain placeholders,
+// filled in afresh for every request so the endpoint can't answer them all
+// from cache. A string that is exactly one placeholder is replaced by its
+// value, a number for slots; placeholders within longer strings are
+// substituted as text.
+//
+//	{{latestSlot}}       the current slot, refreshed every few seconds
+//	{{recentSlot}}       a random slot among the last 100
+//	{{randomPubkey}}     a freshly generated public key
+//	{{recentSignature}}  a transaction signature from a recent block
+//	{{account}}          the next account of the -accounts list
+type RequestTemplate struct {
+	// Name identifies the template in results; it defaults to Method and
+	// must be unique.
+	Name   string          `json:"name,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+
+	params   interface{}
+	accounts bool
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+var placeholders = map[string]bool{
+	"latestSlot":      true,
+	"recentSlot":      true,
+	"randomPubkey":    true,
+	"recentSignature": true,
+	"account":         true,
+}
+
+// templateRefresh is how long a prefetched slot and its signatures are
+// reused before they are fetched again.
+const templateRefresh = 5 * time.Second
+
+// recentSlotRange is how far back {{recentSlot}} reaches.
+const recentSlotRange = 100
+
+// LoadTemplates reads a JSON array of request templates.
+func LoadTemplates(path string) ([]RequestTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var templates []RequestTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("parse templates %s: %w", path, err)
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("templates %s has no requests", path)
+	}
+	seen := make(map[string]bool)
+	for i := range templates {
+		template := &templates[i]
+		if template.Method == "" {
+			return nil, fmt.Errorf("template %d has no method", i)
+		}
+		if template.Name == "" {
+			template.Name = template.Method
+		}
+		if seen[template.Name] {
+			return nil, fmt.Errorf("duplicate template %q; give each a distinct name", template.Name)
+		}
+		seen[template.Name] = true
+		if len(template.Params) > 0 {
+			if err := json.Unmarshal(template.Params, &template.params); err != nil {
+				return nil, fmt.Errorf("template %q: params: %w", template.Name, err)
+			}
+		}
+		template.params = withTransactionVersion(template.Method, template.params)
+		for _, match := range placeholderPattern.FindAllStringSubmatch(string(template.Params), -1) {
+			if !placeholders[match[1]] {
+				return nil, fmt.Errorf("template %q: unknown placeholder {{%s}}", template.Name, match[1])
+			}
+			template.accounts = template.accounts || match[1] == "account"
+		}
+	}
+	return templates, nil
+}
+
+// withTransactionVersion adds maxSupportedTransactionVersion 0 to the
+// configuration of getBlock and getTransaction params that lack it.
+// Without it, any block or transaction with a version 0 transaction is
+// refused, and most recent ones have some. A template that sets the field,
+// even to null, is left as it is.
+func withTransactionVersion(method string, params interface{}) interface{} {
+	if method != "getBlock" && method != "getTransaction" {
+		return params
+	}
+	list, ok := params.([]interface{})
+	if !ok || len(list) == 0 {
+		return params
+	}
+	if len(list) == 1 {
+		return append(list, map[string]interface{}{"maxSupportedTransactionVersion": 0})
+	}
+	if config, ok := list[1].(map[string]interface{}); ok {
+		if _, set := config["maxSupportedTransactionVersion"]; !set {
+			config["maxSupportedTransactionVersion"] = 0
+		}
+	}
+	return params
+}
+
+// templateSet holds a tester's templates and the prefetched values their
+// placeholders draw on, shared by every copy of the tester.
+type templateSet struct {
+	byName map[string]*RequestTemplate
+
+	mu  sync.Mutex
+	rng *mathrand.Rand
+	// slot was fetched at fetchedAt, and signatures, from a block before
+	// it, at signaturesAt.
+	slot                    uint64
+	fetchedAt, signaturesAt time.Time
+	signatures              []string
+	// fetching is closed when the prefetch in flight ends, or nil if none
+	// is.
+	fetching chan struct{}
+}
+
+// UseTemplates makes templates the requests of s's runs, in place of
+// catalog methods.
+func (s *JSONRPCTester) UseTemplates(templates []RequestTemplate) {
+	set := newTemplateSet()
+	s.Methods = nil
+	for i := range templates {
+		set.byName[templates[i].Name] = &templates[i]
+		s.Methods = append(s.Methods, templates[i].Name)
+	}
+	s.templates = set
+}
+
+func newTemplateSet() *templateSet {
+	return &templateSet{
+		byName: make(map[string]*RequestTemplate),
+		rng:    newRand(0),
+	}
+}
+
+// seeded returns a set of the same templates whose random values follow
+// seed, with nothing prefetched.
+func (set *templateSet) seeded(seed int64) *templateSet {
+	return &templateSet{byName: set.byName, rng: newRand(seed)}
+}
+
+func (s *JSONRPCTester) templateNamed(name string) *RequestTemplate {
+	if s.templates == nil {
+		return nil
+	}
+	return s.templates.byName[name]
+}
+
+// runner returns how to send the named catalog method, template or
+// script request, checked against the method's schema with
+// ValidateSchemas and by the script if it asserts on responses.
+func (s *JSONRPCTester) runner(name string) methodRunner {
+	run, method := s.catalog().methods[name].run, name
+	if template := s.templateNamed(name); template != nil {
+		run, method = template.run, template.Method
+	}
+	if s.script != nil && name == ScriptMethod && s.script.request != nil {
+		run = s.script.run
+	}
+	if !s.ValidateSchemas && s.script == nil {
+		return run
+	}
+	return func(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+		result, err := run(ctx, s)
+		if err != nil {
+			return result, err
+		}
+		if s.ValidateSchemas {
+			s.checkSchema(method, result)
+		}
+		if s.script != nil {
+			s.script.assert(ctx, result)
+		}
+		return result, err
+	}
+}
+
+// run sends the template with its placeholders filled in. A request whose
+// placeholders couldn't be, because prefetching their values failed,
+// counts as a failed request rather than ending the run.
+func (t *RequestTemplate) run(ctx context.Context, s *JSONRPCTester) (*rpcclient.Result, error) {
+	startedAt := time.Now()
+	params, err := s.templates.fill(ctx, s, t.params, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return &rpcclient.Result{
+			Method:    t.Name,
+			Latency:   time.Since(startedAt).Milliseconds(),
+			Error:     fmt.Sprintf("template %q: %v", t.Name, err),
+			StartedAt: startedAt,
+		}, nil
+	}
+	result, err := s.Call(ctx, t.Method, params)
+	if result != nil && t.Name != t.Method {
+		result.Method = t.Name
+	}
+	return result, err
+}
+
+// fill returns a copy of params with its placeholders resolved. vars holds
+// extra named values, such as those extracted by earlier steps of a chain,
+// which take precedence over the built-in placeholders.
+func (set *templateSet) fill(ctx context.Context, s *JSONRPCTester, params interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := params.(type) {
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(v); match != nil && match[0] == v {
+			return set.value(ctx, s, match[1], vars)
+		}
+		var err error
+		filled := placeholderPattern.ReplaceAllStringFunc(v, func(placeholder string) string {
+			value, valueErr := set.value(ctx, s, placeholderPattern.FindStringSubmatch(placeholder)[1], vars)
+			if valueErr != nil {
+				err = valueErr
+			}
+			return placeholderText(value)
+		})
+		return filled, err
+	case []interface{}:
+		filled := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if filled[i], err = set.fill(ctx, s, item, vars); err != nil {
+				return nil, err
+			}
+		}
+		return filled, nil
+	case map[string]interface{}:
+		filled := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			var err error
+			if filled[key], err = set.fill(ctx, s, item, vars); err != nil {
+				return nil, err
+			}
+		}
+		return filled, nil
+	}
+	return params, nil
+}
+
+// placeholderText formats a placeholder's value for substitution within a
+// longer string. Numbers decoded from JSON are float64, which fmt would
+// print in exponent form past a million.
+func placeholderText(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func (set *templateSet) value(ctx context.Context, s *JSONRPCTester, name string, vars map[string]interface{}) (interface{}, error) {
+	if value, ok := vars[name]; ok {
+		return value, nil
+	}
+	switch name {
+	case "randomPubkey":
+		var key rpcclient.PublicKey
+		if s.seed == 0 {
+			rand.Read(key[:])
+		} else {
+			set.mu.Lock()
+			set.rng.Read(key[:])
+			set.mu.Unlock()
+		}
+		return key.String(), nil
+	case "account":
+		return s.Accounts.Next(), nil
+	}
+
+	if !placeholders[name] {
+		return nil, fmt.Errorf("unknown placeholder {{%s}}", name)
+	}
+	slot, signatures, err := set.prefetched(ctx, s, name == "recentSignature")
+	if err != nil {
+		return nil, err
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	switch name {
+	case "recentSlot":
+		// A local validator's chain may be shorter than the range.
+		back := uint64(set.rng.Intn(recentSlotRange))
+		if back > slot {
+			back = slot
+		}
+		return slot - back, nil
+	case "recentSignature":
+		return signatures[set.rng.Intn(len(signatures))], nil
+	}
+	return slot, nil
+}
+
+// prefetched returns the current slot, and signatures too if wanted,
+// fetching them when they are older than templateRefresh. One request
+// fetches them at a time, without holding set.mu; the others use what was
+// fetched before meanwhile, and wait only if there is nothing yet.
+func (set *templateSet) prefetched(ctx context.Context, s *JSONRPCTester, wantSignatures bool) (uint64, []string, error) {
+	for {
+		set.mu.Lock()
+		slot, signatures := set.slot, set.signatures
+		stale := time.Since(set.fetchedAt) > templateRefresh
+		staleSignatures := wantSignatures && time.Since(set.signaturesAt) > templateRefresh
+		usable := !set.fetchedAt.IsZero() && (!wantSignatures || len(signatures) > 0)
+		if !stale && !staleSignatures {
+			set.mu.Unlock()
+			return slot, signatures, nil
+		}
+		if fetching := set.fetching; fetching != nil {
+			set.mu.Unlock()
+			if usable {
+				return slot, signatures, nil
+			}
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return 0, nil, ctx.Err()
+			}
+		}
+		done := make(chan struct{})
+		set.fetching = done
+		set.mu.Unlock()
+
+		slot, signatures, err := set.fetch(ctx, s, slot, stale, signatures, staleSignatures)
+		set.mu.Lock()
+		set.fetching = nil
+		close(done)
+		if err == nil {
+			now := time.Now()
+			if stale {
+				set.slot, set.fetchedAt = slot, now
+			}
+			if staleSignatures {
+				set.signatures, set.signaturesAt = signatures, now
+			}
+		}
+		set.mu.Unlock()
+		return slot, signatures, err
+	}
+}
+
+// fetch fetches the current slot if stale, and then signatures if
+// staleSignatures, returning them or the ones it was given.
+func (set *templateSet) fetch(ctx context.Context, s *JSONRPCTester, slot uint64, stale bool, signatures []string, staleSignatures bool) (uint64, []string, error) {
+	if stale {
+		if _, err := s.CallResult(ctx, "getSlot", nil, &slot); err != nil {
+			return 0, nil, fmt.Errorf("prefetch slot: %w", err)
+		}
+	}
+	if staleSignatures {
+		var err error
+		if signatures, err = s.recentSignatures(ctx, slot); err != nil {
+			return 0, nil, err
+		}
+	}
+	return slot, signatures, nil
+}
+
+// recentSignatures returns the transaction signatures of a block before
+// slot: stepping back from the tip, which may not be confirmed yet, the
+// first with transactions.
+func (s *JSONRPCTester) recentSignatures(ctx context.Context, slot uint64) ([]string, error) {
+	for k := uint64(recentSlotRange); k < recentSlotRange+archiveSkipProbe && k < slot; k++ {
+		result, err := s.GetBlock(ctx, slot-k, blockSignaturesConfig())
+		if err != nil {
+			return nil, err
+		}
+		if rpcclient.IsSkippedSlot(result) {
+			continue
+		}
+		var block struct {
+			Signatures []string `json:"signatures"`
+		}
+		if err := rpcclient.DecodeResult(result, &block); err != nil {
+			return nil, fmt.Errorf("prefetch signatures: %w", err)
+		}
+		if len(block.Signatures) > 0 {
+			return block.Signatures, nil
+		}
+	}
+	return nil, fmt.Errorf("prefetch signatures: no transactions in the %d blocks from %d slots before slot %d", archiveSkipProbe, recentSlotRange, slot)
+}
@@ -0,0 +1,206 @@
+package bench
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/mockserver"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// loadTestTemplates writes templates, a JSON array, to a file and loads it.
+func loadTestTemplates(t *testing.T, templates string) []RequestTemplate {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "templates.json")
+	if err := os.WriteFile(path, []byte(templates), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTemplates(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+// prefetchedTester returns a tester whose templates have slot and a
+// signature prefetched, and whose endpoint is down, so that nothing more
+// can be.
+func prefetchedTester(t *testing.T, slot uint64) *JSONRPCTester {
+	t.Helper()
+	tester := NewJSONRPCTester("http://127.0.0.1:1")
+	tester.UseTemplates(nil)
+	now := time.Now()
+	set := tester.templates
+	set.slot, set.fetchedAt, set.signatures, set.signaturesAt = slot, now, []string{"sigA"}, now
+	var err error
+	if tester.Accounts, err = NewAccountPicker([]string{"Acc1", "Acc2"}, "round-robin"); err != nil {
+		t.Fatal(err)
+	}
+	return tester
+}
+
+func TestTemplateFill(t *testing.T) {
+	vars := map[string]interface{}{"owner": "Owner1", "lamports": float64(12_000_000)}
+	tests := []struct {
+		name   string
+		params interface{}
+		want   interface{}
+	}{
+		{name: "slot", params: "{{latestSlot}}", want: uint64(50)},
+		{name: "spaces", params: "{{ latestSlot }}", want: uint64(50)},
+		{name: "within text", params: "slot {{latestSlot}} of {{account}}", want: "slot 50 of Acc1"},
+		{name: "signature", params: "{{recentSignature}}", want: "sigA"},
+		{
+			name:   "nested",
+			params: []interface{}{"{{account}}", map[string]interface{}{"before": "{{recentSignature}}", "limit": float64(10)}},
+			want:   []interface{}{"Acc1", map[string]interface{}{"before": "sigA", "limit": float64(10)}},
+		},
+		{name: "vars first", params: []interface{}{"{{owner}}", "{{lamports}}"}, want: []interface{}{"Owner1", float64(12_000_000)}},
+		{name: "vars within text", params: "{{owner}} has {{lamports}}", want: "Owner1 has 12000000"},
+		{name: "no placeholders", params: []interface{}{"plain", float64(3), true, nil}, want: []interface{}{"plain", float64(3), true, nil}},
+		{name: "nil", params: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := prefetchedTester(t, 50)
+			got, err := tester.templates.fill(context.Background(), tester, tt.params, vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRandomPubkey(t *testing.T) {
+	tester := prefetchedTester(t, 50)
+	got, err := tester.templates.fill(context.Background(), tester, "{{randomPubkey}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rpcclient.ParsePublicKey(got.(string)); err != nil {
+		t.Errorf("{{randomPubkey}} = %v: %v", got, err)
+	}
+}
+
+func TestTemplateRecentSlot(t *testing.T) {
+	tests := []struct {
+		slot, min uint64
+	}{
+		{slot: 0, min: 0},
+		{slot: 5, min: 0},
+		{slot: recentSlotRange - 1, min: 0},
+		{slot: recentSlotRange, min: 1},
+		{slot: 300_000_000, min: 300_000_000 - recentSlotRange + 1},
+	}
+	for _, tt := range tests {
+		tester := prefetchedTester(t, tt.slot)
+		for range 500 {
+			got, err := tester.templates.value(context.Background(), tester, "recentSlot", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slot := got.(uint64); slot < tt.min || slot > tt.slot {
+				t.Fatalf("slot %d: {{recentSlot}} = %d, want %d to %d", tt.slot, slot, tt.min, tt.slot)
+			}
+		}
+	}
+}
+
+func TestTemplatesAgainstMock(t *testing.T) {
+	templates := `[
+		{"method": "getBlocks", "params": ["{{recentSlot}}"]},
+		{"method": "getTransaction", "params": ["{{recentSignature}}"]},
+		{"name": "getBalance-random", "method": "getBalance", "params": ["{{randomPubkey}}"]}
+	]`
+	tests := []struct {
+		name        string
+		cfg         mockserver.Config
+		successRate float64
+	}{
+		{name: "healthy", cfg: mockserver.Config{Seed: 1}, successRate: 100},
+		// The prefetches fail too, which fails the requests instead of the
+		// run.
+		{name: "failing", cfg: mockserver.Config{Seed: 1, ErrorRate: 1}, successRate: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := newMockTester(t, tt.cfg)
+			tester.UseTemplates(loadTestTemplates(t, templates))
+			stats, err := tester.RunBenchmark(context.Background(), 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.TotalRequests != 9 || stats.SuccessRate != tt.successRate {
+				t.Errorf("%d requests, %.0f%% successful; want 9, %.0f%%", stats.TotalRequests, stats.SuccessRate, tt.successRate)
+			}
+		})
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		templates, err string
+	}{
+		{templates: `[]`, err: "has no requests"},
+		{templates: `[{"params": []}]`, err: "has no method"},
+		{templates: `[{"method": "getSlot"}, {"method": "getSlot"}]`, err: "duplicate template"},
+		{templates: `[{"method": "getBlock", "params": ["{{oldSlot}}"]}]`, err: "unknown placeholder {{oldSlot}}"},
+		{templates: `[{"method": "getBlock", "params": {"slot": }}]`, err: "parse templates"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "templates.json")
+		if err := os.WriteFile(path, []byte(tt.templates), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTemplates(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("LoadTemplates(%s) = %v, want an error containing %q", tt.templates, err, tt.err)
+		}
+	}
+}
+
+func TestTemplatePrefetchInFlight(t *testing.T) {
+	tests := []struct {
+		name          string
+		fetched       bool
+		placeholder   string
+		want          interface{}
+		waitsForFetch bool
+	}{
+		{name: "stale slot", fetched: true, placeholder: "latestSlot", want: uint64(50)},
+		{name: "stale signature", fetched: true, placeholder: "recentSignature", want: "sigA"},
+		{name: "nothing yet", placeholder: "latestSlot", waitsForFetch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Another request is prefetching, slowly: the endpoint is down,
+			// so no value can come from a fetch of this request's own.
+			tester := prefetchedTester(t, 50)
+			set := tester.templates
+			set.fetchedAt, set.signaturesAt = time.Now().Add(-time.Minute), time.Now().Add(-time.Minute)
+			if !tt.fetched {
+				set.fetchedAt, set.signatures = time.Time{}, nil
+			}
+			set.fetching = make(chan struct{})
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			got, err := set.value(ctx, tester, tt.placeholder, nil)
+			if tt.waitsForFetch {
+				if err != context.DeadlineExceeded {
+					t.Errorf("got %v, %v; want to wait for the prefetch in flight", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %v, %v; want %v from before", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,588 @@
+// Package bench runs Solana RPC benchmarks on top of rpcclient: the
+// per-method latency benchmark and the specialised probes (transaction
+// landing, simulation, blockhash validity, archival depth and so on). The
+// per-method benchmark and the load modes built on it also speak EVM
+// JSON-RPC; see UseChain.
+package bench
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// LatencyStats is stats.LatencyStats, aliased because nearly every result
+// type in this package embeds one.
+type LatencyStats = stats.LatencyStats
+
+// summarize summarizes samples with the tester's extra percentiles.
+func (s *JSONRPCTester) summarize(samples []int64) LatencyStats {
+	return stats.Summarize(samples, s.Percentiles...)
+}
+
+// BenchmarkStats summarises a run. TimedOutRequests counts the failed
+// requests that hit their timeout rather than getting an answer, and
+// UnsupportedRequests those the endpoint refused because it doesn't serve
+// the method, listed in UnsupportedMethods; a provider that switches a
+// method off fails it every time, which says nothing of its latency.
+// SchemaViolations counts the failed requests whose result was malformed,
+// when JSONRPCTester.ValidateSchemas is set, by method in
+// SchemaViolationMethods.
+// Histogram counts successful latencies into the buckets of
+// JSONRPCTester.HistogramBuckets, Apdex scores it against
+// JSONRPCTester.ApdexTarget, and TimeSeries breaks the run down by
+// JSONRPCTester.TimeSeriesInterval. Cost prices the requests with the
+// cost model JSONRPCTester.CostModels holds for the endpoint, and Quota
+// follows the allowance the endpoint's rate-limit headers reported.
+// Breaker is what JSONRPCTester.Breaker did, when it is set.
+type BenchmarkStats struct {
+	// Cluster is the cluster the endpoint was found to serve, when
+	// JSONRPCTester.VerifyCluster checked it, and Fingerprint what
+	// JSONRPCTester.TakeFingerprint found out about it.
+	Cluster     string       `json:"cluster,omitempty"`
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
+	// Seed is the seed of the run's random choices, set by
+	// JSONRPCTester.UseSeed.
+	Seed int64 `json:"seed,omitempty"`
+	// StartedAt is when the measured run started; TimeSeries offsets
+	// count from it.
+	StartedAt time.Time `json:"startedAt,omitzero"`
+	// ClusterPerformance is the cluster's activity during the run, when
+	// JSONRPCTester.ClusterContext is set.
+	ClusterPerformance *ClusterPerformance `json:"clusterPerformance,omitempty"`
+	// BlockCompleteness is what checking the blocks getBlock fetched
+	// found, when JSONRPCTester.VerifyBlocks is set.
+	BlockCompleteness      *BlockCompleteness `json:"blockCompleteness,omitempty"`
+	TotalRequests          int                `json:"totalRequests"`
+	SuccessfulRequests     int                `json:"successfulRequests"`
+	FailedRequests         int                `json:"failedRequests"`
+	TimedOutRequests       int                `json:"timedOutRequests"`
+	UnsupportedRequests    int                `json:"unsupportedRequests,omitempty"`
+	UnsupportedMethods     []string           `json:"unsupportedMethods,omitempty"`
+	SchemaViolations       int                `json:"schemaViolations,omitempty"`
+	SchemaViolationMethods map[string]int     `json:"schemaViolationMethods,omitempty"`
+	SuccessRate            float64            `json:"successRate"`
+	WarmupRequests         int                `json:"warmupRequests,omitempty"`
+	Interrupted            bool               `json:"interrupted,omitempty"`
+	Latency                LatencyStats       `json:"latency"`
+	Histogram              []stats.Bucket     `json:"histogram,omitempty"`
+	Apdex                  *ApdexStats        `json:"apdex,omitempty"`
+	Outliers               *OutlierStats      `json:"outliers,omitempty"`
+	Cost                   *CostStats         `json:"cost,omitempty"`
+	Quota                  *QuotaStats        `json:"quota,omitempty"`
+	Breaker                *BreakerStats      `json:"breaker,omitempty"`
+	Bandwidth              BandwidthStats     `json:"bandwidth"`
+	Connections            ConnectionStats    `json:"connections"`
+	TimeSeries             []TimeBucket       `json:"timeSeries,omitempty"`
+	// LatencySamples holds every successful latency, in completion order,
+	// when JSONRPCTester.KeepSamples is set.
+	LatencySamples []int64 `json:"latencySamples,omitempty"`
+	// LatencyDistribution counts every successful latency in a streaming
+	// histogram, so that MergeResults can combine runs exactly.
+	LatencyDistribution *stats.Histogram `json:"latencyDistribution,omitempty"`
+}
+
+// ConnectionStats splits requests by whether they opened a new connection
+// or reused a pooled one. The latencies cover successful requests only, so
+// cold and warm requests can be compared directly.
+type ConnectionStats struct {
+	New           int          `json:"new"`
+	Reused        int          `json:"reused"`
+	ReuseRatio    float64      `json:"reuseRatio"`
+	NewLatency    LatencyStats `json:"newLatency"`
+	ReusedLatency LatencyStats `json:"reusedLatency"`
+	// Protocols counts responses by negotiated HTTP version and
+	// Families by the address family of the connection.
+	Protocols map[string]int `json:"protocols,omitempty"`
+	Families  map[string]int `json:"families,omitempty"`
+	// DNSLookups counts requests that resolved the hostname; DNSMicros
+	// summarises how long those lookups took.
+	DNSLookups int           `json:"dnsLookups"`
+	DNSMicros  *LatencyStats `json:"dnsMicros,omitempty"`
+}
+
+// BandwidthStats covers response bodies of every request, failed ones
+// included, since they were transferred all the same. Sizes are after
+// decompression; WireBytes and the compression fields are only filled in
+// when the client requested compression itself.
+type BandwidthStats struct {
+	TotalBytes       int64                      `json:"totalBytes"`
+	WireBytes        int64                      `json:"wireBytes,omitempty"`
+	CompressionRatio float64                    `json:"compressionRatio,omitempty"`
+	DecompressMicros *LatencyStats              `json:"decompressMicros,omitempty"`
+	DurationMs       int64                      `json:"durationMs"`
+	ThroughputMBps   float64                    `json:"throughputMBps"`
+	PerMethod        map[string]MethodSizeStats `json:"perMethod,omitempty"`
+}
+
+type MethodSizeStats struct {
+	Requests   int          `json:"requests"`
+	TotalBytes int64        `json:"totalBytes"`
+	WireBytes  int64        `json:"wireBytes,omitempty"`
+	Size       LatencyStats `json:"size"`
+}
+
+// ResultSink follows a benchmark run. Start is called with the number of
+// requests the run expects to make, Observe with every measured result and
+// Stop once the run ends.
+type ResultSink interface {
+	Start(expected int)
+	Observe(result rpcclient.Result)
+	Stop()
+}
+
+// JSONRPCTester benchmarks a JSON-RPC endpoint. The protocol-neutral
+// parts, the client, run modes and stats, live here; what methods it can
+// send comes from the method catalog of the chain selected with UseChain,
+// Solana unless another is chosen. The Solana-only probes, such as
+// RunSendTransactionBenchmark, are methods of it too and expect a Solana
+// endpoint.
+type JSONRPCTester struct {
+	*rpcclient.Client
+
+	Label   string
+	Methods []string
+	// Cluster, set by VerifyCluster, and Fingerprint, set by
+	// TakeFingerprint, are reported in benchmark stats.
+	Cluster     string
+	Fingerprint *Fingerprint
+	// ClusterContext, if set, has benchmark stats include what the
+	// cluster was doing during the run, from getRecentPerformanceSamples.
+	ClusterContext bool
+	// VerifyBlocks, if set, checks every block the getBlock method
+	// fetches for signs of truncation, reported in benchmark stats.
+	VerifyBlocks bool
+	// ValidateSchemas, if set, checks every result against the embedded
+	// schema of its method, failing those that don't match.
+	ValidateSchemas bool
+	Accounts        *AccountPicker
+
+	MultipleAccountsBatch int
+
+	TokenOwners  *AccountPicker
+	TokenMints   *AccountPicker
+	TokenProgram string
+
+	// DASAssets are the asset IDs the das provider's getAsset looks up,
+	// falling back to TokenMints, and DASLimit is the page size its
+	// getAssetsByOwner and searchAssets ask for.
+	DASAssets *AccountPicker
+	DASLimit  int
+
+	// EVMLogsRange is how many blocks, ending at the chain head,
+	// eth_getLogs queries; EVMLogAddresses, if set, are the contracts
+	// whose logs it asks for.
+	EVMLogsRange    int
+	EVMLogAddresses *AccountPicker
+
+	Warmup Warmup
+
+	// TimeSeriesInterval, if positive, adds per-interval buckets to the
+	// stats of RunBenchmark and RunOpenLoop.
+	TimeSeriesInterval time.Duration
+	// HistogramBuckets, if set, are the ascending millisecond bounds of
+	// the latency histogram in the stats of RunBenchmark and RunOpenLoop.
+	HistogramBuckets []int64
+	// ApdexTarget, if positive, is the Apdex threshold T the stats of
+	// RunBenchmark and RunOpenLoop are scored against.
+	ApdexTarget time.Duration
+	// Percentiles, if set, are the extra percentiles, between 0 and 100,
+	// every latency summary reports.
+	Percentiles []float64
+	// OutlierZ, if positive, is the modified z-score above which a
+	// latency is flagged as an outlier.
+	OutlierZ float64
+	// KeepSamples adds the raw latencies to the stats, so later runs can
+	// be tested against them with CompareToBaseline.
+	KeepSamples bool
+	// CostModels, if set, price each run's requests with the model of
+	// CostProvider, or the one CostModelFor picks for the endpoint.
+	CostModels   []CostModel
+	CostProvider string
+	// Breaker, if enabled, stops requests to an endpoint that keeps
+	// failing, for a while: RunBenchmark and RunComparison wait out its
+	// cooldown, and RunOpenLoop skips the requests due meanwhile. Runs
+	// against the same endpoint, such as batch jobs, share its breaker.
+	Breaker BreakerConfig
+
+	// Logger receives status output; nil means slog.Default(). Records
+	// are tagged with Label when it is set.
+	Logger *slog.Logger
+
+	// NewSinks, if set, creates the sinks that follow each RunBenchmark.
+	// It is called per run so batch jobs get sinks of their own.
+	NewSinks func(s *JSONRPCTester) []ResultSink
+
+	// templates, set by UseTemplates, are sent in place of catalog
+	// methods.
+	templates *templateSet
+	// script, set by UseScript, generates requests, rewrites their params
+	// or checks their responses.
+	script *Script
+	// chain, set by UseChain, selects the method catalog; evm caches the
+	// chain head the EVM catalog's methods query relative to.
+	chain string
+	evm   *evmHead
+	// blocks hands out the slots the Solana getBlock method fetches.
+	blocks *blockSource
+	// breakers are the circuit breakers of the endpoints the tester and
+	// the testers copied from it run against.
+	breakers *breakerSet
+	// seed, set by UseSeed, seeds every random choice; 0 leaves them
+	// random.
+	seed int64
+
+	// Stop is closed to interrupt a run; benchmarks stop issuing new
+	// requests and return stats for what completed.
+	Stop <-chan struct{}
+}
+
+// SolanaRPCTester is the name JSONRPCTester had before it spoke other
+// chains.
+//
+// Deprecated: use JSONRPCTester.
+type SolanaRPCTester = JSONRPCTester
+
+// NewJSONRPCTester returns a tester for endpoint, speaking Solana until
+// UseChain selects another chain.
+func NewJSONRPCTester(endpoint string) *JSONRPCTester {
+	return &JSONRPCTester{
+		Client:                rpcclient.NewClient(endpoint),
+		MultipleAccountsBatch: 10,
+		EVMLogsRange:          10,
+		DASLimit:              100,
+		blocks:                &blockSource{},
+		breakers:              &breakerSet{},
+	}
+}
+
+// NewSolanaRPCTester returns a tester for the Solana endpoint.
+//
+// Deprecated: use NewJSONRPCTester.
+func NewSolanaRPCTester(endpoint string) *JSONRPCTester {
+	return NewJSONRPCTester(endpoint)
+}
+
+// withEndpoint returns a copy of s that shares its benchmark settings but
+// targets endpoint with its own client, headers and rate limit.
+func (s *JSONRPCTester) withEndpoint(endpoint string) *JSONRPCTester {
+	c := *s
+	c.Client = s.Client.Clone(endpoint)
+	c.Label = ""
+	c.Cluster = ""
+	c.Fingerprint = nil
+	c.blocks = &blockSource{}
+	c.reseed()
+	return &c
+}
+
+// Log returns the tester's logger, tagged with its label.
+func (s *JSONRPCTester) Log() *slog.Logger {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if s.Label != "" {
+		logger = logger.With("job", s.Label)
+	}
+	return logger
+}
+
+// stopped reports whether the run has been interrupted or ctx has ended.
+func (s *JSONRPCTester) stopped(ctx context.Context) bool {
+	select {
+	case <-s.Stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// awaitBreaker waits until breaker lets a request through, reporting
+// whether it did before the run was stopped.
+func (s *JSONRPCTester) awaitBreaker(ctx context.Context, breaker *breakerRun) bool {
+	for wait := breaker.wait(time.Now()); wait > 0; wait = breaker.wait(time.Now()) {
+		s.sleep(ctx, wait)
+		if s.stopped(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// sleep waits for d, returning early if the run is stopped.
+func (s *JSONRPCTester) sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-s.Stop:
+	case <-ctx.Done():
+	}
+}
+
+func (s *JSONRPCTester) RunBenchmark(ctx context.Context, iterations int) (*BenchmarkStats, error) {
+	if err := s.validateMethods(); err != nil {
+		return nil, err
+	}
+	methods := s.methods()
+
+	warmupRequests, err := s.runWarmup(ctx, methods)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Log().Info("running benchmark", "endpoint", rpcclient.RedactURL(s.Endpoint), "iterations", iterations, "methods", methods)
+
+	var sinks []ResultSink
+	if s.NewSinks != nil {
+		sinks = s.NewSinks(s)
+	}
+	for _, sink := range sinks {
+		sink.Start(iterations * len(methods))
+	}
+	stopSinks := func() {
+		for _, sink := range sinks {
+			sink.Stop()
+		}
+	}
+
+	var results []rpcclient.Result
+	var done []time.Duration
+	start := time.Now()
+	breaker := s.newBreaker(start)
+
+run:
+	for i := 0; i < iterations; i++ {
+		for _, method := range methods {
+			if s.stopped(ctx) {
+				break run
+			}
+			if !s.awaitBreaker(ctx, breaker) {
+				break run
+			}
+			result, err := s.runner(method)(ctx, s)
+			if err != nil && ctx.Err() != nil {
+				break run
+			}
+			if err != nil {
+				stopSinks()
+				return nil, err
+			}
+			breaker.record(time.Now(), result)
+			stampOffset(result, start)
+			results = append(results, *result)
+			done = append(done, time.Since(start))
+			for _, sink := range sinks {
+				sink.Observe(*result)
+			}
+		}
+	}
+	stopSinks()
+
+	stats := s.calculateStats(results, time.Since(start))
+	s.addBreakdowns(ctx, stats, results, start, done)
+	stats.Breaker = breaker.finish(time.Now())
+	stats.WarmupRequests = warmupRequests
+	stats.Interrupted = s.stopped(ctx)
+	return stats, nil
+}
+
+// stampOffset sets result's offset from start, the start of its run.
+func stampOffset(result *rpcclient.Result, start time.Time) {
+	if !result.StartedAt.IsZero() {
+		result.OffsetMicros = result.StartedAt.Sub(start).Microseconds()
+	}
+}
+
+// addBreakdowns adds the optional parts of stats that the tester's
+// settings ask for, and what VerifyCluster and TakeFingerprint found;
+// done holds when each result completed, as an offset
+// from start.
+func (s *JSONRPCTester) addBreakdowns(ctx context.Context, stats *BenchmarkStats, results []rpcclient.Result, start time.Time, done []time.Duration) {
+	stats.Cluster = s.Cluster
+	stats.Fingerprint = s.Fingerprint
+	stats.Seed = s.seed
+	stats.StartedAt = start.Round(0).UTC()
+	for _, method := range stats.UnsupportedMethods {
+		s.Log().Warn("endpoint does not support method", "method", method)
+	}
+	if stats.SchemaViolations > 0 {
+		s.Log().Warn("endpoint returned malformed results", "violations", stats.SchemaViolations)
+	}
+	if s.ClusterContext && s.Chain() == ChainSolana {
+		stats.ClusterPerformance = s.clusterPerformance(ctx, time.Since(start))
+	}
+	if s.VerifyBlocks && s.blocks != nil {
+		if stats.BlockCompleteness = s.blocks.take(); stats.BlockCompleteness != nil && stats.BlockCompleteness.Incomplete > 0 {
+			s.Log().Warn("endpoint served incomplete blocks", "blocks", stats.BlockCompleteness.Blocks,
+				"incomplete", stats.BlockCompleteness.Incomplete)
+		}
+	}
+	stats.Histogram = latencyHistogram(results, s.HistogramBuckets)
+	stats.Apdex = calculateApdex(results, s.ApdexTarget)
+	stats.Outliers = detectOutliers(results, start, done, s.OutlierZ)
+	stats.TimeSeries = s.calculateTimeSeries(results, done, s.TimeSeriesInterval)
+	if stats.Quota = trackQuota(results, done); stats.Quota != nil && stats.Quota.Responses > 0 {
+		s.Log().Info("rate-limit quota", "remaining", stats.Quota.LastRemaining, "min_remaining", stats.Quota.MinRemaining,
+			"limit", stats.Quota.Limit, "rate_limited", stats.Quota.RateLimited)
+	} else if stats.Quota != nil {
+		s.Log().Info("rate-limit quota", "rate_limited", stats.Quota.RateLimited, "max_retry_after_ms", stats.Quota.MaxRetryAfterMs)
+	}
+	if model, err := CostModelFor(s.CostModels, s.CostProvider, s.Endpoint); err != nil {
+		s.Log().Warn("run not priced", "error", err)
+	} else if model != nil {
+		stats.Cost = model.Cost(results, stats.TotalRequests)
+		s.Log().Info("estimated cost", "provider", model.Provider, "credits", stats.Cost.Credits,
+			"usd", stats.Cost.USD, "usd_per_million", stats.Cost.USDPerMillion)
+	}
+	if s.KeepSamples {
+		stats.LatencySamples = successfulLatencies(results)
+	}
+	stats.LatencyDistribution = latencyDistribution(results)
+}
+
+func successfulLatencies(results []rpcclient.Result) []int64 {
+	latencies := []int64{}
+	for _, result := range results {
+		if result.Success {
+			latencies = append(latencies, result.Latency)
+		}
+	}
+	return latencies
+}
+
+func (s *JSONRPCTester) calculateStats(results []rpcclient.Result, elapsed time.Duration) *BenchmarkStats {
+	var latencies []int64
+	successfulRequests := 0
+	timedOutRequests := 0
+	unsupported := map[string]int{}
+	violations := map[string]int{}
+
+	for _, result := range results {
+		if result.Success {
+			successfulRequests++
+			latencies = append(latencies, result.Latency)
+		} else if result.TimedOut {
+			timedOutRequests++
+		} else if result.Unsupported {
+			unsupported[result.Method]++
+		} else if result.SchemaViolation {
+			violations[result.Method]++
+		}
+	}
+
+	stats := &BenchmarkStats{
+		TotalRequests:      len(results),
+		SuccessfulRequests: successfulRequests,
+		FailedRequests:     len(results) - successfulRequests,
+		TimedOutRequests:   timedOutRequests,
+		Bandwidth:          s.calculateBandwidth(results, elapsed),
+		Connections:        s.calculateConnections(results),
+	}
+	for method, n := range unsupported {
+		stats.UnsupportedRequests += n
+		stats.UnsupportedMethods = append(stats.UnsupportedMethods, method)
+	}
+	sort.Strings(stats.UnsupportedMethods)
+	for _, n := range violations {
+		stats.SchemaViolations += n
+	}
+	if len(violations) > 0 {
+		stats.SchemaViolationMethods = violations
+	}
+	if len(latencies) > 0 {
+		stats.SuccessRate = float64(successfulRequests) / float64(len(results)) * 100
+		stats.Latency = s.summarize(latencies)
+	}
+	return stats
+}
+
+func (s *JSONRPCTester) calculateConnections(results []rpcclient.Result) ConnectionStats {
+	connections := ConnectionStats{Protocols: make(map[string]int), Families: make(map[string]int)}
+	var newLatencies, reusedLatencies, dnsMicros []int64
+	for _, result := range results {
+		if result.Protocol != "" {
+			connections.Protocols[result.Protocol]++
+		}
+		if result.IPFamily != "" {
+			connections.Families[result.IPFamily]++
+		}
+		if result.DNSMicros > 0 {
+			dnsMicros = append(dnsMicros, result.DNSMicros)
+		}
+		switch result.Connection {
+		case rpcclient.ConnNew:
+			connections.New++
+			if result.Success {
+				newLatencies = append(newLatencies, result.Latency)
+			}
+		case rpcclient.ConnReused:
+			connections.Reused++
+			if result.Success {
+				reusedLatencies = append(reusedLatencies, result.Latency)
+			}
+		}
+	}
+	if total := connections.New + connections.Reused; total > 0 {
+		connections.ReuseRatio = float64(connections.Reused) / float64(total)
+	}
+	connections.NewLatency = s.summarize(newLatencies)
+	connections.ReusedLatency = s.summarize(reusedLatencies)
+	if len(dnsMicros) > 0 {
+		connections.DNSLookups = len(dnsMicros)
+		dnsStats := s.summarize(dnsMicros)
+		connections.DNSMicros = &dnsStats
+	}
+	return connections
+}
+
+func (s *JSONRPCTester) calculateBandwidth(results []rpcclient.Result, elapsed time.Duration) BandwidthStats {
+	bandwidth := BandwidthStats{
+		DurationMs: elapsed.Milliseconds(),
+		PerMethod:  make(map[string]MethodSizeStats),
+	}
+
+	sizes := make(map[string][]int64)
+	wireBytes := make(map[string]int64)
+	var compressedBytes int64
+	var decompress []int64
+	for _, result := range results {
+		bandwidth.TotalBytes += int64(result.Size)
+		sizes[result.Method] = append(sizes[result.Method], int64(result.Size))
+		if result.WireSize > 0 {
+			bandwidth.WireBytes += int64(result.WireSize)
+			wireBytes[result.Method] += int64(result.WireSize)
+			compressedBytes += int64(result.Size)
+			decompress = append(decompress, result.DecompressMicros)
+		}
+	}
+	for method, methodSizes := range sizes {
+		var total int64
+		for _, size := range methodSizes {
+			total += size
+		}
+		bandwidth.PerMethod[method] = MethodSizeStats{
+			Requests:   len(methodSizes),
+			TotalBytes: total,
+			WireBytes:  wireBytes[method],
+			Size:       s.summarize(methodSizes),
+		}
+	}
+	if bandwidth.WireBytes > 0 {
+		bandwidth.CompressionRatio = float64(compressedBytes) / float64(bandwidth.WireBytes)
+		decompressStats := s.summarize(decompress)
+		bandwidth.DecompressMicros = &decompressStats
+	}
+
+	if elapsed > 0 {
+		bandwidth.ThroughputMBps = float64(bandwidth.TotalBytes) / 1e6 / elapsed.Seconds()
+	}
+	return bandwidth
+}
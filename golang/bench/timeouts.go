@@ -0,0 +1,50 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseMethodTimeouts parses per-method timeout overrides written as
+// "getSlot=2s,getBlock=60s". Any RPC method name is accepted, including
+// ones the probes call directly rather than through -methods.
+func ParseMethodTimeouts(list string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, item := range SplitList(list) {
+		method, value, ok := strings.Cut(item, "=")
+		method = strings.TrimSpace(method)
+		if !ok || method == "" {
+			return nil, fmt.Errorf("method timeout %q is not of the form method=duration", item)
+		}
+		timeout, err := parseTimeout(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("timeout for %s: %w", method, err)
+		}
+		timeouts[method] = timeout
+	}
+	return timeouts, nil
+}
+
+// mergeTimeouts returns base with overrides applied, leaving both intact.
+func mergeTimeouts(base, overrides map[string]time.Duration) map[string]time.Duration {
+	merged := make(map[string]time.Duration, len(base)+len(overrides))
+	for method, timeout := range base {
+		merged[method] = timeout
+	}
+	for method, timeout := range overrides {
+		merged[method] = timeout
+	}
+	return merged
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("timeout must not be negative")
+	}
+	return timeout, nil
+}
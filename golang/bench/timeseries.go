@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// TimeBucket summarizes the requests that completed during one interval
+// of a run. StartMs is the interval's offset from the start of the run.
+type TimeBucket struct {
+	StartMs    int64        `json:"startMs"`
+	Requests   int          `json:"requests"`
+	Errors     int          `json:"errors"`
+	TimedOut   int          `json:"timedOut"`
+	Throughput float64      `json:"throughput"`
+	Latency    LatencyStats `json:"latency"`
+	// QuotaRemaining is the least allowance the rate-limit headers of the
+	// interval's responses reported, if any carried them.
+	QuotaRemaining *int64 `json:"quotaRemaining,omitempty"`
+}
+
+// calculateTimeSeries buckets results by when they completed, given as
+// offsets from the start of the run in done. Intervals in which nothing
+// completed are kept, so a stall shows up as a gap rather than vanishing.
+func (s *JSONRPCTester) calculateTimeSeries(results []rpcclient.Result, done []time.Duration, interval time.Duration) []TimeBucket {
+	if interval <= 0 || len(results) == 0 {
+		return nil
+	}
+	var last time.Duration
+	for _, offset := range done {
+		last = max(last, offset)
+	}
+	buckets := make([]TimeBucket, last/interval+1)
+	latencies := make([][]int64, len(buckets))
+	for i, result := range results {
+		n := done[i] / interval
+		bucket := &buckets[n]
+		bucket.Requests++
+		if quota := rpcclient.ParseQuota(result.LimitHeaders, completedAt(result)); quota != nil && quota.HasRemaining {
+			if bucket.QuotaRemaining == nil || quota.Remaining < *bucket.QuotaRemaining {
+				bucket.QuotaRemaining = &quota.Remaining
+			}
+		}
+		switch {
+		case result.Success:
+			latencies[n] = append(latencies[n], result.Latency)
+		case result.TimedOut:
+			bucket.TimedOut++
+			bucket.Errors++
+		default:
+			bucket.Errors++
+		}
+	}
+	for i := range buckets {
+		buckets[i].StartMs = (time.Duration(i) * interval).Milliseconds()
+		buckets[i].Throughput = float64(buckets[i].Requests) / interval.Seconds()
+		buckets[i].Latency = s.summarize(latencies[i])
+	}
+	return buckets
+}
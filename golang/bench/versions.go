@@ -0,0 +1,387 @@
+package bench
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// versionScanBlocks is how many blocks RunVersionCheck looks through for
+// a version 0 transaction that uses an address lookup table.
+const versionScanBlocks = 50
+
+// VersionCheck is one request RunVersionCheck made and whether the
+// endpoint handled the version 0 transaction in it correctly.
+type VersionCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// VersionCheckStats covers a versioned transaction check. Slot and
+// Signature are the block and version 0 transaction checked, and
+// LookupTables whether the transaction loads accounts from address lookup
+// tables; without one in the blocks scanned, those parts of the checks
+// are left out. Findings describe the checks that failed.
+type VersionCheckStats struct {
+	Slot          uint64         `json:"slot"`
+	Signature     string         `json:"signature"`
+	LookupTables  bool           `json:"lookupTables"`
+	BlocksScanned int            `json:"blocksScanned"`
+	Passed        int            `json:"passed"`
+	Failed        int            `json:"failed"`
+	Checks        []VersionCheck `json:"checks"`
+	Findings      []string       `json:"findings,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	Interrupted   bool           `json:"interrupted,omitempty"`
+}
+
+// versionedTransaction is a transaction as getTransaction returns it, or
+// as a block lists it; Transaction is in whichever encoding was asked
+// for.
+type versionedTransaction struct {
+	Version json.RawMessage `json:"version"`
+	Meta    *struct {
+		LoadedAddresses *struct {
+			Writable []string `json:"writable"`
+			Readonly []string `json:"readonly"`
+		} `json:"loadedAddresses"`
+	} `json:"meta"`
+	Transaction json.RawMessage `json:"transaction"`
+}
+
+// transactionBody is a transaction in the json and jsonParsed encodings,
+// or with transactionDetails accounts, which puts the keys at the top.
+type transactionBody struct {
+	Signatures  []string          `json:"signatures"`
+	AccountKeys []json.RawMessage `json:"accountKeys"`
+	Message     struct {
+		AccountKeys         []json.RawMessage `json:"accountKeys"`
+		AddressTableLookups []json.RawMessage `json:"addressTableLookups"`
+	} `json:"message"`
+}
+
+type blockTransactions struct {
+	Transactions []versionedTransaction `json:"transactions"`
+}
+
+// usesLookupTables reports whether tx, with transactionDetails accounts,
+// has keys loaded from lookup tables.
+func (tx versionedTransaction) usesLookupTables() bool {
+	body, err := tx.body()
+	return err == nil && lookupTableKeys(body.AccountKeys) > 0
+}
+
+func (tx versionedTransaction) isV0() bool {
+	return string(tx.Version) == "0"
+}
+
+func (tx versionedTransaction) body() (transactionBody, error) {
+	var body transactionBody
+	err := json.Unmarshal(tx.Transaction, &body)
+	return body, err
+}
+
+func (tx versionedTransaction) signature() string {
+	if body, err := tx.body(); err == nil && len(body.Signatures) > 0 {
+		return body.Signatures[0]
+	}
+	return ""
+}
+
+// checkVersion fails unless tx says it is version 0.
+func (tx versionedTransaction) checkVersion() error {
+	if len(tx.Version) == 0 {
+		return fmt.Errorf("no version field")
+	}
+	if !tx.isV0() {
+		return fmt.Errorf("version %s, want 0", tx.Version)
+	}
+	return nil
+}
+
+// checkLoaded fails unless the meta lists the accounts tx loaded from
+// lookup tables.
+func (tx versionedTransaction) checkLoaded() error {
+	if tx.Meta == nil || tx.Meta.LoadedAddresses == nil {
+		return fmt.Errorf("no meta.loadedAddresses")
+	}
+	if len(tx.Meta.LoadedAddresses.Writable)+len(tx.Meta.LoadedAddresses.Readonly) == 0 {
+		return fmt.Errorf("meta.loadedAddresses is empty")
+	}
+	return nil
+}
+
+// lookupTableKeys counts the parsed account keys that came from lookup
+// tables.
+func lookupTableKeys(keys []json.RawMessage) int {
+	n := 0
+	for _, raw := range keys {
+		var key struct {
+			Source string `json:"source"`
+		}
+		if json.Unmarshal(raw, &key) == nil && key.Source == "lookupTable" {
+			n++
+		}
+	}
+	return n
+}
+
+// isVersionedWire reports whether a serialized transaction's message is
+// versioned: after the signatures, its first byte has the top bit set.
+func isVersionedWire(tx []byte) (bool, error) {
+	// The signature count is a compact-u16.
+	count, off := 0, 0
+	for shift := 0; ; shift += 7 {
+		if off >= len(tx) || shift > 14 {
+			return false, fmt.Errorf("transaction truncated")
+		}
+		b := tx[off]
+		off++
+		count |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	off += 64 * count
+	if off >= len(tx) {
+		return false, fmt.Errorf("transaction truncated")
+	}
+	return tx[off]&0x80 != 0, nil
+}
+
+// checkWire fails unless tx's transaction is base64 and serialized as
+// versioned.
+func (tx versionedTransaction) checkWire() error {
+	var encoded []string
+	if err := json.Unmarshal(tx.Transaction, &encoded); err != nil || len(encoded) != 2 {
+		return fmt.Errorf("transaction is not [data, encoding]")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded[0])
+	if err != nil {
+		return fmt.Errorf("decode transaction: %w", err)
+	}
+	versioned, err := isVersionedWire(data)
+	if err != nil {
+		return err
+	}
+	if !versioned {
+		return fmt.Errorf("serialized as a legacy message")
+	}
+	return nil
+}
+
+// versionConfig returns a getBlock or getTransaction configuration for
+// version 0 transactions in encoding.
+func versionConfig(encoding string) map[string]interface{} {
+	return map[string]interface{}{
+		"encoding":                       encoding,
+		"maxSupportedTransactionVersion": 0,
+		"commitment":                     "finalized",
+	}
+}
+
+// RunVersionCheck finds a recent version 0 transaction, preferably one
+// using an address lookup table, and fetches it and its block in each
+// encoding, checking the endpoint returns it whole and marked as version
+// 0. It also checks that a request without maxSupportedTransactionVersion
+// is refused, as clients that don't send it can't read such
+// transactions. Some providers fail on these transactions in ways a
+// latency benchmark never notices; this reports them as findings.
+func (s *JSONRPCTester) RunVersionCheck(ctx context.Context) (*VersionCheckStats, error) {
+	stats := &VersionCheckStats{Checks: []VersionCheck{}}
+	var tip uint64
+	if _, err := s.CallResult(ctx, "getSlot", []interface{}{map[string]interface{}{"commitment": "finalized"}}, &tip); err != nil {
+		return nil, fmt.Errorf("fetch finalized slot: %w", err)
+	}
+	s.Log().Info("checking versioned transactions", "endpoint", rpcclient.RedactURL(s.Endpoint), "slot", tip)
+
+	record := func(name string, result *rpcclient.Result, check func() error) {
+		entry := VersionCheck{Name: name, Latency: result.Latency}
+		var err error
+		if !result.Success {
+			err = fmt.Errorf("%s", result.Error)
+		} else if check != nil {
+			err = check()
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			stats.Failed++
+			stats.Findings = append(stats.Findings, fmt.Sprintf("%s: %s", name, entry.Error))
+			s.Log().Warn("versioned transaction check failed", "check", name, "error", entry.Error)
+		} else {
+			entry.Passed = true
+			stats.Passed++
+		}
+		stats.Checks = append(stats.Checks, entry)
+	}
+
+	// Look back through recent blocks, fetched with just their account
+	// keys, for a version 0 transaction.
+	config := versionConfig("json")
+	config["transactionDetails"] = "accounts"
+	config["rewards"] = false
+	for slot := tip; stats.BlocksScanned < versionScanBlocks && tip-slot < 2*versionScanBlocks && !stats.LookupTables; slot-- {
+		if s.stopped(ctx) {
+			stats.Interrupted = true
+			return stats, nil
+		}
+		result, err := s.GetBlock(ctx, slot, config)
+		if err != nil {
+			return nil, err
+		}
+		if rpcclient.IsSkippedSlot(result) {
+			continue
+		}
+		stats.BlocksScanned++
+		var block blockTransactions
+		if result.Success {
+			err = rpcclient.DecodeResult(result, &block)
+		}
+		// The first block is a check itself, and so is any that fails.
+		if stats.BlocksScanned == 1 || !result.Success || err != nil {
+			record("getBlock transactionDetails=accounts", result, func() error { return err })
+			if !result.Success || err != nil {
+				break
+			}
+		}
+		for _, tx := range block.Transactions {
+			if !tx.isV0() || stats.Signature != "" && !tx.usesLookupTables() {
+				continue
+			}
+			stats.Slot, stats.Signature, stats.LookupTables = slot, tx.signature(), tx.usesLookupTables()
+			if stats.LookupTables {
+				break
+			}
+		}
+	}
+	if stats.Signature == "" {
+		if stats.Failed == 0 {
+			stats.Error = fmt.Sprintf("no version 0 transactions in %d blocks", stats.BlocksScanned)
+		}
+		return stats, nil
+	}
+	s.Log().Info("found version 0 transaction", "slot", stats.Slot, "signature", stats.Signature,
+		"lookup_tables", stats.LookupTables)
+
+	config = versionConfig("json")
+	config["transactionDetails"] = "full"
+	config["rewards"] = false
+	result, err := s.GetBlock(ctx, stats.Slot, config)
+	if err != nil {
+		return nil, err
+	}
+	record("getBlock json", result, func() error {
+		var block blockTransactions
+		if err := rpcclient.DecodeResult(result, &block); err != nil {
+			return err
+		}
+		for _, tx := range block.Transactions {
+			if tx.signature() == stats.Signature {
+				return checkV0(tx, false, stats.LookupTables)
+			}
+		}
+		return fmt.Errorf("transaction %s missing from block", stats.Signature)
+	})
+
+	// Binary transactions can't be told apart without decoding them, so
+	// every version 0 transaction in the block is checked.
+	config = versionConfig("base64")
+	config["transactionDetails"] = "full"
+	config["rewards"] = false
+	result, err = s.GetBlock(ctx, stats.Slot, config)
+	if err != nil {
+		return nil, err
+	}
+	record("getBlock base64", result, func() error {
+		var block blockTransactions
+		if err := rpcclient.DecodeResult(result, &block); err != nil {
+			return err
+		}
+		versioned := 0
+		for _, tx := range block.Transactions {
+			if tx.isV0() {
+				if err := tx.checkWire(); err != nil {
+					return err
+				}
+				versioned++
+			}
+		}
+		if versioned == 0 {
+			return fmt.Errorf("no version 0 transactions in the block")
+		}
+		return nil
+	})
+
+	for _, encoding := range []string{"json", "jsonParsed", "base64"} {
+		result, err := s.GetTransaction(ctx, stats.Signature, versionConfig(encoding))
+		if err != nil {
+			return nil, err
+		}
+		record("getTransaction "+encoding, result, func() error {
+			var tx versionedTransaction
+			if err := rpcclient.DecodeResult(result, &tx); err != nil {
+				return err
+			}
+			if len(tx.Transaction) == 0 || string(tx.Transaction) == "null" {
+				return fmt.Errorf("transaction not found")
+			}
+			if encoding == "base64" {
+				if err := tx.checkVersion(); err != nil {
+					return err
+				}
+				return tx.checkWire()
+			}
+			return checkV0(tx, encoding == "jsonParsed", stats.LookupTables)
+		})
+	}
+
+	// Clients that don't send maxSupportedTransactionVersion must be
+	// refused rather than handed a transaction they can't parse.
+	result, err = s.GetTransaction(ctx, stats.Signature, map[string]interface{}{"encoding": "json", "commitment": "finalized"})
+	if err != nil {
+		return nil, err
+	}
+	name := "getTransaction without maxSupportedTransactionVersion"
+	if result.ErrorCode == rpcclient.ErrCodeUnsupportedTransactionVersion {
+		stats.Passed++
+		stats.Checks = append(stats.Checks, VersionCheck{Name: name, Passed: true, Latency: result.Latency})
+	} else {
+		if result.Success {
+			result.Success, result.Error = false, "returned the transaction instead of refusing it"
+		}
+		record(name, result, nil)
+	}
+	stats.Interrupted = s.stopped(ctx)
+	s.Log().Info("versioned transaction checks", "passed", stats.Passed, "failed", stats.Failed)
+	return stats, nil
+}
+
+// checkV0 checks a version 0 transaction in the json or jsonParsed
+// encoding, and, if it uses lookup tables, that they show.
+func checkV0(tx versionedTransaction, parsed, lookups bool) error {
+	if err := tx.checkVersion(); err != nil {
+		return err
+	}
+	body, err := tx.body()
+	if err != nil {
+		return fmt.Errorf("decode transaction: %w", err)
+	}
+	if body.Message.AddressTableLookups == nil {
+		return fmt.Errorf("no message.addressTableLookups")
+	}
+	if !lookups {
+		return nil
+	}
+	if len(body.Message.AddressTableLookups) == 0 {
+		return fmt.Errorf("message.addressTableLookups is empty")
+	}
+	if parsed && lookupTableKeys(body.Message.AccountKeys) == 0 {
+		return fmt.Errorf("no accountKeys with source lookupTable")
+	}
+	return tx.checkLoaded()
+}
@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Warmup is either a request count or a duration of traffic sent before
+// measurement starts; its results are discarded.
+type Warmup struct {
+	Requests int
+	Duration time.Duration
+}
+
+// ParseWarmup accepts a plain request count ("50") or a Go duration ("10s").
+func ParseWarmup(value string) (Warmup, error) {
+	if value == "" {
+		return Warmup{}, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 {
+			return Warmup{}, fmt.Errorf("warmup request count must not be negative")
+		}
+		return Warmup{Requests: n}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return Warmup{}, fmt.Errorf("warmup %q is neither a request count nor a duration", value)
+	}
+	if d < 0 {
+		return Warmup{}, fmt.Errorf("warmup duration must not be negative")
+	}
+	return Warmup{Duration: d}, nil
+}
+
+func (w Warmup) enabled() bool {
+	return w.Requests > 0 || w.Duration > 0
+}
+
+func (w Warmup) String() string {
+	if w.Duration > 0 {
+		return w.Duration.String()
+	}
+	return fmt.Sprintf("%d requests", w.Requests)
+}
+
+// runWarmup cycles through methods until the warmup is exhausted and
+// returns how many requests were sent.
+func (s *JSONRPCTester) runWarmup(ctx context.Context, methods []string) (int, error) {
+	if !s.Warmup.enabled() {
+		return 0, nil
+	}
+	s.Log().Info("warming up", "warmup", s.Warmup.String())
+
+	start := time.Now()
+	sent := 0
+	for !s.stopped(ctx) {
+		if s.Warmup.Duration > 0 && time.Since(start) >= s.Warmup.Duration {
+			break
+		}
+		if s.Warmup.Duration == 0 && sent >= s.Warmup.Requests {
+			break
+		}
+		if _, err := s.runner(methods[sent%len(methods)])(ctx, s); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/report"
+)
+
+// envPrefix starts the environment variable that overrides each flag:
+// -max-conns-per-host is SOLANA_RPC_BENCH_MAX_CONNS_PER_HOST.
+const envPrefix = "SOLANA_RPC_BENCH_"
+
+// literalArgsEnv is set in the environment of an agent's job process: the
+// agent has already expanded the ${VAR} references it allows, so the job
+// takes its command line as it is.
+const literalArgsEnv = envPrefix + "LITERAL_ARGS"
+
+// expandArg expands ${VAR} in a value from the command line from the
+// environment, unless the command line is an agent's job.
+func expandArg(value string) string {
+	if os.Getenv(literalArgsEnv) != "" {
+		return value
+	}
+	return os.ExpandEnv(value)
+}
+
+// envName returns the environment variable that overrides the flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets every flag not given on the command line from its
+// environment variable, if set. A flag so set counts as changed, as if it
+// had been given.
+func applyEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || f.Changed || err != nil {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// loadEnvFile sets the variables of a .env file, KEY=VALUE lines, that
+// the environment doesn't already set. Blank lines and # comments are
+// skipped, an "export " prefix is allowed, and a value may be quoted. A
+// missing file is only an error if required.
+func loadEnvFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return fmt.Errorf("%s:%d: not of the form KEY=VALUE", path, i+1)
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// headerValue collects -header values by name.
+type headerValue map[string]string
+
+// String doesn't show the values, which are often credentials.
+func (h headerValue) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func (h headerValue) Set(header string) error {
+	name, value, ok := strings.Cut(header, ":")
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return fmt.Errorf("header %q is not of the form \"Name: value\"", header)
+	}
+	h[name] = strings.TrimSpace(value)
+	return nil
+}
+
+// expand returns the headers with ${VAR} in their values expanded as
+// expandArg does, or nil if there are none.
+func (h headerValue) expand() map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	expanded := make(map[string]string, len(h))
+	for name, value := range h {
+		expanded[name] = expandArg(value)
+	}
+	return expanded
+}
+
+// longFlags rewrites single-dash long flags, such as -seed, to the
+// double-dash form pflag expects, so the Go-style flags the tool has
+// always taken keep working. Single-letter flags are shorthands either
+// way, and everything after "--" is left alone.
+func longFlags(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			copy(out[i:], args[i:])
+			break
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && len(name) > 2 {
+			arg = "-" + arg
+		}
+		out[i] = arg
+	}
+	return out
+}
+
+// presetCompare maps compare's arguments, endpoint, other and an optional
+// iteration count, to -compare.
+func presetCompare(flags *pflag.FlagSet, args []string) ([]string, error) {
+	if err := flags.Set("compare", args[1]); err != nil {
+		return nil, err
+	}
+	return append([]string{args[0]}, args[2:]...), nil
+}
+
+// withResultsDiff makes compare, given two results files rather than two
+// endpoints, diff the saved runs instead of benchmarking.
+func withResultsDiff(cmd *cobra.Command) *cobra.Command {
+	compareEndpoints := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 || !isResultsFile(args[0]) || !isResultsFile(args[1]) {
+			return compareEndpoints(cmd, args)
+		}
+		return runDiff(cmd.OutOrStdout(), args[0], args[1], cmd.Flags().Lookup("output").Value.String())
+	}
+	return cmd
+}
+
+// isResultsFile reports whether arg names a JSON file rather than an
+// endpoint.
+func isResultsFile(arg string) bool {
+	if !strings.HasSuffix(arg, ".json") {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// runDiff writes how the run saved at b differs from the one at a to w,
+// and the delta as JSON to output if it isn't empty.
+func runDiff(w io.Writer, a, b, output string) error {
+	runA, err := bench.LoadResults(a)
+	if err != nil {
+		return err
+	}
+	runB, err := bench.LoadResults(b)
+	if err != nil {
+		return err
+	}
+	delta := bench.DiffResults(runA, runB)
+	if output != "" {
+		if err := report.WriteJSONFile(output, delta); err != nil {
+			return err
+		}
+	}
+	return bench.WriteResultsDelta(w, delta, colorOutput(w))
+}
+
+// colorOutput reports whether w is a terminal that takes colors: not
+// redirected, and neither NO_COLOR set nor TERM=dumb.
+func colorOutput(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// presetReplay maps replay's arguments, a recording and an optional
+// endpoint, to -replay.
+func presetReplay(flags *pflag.FlagSet, args []string) ([]string, error) {
+	if err := flags.Set("replay", args[0]); err != nil {
+		return nil, err
+	}
+	return args[1:], nil
+}
+
+// defaultMonitorDuration is how long monitor watches an endpoint when
+// neither a duration argument nor -health-monitor says.
+const defaultMonitorDuration = "1h"
+
+// presetMonitor maps monitor's arguments, an optional endpoint and
+// duration, to -health-monitor.
+func presetMonitor(flags *pflag.FlagSet, args []string) ([]string, error) {
+	duration := defaultMonitorDuration
+	if flags.Changed("health-monitor") {
+		duration = flags.Lookup("health-monitor").Value.String()
+	}
+	if len(args) > 1 {
+		duration = args[1]
+		args = args[:1]
+	}
+	if err := flags.Set("health-monitor", duration); err != nil {
+		return nil, fmt.Errorf("duration: %w", err)
+	}
+	return args, nil
+}
+
+// newReportCommand returns the report command, which summarizes saved
+// results files.
+func newReportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:          "report <results.json>...",
+		Short:        "Summarize results files written with -output, or batch job reports, in a table",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReport(cmd.OutOrStdout(), args)
+		},
+	}
+}
+
+// newMergeCommand returns the merge command, which combines saved results
+// files into one.
+func newMergeCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:          "merge <results.json>...",
+		Short:        "Combine results files, such as one per region, into the results of a single run",
+		Args:         cobra.MinimumNArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMerge(cmd.OutOrStdout(), args, output)
+		},
+	}
+	cmd.Flags().StringVar(&output, "output", "", "write the merged results to this file")
+	return cmd
+}
+
+// runMerge merges the results files at paths, writing a table of them
+// and the merged run to w, and the merged results to output if it isn't
+// empty.
+func runMerge(w io.Writer, paths []string, output string) error {
+	var runs []*bench.SavedResults
+	for _, path := range paths {
+		run, err := bench.LoadResults(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	merged, err := bench.MergeResults(runs)
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		if err := report.WriteJSONFile(output, merged); err != nil {
+			return err
+		}
+	}
+	return bench.WriteResultsTable(w, append(runs, &bench.SavedResults{Path: "merged", Stats: merged}))
+}
+
+// runReport writes a table of the results files at paths to w.
+func runReport(w io.Writer, paths []string) error {
+	var runs []*bench.SavedResults
+	for _, path := range paths {
+		run, err := bench.LoadResults(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	return bench.WriteResultsTable(w, runs)
+}
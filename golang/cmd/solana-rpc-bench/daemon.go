@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"solana-rpc-performance-golang/alert"
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/daemon"
+	"solana-rpc-performance-golang/report"
+	"solana-rpc-performance-golang/store"
+)
+
+// newDaemonCommand returns the daemon command, which runs benchmarks on
+// request from an HTTP API and on schedules, and charts the run history.
+func newDaemonCommand() *cobra.Command {
+	var listen, dir, token, schedules, storePath, notifyURL, pagerDutyKey, opsgenieKey, alertURL, logFormat string
+	var thresholds daemon.Thresholds
+	var insecure bool
+	var keepJobs int
+	cmd := &cobra.Command{
+		Use:          "daemon",
+		Short:        "Serve an HTTP API to start, stop and query benchmark jobs, and run jobs on schedules",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := report.NewLogger(os.Stderr, logFormat, slog.LevelInfo)
+			if err != nil {
+				return err
+			}
+			slog.SetDefault(logger)
+			token := os.ExpandEnv(token)
+			if token == "" && !insecure {
+				return fmt.Errorf("--token is required, since the API runs command lines as the daemon's user; pass --insecure to serve it without one")
+			}
+			hostname, _ := os.Hostname()
+			notifier := &regressionNotifier{webhook: os.ExpandEnv(notifyURL), source: hostname}
+			switch {
+			case pagerDutyKey != "" && opsgenieKey != "":
+				return fmt.Errorf("--pagerduty-key and --opsgenie-key cannot be combined")
+			case pagerDutyKey != "":
+				pagerDuty := alert.NewPagerDuty(os.ExpandEnv(pagerDutyKey))
+				if alertURL != "" {
+					pagerDuty.URL = alertURL
+				}
+				notifier.alerter = pagerDuty
+			case opsgenieKey != "":
+				opsgenie := alert.NewOpsgenie(os.ExpandEnv(opsgenieKey))
+				if alertURL != "" {
+					opsgenie.URL = alertURL
+				}
+				notifier.alerter = opsgenie
+			}
+			d := &daemon.Daemon{
+				Dir:          dir,
+				Run:          runBenchProcess,
+				Store:        storePath,
+				Thresholds:   thresholds,
+				KeepFinished: keepJobs,
+				Checked:      notifier.checked,
+				Logger:       logger,
+			}
+			if storePath != "" {
+				history, err := store.Open(os.ExpandEnv(storePath))
+				if err != nil {
+					return err
+				}
+				defer history.Close()
+				d.History = history
+			}
+			if schedules != "" {
+				jobs, err := daemon.LoadSchedules(schedules)
+				if err != nil {
+					return err
+				}
+				for _, job := range jobs {
+					if _, err := d.Schedule(job); err != nil {
+						return err
+					}
+				}
+			}
+			server := &http.Server{
+				Addr:              listen,
+				Handler:           d.Handler(token),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			served := make(chan error, 1)
+			go func() { served <- server.ListenAndServe() }()
+			logger.Info("daemon listening", "url", "http://"+listen, "dir", dir)
+			select {
+			case err := <-served:
+				return err
+			case <-ctx.Done():
+			}
+			// Running jobs are interrupted, and write what they measured.
+			logger.Info("shutting down")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), jobStopTimeout)
+			defer cancel()
+			err = server.Shutdown(shutdownCtx)
+			d.Shutdown()
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", "127.0.0.1:8080", "address to serve the API on")
+	cmd.Flags().StringVar(&dir, "dir", "daemon-results", "directory jobs write their results to")
+	cmd.Flags().StringVar(&token, "token", "", "bearer token every API request must carry; ${VAR} references are expanded")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "serve the API without --token, to anyone who can reach it")
+	cmd.Flags().IntVar(&keepJobs, "keep-jobs", 100, "how many finished jobs to keep track of; older ones are forgotten, though their results files stay in --dir")
+	cmd.Flags().StringVar(&schedules, "schedules", "", "run the jobs in this schedules file on their schedules")
+	cmd.Flags().StringVar(&storePath, "store", "", "record scheduled runs in this SQLite file or postgres:// database, unless a schedule's command line sets -store, and chart its runs on the dashboard")
+	cmd.Flags().Float64Var(&thresholds.Latency, "regression-latency", 20, "a scheduled run regressed if its p50, p95 or p99 rose by more than this percentage over the run before")
+	cmd.Flags().Float64Var(&thresholds.SuccessRate, "regression-success-rate", 1, "a scheduled run regressed if its success rate fell by more than this many percentage points")
+	cmd.Flags().StringVar(&notifyURL, "notify", "", "post scheduled runs that regressed to this Slack or Discord webhook URL; ${VAR} is expanded from the environment")
+	cmd.Flags().StringVar(&pagerDutyKey, "pagerduty-key", "", "open a PagerDuty incident for a schedule whose run regressed, resolved by its next run that doesn't; ${VAR} is expanded")
+	cmd.Flags().StringVar(&opsgenieKey, "opsgenie-key", "", "the same with an Opsgenie alert; ${VAR} is expanded")
+	cmd.Flags().StringVar(&alertURL, "alert-url", "", "with --pagerduty-key or --opsgenie-key, the API to send to instead")
+	cmd.Flags().StringVar(&logFormat, "log-format", "text", "log output format: text or json")
+	return cmd
+}
+
+// regressionNotifier tells of scheduled runs that regressed: it posts them
+// to a webhook, and opens an incident for the schedule that its next run
+// that holds up resolves.
+type regressionNotifier struct {
+	webhook string
+	alerter alert.Alerter
+	// source names the daemon's host in incidents.
+	source string
+
+	mu   sync.Mutex
+	open map[string]alert.Alert
+}
+
+func (n *regressionNotifier) checked(job daemon.ScheduledJob, results *bench.SavedResults, regression *daemon.Regression) {
+	ctx := context.Background()
+	if regression == nil {
+		n.mu.Lock()
+		incident, ok := n.open[job.Name]
+		delete(n.open, job.Name)
+		n.mu.Unlock()
+		if ok {
+			if err := n.alerter.Resolve(ctx, incident); err != nil {
+				slog.Warn("alert not resolved", "schedule", job.Name, "error", err)
+			}
+		}
+		return
+	}
+
+	if n.webhook != "" {
+		stats := results.Stats
+		summary := &report.RunSummary{
+			Title:       fmt.Sprintf("Scheduled benchmark %s regressed", job.Name),
+			Requests:    stats.TotalRequests,
+			SuccessRate: stats.SuccessRate,
+			Latency:     stats.Latency,
+			Regressions: regression.Reasons,
+		}
+		if err := report.Notify(n.webhook, summary); err != nil {
+			slog.Warn("regression not posted", "schedule", job.Name, "error", err)
+		}
+	}
+	if n.alerter != nil {
+		incident := alert.Alert{
+			Key:      "solana-rpc-bench/schedule/" + job.Name,
+			Summary:  fmt.Sprintf("Scheduled benchmark %s regressed: %s", job.Name, strings.Join(regression.Reasons, "; ")),
+			Source:   n.source,
+			Severity: alert.SeverityWarning,
+			Since:    time.Now(),
+			Details: map[string]interface{}{
+				"schedule": job.Name,
+				"job":      regression.Job,
+				"previous": regression.Previous,
+				"reasons":  regression.Reasons,
+			},
+		}
+		if err := n.alerter.Trigger(ctx, incident); err != nil {
+			slog.Warn("alert not triggered", "schedule", job.Name, "error", err)
+			return
+		}
+		n.mu.Lock()
+		if n.open == nil {
+			n.open = make(map[string]alert.Alert)
+		}
+		n.open[job.Name] = incident
+		n.mu.Unlock()
+	}
+}
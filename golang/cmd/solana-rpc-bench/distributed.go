@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/distributed"
+	"solana-rpc-performance-golang/report"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// coordinatorFlags are the flags the coordinator keeps to itself rather
+// than pass on to agents: where its results go, and how it shows them.
+var coordinatorFlags = map[string]bool{
+	"coordinator": true, "agents": true, "regions": true, "agent-token": true, "coordinator-cert": true, "coordinator-key": true, "env-file": true,
+	"output": true, "results-out": true, "junit": true, "report-md": true, "timeseries-out": true,
+	"influx": true, "influx-token": true, "statsd": true, "statsd-prefix": true, "statsd-tags": true, "statsd-plain": true,
+	"otlp": true, "otlp-sample": true, "notify": true, "notify-on": true, "slo-p99": true, "slo-error-rate": true,
+	"store": true, "upload": true, "baseline": true, "har": true, "record": true, "capture-bodies": true, "capture-max-bytes": true,
+	"tui": true, "quiet": true, "progress-interval": true, "v": true, "vv": true, "log-format": true,
+}
+
+// agentJobArgs returns the bench command line agents run: the one cmd was
+// run with, as typed, less the coordinator's own flags. ${VAR} references
+// are passed on unexpanded, so secrets stay on the machines that hold
+// them. With keepSamples, agents keep their latency samples.
+func agentJobArgs(cmd *cobra.Command, argv []string, keepSamples bool) []string {
+	var args []string
+	if cmd.HasParent() {
+		// Drop the subcommand's name.
+		for i, arg := range argv {
+			if arg == cmd.Name() {
+				argv = append(argv[:i:i], argv[i+1:]...)
+				break
+			}
+		}
+	}
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			args = append(args, argv[i:]...)
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		flag := cmd.Flags().Lookup(name)
+		if !strings.HasPrefix(arg, "--") || flag == nil {
+			args = append(args, arg)
+			continue
+		}
+		// The value may be the next argument.
+		n := 1
+		if !hasValue && flag.NoOptDefVal == "" && i+1 < len(argv) {
+			n = 2
+		}
+		if !coordinatorFlags[name] {
+			args = append(args, argv[i:i+n]...)
+		}
+		i += n - 1
+	}
+	if keepSamples {
+		args = append(args, "--keep-samples")
+	}
+	return args
+}
+
+// runCoordinator runs a benchmark on agents, waiting on addr for count of
+// them to join, or count in each of regions, and serving them TLS with
+// config, then hands them job. The results they stream in go to tester's sinks, labelled with
+// the agent's region and name.
+func runCoordinator(tester *bench.JSONRPCTester, addr string, count int, regions []string, config *tls.Config, token string, job distributed.Job) (*distributed.Results, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	coordinator := &distributed.Coordinator{
+		Agents:  count,
+		Regions: regions,
+		Job:     job,
+		TLS:     config,
+		Token:   token,
+		Logger:  tester.Log(),
+		NewSinks: func(agent string) []bench.ResultSink {
+			if tester.NewSinks == nil {
+				return nil
+			}
+			labelled := *tester
+			labelled.Label = agent
+			return tester.NewSinks(&labelled)
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-tester.Stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return coordinator.Run(ctx, lis)
+}
+
+// newAgentCommand returns the agent command, which runs the benchmarks a
+// coordinator hands out.
+func newAgentCommand() *cobra.Command {
+	var name, region, token, caFile, expandEnv, logFormat string
+	var retry time.Duration
+	cmd := &cobra.Command{
+		Use:          "agent <coordinator>",
+		Short:        "Run the benchmarks a coordinator (-coordinator) hands out, streaming results back",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := report.NewLogger(os.Stderr, logFormat, slog.LevelInfo)
+			if err != nil {
+				return err
+			}
+			slog.SetDefault(logger)
+			config, err := distributed.ClientTLS(caFile)
+			if err != nil {
+				return err
+			}
+			agent := &distributed.Agent{
+				Name:   name,
+				Region: region,
+				TLS:    config,
+				Token:  os.ExpandEnv(token),
+				Run: func(ctx context.Context, job distributed.Job, send func([]rpcclient.Result)) (*bench.BenchmarkStats, error) {
+					return runAgentJob(ctx, job, bench.SplitList(expandEnv), send)
+				},
+				Logger: logger.With("agent", name),
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			logger.Info("waiting for jobs", "agent", name, "region", region, "coordinator", args[0])
+			if err := agent.Serve(ctx, args[0], retry); err != nil && ctx.Err() == nil {
+				return err
+			}
+			return nil
+		},
+	}
+	hostname, _ := os.Hostname()
+	cmd.Flags().StringVar(&name, "name", hostname, "the agent's name in the coordinator's results")
+	cmd.Flags().StringVar(&region, "region", "", "the region the agent runs in, which the coordinator breaks results down by")
+	cmd.Flags().StringVar(&token, "token", "", "the token the coordinator was given with -agent-token")
+	cmd.Flags().StringVar(&caFile, "ca-file", "", "PEM bundle of the CAs to check the coordinator's certificate against instead of the system's")
+	cmd.Flags().StringVar(&expandEnv, "expand-env", "", "comma-separated environment variables the coordinator's jobs may reference as ${VAR}, such as RPC_KEY; jobs referencing any other are refused")
+	cmd.Flags().DurationVar(&retry, "retry", 5*time.Second, "how often to try the coordinator while it is down or between runs")
+	cmd.Flags().StringVar(&logFormat, "log-format", "text", "log output format: text or json")
+	return cmd
+}
+
+// runAgentJob runs job's bench command line in a process of its own,
+// sending the results it writes as they come. Only the variables in
+// expandEnv are expanded in it.
+func runAgentJob(ctx context.Context, job distributed.Job, expandEnv []string, send func([]rpcclient.Result)) (*bench.BenchmarkStats, error) {
+	job, err := job.Expand(expandEnv)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "solana-rpc-bench-agent-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	statsPath := filepath.Join(dir, "stats.json")
+	if err := runBenchProcessEnv(ctx, job.Args, []string{literalArgsEnv + "=1"}, statsPath, send); err != nil {
+		return nil, err
+	}
+	saved, err := bench.LoadResults(statsPath)
+	if err != nil {
+		return nil, err
+	}
+	return saved.Stats, nil
+}
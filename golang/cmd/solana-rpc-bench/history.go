@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"solana-rpc-performance-golang/report"
+	"solana-rpc-performance-golang/store"
+)
+
+// newHistoryCommand returns the history command, which lists and queries
+// the runs recorded with -store.
+func newHistoryCommand() *cobra.Command {
+	var (
+		path    string
+		filter  store.Filter
+		since   string
+		asJSON  bool
+		period  time.Duration
+		method  string
+		openDB  = func() (*store.Store, error) { return openHistory(path, since, &filter) }
+		history = &cobra.Command{
+			Use:          "history",
+			Short:        "List the runs recorded with -store",
+			Args:         cobra.NoArgs,
+			SilenceUsage: true,
+		}
+	)
+	history.PersistentFlags().StringVar(&path, "store", "bench.db", "the SQLite file or postgres:// database runs were recorded in")
+	history.PersistentFlags().StringVar(&filter.Endpoint, "endpoint-contains", "", "only runs whose endpoint contains this")
+	history.PersistentFlags().StringVar(&filter.Command, "command", "", "only runs of this command: solana-rpc-bench, bench, compare, replay or monitor")
+	history.PersistentFlags().StringVar(&since, "since", "", "only runs started within this long, e.g. 36h or 7d")
+	history.PersistentFlags().BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	history.Flags().IntVar(&filter.Limit, "limit", 20, "list at most this many runs, newest first (0 is all)")
+	history.RunE = func(cmd *cobra.Command, args []string) error {
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		runs, err := db.Runs(filter)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return report.PrintJSON("Go Run History", runs)
+		}
+		return writeRunsTable(cmd.OutOrStdout(), runs)
+	}
+
+	show := &cobra.Command{
+		Use:          "show <id>",
+		Short:        "Print a recorded run's config and summary",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("run id %q is not a number", args[0])
+			}
+			db, err := openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			run, err := db.Run(id)
+			if err != nil {
+				return err
+			}
+			return report.PrintJSON(fmt.Sprintf("Go Run %d", id), run)
+		},
+	}
+
+	trend := &cobra.Command{
+		Use:          "trend",
+		Short:        "Show request latency and success rate per period across the recorded runs",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			points, err := db.Trend(filter, method, period)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return report.PrintJSON("Go Run Trend", points)
+			}
+			return writeTrendTable(cmd.OutOrStdout(), points)
+		},
+	}
+	trend.Flags().DurationVar(&period, "period", 24*time.Hour, "the length of each period")
+	trend.Flags().StringVar(&method, "method", "", "only requests of this method")
+
+	history.AddCommand(show, trend)
+	return history
+}
+
+// openHistory opens the store at path, which must exist if it is a SQLite
+// file, and sets filter.Since from since.
+func openHistory(path, since string, filter *store.Filter) (*store.Store, error) {
+	if since != "" {
+		ago, err := parseAge(since)
+		if err != nil {
+			return nil, fmt.Errorf("-since: %w", err)
+		}
+		filter.Since = time.Now().Add(-ago)
+	}
+	path = os.ExpandEnv(path)
+	if store.IsPostgres(path) {
+		return store.Open(path)
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no store at %s; record runs with -store %s", path, path)
+	}
+	return store.Open(path)
+}
+
+// parseAge parses a duration, also allowing whole days such as 7d.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// writeRunsTable writes a row of headline numbers per run, latencies in
+// milliseconds. Runs without a summary show dashes.
+func writeRunsTable(w io.Writer, runs []store.RunRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"ID", "STARTED", "STATUS", "COMMAND", "ENDPOINT", "REQUESTS", "SUCCESS", "P50", "P95", "P99"}, "\t"))
+	for _, run := range runs {
+		numbers := "-\t-\t-\t-\t-"
+		if run.TotalRequests > 0 {
+			numbers = fmt.Sprintf("%d\t%.1f%%\t%d\t%d\t%d", run.TotalRequests, run.SuccessRate, run.P50, run.P95, run.P99)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", run.ID, run.StartedAt.Format("2006-01-02 15:04:05"), run.Status,
+			run.Command, run.Endpoint, numbers)
+	}
+	return tw.Flush()
+}
+
+// writeTrendTable writes a row per period, latencies in milliseconds.
+func writeTrendTable(w io.Writer, points []store.TrendPoint) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"PERIOD", "RUNS", "REQUESTS", "SUCCESS", "AVG", "P50", "P95", "P99", "MAX"}, "\t"))
+	for _, point := range points {
+		latency := point.Latency
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%.1f\t%d\t%d\t%d\t%d\n", point.Start.Format("2006-01-02 15:04"), point.Runs,
+			point.Requests, point.SuccessRate, latency.Avg, latency.P50, latency.P95, latency.P99, latency.Max)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,751 @@
+// Command solana-rpc-bench benchmarks a Solana RPC endpoint from the command
+// line; the benchmarks themselves live in the bench package.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/providers/das"
+	"solana-rpc-performance-golang/report"
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/store"
+	"solana-rpc-performance-golang/upload"
+)
+
+func main() {
+	os.Args = append(os.Args[:1], longFlags(os.Args[1:])...)
+	root := newBenchCommand("solana-rpc-bench [endpoint] [iterations]", "Benchmark a Solana RPC endpoint", cobra.MaximumNArgs(2), nil)
+	root.AddCommand(
+		newBenchCommand("bench [endpoint] [iterations]", "Benchmark an endpoint (the default command)", cobra.MaximumNArgs(2), nil),
+		withResultsDiff(newBenchCommand("compare <endpoint|a.json> <other|b.json> [iterations]", "Compare two endpoints request by request (-compare), or diff two results files", cobra.RangeArgs(2, 3), presetCompare)),
+		newBenchCommand("replay <recording> [endpoint]", "Replay a recording against an endpoint (-replay)", cobra.RangeArgs(1, 2), presetReplay),
+		newBenchCommand("monitor [endpoint] [duration]", "Monitor an endpoint's health (-health-monitor)", cobra.RangeArgs(0, 2), presetMonitor),
+		newReportCommand(),
+		newMergeCommand(),
+		newAgentCommand(),
+		newDaemonCommand(),
+		newHistoryCommand(),
+		newMockServerCommand(),
+	)
+	envFile := root.PersistentFlags().String("env-file", ".env", "read environment variables from this file, if it exists; variables already set win")
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		path, required := *envFile, cmd.Flags().Changed("env-file")
+		if env, ok := os.LookupEnv(envName("env-file")); ok && !required {
+			path, required = env, true
+		}
+		if err := loadEnvFile(path, required); err != nil {
+			return err
+		}
+		return applyEnv(cmd.Flags())
+	}
+	if err := root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newBenchCommand returns a command that runs the benchmark with every
+// flag. preset, if set, turns the command's arguments into flag values
+// and returns the endpoint and iterations arguments left.
+func newBenchCommand(use, short string, positional cobra.PositionalArgs, preset func(flags *pflag.FlagSet, args []string) ([]string, error)) *cobra.Command {
+	fs := flag.NewFlagSet(use, flag.ContinueOnError)
+	var flags benchFlags
+	flags.register(fs)
+
+	cmd := &cobra.Command{
+		Use:          use,
+		Short:        short,
+		Args:         positional,
+		SilenceUsage: true,
+	}
+	cmd.Flags().AddGoFlagSet(fs)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if preset != nil {
+			var err error
+			if args, err = preset(cmd.Flags(), args); err != nil {
+				return err
+			}
+		}
+		return runBench(cmd, args, flags)
+	}
+	return cmd
+}
+
+// benchRun is one run of a bench command: its flags and arguments, the
+// tester they configure and the outputs it has open.
+type benchRun struct {
+	benchFlags
+
+	cmd        *cobra.Command
+	args       []string
+	endpoint   string
+	iterations int
+	logger     *slog.Logger
+	tester     *bench.JSONRPCTester
+
+	notifier        *runNotifier
+	baselineSamples []int64
+
+	// closers close the outputs the run opened, in the order they were
+	// opened.
+	closers []func() error
+	// stored is the run being recorded with -store, if any, in db.
+	stored *store.Run
+	db     *store.Store
+	// dest is where -upload puts the run's artifacts, if set, including
+	// the HTML report html.
+	dest *upload.Destination
+	html *report.HTMLReport
+	// summary is the first results printJSON printed, the run's summary.
+	summary interface{}
+}
+
+// runBench runs a bench command with flags: it configures a tester, opens
+// the outputs the flags ask for and runs the mode they select. Errors
+// closing the outputs are joined to the run's, and a failed run is
+// neither uploaded nor recorded as a success.
+func runBench(cmd *cobra.Command, args []string, flags benchFlags) (err error) {
+	r := &benchRun{
+		benchFlags: flags,
+		cmd:        cmd,
+		args:       args,
+		endpoint:   "https://api.mainnet-beta.solana.com",
+		iterations: 100,
+	}
+	if env, ok := os.LookupEnv(envName("endpoint")); ok {
+		r.endpoint = env
+	}
+	if len(args) > 0 {
+		r.endpoint = args[0]
+	}
+	// Keys can stay in the environment, out of shell history, as in
+	// batch files.
+	r.endpoint = expandArg(r.endpoint)
+	r.compare = expandArg(r.compare)
+	r.failover = expandArg(r.failover)
+	if len(args) > 1 {
+		if i, err := strconv.Atoi(args[1]); err == nil {
+			r.iterations = i
+		}
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case r.veryVerbose:
+		level = rpcclient.LevelTrace
+	case r.verbose:
+		level = slog.LevelDebug
+	case r.quiet, r.tui:
+		// The dashboard owns the terminal; status records would garble it.
+		level = slog.LevelWarn
+	}
+	if r.logger, err = report.NewLogger(os.Stderr, r.logFormat, level); err != nil {
+		return err
+	}
+	slog.SetDefault(r.logger)
+
+	if r.tester, err = r.newTester(level); err != nil {
+		return err
+	}
+	if r.notifyOn != "always" && r.notifyOn != "breach" {
+		return fmt.Errorf("unknown -notify-on %q (want always or breach)", r.notifyOn)
+	}
+	if r.baseline != "" {
+		if r.baselineSamples, err = bench.LoadBaselineSamples(r.baseline); err != nil {
+			return err
+		}
+	}
+	r.notifier = &runNotifier{
+		webhook:         os.ExpandEnv(r.notifyURL),
+		onBreach:        r.notifyOn == "breach",
+		p99:             r.sloP99,
+		errorRate:       r.sloErrorRate,
+		baseline:        r.baseline,
+		baselineSamples: r.baselineSamples,
+	}
+
+	ctx := context.Background()
+	if r.dryRun {
+		return r.runDryRun(ctx)
+	}
+
+	if r.uploadURL != "" {
+		if r.dest, err = upload.Open(ctx, os.ExpandEnv(r.uploadURL)); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		for i := len(r.closers) - 1; i >= 0; i-- {
+			err = errors.Join(err, r.closers[i]())
+		}
+		if r.stored != nil {
+			r.finishStored(err)
+		}
+		if err == nil && r.dest != nil {
+			err = r.uploadArtifacts(ctx)
+		}
+	}()
+	if err := r.openRequestLogs(); err != nil {
+		return err
+	}
+	if err := r.openSinks(); err != nil {
+		return err
+	}
+	return r.run(ctx)
+}
+
+// newTester returns a tester configured by r's flags, logging at level.
+func (r *benchRun) newTester(level slog.Level) (*bench.JSONRPCTester, error) {
+	tester := bench.NewJSONRPCTester(r.endpoint)
+	tester.Logger = r.logger
+	if level <= slog.LevelDebug {
+		tester.Client.Logger = r.logger
+	}
+
+	if err := tester.UseChain(r.chain); err != nil {
+		return nil, err
+	}
+	if r.chain != bench.ChainSolana {
+		if len(r.args) == 0 {
+			return nil, fmt.Errorf("-chain %s needs an endpoint", r.chain)
+		}
+		if name := firstSetFlag(r.cmd, solanaOnlyFlags); name != "" {
+			return nil, fmt.Errorf("-%s only works with -chain solana", name)
+		}
+	}
+	if r.methodList == "" {
+		r.methodList = strings.Join(bench.ChainDefaultMethods(r.chain), ",")
+	}
+	methods, err := bench.ParseChainMethods(r.chain, r.methodList)
+	if r.scenario != "" {
+		methods, err = bench.ChainScenarioMethods(r.chain, r.scenario)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tester.Methods = methods
+	if r.templatesFile != "" {
+		templates, err := bench.LoadTemplates(r.templatesFile)
+		if err != nil {
+			return nil, err
+		}
+		tester.UseTemplates(templates)
+	}
+	if r.scriptFile != "" {
+		script, err := bench.LoadScript(r.scriptFile)
+		if err != nil {
+			return nil, err
+		}
+		tester.UseScript(script)
+	}
+	if r.multipleAccounts < 1 || r.multipleAccounts > 100 {
+		return nil, errors.New("-multiple-accounts must be between 1 and 100")
+	}
+	tester.MultipleAccountsBatch = r.multipleAccounts
+	if r.accountsFile != "" {
+		loadAccounts := bench.LoadAccountList
+		if r.chain == bench.ChainEVM {
+			loadAccounts = bench.LoadEVMAddressList
+		}
+		keys, err := loadAccounts(r.accountsFile)
+		if err != nil {
+			return nil, err
+		}
+		if tester.Accounts, err = bench.NewAccountPicker(keys, r.accountSampling); err != nil {
+			return nil, err
+		}
+	}
+	if r.tokenOwners != "" {
+		keys, err := bench.LoadAccountList(r.tokenOwners)
+		if err != nil {
+			return nil, err
+		}
+		if tester.TokenOwners, err = bench.NewAccountPicker(keys, r.accountSampling); err != nil {
+			return nil, err
+		}
+	}
+	if mints := bench.SplitList(r.tokenMints); len(mints) > 0 {
+		if tester.TokenMints, err = bench.NewAccountPicker(mints, r.accountSampling); err != nil {
+			return nil, err
+		}
+	}
+	tester.TokenProgram = r.tokenProgram
+	tester.EVMLogsRange = r.evmLogsRange
+	if addresses := bench.SplitList(r.evmLogAddresses); len(addresses) > 0 {
+		if tester.EVMLogAddresses, err = bench.NewAccountPicker(addresses, r.accountSampling); err != nil {
+			return nil, err
+		}
+	}
+	if r.dasAssets != "" {
+		ids, err := bench.LoadAccountList(r.dasAssets)
+		if err != nil {
+			return nil, err
+		}
+		if tester.DASAssets, err = bench.NewAccountPicker(ids, r.accountSampling); err != nil {
+			return nil, err
+		}
+	}
+	if r.dasLimit < 1 || r.dasLimit > das.MaxLimit {
+		return nil, errors.New("-das-limit must be between 1 and 1000")
+	}
+	tester.DASLimit = r.dasLimit
+	if r.seed != 0 {
+		tester.UseSeed(r.seed)
+	}
+	httpClient, err := rpcclient.NewHTTPClient(rpcclient.TransportOptions{
+		MaxIdleConnsPerHost: r.maxIdleConnsPerHost,
+		MaxConnsPerHost:     r.maxConnsPerHost,
+		IdleConnTimeout:     r.idleConnTimeout,
+		DisableKeepAlives:   r.disableKeepAlive,
+		Protocol:            r.proto,
+		DisableCompression:  r.compression == "none",
+		Proxy:               r.proxy,
+		TLS: rpcclient.TLSOptions{
+			CAFile:     r.caFile,
+			CertFile:   r.clientCert,
+			KeyFile:    r.clientKey,
+			MinVersion: r.tlsMinVersion,
+			Insecure:   r.insecure,
+		},
+		SourceIP:  r.sourceIP,
+		DNS:       r.dnsStrategy,
+		IPVersion: r.ipVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tester.HTTP = httpClient
+	if r.proxy != "" {
+		r.logger.Info("using proxy", "proxy", rpcclient.RedactURL(r.proxy))
+	}
+	if r.sourceIP != "" {
+		r.logger.Info("binding outgoing connections", "source", r.sourceIP)
+	}
+	if r.insecure {
+		r.logger.Warn("TLS certificate verification is disabled")
+	}
+	switch r.compression {
+	case "auto", "none":
+	default:
+		encodings := bench.SplitList(r.compression)
+		for _, encoding := range encodings {
+			if encoding != "gzip" && encoding != "deflate" {
+				return nil, fmt.Errorf("unknown compression %q (want auto, none, gzip or deflate)", encoding)
+			}
+		}
+		tester.AcceptEncoding = strings.Join(encodings, ", ")
+	}
+	tester.Timeout = r.timeout
+	tester.Headers = r.headers.expand()
+	if tester.Timeouts, err = bench.ParseMethodTimeouts(r.methodTimeouts); err != nil {
+		return nil, err
+	}
+	tester.NewSinks = func(s *bench.JSONRPCTester) []bench.ResultSink {
+		switch {
+		case r.tui:
+			return []bench.ResultSink{report.NewDashboard(s.Endpoint)}
+		case r.progressInterval > 0 && !r.quiet:
+			return []bench.ResultSink{report.NewProgressReporter(r.progressInterval, s.Log())}
+		}
+		return nil
+	}
+	tester.Stop = notifyInterrupt(func() {
+		if r.tui {
+			fmt.Print(report.TerminalRestore)
+		}
+	})
+	tester.TimeSeriesInterval = r.timeSeries
+	tester.ApdexTarget = r.apdexTarget
+	tester.OutlierZ = r.outlierZ
+	if tester.Breaker, err = bench.ParseBreaker(r.breakerErrorRate, r.breakerWindow, r.breakerCooldown); err != nil {
+		return nil, err
+	}
+	if r.costModelFile != "" {
+		if tester.CostModels, err = bench.LoadCostModels(r.costModelFile); err != nil {
+			return nil, err
+		}
+		if _, err := bench.CostModelFor(tester.CostModels, r.costProvider, ""); err != nil {
+			return nil, err
+		}
+		tester.CostProvider = r.costProvider
+	} else if r.costProvider != "" {
+		return nil, errors.New("-cost-provider needs -cost-model")
+	}
+	if tester.Percentiles, err = bench.ParsePercentiles(r.percentiles); err != nil {
+		return nil, err
+	}
+	tester.KeepSamples = r.keepSamples || r.baseline != ""
+	tester.ClusterContext = r.clusterContext
+	tester.VerifyBlocks = r.verifyBlocks
+	tester.ValidateSchemas = r.validateSchemas
+	if tester.HistogramBuckets, err = bench.ParseHistogramBuckets(r.histogramBuckets); err != nil {
+		return nil, err
+	}
+	if tester.Warmup, err = bench.ParseWarmup(r.warmup); err != nil {
+		return nil, err
+	}
+	return tester, nil
+}
+
+// onClose has close called when the run ends, before the outputs opened
+// earlier are closed.
+func (r *benchRun) onClose(close func() error) {
+	r.closers = append(r.closers, close)
+}
+
+// openRequestLogs opens the outputs that log each request as it is sent:
+// -record, -har, -otlp and -capture-bodies.
+func (r *benchRun) openRequestLogs() error {
+	if r.record != "" {
+		recorder, err := rpcclient.NewRecorder(r.record)
+		if err != nil {
+			return err
+		}
+		r.onClose(func() error {
+			if err := recorder.Close(); err != nil {
+				return fmt.Errorf("write recording: %w", err)
+			}
+			return nil
+		})
+		r.tester.Recorder = recorder
+		r.logger.Info("recording requests", "file", r.record)
+	}
+	if r.harFile != "" {
+		harLog := rpcclient.NewHARLog()
+		r.onClose(func() error { return harLog.WriteFile(r.harFile) })
+		r.tester.HAR = harLog
+	}
+	if r.otlpEndpoint != "" {
+		tracer, shutdown, err := startTracing(r.otlpEndpoint, r.otlpSample)
+		if err != nil {
+			return err
+		}
+		r.onClose(func() error {
+			if err := shutdown(); err != nil {
+				r.logger.Warn("some spans could not be exported", "otlp", r.otlpEndpoint, "error", err)
+			}
+			return nil
+		})
+		r.tester.Tracer = tracer
+		r.logger.Info("tracing requests", "otlp", r.otlpEndpoint, "sample", r.otlpSample)
+	}
+	if r.captureBodies != "" {
+		capture, err := rpcclient.NewBodyCapture(r.captureBodies, r.captureMaxBytes)
+		if err != nil {
+			return err
+		}
+		r.onClose(capture.Close)
+		r.tester.Capture = capture
+		r.logger.Info("capturing response bodies", "dir", r.captureBodies)
+	}
+	return nil
+}
+
+// openSinks opens the outputs that are sent every run's results: -store,
+// -results-out, -junit, -report-md, -report-html and -statsd.
+func (r *benchRun) openSinks() error {
+	if r.storePath != "" {
+		db, err := store.Open(os.ExpandEnv(r.storePath))
+		if err != nil {
+			return err
+		}
+		run, err := db.BeginRun(store.RunConfig{
+			Command:  r.cmd.Name(),
+			Endpoint: r.endpoint,
+			Chain:    r.chain,
+			Config:   runConfig(r.cmd, r.args),
+		})
+		if err != nil {
+			db.Close()
+			return err
+		}
+		r.stored, r.db = run, db
+		r.addSink(func(s *bench.JSONRPCTester) bench.ResultSink { return run.Sink(s.Label) })
+		r.logger.Info("recording run", "store", rpcclient.RedactURL(r.storePath), "run", run.ID)
+	}
+	if r.resultsOut != "" {
+		raw, err := report.CreateRawResults(r.resultsOut)
+		if err != nil {
+			return err
+		}
+		r.onClose(func() error {
+			if err := raw.Close(); err != nil {
+				return fmt.Errorf("write %s: %w", r.resultsOut, err)
+			}
+			return nil
+		})
+		r.addSink(func(s *bench.JSONRPCTester) bench.ResultSink { return raw.Sink(s.Label, s.Endpoint) })
+	}
+	if r.junitOut != "" {
+		junit := report.NewJUnitReport(r.sloP99, r.sloErrorRate)
+		r.onClose(func() error { return junit.WriteFile(r.junitOut) })
+		r.addSink(func(s *bench.JSONRPCTester) bench.ResultSink { return junit.Sink(s.Label, s.Endpoint) })
+	}
+	if r.reportMarkdown != "" {
+		markdown := report.NewMarkdownReport()
+		r.onClose(func() error { return markdown.WriteFile(r.reportMarkdown) })
+		r.addSink(func(s *bench.JSONRPCTester) bench.ResultSink { return markdown.Sink(s.Label, s.Endpoint) })
+	}
+	if r.reportHTML != "" || r.dest != nil {
+		r.html = report.NewHTMLReport()
+		if r.reportHTML != "" {
+			r.onClose(func() error { return r.html.WriteFile(r.reportHTML) })
+		}
+		r.addSink(func(s *bench.JSONRPCTester) bench.ResultSink { return r.html.Sink(s.Label, s.Endpoint) })
+	}
+	if r.statsdAddr != "" {
+		var tags []string
+		if r.statsdTags != "" {
+			tags = strings.Split(r.statsdTags, ",")
+		}
+		statsd, err := report.DialStatsD(r.statsdAddr, r.statsdPrefix, !r.statsdPlain, tags)
+		if err != nil {
+			return err
+		}
+		r.onClose(func() error {
+			if err := statsd.Close(); err != nil {
+				r.logger.Warn("some metrics could not be sent", "statsd", r.statsdAddr, "error", err)
+			}
+			return nil
+		})
+		r.addSink(func(s *bench.JSONRPCTester) bench.ResultSink { return statsd.Sink(s.Label, s.Endpoint) })
+	}
+	return nil
+}
+
+// addSink has every run of the tester send its results to the sink
+// newSink returns, too.
+func (r *benchRun) addSink(newSink func(s *bench.JSONRPCTester) bench.ResultSink) {
+	newSinks := r.tester.NewSinks
+	r.tester.NewSinks = func(s *bench.JSONRPCTester) []bench.ResultSink {
+		return append(newSinks(s), newSink(s))
+	}
+}
+
+// finishStored records the end of the -store run, failed if err is set.
+func (r *benchRun) finishStored(err error) {
+	if err := r.stored.Finish(err); err != nil {
+		r.logger.Warn("could not record the end of the run", "error", err)
+	}
+	if err := r.stored.Err(); err != nil {
+		r.logger.Warn("some requests could not be stored", "error", err)
+	}
+	r.db.Close()
+}
+
+// solanaOnlyFlags are the modes that call Solana methods of their own, and
+// so cannot run against other chains.
+var solanaOnlyFlags = []string{
+	"send-tx", "jito", "simulate", "blockhash-probe", "health-monitor", "finality", "ws-freshness", "ws-subscribe",
+	"batch-compare", "paginate", "das-paginate", "archive-probe", "encoding-compare", "geyser", "chains",
+	"cluster-nodes", "nodes", "cluster-context", "fee-track",
+	"support-matrix", "version-check",
+}
+
+// unplannedFlags are the modes -dry-run can't plan, since they don't run
+// the method benchmark.
+var unplannedFlags = []string{
+	"batch", "send-tx", "jito", "simulate", "blockhash-probe", "finality", "ws-freshness", "ws-subscribe",
+	"fee-track", "health-monitor", "batch-compare", "paginate", "das-paginate", "archive-probe",
+	"encoding-compare", "cluster-nodes", "nodes", "version-check", "support-matrix", "per-ip",
+	"find-capacity", "find-rate-limit", "failover", "geyser", "chains", "chains-file", "replay",
+}
+
+// firstSetFlag returns the first of names set on cmd's command line or
+// by the environment, or "".
+func firstSetFlag(cmd *cobra.Command, names []string) string {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func writeResults(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	return report.WriteJSONFile(path, v)
+}
+
+// printResults writes v to -output, if set, and prints it under title.
+func (r *benchRun) printResults(title string, v interface{}) error {
+	if err := writeResults(r.output, v); err != nil {
+		return err
+	}
+	return r.printJSON(title, v)
+}
+
+// printBaselineComparison prints how current compares to the -baseline
+// run, if there is one.
+func (r *benchRun) printBaselineComparison(current *bench.BenchmarkStats) error {
+	if r.baseline == "" {
+		return nil
+	}
+	return r.printJSON("Go Baseline Comparison", bench.CompareToBaseline(current, r.baseline, r.baselineSamples))
+}
+
+func writeTimeSeries(path string, buckets []bench.TimeBucket) error {
+	if path == "" {
+		return nil
+	}
+	return report.WriteJSONLinesFile(path, buckets)
+}
+
+// writeInflux writes the time series of stats as InfluxDB line protocol
+// to target, a file or write URL, tagged with the endpoint, chain and
+// host.
+func writeInflux(target, token, endpoint, chain string, stats *bench.BenchmarkStats) error {
+	if target == "" {
+		return nil
+	}
+	host, _ := os.Hostname()
+	tags := map[string]string{
+		"endpoint": rpcclient.RedactURL(endpoint),
+		"chain":    chain,
+		"host":     host,
+	}
+	return report.WriteInflux(target, token, bench.InfluxLines(stats, tags))
+}
+
+// runNotifier posts a run's summary to a webhook, with -notify.
+type runNotifier struct {
+	webhook  string
+	onBreach bool
+	// p99 and errorRate are the SLO; baseline and baselineSamples the
+	// -baseline run, if any.
+	p99             time.Duration
+	errorRate       float64
+	baseline        string
+	baselineSamples []int64
+}
+
+// notify posts the summary of stats, a run of endpoint, unless n only
+// posts breaches and the run met its SLO. latency is the summary whose
+// p99 the SLO applies to, and throughput the achieved rate, if known.
+func (n *runNotifier) notify(endpoint string, stats *bench.BenchmarkStats, latency bench.LatencyStats, throughput float64) error {
+	if n.webhook == "" {
+		return nil
+	}
+	summary := &report.RunSummary{
+		Title:       "Benchmark of " + rpcclient.RedactURL(endpoint),
+		Endpoint:    endpoint,
+		Requests:    stats.TotalRequests,
+		SuccessRate: stats.SuccessRate,
+		Latency:     latency,
+		Throughput:  throughput,
+		Breaches:    bench.SLOBreaches(stats, latency, n.p99, n.errorRate),
+	}
+	if n.onBreach && len(summary.Breaches) == 0 {
+		return nil
+	}
+	if len(summary.Breaches) > 0 {
+		summary.Title += " breached its SLO"
+	}
+	if n.baseline != "" {
+		summary.Baseline = &bench.CompareToBaseline(stats, n.baseline, n.baselineSamples).Significance
+		summary.BaselinePath = filepath.Base(n.baseline)
+	}
+	return report.Notify(n.webhook, summary)
+}
+
+// uploadArtifacts uploads the run's config, summary and HTML report, and
+// those of the files it was asked to write that exist, to -upload.
+func (r *benchRun) uploadArtifacts(ctx context.Context) error {
+	files := []string{r.output, r.resultsOut, r.junitOut, r.reportMarkdown, r.timeSeriesOut, r.harFile, r.record}
+	if !strings.Contains(r.influxTarget, "://") {
+		files = append(files, r.influxTarget)
+	}
+	if r.soak > 0 {
+		files = append(files, r.soakOut)
+	}
+	r.logger.Info("uploading artifacts", "to", r.dest.Location())
+	if err := r.dest.PutJSON(ctx, "config.json", runConfig(r.cmd, r.args)); err != nil {
+		return err
+	}
+	if r.summary != nil {
+		if err := r.dest.PutJSON(ctx, "summary.json", r.summary); err != nil {
+			return err
+		}
+	}
+	html, err := r.html.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := r.dest.PutBytes(ctx, "report.html", "text/html; charset=utf-8", html); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if err := r.dest.PutFile(ctx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secretFlags are the flags whose values are credentials as a whole,
+// such as a webhook URL with its secret in the path.
+var secretFlags = map[string]bool{"influx-token": true, "notify": true, "pagerduty-key": true, "opsgenie-key": true, "agent-token": true}
+
+// runConfig returns what a run recorded with -store was started with: its
+// arguments and the flags set, URLs and secrets redacted. -header values
+// are left out, as its String shows only header names.
+func runConfig(cmd *cobra.Command, args []string) map[string]interface{} {
+	flags := map[string]string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if secretFlags[f.Name] {
+			flags[f.Name] = "REDACTED"
+			return
+		}
+		flags[f.Name] = redactValue(f.Value.String())
+	})
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = redactValue(arg)
+	}
+	return map[string]interface{}{"args": redacted, "flags": flags}
+}
+
+// redactValue redacts the credentials in value if it is a URL.
+func redactValue(value string) string {
+	if strings.Contains(value, "://") {
+		return rpcclient.RedactURL(value)
+	}
+	return value
+}
+
+// printJSON prints v under title, keeping the first results printed as
+// the run's summary, also recorded with -store.
+func (r *benchRun) printJSON(title string, v interface{}) error {
+	if err := report.PrintJSON(title, v); err != nil {
+		return err
+	}
+	if r.summary == nil {
+		r.summary = v
+	}
+	if r.stored != nil {
+		if err := r.stored.Summarize(title, v); err != nil {
+			r.logger.Warn("could not record the run's summary", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"solana-rpc-performance-golang/mockserver"
+	"solana-rpc-performance-golang/report"
+)
+
+// newMockServerCommand returns the mockserver command.
+func newMockServerCommand() *cobra.Command {
+	fs := flag.NewFlagSet("mockserver", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8899", "address to listen on")
+	latency := fs.Duration("latency", 20*time.Millisecond, "artificial latency added to every response")
+	jitter := fs.Duration("jitter", 5*time.Millisecond, "random variation of -latency, plus or minus")
+	errorRate := fs.Float64("error-rate", 0, "fraction of requests (0-1) answered with an internal error")
+	rateLimit := fs.Float64("rate-limit", 0, "answer requests beyond this many per second with HTTP 429, as a throttling provider would (0 disables)")
+	burst := fs.Int("burst", 10, "with -rate-limit, how many requests may arrive at once before it applies")
+	seed := fs.Int64("seed", 0, "random seed for latency and errors (default: time-based)")
+	tlsCert := fs.String("tls-cert", "", "serve HTTPS with this PEM certificate (needs -tls-key)")
+	tlsKey := fs.String("tls-key", "", "PEM private key for -tls-cert")
+	logFormat := fs.String("log-format", "text", "log output format: text or json")
+
+	cmd := &cobra.Command{
+		Use:          "mockserver",
+		Short:        "Serve a mock Solana RPC endpoint for offline runs",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+	cmd.Flags().AddGoFlagSet(fs)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		logger, err := report.NewLogger(os.Stderr, *logFormat, slog.LevelInfo)
+		if err != nil {
+			return err
+		}
+		slog.SetDefault(logger)
+
+		if *errorRate < 0 || *errorRate > 1 {
+			return errors.New("-error-rate must be between 0 and 1")
+		}
+		if *rateLimit < 0 || *burst < 1 {
+			return errors.New("-rate-limit must not be negative and -burst must be positive")
+		}
+
+		// Serve prior-knowledge HTTP/2 (h2c) too, so -proto h2 can be tried
+		// without TLS.
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		server := &http.Server{
+			Addr: *addr,
+			Handler: mockserver.New(mockserver.Config{
+				Latency:   *latency,
+				Jitter:    *jitter,
+				ErrorRate: *errorRate,
+				Seed:      *seed,
+				RateLimit: *rateLimit,
+				Burst:     *burst,
+			}),
+			Protocols: protocols,
+		}
+		scheme := "http"
+		if *tlsCert != "" {
+			scheme = "https"
+		}
+		slog.Info("mock Solana RPC listening", "url", scheme+"://"+*addr,
+			"latency", *latency, "jitter", *jitter, "error_rate", *errorRate, "rate_limit", *rateLimit)
+		if *tlsCert != "" {
+			return server.ListenAndServeTLS(*tlsCert, *tlsKey)
+		}
+		return server.ListenAndServe()
+	}
+	return cmd
+}
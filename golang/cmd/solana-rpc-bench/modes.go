@@ -0,0 +1,678 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"solana-rpc-performance-golang/alert"
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/distributed"
+	"solana-rpc-performance-golang/geyser"
+	"solana-rpc-performance-golang/providers/das"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// run runs the mode r's flags select, the method benchmark if none. The
+// first set of these wins.
+func (r *benchRun) run(ctx context.Context) error {
+	switch {
+	case r.coordinatorAddr != "":
+		return r.runDistributed()
+	case r.batchFile != "":
+		return r.runBatch(ctx)
+	}
+
+	// The endpoint argument goes unused with -geyser and -nodes.
+	if r.geyserEndpoint == "" && r.nodesFile == "" {
+		if err := r.verifyEndpoint(ctx); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case r.jito != "":
+		return r.runJito(ctx)
+	case r.sendTx > 0:
+		return r.runSendTx(ctx)
+	case r.clusterNodes || r.nodesFile != "":
+		return r.runNodes(ctx)
+	case r.versionCheck:
+		return r.runVersionCheck(ctx)
+	case r.supportMatrix:
+		return r.runSupportMatrix(ctx)
+	case r.perIP:
+		return r.runPerIP(ctx)
+	case r.encodingCompare > 0:
+		return r.runEncodingCompare(ctx)
+	case r.archiveProbe:
+		return r.runArchiveProbe(ctx)
+	case r.paginate != "":
+		return r.runPaginate(ctx)
+	case r.dasPaginate != "":
+		return r.runDASPaginate(ctx)
+	case r.batchCompare > 0:
+		return r.runBatchCompare(ctx)
+	case r.blockhashProbe > 0:
+		return r.runBlockhashProbe(ctx)
+	case r.finality > 0:
+		return r.runFinality(ctx)
+	case r.feeTrack > 0:
+		return r.runFeeTracker(ctx)
+	case r.wsFreshness > 0:
+		return r.runWSFreshness(ctx)
+	case r.wsSubscribe > 0:
+		return r.runWSSubscribe(ctx)
+	case r.healthMonitor > 0:
+		return r.runHealthMonitor(ctx)
+	case r.simulate > 0:
+		return r.runSimulate(ctx)
+	case r.findCapacity:
+		return r.runFindCapacity(ctx)
+	case r.findRateLimit:
+		return r.runFindRateLimit(ctx)
+	case r.geyserEndpoint != "":
+		return r.runGeyser(ctx)
+	case r.chains != "" || r.chainsFile != "":
+		return r.runChains(ctx)
+	case r.replay != "":
+		return r.runReplay(ctx)
+	case r.compare != "":
+		return r.runCompare(ctx)
+	case r.failover != "":
+		return r.runFailover(ctx)
+	case r.soak > 0:
+		return r.runSoak(ctx)
+	case r.spike != "":
+		return r.runSpike(ctx)
+	case r.ramp != "":
+		return r.runRamp(ctx)
+	case r.rate > 0:
+		return r.runOpenLoop(ctx)
+	}
+	return r.runBenchmark(ctx)
+}
+
+// verifyEndpoint checks the endpoint is on the cluster -expect-cluster
+// names, and takes its fingerprint.
+func (r *benchRun) verifyEndpoint(ctx context.Context) error {
+	if r.chain == bench.ChainSolana {
+		if r.clusterMismatch != "abort" && r.clusterMismatch != "warn" {
+			return fmt.Errorf("unknown -cluster-mismatch %q (want abort or warn)", r.clusterMismatch)
+		}
+		if err := r.tester.VerifyCluster(ctx, r.expectCluster, r.clusterMismatch == "warn"); err != nil {
+			return err
+		}
+	}
+	return r.tester.TakeFingerprint(ctx)
+}
+
+// loadConfig returns the open-loop load of -rate, -concurrency and
+// -arrival, lasting runFor.
+func (r *benchRun) loadConfig(runFor time.Duration) bench.LoadConfig {
+	return bench.LoadConfig{
+		Rate:        r.rate,
+		Duration:    runFor,
+		Concurrency: r.concurrency,
+		Arrival:     r.arrival,
+	}
+}
+
+func (r *benchRun) runDryRun(ctx context.Context) error {
+	if name := firstSetFlag(r.cmd, unplannedFlags); name != "" {
+		return fmt.Errorf("-dry-run only plans method benchmarks, not -%s", name)
+	}
+	plan, err := r.tester.Plan(ctx, r.iterations)
+	if err != nil {
+		return err
+	}
+	load := r.loadConfig(r.duration)
+	// The same precedence as run.
+	switch {
+	case r.compare != "":
+		plan.SetCompare(r.compare)
+	case r.soak > 0:
+		plan.SetLoad(bench.PlanSoak, []bench.LoadStep{{Rate: r.rate, Duration: r.soak}}, load)
+	case r.spike != "":
+		burst, err := bench.ParseSpike(r.spike)
+		if err != nil {
+			return err
+		}
+		if load.Duration <= 0 {
+			load.Duration = time.Minute
+		}
+		plan.SetLoad(bench.PlanSpike, burst.Steps(load), load)
+	case r.ramp != "":
+		steps, err := bench.ParseRamp(r.ramp)
+		if err != nil {
+			return err
+		}
+		plan.SetLoad(bench.PlanRamp, steps, load)
+	case r.rate > 0:
+		if r.duration <= 0 {
+			load.Requests = r.iterations * len(r.tester.Methods)
+		}
+		plan.SetLoad(bench.PlanOpenLoop, []bench.LoadStep{{Rate: r.rate, Duration: r.duration}}, load)
+	}
+	return r.printResults("Go Dry Run", plan)
+}
+
+func (r *benchRun) runDistributed() error {
+	if r.tui {
+		return errors.New("-tui cannot be combined with -coordinator")
+	}
+	if name := firstSetFlag(r.cmd, append(unplannedFlags, "compare")); name != "" {
+		return fmt.Errorf("-coordinator runs only method benchmarks, not -%s", name)
+	}
+	if r.coordinatorCert == "" || r.coordinatorKey == "" {
+		return errors.New("-coordinator needs -coordinator-cert and -coordinator-key: agents connect only over TLS")
+	}
+	config, err := distributed.ServerTLS(r.coordinatorCert, r.coordinatorKey)
+	if err != nil {
+		return err
+	}
+	job := distributed.Job{Args: agentJobArgs(r.cmd, os.Args[1:], r.baseline != "")}
+	if err := job.Check(); err != nil {
+		return fmt.Errorf("-coordinator: %w", err)
+	}
+	results, err := runCoordinator(r.tester, r.coordinatorAddr, r.agents, bench.SplitList(r.regions), config, os.ExpandEnv(r.agentToken), job)
+	if results != nil {
+		if err := r.printResults("Go Distributed Results", results); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.printBaselineComparison(results.Stats); err != nil {
+		return err
+	}
+	return r.notifier.notify(r.endpoint, results.Stats, results.Stats.Latency, 0)
+}
+
+func (r *benchRun) runBatch(ctx context.Context) error {
+	if r.tui {
+		return errors.New("-tui cannot be combined with -batch")
+	}
+	return bench.RunBatch(ctx, r.batchFile, r.iterations, r.parallel, r.tester)
+}
+
+func (r *benchRun) runJito(ctx context.Context) error {
+	cfg := bench.JitoConfig{
+		BlockEngine:  r.jito,
+		TipRequests:  r.jitoTipRequests,
+		Bundles:      r.jitoBundles,
+		Simulate:     r.jitoSimulate,
+		Lamports:     r.txLamports,
+		Tip:          r.jitoTip,
+		Interval:     r.jitoInterval,
+		AllowMainnet: r.allowMainnet,
+	}
+	if cfg.Bundles > 0 {
+		keypair, recipient, err := r.loadTransfer()
+		if err != nil {
+			return err
+		}
+		cfg.Keypair, cfg.Recipient = keypair, recipient
+	}
+	jitoStats, err := r.tester.RunJitoBenchmark(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Jito Results", jitoStats)
+}
+
+// loadTransfer loads the -keypair transfers are signed with, and their
+// recipient: -tx-to, or the keypair itself.
+func (r *benchRun) loadTransfer() (ed25519.PrivateKey, rpcclient.PublicKey, error) {
+	keypair, err := rpcclient.LoadKeypair(r.keypairPath)
+	if err != nil {
+		return nil, rpcclient.PublicKey{}, err
+	}
+	recipient := rpcclient.PublicKeyOf(keypair)
+	if r.txTo != "" {
+		if recipient, err = rpcclient.ParsePublicKey(r.txTo); err != nil {
+			return nil, rpcclient.PublicKey{}, err
+		}
+	}
+	return keypair, recipient, nil
+}
+
+func (r *benchRun) runSendTx(ctx context.Context) error {
+	keypair, recipient, err := r.loadTransfer()
+	if err != nil {
+		return err
+	}
+	txStats, err := r.tester.RunSendTransactionBenchmark(ctx, bench.TxBenchmarkConfig{
+		Submissions:      r.sendTx,
+		Keypair:          keypair,
+		Recipient:        recipient,
+		Lamports:         r.txLamports,
+		Interval:         r.txInterval,
+		Commitment:       r.commitment,
+		ConfirmTimeout:   r.confirmTimeout,
+		PollInterval:     r.pollInterval,
+		BlockhashRefresh: r.blockhashRefresh,
+		SkipPreflight:    r.skipPreflight,
+		AllowMainnet:     r.allowMainnet,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go sendTransaction Results", txStats)
+}
+
+func (r *benchRun) runNodes(ctx context.Context) error {
+	if r.tui {
+		return errors.New("-tui cannot be combined with -cluster-nodes or -nodes")
+	}
+	cfg := bench.NodesConfig{
+		Limit:        r.nodesLimit,
+		Iterations:   r.iterations,
+		Parallel:     r.nodesParallel,
+		ProbeTimeout: r.nodesProbeTimeout,
+	}
+	if r.nodesFile != "" {
+		var err error
+		if cfg.Nodes, err = bench.LoadNodeList(r.nodesFile); err != nil {
+			return err
+		}
+	}
+	nodeStats, err := r.tester.RunNodesBenchmark(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := r.printResults("Go Per-Node Results", nodeStats); err != nil {
+		return err
+	}
+	fmt.Println("\n=== Node Ranking ===")
+	return nodeStats.WriteTable(os.Stdout)
+}
+
+func (r *benchRun) runVersionCheck(ctx context.Context) error {
+	versionStats, err := r.tester.RunVersionCheck(ctx)
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Versioned Transaction Results", versionStats)
+}
+
+func (r *benchRun) runSupportMatrix(ctx context.Context) error {
+	endpoints := append([]string{r.endpoint}, bench.SplitList(r.supportEndpoints)...)
+	matrix, err := r.tester.RunSupportMatrix(ctx, endpoints, r.supportInterval)
+	if err != nil {
+		return err
+	}
+	if err := r.printResults("Go Method Support Results", matrix); err != nil {
+		return err
+	}
+	fmt.Println("\n=== Method Support ===")
+	return matrix.WriteTable(os.Stdout)
+}
+
+func (r *benchRun) runPerIP(ctx context.Context) error {
+	ipStats, err := r.tester.RunPerIPBenchmark(ctx, r.iterations)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Per-IP Results", ipStats)
+}
+
+func (r *benchRun) runEncodingCompare(ctx context.Context) error {
+	encStats, err := r.tester.RunEncodingComparison(ctx, r.encodingCompare, bench.SplitList(r.encodingMethods))
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Encoding Comparison Results", encStats)
+}
+
+func (r *benchRun) runArchiveProbe(ctx context.Context) error {
+	archiveStats, err := r.tester.RunArchiveProbe(ctx)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Archive Depth Results", archiveStats)
+}
+
+func (r *benchRun) runPaginate(ctx context.Context) error {
+	pageStats, err := r.tester.RunPaginationBenchmark(ctx, r.paginate, r.pageDepth, r.pageLimit)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Pagination Results", pageStats)
+}
+
+func (r *benchRun) runDASPaginate(ctx context.Context) error {
+	pageStats, err := das.RunPagination(ctx, r.tester, r.dasPaginateMethod, r.dasPaginate, r.pageDepth, r.dasLimit)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go DAS Pagination Results", pageStats)
+}
+
+func (r *benchRun) runBatchCompare(ctx context.Context) error {
+	batchStats, err := r.tester.RunBatchingComparison(ctx, r.batchCompare)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Batching Comparison Results", batchStats)
+}
+
+func (r *benchRun) runBlockhashProbe(ctx context.Context) error {
+	bhStats, err := r.tester.RunBlockhashProbe(ctx, bench.BlockhashProbeConfig{
+		Probes:        r.blockhashProbe,
+		Interval:      r.blockhashInterval,
+		CheckInterval: r.validityInterval,
+		Timeout:       r.validityTimeout,
+		Commitment:    r.commitment,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Blockhash Validity Results", bhStats)
+}
+
+func (r *benchRun) runFinality(ctx context.Context) error {
+	finalityStats, err := r.tester.RunFinalityProbe(ctx, bench.FinalityConfig{
+		Duration:     r.finality,
+		PollInterval: r.finalityInterval,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Finality Results", finalityStats)
+}
+
+func (r *benchRun) runFeeTracker(ctx context.Context) error {
+	feeStats, err := r.tester.RunFeeTracker(ctx, bench.FeeTrackerConfig{
+		Duration: r.feeTrack,
+		Interval: r.feeInterval,
+		Accounts: bench.SplitList(r.feeAccounts),
+	})
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Prioritization Fee Results", feeStats)
+}
+
+func (r *benchRun) runWSFreshness(ctx context.Context) error {
+	freshnessStats, err := r.tester.RunFreshnessComparison(ctx, bench.FreshnessConfig{
+		WSURL:        r.wsURL,
+		Duration:     r.wsFreshness,
+		PollInterval: r.wsPollInterval,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go WebSocket Freshness Results", freshnessStats)
+}
+
+func (r *benchRun) runWSSubscribe(ctx context.Context) error {
+	subscriptionStats, err := r.tester.RunSubscriptionBenchmark(ctx, bench.SubscriptionConfig{
+		WSURL:      r.wsURL,
+		Method:     r.wsMethod,
+		Target:     r.wsTarget,
+		Commitment: r.commitment,
+		Duration:   r.wsSubscribe,
+		Reconnects: r.wsReconnects,
+		DropEvery:  r.wsDropEvery,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Subscription Results", subscriptionStats)
+}
+
+func (r *benchRun) runHealthMonitor(ctx context.Context) error {
+	cfg := bench.HealthConfig{
+		Interval:   r.healthInterval,
+		Duration:   r.healthMonitor,
+		AlertAfter: r.alertAfter,
+		LatencySLO: r.alertLatency,
+	}
+	switch {
+	case r.pagerDutyKey != "" && r.opsgenieKey != "":
+		return errors.New("-pagerduty-key and -opsgenie-key cannot be combined")
+	case r.pagerDutyKey != "":
+		pagerDuty := alert.NewPagerDuty(os.ExpandEnv(r.pagerDutyKey))
+		if r.alertURL != "" {
+			pagerDuty.URL = r.alertURL
+		}
+		cfg.Alerter = pagerDuty
+	case r.opsgenieKey != "":
+		opsgenie := alert.NewOpsgenie(os.ExpandEnv(r.opsgenieKey))
+		if r.alertURL != "" {
+			opsgenie.URL = r.alertURL
+		}
+		cfg.Alerter = opsgenie
+	}
+	healthStats, err := r.tester.RunHealthMonitor(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Health Monitor Results", healthStats)
+}
+
+func (r *benchRun) runSimulate(ctx context.Context) error {
+	var tx []byte
+	var payer rpcclient.PublicKey
+	var err error
+	switch {
+	case r.simulateTx != "":
+		tx, err = bench.LoadSerializedTransaction(r.simulateTx)
+	case r.simulatePayer != "":
+		payer, err = rpcclient.ParsePublicKey(r.simulatePayer)
+	default:
+		var keypair ed25519.PrivateKey
+		keypair, err = rpcclient.LoadKeypair(r.keypairPath)
+		if err == nil {
+			payer = rpcclient.PublicKeyOf(keypair)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	simStats, err := r.tester.RunSimulationBenchmark(ctx, r.simulate, tx, payer)
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go simulateTransaction Results", simStats)
+}
+
+func (r *benchRun) runFindCapacity(ctx context.Context) error {
+	capacityStats, err := r.tester.RunCapacitySearch(ctx, bench.CapacityConfig{
+		TargetP99:     r.sloP99,
+		MaxErrorRate:  r.sloErrorRate,
+		MinRate:       r.minRate,
+		MaxRate:       r.maxRate,
+		ProbeDuration: r.probeDuration,
+		Concurrency:   r.concurrency,
+		Arrival:       r.arrival,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Capacity Results", capacityStats)
+}
+
+func (r *benchRun) runFindRateLimit(ctx context.Context) error {
+	limitStats, err := r.tester.RunRateLimitDiscovery(ctx, bench.RateLimitConfig{
+		MinRate:       r.minRate,
+		MaxRate:       r.maxRate,
+		ProbeDuration: r.probeDuration,
+		Cooldown:      r.cooldown,
+		MaxErrorRate:  r.sloErrorRate,
+		BurstRequests: r.burstRequests,
+		Concurrency:   r.concurrency,
+		Arrival:       r.arrival,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Rate Limit Results", limitStats)
+}
+
+func (r *benchRun) runGeyser(ctx context.Context) error {
+	geyserStats, err := geyser.Run(ctx, geyser.Config{
+		Endpoint:            r.geyserEndpoint,
+		Token:               r.geyserToken,
+		Streams:             geyser.ParseStreams(r.geyserStreams),
+		Accounts:            bench.SplitList(r.geyserAccounts),
+		Owners:              bench.SplitList(r.geyserOwners),
+		TransactionAccounts: bench.SplitList(r.geyserTxAccounts),
+		Commitment:          r.geyserCommitment,
+		Duration:            r.geyserDuration,
+		Logger:              r.tester.Log(),
+		Stop:                r.tester.Stop,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Geyser Results", geyserStats)
+}
+
+func (r *benchRun) runChains(ctx context.Context) error {
+	var definitions []bench.Chain
+	var err error
+	if r.chainsFile != "" {
+		definitions, err = bench.LoadChains(r.chainsFile)
+	} else {
+		definitions, err = bench.BuiltinChains(r.chains)
+	}
+	if err != nil {
+		return err
+	}
+	chainStats, err := r.tester.RunChains(ctx, definitions, r.iterations)
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Chain Results", chainStats)
+}
+
+func (r *benchRun) runReplay(ctx context.Context) error {
+	replayStats, err := r.tester.RunReplay(ctx, r.replay, r.replaySpeed, r.concurrency)
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Replay Results", replayStats)
+}
+
+func (r *benchRun) runCompare(ctx context.Context) error {
+	comparison, err := r.tester.RunComparison(ctx, r.iterations, r.compare)
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Endpoint Comparison", comparison)
+}
+
+func (r *benchRun) runFailover(ctx context.Context) error {
+	// The run lasts -duration, the failure comes within it.
+	runFor := r.duration
+	if runFor <= 0 {
+		runFor = time.Minute
+	}
+	failoverStats, err := r.tester.RunFailover(ctx, bench.Failover{
+		Backup:    r.failover,
+		FailAfter: r.failPrimaryAfter,
+		Threshold: r.failoverAfter,
+	}, r.loadConfig(runFor))
+	if err != nil {
+		return err
+	}
+	return r.printResults("Go Failover Results", failoverStats)
+}
+
+func (r *benchRun) runSoak(ctx context.Context) error {
+	out, err := os.Create(r.soakOut)
+	if err != nil {
+		return err
+	}
+	soakStats, err := r.tester.RunSoak(ctx, r.loadConfig(r.soak), r.soakWindow, out)
+	if err := errors.Join(err, out.Close()); err != nil {
+		return err
+	}
+	return r.printJSON("Go Soak Results", soakStats)
+}
+
+func (r *benchRun) runSpike(ctx context.Context) error {
+	burst, err := bench.ParseSpike(r.spike)
+	if err != nil {
+		return err
+	}
+	// Baseline and recovery each last -duration.
+	phase := r.duration
+	if phase <= 0 {
+		phase = time.Minute
+	}
+	spikeStats, err := r.tester.RunSpike(ctx, burst, r.loadConfig(phase))
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Spike Results", spikeStats)
+}
+
+func (r *benchRun) runRamp(ctx context.Context) error {
+	steps, err := bench.ParseRamp(r.ramp)
+	if err != nil {
+		return err
+	}
+	rampStats, err := r.tester.RunRamp(ctx, steps, bench.LoadConfig{
+		Concurrency: r.concurrency,
+		Arrival:     r.arrival,
+	})
+	if err != nil {
+		return err
+	}
+	return r.printJSON("Go Ramp Results", rampStats)
+}
+
+func (r *benchRun) runOpenLoop(ctx context.Context) error {
+	cfg := r.loadConfig(r.duration)
+	if r.duration <= 0 {
+		cfg.Requests = r.iterations * len(r.tester.Methods)
+	}
+	loadStats, err := r.tester.RunOpenLoop(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := r.exportTimeSeries(&loadStats.BenchmarkStats); err != nil {
+		return err
+	}
+	if err := r.printResults("Go Open-Loop Results", loadStats); err != nil {
+		return err
+	}
+	if err := r.printBaselineComparison(&loadStats.BenchmarkStats); err != nil {
+		return err
+	}
+	return r.notifier.notify(r.endpoint, &loadStats.BenchmarkStats, loadStats.CorrectedLatency, loadStats.AchievedRate)
+}
+
+func (r *benchRun) runBenchmark(ctx context.Context) error {
+	stats, err := r.tester.RunBenchmark(ctx, r.iterations)
+	if err != nil {
+		return err
+	}
+	if err := r.exportTimeSeries(stats); err != nil {
+		return err
+	}
+	if err := r.printResults("Go RPC Performance Results", stats); err != nil {
+		return err
+	}
+	if err := r.printBaselineComparison(stats); err != nil {
+		return err
+	}
+	return r.notifier.notify(r.endpoint, stats, stats.Latency, 0)
+}
+
+// exportTimeSeries writes the time series of stats to -timeseries-out and
+// -influx, if set.
+func (r *benchRun) exportTimeSeries(stats *bench.BenchmarkStats) error {
+	if err := writeTimeSeries(r.timeSeriesOut, stats.TimeSeries); err != nil {
+		return err
+	}
+	return writeInflux(r.influxTarget, os.ExpandEnv(r.influxToken), r.endpoint, r.chain, stats)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"solana-rpc-performance-golang/report"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+const (
+	// jobSendInterval is how often the results a job process has written
+	// are passed on.
+	jobSendInterval = 250 * time.Millisecond
+	// jobStopTimeout is how long an interrupted job gets to finish its
+	// in-flight requests and write its results.
+	jobStopTimeout = 30 * time.Second
+)
+
+// runBenchProcess runs the bench command line args in a process of its
+// own, writing its results to output and passing the results of its
+// requests to observe as they come. Ending ctx interrupts the process,
+// which still writes what it measured.
+func runBenchProcess(ctx context.Context, args []string, output string, observe func([]rpcclient.Result)) error {
+	return runBenchProcessEnv(ctx, args, nil, output, observe)
+}
+
+// runBenchProcessEnv is runBenchProcess with env added to the process's
+// environment.
+func runBenchProcessEnv(ctx context.Context, args, env []string, output string, observe func([]rpcclient.Result)) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	results, err := os.CreateTemp("", "solana-rpc-bench-results-*.jsonl")
+	if err != nil {
+		return err
+	}
+	results.Close()
+	defer os.Remove(results.Name())
+
+	argv := append([]string{"bench"}, args...)
+	argv = append(argv, "--quiet", "--output", output, "--results-out", results.Name())
+	run := exec.CommandContext(ctx, exe, argv...)
+	if env != nil {
+		run.Env = append(os.Environ(), env...)
+	}
+	lastLine := &lastLineWriter{}
+	run.Stderr = io.MultiWriter(os.Stderr, lastLine)
+	run.Cancel = func() error { return run.Process.Signal(os.Interrupt) }
+	run.WaitDelay = jobStopTimeout
+	if err := run.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- run.Wait() }()
+
+	tail := &resultsTail{path: results.Name()}
+	ticker := time.NewTicker(jobSendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			observe(tail.read())
+			continue
+		case err = <-exited:
+		}
+		break
+	}
+	observe(tail.read())
+	if err != nil {
+		if line := lastLine.String(); line != "" {
+			return fmt.Errorf("bench: %w: %s", err, line)
+		}
+		return fmt.Errorf("bench: %w", err)
+	}
+	return nil
+}
+
+// lastLineWriter keeps the last line written to it, which for a failed
+// job is usually why.
+type lastLineWriter struct {
+	line, partial []byte
+}
+
+func (w *lastLineWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\n' {
+			if len(w.partial) < 1024 {
+				w.partial = append(w.partial, b)
+			}
+			continue
+		}
+		if len(bytes.TrimSpace(w.partial)) > 0 {
+			w.line = append(w.line[:0], w.partial...)
+		}
+		w.partial = w.partial[:0]
+	}
+	return len(p), nil
+}
+
+func (w *lastLineWriter) String() string {
+	if len(bytes.TrimSpace(w.partial)) > 0 {
+		return string(bytes.TrimSpace(w.partial))
+	}
+	return string(bytes.TrimSpace(w.line))
+}
+
+// resultsTail reads the results a running job appends to a JSON lines
+// file.
+type resultsTail struct {
+	path   string
+	offset int64
+}
+
+// read returns the results written in full since the last read.
+func (t *resultsTail) read() []rpcclient.Result {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	var results []rpcclient.Result
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// A partial line is read again next time.
+			return results
+		}
+		t.offset += int64(len(line))
+		var row report.RawRow
+		if json.Unmarshal(line, &row) == nil {
+			results = append(results, row.Result())
+		}
+	}
+}
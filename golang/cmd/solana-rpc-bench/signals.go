@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyInterrupt returns a channel that is closed on the first SIGINT or
+// SIGTERM, so benchmarks stop issuing requests and report what they have.
+// A second signal runs cleanup and exits immediately.
+func notifyInterrupt(cleanup func()) <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		slog.Warn("interrupted: finishing in-flight requests, interrupt again to exit immediately")
+		close(stop)
+		<-signals
+		if cleanup != nil {
+			cleanup()
+		}
+		os.Exit(130)
+	}()
+	return stop
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracingQueueSize is how many finished spans can wait for export
+	// before more are dropped, a few seconds of a fast open-loop run.
+	tracingQueueSize = 1 << 16
+	// tracingShutdownTimeout bounds the export of the last spans.
+	tracingShutdownTimeout = 10 * time.Second
+)
+
+// startTracing returns a tracer exporting a span per request to endpoint,
+// an OTLP/HTTP collector base URL such as http://localhost:4318, sampling
+// the fraction sample of requests. The returned function exports what is
+// left and shuts the exporter down. OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_SERVICE_NAME apply as usual.
+func startTracing(endpoint string, sample float64) (trace.Tracer, func() error, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, nil, fmt.Errorf("-otlp %q: want an http:// or https:// collector URL", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	}
+	ctx := context.Background()
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("otlp exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "solana-rpc-bench")),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, nil, fmt.Errorf("otlp resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithMaxQueueSize(tracingQueueSize)),
+		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(sample)),
+		sdktrace.WithResource(res))
+	shutdown := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	}
+	return provider.Tracer("solana-rpc-performance-golang/rpcclient"), shutdown, nil
+}
@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+)
+
+// maxRequestSize bounds a request body.
+const maxRequestSize = 1 << 20
+
+// startRequest is the body of POST /jobs.
+type startRequest struct {
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+// scheduleRequest is the body of PUT /schedules/{name}.
+type scheduleRequest struct {
+	Schedule string   `json:"schedule"`
+	Args     []string `json:"args"`
+}
+
+// Handler returns the daemon's HTTP API. If token isn't empty, every
+// request but GET /healthz and GET / must carry it as a bearer token.
+// Request bodies must be sent as application/json.
+//
+//	GET    /healthz           200 once the daemon is up
+//	GET    /jobs              every job's status, newest first
+//	POST   /jobs              start a job: {"name": ..., "args": [...]}
+//	GET    /jobs/{id}         a job's status and progress
+//	POST   /jobs/{id}/stop    interrupt a running job
+//	GET    /jobs/{id}/results a finished job's results file
+//	DELETE /jobs/{id}         forget a finished job and its results
+//	GET    /schedules         every schedule, its next run and last regression
+//	PUT    /schedules/{name}  schedule a job: {"schedule": ..., "args": [...]}
+//	DELETE /schedules/{name}  stop a schedule
+//	GET    /                  the dashboard, charting History
+//	GET    /history/series    History's completed runs, for the dashboard
+//
+// The dashboard page holds no data, and fetches the series with the
+// token given in its URL's fragment, #token=..., which browsers don't
+// send on, so that it can be shared as a link.
+func (d *Daemon) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Jobs())
+	})
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		var req startRequest
+		if code, err := decodeRequest(w, r, &req); err != nil {
+			writeError(w, code, err)
+			return
+		}
+		status, err := d.Start(req.Name, req.Args)
+		if err != nil {
+			writeError(w, requestStatusOf(err), err)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+status.ID)
+		writeJSON(w, http.StatusCreated, status)
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		status, err := d.Status(r.PathValue("id"))
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("POST /jobs/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		status, err := d.Stop(r.PathValue("id"))
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, status)
+	})
+	mux.HandleFunc("GET /jobs/{id}/results", func(w http.ResponseWriter, r *http.Request) {
+		path, err := d.Results(r.PathValue("id"))
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, http.StatusNotFound, errors.New("the job wrote no results"))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	mux.HandleFunc("DELETE /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := d.Delete(r.PathValue("id")); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /{$}", d.serveDashboard)
+	mux.HandleFunc("GET /history/series", d.serveSeries)
+	mux.HandleFunc("GET /schedules", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Schedules())
+	})
+	mux.HandleFunc("PUT /schedules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var req scheduleRequest
+		if code, err := decodeRequest(w, r, &req); err != nil {
+			writeError(w, code, err)
+			return
+		}
+		status, err := d.Schedule(ScheduledJob{Name: r.PathValue("name"), Schedule: req.Schedule, Args: req.Args})
+		if err != nil {
+			writeError(w, requestStatusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("DELETE /schedules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if err := d.Unschedule(r.PathValue("name")); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		open := r.Method == http.MethodGet && (r.URL.Path == "/healthz" || r.URL.Path == "/")
+		if !open && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("wrong or missing bearer token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// decodeRequest decodes r's JSON body into v, returning the status to
+// answer with if it can't: a body that isn't declared as JSON is refused,
+// so that a browser can't be made to send one from a plain form.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) (int, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return http.StatusUnsupportedMediaType, errors.New("the request body must be sent as Content-Type: application/json")
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(v); err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrNoSchedule):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRunning):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// requestStatusOf is the status for a job or schedule that wasn't
+// started: the request was bad, unless the daemon is going away.
+func requestStatusOf(err error) int {
+	if errors.Is(err, errClosed) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
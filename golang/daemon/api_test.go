@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// newTestDaemon returns a daemon whose jobs finish at once, writing no
+// results.
+func newTestDaemon(t *testing.T) *Daemon {
+	d := &Daemon{
+		Dir: t.TempDir(),
+		Run: func(ctx context.Context, args []string, output string, observe func([]rpcclient.Result)) error {
+			return nil
+		},
+	}
+	t.Cleanup(d.Shutdown)
+	return d
+}
+
+func TestHandlerAuth(t *testing.T) {
+	handler := newTestDaemon(t).Handler("secret")
+	tests := []struct {
+		method, path, auth string
+		want               int
+	}{
+		{method: "GET", path: "/healthz", want: http.StatusOK},
+		{method: "GET", path: "/", want: http.StatusOK},
+		{method: "GET", path: "/jobs", want: http.StatusUnauthorized},
+		{method: "GET", path: "/jobs", auth: "Bearer wrong", want: http.StatusUnauthorized},
+		{method: "GET", path: "/jobs", auth: "Bearer secret", want: http.StatusOK},
+		{method: "GET", path: "/jobs?token=secret", want: http.StatusUnauthorized},
+		{method: "GET", path: "/history/series?token=secret", want: http.StatusUnauthorized},
+		{method: "POST", path: "/?token=secret", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q = %d, want %d", tt.method, tt.path, tt.auth, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestHandlerRequiresJSON(t *testing.T) {
+	handler := newTestDaemon(t).Handler("")
+	body := `{"args": ["https://rpc.example.com"]}`
+	tests := []struct {
+		method, path, contentType string
+		want                      int
+	}{
+		{method: "POST", path: "/jobs", contentType: "application/json", want: http.StatusCreated},
+		{method: "POST", path: "/jobs", contentType: "application/json; charset=utf-8", want: http.StatusCreated},
+		{method: "POST", path: "/jobs", want: http.StatusUnsupportedMediaType},
+		{method: "POST", path: "/jobs", contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{method: "POST", path: "/jobs", contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+		{method: "PUT", path: "/schedules/nightly", contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s as %q = %d, want %d: %s", tt.method, tt.path, tt.contentType, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+func TestDaemonForgetsOldestFinishedJobs(t *testing.T) {
+	d := newTestDaemon(t)
+	d.KeepFinished = 2
+	release := make(chan struct{})
+	d.Run = func(ctx context.Context, args []string, output string, observe func([]rpcclient.Result)) error {
+		if args[0] == "running" {
+			<-release
+		}
+		return nil
+	}
+	defer close(release)
+	running, err := d.start("", []string{"running"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := range 4 {
+		j, err := d.start("", []string{fmt.Sprint(i)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-j.done
+		ids = append(ids, j.status.ID)
+		// Finish times tell the jobs apart.
+		time.Sleep(time.Millisecond)
+	}
+	for i, id := range ids {
+		_, err := d.Status(id)
+		if kept := i >= 2; kept != (err == nil) {
+			t.Errorf("job %d: Status = %v, want kept %v", i, err, kept)
+		}
+	}
+	if _, err := d.Status(running.status.ID); err != nil {
+		t.Errorf("running job forgotten: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+		err      string
+	}{
+		{field: "*", min: 0, max: 5, want: []int{0, 1, 2, 3, 4, 5}},
+		{field: "3", min: 0, max: 59, want: []int{3}},
+		{field: "1-4", min: 0, max: 59, want: []int{1, 2, 3, 4}},
+		{field: "*/15", min: 0, max: 59, want: []int{0, 15, 30, 45}},
+		{field: "10-20/5", min: 0, max: 59, want: []int{10, 15, 20}},
+		{field: "50/5", min: 0, max: 59, want: []int{50, 55}},
+		{field: "1,3,5-6", min: 0, max: 59, want: []int{1, 3, 5, 6}},
+		{field: "1-31/10", min: 1, max: 31, want: []int{1, 11, 21, 31}},
+		{field: "0", min: 1, max: 31, err: "outside 1-31"},
+		{field: "60", min: 0, max: 59, err: "outside 0-59"},
+		{field: "5-1", min: 0, max: 59, err: "outside"},
+		{field: "*/0", min: 0, max: 59, err: "bad step"},
+		{field: "*/x", min: 0, max: 59, err: "bad step"},
+		{field: "mon", min: 0, max: 7, err: "bad value"},
+		{field: "1-x", min: 0, max: 59, err: "bad value"},
+		{field: "", min: 0, max: 59, err: "bad value"},
+	}
+	for _, tt := range tests {
+		got, err := parseCronField(tt.field, tt.min, tt.max)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseCronField(%q) = %v, want an error containing %q", tt.field, err, tt.err)
+			}
+			continue
+		}
+		var want uint64
+		for _, v := range tt.want {
+			want |= 1 << v
+		}
+		if err != nil || got != want {
+			t.Errorf("parseCronField(%q) = %b, %v, want %b", tt.field, got, err, want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{spec: "* * * *", err: "want 5 fields"},
+		{spec: "* * * * * *", err: "want 5 fields"},
+		{spec: "@yearly", err: "want 5 fields"},
+		{spec: "@every 30s", err: "at least a minute apart"},
+		{spec: "@every soon", err: "invalid duration"},
+		{spec: "* 24 * * *", err: "hour"},
+		{spec: "* * 0 * *", err: "day of month"},
+		{spec: "* * * 13 *", err: "month"},
+		{spec: "* * * * 8", err: "day of week"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSchedule(tt.spec); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseSchedule(%q) = %v, want an error containing %q", tt.spec, err, tt.err)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2026, 3, 11, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2026, 3, 11, 10, 18, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2026, 3, 11, 11, 0, 0, 0, time.UTC)},
+		{spec: "@daily", want: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{spec: "@midnight", want: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{spec: "@weekly", want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", want: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "@every 90m", want: from.Add(90 * time.Minute)},
+		{spec: " */15 * * * * ", want: time.Date(2026, 3, 11, 10, 30, 0, 0, time.UTC)},
+		{spec: "17 10 * * *", want: time.Date(2026, 3, 12, 10, 17, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * *", want: time.Date(2026, 3, 11, 13, 0, 0, 0, time.UTC)},
+		{spec: "30 2 * * 1-5", want: time.Date(2026, 3, 12, 2, 30, 0, 0, time.UTC)},
+		// Sunday is 0 or 7.
+		{spec: "0 0 * * 7", want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 0", want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		// With both days restricted, either will do.
+		{spec: "0 0 20 * 5", want: time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 12 * 0", want: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 * *", want: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Days a month never has never come.
+		{spec: "0 0 30 2 *"},
+		{spec: "0 0 31 4 *"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// Package daemon runs benchmark jobs on request, from an HTTP API, so that
+// other services can start runs, follow them and fetch their results, and
+// on schedules, comparing each scheduled run with the one before.
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+	"solana-rpc-performance-golang/store"
+)
+
+// Job states.
+const (
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateStopped   = "stopped"
+)
+
+// ErrNotFound is returned for a job the daemon doesn't know.
+var ErrNotFound = errors.New("no such job")
+
+// ErrRunning is returned for what can't be done to a running job.
+var ErrRunning = errors.New("job is still running")
+
+var errClosed = errors.New("the daemon is shutting down")
+
+// defaultKeepFinished is how many finished jobs the daemon keeps track of
+// by default.
+const defaultKeepFinished = 100
+
+// RunFunc runs a bench command line, writing its results to output and
+// passing results to observe in batches as they complete.
+type RunFunc func(ctx context.Context, args []string, output string, observe func([]rpcclient.Result)) error
+
+// Daemon starts jobs and keeps track of them. Each job's results are
+// written to a file of its own in Dir.
+type Daemon struct {
+	Dir string
+	Run RunFunc
+	// Store, if set, is where scheduled jobs record their runs: it is
+	// passed as -store to those whose command line doesn't set one.
+	Store string
+	// History, if set, is the run history the dashboard charts.
+	History *store.Store
+	// Thresholds decide which scheduled runs regressed.
+	Thresholds Thresholds
+	// KeepFinished is how many finished jobs the daemon keeps track of;
+	// older ones are forgotten, though their results files stay in Dir.
+	// 0 means 100.
+	KeepFinished int
+	// Checked, if set, is called each time a scheduled run is compared
+	// with the run before it, with its regression, or nil if it held up.
+	Checked func(job ScheduledJob, results *bench.SavedResults, regression *Regression)
+	// Logger receives status output; nil means slog.Default().
+	Logger *slog.Logger
+
+	mu        sync.Mutex
+	jobs      map[string]*job
+	schedules map[string]*schedule
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Status is what the daemon reports about a job. Progress covers the
+// requests completed so far.
+type Status struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name,omitempty"`
+	Args       []string   `json:"args"`
+	State      string     `json:"state"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Progress   Progress   `json:"progress"`
+}
+
+// Progress is a running tally of a job's requests.
+type Progress struct {
+	Requests int                `json:"requests"`
+	Errors   int                `json:"errors"`
+	Latency  stats.LatencyStats `json:"latency"`
+}
+
+type job struct {
+	status  Status
+	output  string
+	stop    context.CancelFunc
+	latency stats.Histogram
+	// done is closed when the job finishes.
+	done chan struct{}
+}
+
+func (d *Daemon) log() *slog.Logger {
+	if d.Logger == nil {
+		return slog.Default()
+	}
+	return d.Logger
+}
+
+// Start starts a job running the bench command line args, named name if
+// it isn't empty.
+func (d *Daemon) Start(name string, args []string) (*Status, error) {
+	j, err := d.start(name, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	return d.Status(j.status.ID)
+}
+
+// start starts a job running args, and then extra, which its status
+// leaves out.
+func (d *Daemon) start(name string, args, extra []string) (*job, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("a job needs a command line")
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(d.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	j := &job{
+		status: Status{ID: id, Name: name, Args: args, State: StateRunning, StartedAt: time.Now().UTC()},
+		output: filepath.Join(d.Dir, id+".json"),
+		stop:   stop,
+		done:   make(chan struct{}),
+	}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		stop()
+		return nil, errClosed
+	}
+	if d.jobs == nil {
+		d.jobs = make(map[string]*job)
+	}
+	d.jobs[id] = j
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	log := d.log().With("id", id)
+	if name != "" {
+		log = log.With("name", name)
+	}
+	log.Info("job started", "args", args)
+	go func() {
+		defer d.wg.Done()
+		defer close(j.done)
+		defer stop()
+		err := d.Run(ctx, append(slices.Clip(args), extra...), j.output, func(results []rpcclient.Result) { d.observe(j, results) })
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		finished := time.Now().UTC()
+		j.status.FinishedAt = &finished
+		switch {
+		case ctx.Err() != nil:
+			j.status.State = StateStopped
+		case err != nil:
+			j.status.State = StateFailed
+			j.status.Error = err.Error()
+		default:
+			j.status.State = StateSucceeded
+		}
+		log.Info("job finished", "state", j.status.State, "requests", j.status.Progress.Requests)
+		d.forgetOldest()
+	}()
+	return j, nil
+}
+
+func (d *Daemon) observe(j *job, results []rpcclient.Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, result := range results {
+		j.status.Progress.Requests++
+		if result.Success {
+			j.latency.Record(result.Latency)
+		} else {
+			j.status.Progress.Errors++
+		}
+	}
+}
+
+// Stop interrupts a running job, which finishes its in-flight requests
+// and writes its results.
+func (d *Daemon) Stop(id string) (*Status, error) {
+	d.mu.Lock()
+	j, ok := d.jobs[id]
+	d.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	j.stop()
+	return d.Status(id)
+}
+
+// Status returns the status of job id.
+func (d *Daemon) Status(id string) (*Status, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j, ok := d.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// snapshot copies the job's status; the daemon's lock must be held.
+func (j *job) snapshot() *Status {
+	status := j.status
+	status.Progress.Latency = j.latency.Summary()
+	return &status
+}
+
+// Jobs returns the status of every job, newest first.
+func (d *Daemon) Jobs() []*Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	statuses := make([]*Status, 0, len(d.jobs))
+	for _, j := range d.jobs {
+		statuses = append(statuses, j.snapshot())
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].StartedAt.After(statuses[k].StartedAt) })
+	return statuses
+}
+
+// Results returns the path of the results file of job id, once it has
+// finished.
+func (d *Daemon) Results(id string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j, ok := d.jobs[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if j.status.State == StateRunning {
+		return "", ErrRunning
+	}
+	return j.output, nil
+}
+
+// Delete forgets a finished job and removes its results file.
+func (d *Daemon) Delete(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j, ok := d.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if j.status.State == StateRunning {
+		return ErrRunning
+	}
+	delete(d.jobs, id)
+	if err := os.Remove(j.output); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// forgetOldest forgets the jobs that finished longest ago, beyond the
+// most the daemon keeps; the daemon's lock must be held.
+func (d *Daemon) forgetOldest() {
+	keep := d.KeepFinished
+	if keep <= 0 {
+		keep = defaultKeepFinished
+	}
+	var finished []*job
+	for _, j := range d.jobs {
+		if j.status.State != StateRunning {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= keep {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].status.FinishedAt.Before(*finished[k].status.FinishedAt) })
+	for _, j := range finished[:len(finished)-keep] {
+		delete(d.jobs, j.status.ID)
+	}
+}
+
+// Shutdown stops every schedule and running job, and waits for the jobs
+// to finish.
+func (d *Daemon) Shutdown() {
+	d.mu.Lock()
+	d.closed = true
+	for _, s := range d.schedules {
+		s.stop()
+	}
+	for _, j := range d.jobs {
+		j.stop()
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// newJobID returns a job ID that sorts by time and won't collide with
+// the results files of earlier daemons in the same directory.
+func newJobID() (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	_ "embed"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"solana-rpc-performance-golang/store"
+)
+
+// dashboardHTML charts the runs in the daemon's History. It loads
+// Chart.js from a CDN, so the browser needs internet access, but the
+// daemon doesn't.
+//
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// defaultSeriesSince is how far back the history series goes without a
+// since parameter.
+const defaultSeriesSince = 7 * 24 * time.Hour
+
+// maxSeriesRuns bounds the runs in a history series.
+const maxSeriesRuns = 5000
+
+func (d *Daemon) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// serveSeries serves the completed runs in History, oldest first: those
+// started within since (a Go duration), of endpoints containing
+// endpoint, up to limit of the newest.
+func (d *Daemon) serveSeries(w http.ResponseWriter, r *http.Request) {
+	if d.History == nil {
+		writeError(w, http.StatusNotFound, errors.New("the daemon has no run history; start it with --store"))
+		return
+	}
+	query := r.URL.Query()
+	since := defaultSeriesSince
+	if value := query.Get("since"); value != "" {
+		var err error
+		if since, err = time.ParseDuration(value); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	filter := store.Filter{Endpoint: query.Get("endpoint"), Since: time.Now().Add(-since), Limit: maxSeriesRuns}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive number"))
+			return
+		}
+		filter.Limit = min(limit, maxSeriesRuns)
+	}
+	points, err := d.History.Series(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if points == nil {
+		points = []store.SeriesPoint{}
+	}
+	writeJSON(w, http.StatusOK, points)
+}
@@ -0,0 +1,283 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/bench"
+)
+
+// ErrNoSchedule is returned for a schedule the daemon doesn't know.
+var ErrNoSchedule = errors.New("no such schedule")
+
+// ScheduledJob is a job the daemon starts on a schedule.
+type ScheduledJob struct {
+	Name string `json:"name"`
+	// Schedule is a cron expression or alias, as ParseSchedule takes.
+	Schedule string   `json:"schedule"`
+	Args     []string `json:"args"`
+}
+
+// ScheduleStatus is what the daemon reports about a schedule.
+type ScheduleStatus struct {
+	ScheduledJob
+	NextRun time.Time `json:"nextRun"`
+	// LastJob is the ID of the schedule's latest job, and Regression how
+	// its run did worse than the one before, if it did.
+	LastJob    string      `json:"lastJob,omitempty"`
+	Regression *Regression `json:"regression,omitempty"`
+}
+
+// Regression is how a scheduled run did worse than the schedule's run
+// before it, beyond the daemon's Thresholds.
+type Regression struct {
+	Schedule string              `json:"schedule"`
+	Job      string              `json:"job"`
+	Previous string              `json:"previous"`
+	Reasons  []string            `json:"reasons"`
+	Delta    *bench.ResultsDelta `json:"delta"`
+}
+
+// Thresholds are how much worse than the run before a scheduled run may
+// do before it counts as a regression.
+type Thresholds struct {
+	// Latency is the largest rise in p50, p95 or p99, in percent.
+	Latency float64
+	// SuccessRate is the largest drop in success rate, in percentage
+	// points.
+	SuccessRate float64
+}
+
+// regressions lists how delta's run B regressed from A. When both runs
+// kept their samples, latency changes the significance test puts down
+// to noise don't count.
+func (t Thresholds) regressions(delta *bench.ResultsDelta) []string {
+	var reasons []string
+	if drop := -delta.SuccessRate.Change; drop > t.SuccessRate {
+		reasons = append(reasons, fmt.Sprintf("success rate down %.2f pp, from %.2f%% to %.2f%%",
+			drop, delta.SuccessRate.A, delta.SuccessRate.B))
+	}
+	if significance := delta.Significance; significance != nil && !significance.Significant {
+		return reasons
+	}
+	for _, metric := range delta.Latency {
+		if metric.Name != "p50" && metric.Name != "p95" && metric.Name != "p99" {
+			continue
+		}
+		if metric.Percent != nil && *metric.Percent > t.Latency {
+			reasons = append(reasons, fmt.Sprintf("%s up %.1f%%, from %.0f ms to %.0f ms",
+				metric.Name, *metric.Percent, metric.A, metric.B))
+		}
+	}
+	return reasons
+}
+
+type schedule struct {
+	job  ScheduledJob
+	when *Schedule
+	stop context.CancelFunc
+
+	next       time.Time
+	lastJob    string
+	regression *Regression
+	// last is the results of the latest run that succeeded, which the
+	// next is compared with.
+	last *bench.SavedResults
+}
+
+// LoadSchedules reads a schedules file: a JSON object whose "schedules"
+// lists ScheduledJobs.
+func LoadSchedules(path string) ([]ScheduledJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Schedules []ScheduledJob `json:"schedules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse schedules %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for i, job := range file.Schedules {
+		if job.Name == "" {
+			return nil, fmt.Errorf("schedule %d has no name", i)
+		}
+		if seen[job.Name] {
+			return nil, fmt.Errorf("duplicate schedule %q", job.Name)
+		}
+		seen[job.Name] = true
+	}
+	return file.Schedules, nil
+}
+
+// Schedule starts running job on its schedule, replacing any schedule of
+// the same name. A replacement with the same command line keeps the
+// results the next run is compared with.
+func (d *Daemon) Schedule(job ScheduledJob) (*ScheduleStatus, error) {
+	if job.Name == "" {
+		return nil, fmt.Errorf("a schedule needs a name")
+	}
+	if len(job.Args) == 0 {
+		return nil, fmt.Errorf("schedule %q needs a command line", job.Name)
+	}
+	when, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	next := when.Next(time.Now())
+	if next.IsZero() {
+		return nil, fmt.Errorf("schedule %q never falls due", job.Schedule)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	s := &schedule{job: job, when: when, stop: stop, next: next}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		stop()
+		return nil, errClosed
+	}
+	if old, ok := d.schedules[job.Name]; ok {
+		old.stop()
+		if slices.Equal(old.job.Args, job.Args) {
+			s.last, s.lastJob, s.regression = old.last, old.lastJob, old.regression
+		}
+	}
+	if d.schedules == nil {
+		d.schedules = make(map[string]*schedule)
+	}
+	d.schedules[job.Name] = s
+	d.log().Info("job scheduled", "schedule", job.Name, "when", job.Schedule, "next_run", s.next, "args", job.Args)
+	go d.runSchedule(ctx, s)
+	return s.snapshot(), nil
+}
+
+// Unschedule stops the schedule name. A job it started runs on.
+func (d *Daemon) Unschedule(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.schedules[name]
+	if !ok {
+		return ErrNoSchedule
+	}
+	s.stop()
+	delete(d.schedules, name)
+	return nil
+}
+
+// Schedules returns the status of every schedule, by name.
+func (d *Daemon) Schedules() []*ScheduleStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	statuses := make([]*ScheduleStatus, 0, len(d.schedules))
+	for _, s := range d.schedules {
+		statuses = append(statuses, s.snapshot())
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+// snapshot copies the schedule's status; the daemon's lock must be held.
+func (s *schedule) snapshot() *ScheduleStatus {
+	return &ScheduleStatus{ScheduledJob: s.job, NextRun: s.next, LastJob: s.lastJob, Regression: s.regression}
+}
+
+// runSchedule starts s's job each time it falls due, until ctx ends. A
+// run still going when the next falls due makes that one skip.
+func (d *Daemon) runSchedule(ctx context.Context, s *schedule) {
+	log := d.log().With("schedule", s.job.Name)
+	for {
+		d.mu.Lock()
+		next := s.next
+		d.mu.Unlock()
+		if next.IsZero() {
+			log.Warn("schedule never falls due", "when", s.job.Schedule)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		j, err := d.start(s.job.Name, s.job.Args, d.storeArgs(s.job.Args))
+		if err != nil {
+			log.Warn("scheduled job did not start", "error", err)
+		} else {
+			d.mu.Lock()
+			s.lastJob = j.status.ID
+			d.mu.Unlock()
+			<-j.done
+			d.check(s, j)
+		}
+		d.mu.Lock()
+		s.next = s.when.Next(time.Now())
+		d.mu.Unlock()
+	}
+}
+
+// storeArgs returns the flags that make a scheduled job with args record
+// its run in the daemon's Store, unless args give a store of their own.
+func (d *Daemon) storeArgs(args []string) []string {
+	if d.Store == "" {
+		return nil
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && name == "store" {
+			return nil
+		}
+	}
+	return []string{"--store", d.Store}
+}
+
+// check compares the run of j, which s started, with s's run before it,
+// if both succeeded.
+func (d *Daemon) check(s *schedule, j *job) {
+	d.mu.Lock()
+	state, id := j.status.State, j.status.ID
+	d.mu.Unlock()
+	if state != StateSucceeded {
+		return
+	}
+	log := d.log().With("schedule", s.job.Name, "id", id)
+	current, err := bench.LoadResults(j.output)
+	if err != nil {
+		log.Warn("scheduled run can't be compared", "error", err)
+		return
+	}
+	current.Path = id
+
+	d.mu.Lock()
+	previous := s.last
+	s.last = current
+	d.mu.Unlock()
+	if previous == nil {
+		return
+	}
+	delta := bench.DiffResults(previous, current)
+	var regression *Regression
+	if reasons := d.Thresholds.regressions(delta); len(reasons) > 0 {
+		regression = &Regression{Schedule: s.job.Name, Job: id, Previous: previous.Path, Reasons: reasons, Delta: delta}
+		log.Warn("scheduled run regressed", "previous", previous.Path, "reasons", reasons)
+	}
+	d.mu.Lock()
+	s.regression = regression
+	d.mu.Unlock()
+	if d.Checked != nil {
+		d.Checked(s.job, current, regression)
+	}
+}
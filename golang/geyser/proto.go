@@ -0,0 +1,208 @@
+package geyser
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of Yellowstone's geyser.proto are encoded and decoded by
+// hand, for the few fields the benchmark uses, rather than through the
+// generated package; field numbers are those of geyser.proto.
+
+const subscribeMethod = "/geyser.Geyser/Subscribe"
+
+// Commitment levels, as geyser.proto's CommitmentLevel.
+var commitments = map[string]uint64{
+	"processed": 0,
+	"confirmed": 1,
+	"finalized": 2,
+}
+
+// rawCodec passes already-encoded messages through gRPC untouched.
+type rawCodec struct{}
+
+func (rawCodec) Name() string { return "proto" }
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("geyser: cannot marshal %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("geyser: cannot unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// subscribeRequest encodes a SubscribeRequest for the streams in cfg.
+// Slots are always subscribed, with inter-slot updates, since message
+// lag is measured from when a slot was first announced.
+func subscribeRequest(cfg Config) []byte {
+	var req []byte
+
+	// map<string, SubscribeRequestFilterSlots> slots = 2
+	var slots []byte
+	slots = protowire.AppendTag(slots, 2, protowire.VarintType) // interslot_updates
+	slots = protowire.AppendVarint(slots, 1)
+	req = appendMapEntry(req, 2, StreamSlots, slots)
+
+	if cfg.wants(StreamAccounts) {
+		// map<string, SubscribeRequestFilterAccounts> accounts = 1
+		var accounts []byte
+		for _, key := range cfg.Accounts {
+			accounts = protowire.AppendTag(accounts, 2, protowire.BytesType) // account
+			accounts = protowire.AppendString(accounts, key)
+		}
+		for _, key := range cfg.Owners {
+			accounts = protowire.AppendTag(accounts, 3, protowire.BytesType) // owner
+			accounts = protowire.AppendString(accounts, key)
+		}
+		req = appendMapEntry(req, 1, StreamAccounts, accounts)
+	}
+	if cfg.wants(StreamTransactions) {
+		// map<string, SubscribeRequestFilterTransactions> transactions = 3
+		var transactions []byte
+		transactions = protowire.AppendTag(transactions, 1, protowire.VarintType) // vote
+		transactions = protowire.AppendVarint(transactions, 0)
+		for _, key := range cfg.TransactionAccounts {
+			transactions = protowire.AppendTag(transactions, 3, protowire.BytesType) // account_include
+			transactions = protowire.AppendString(transactions, key)
+		}
+		req = appendMapEntry(req, 3, StreamTransactions, transactions)
+	}
+
+	// optional CommitmentLevel commitment = 6
+	req = protowire.AppendTag(req, 6, protowire.VarintType)
+	req = protowire.AppendVarint(req, commitments[cfg.Commitment])
+	return req
+}
+
+// pingRequest encodes a SubscribeRequest carrying only a ping, the reply
+// the server expects to its own pings to keep the stream open.
+func pingRequest(id int32) []byte {
+	var ping []byte
+	ping = protowire.AppendTag(ping, 1, protowire.VarintType)
+	ping = protowire.AppendVarint(ping, uint64(id))
+	var req []byte
+	req = protowire.AppendTag(req, 9, protowire.BytesType)
+	return protowire.AppendBytes(req, ping)
+}
+
+func appendMapEntry(b []byte, field protowire.Number, key string, value []byte) []byte {
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, key)
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, value)
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, entry)
+}
+
+// Update kinds, the SubscribeUpdate oneof members the benchmark tells
+// apart.
+const (
+	updateOther = iota
+	updateSlot
+	updateAccount
+	updateTransaction
+	updatePing
+)
+
+// update is what the benchmark reads from a SubscribeUpdate.
+type update struct {
+	kind      int
+	slot      uint64
+	createdAt time.Time
+}
+
+// parseUpdate decodes the kind, slot and created_at of a SubscribeUpdate.
+func parseUpdate(b []byte) (update, error) {
+	var u update
+	err := walk(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 2: // SubscribeUpdateAccount account: uint64 slot = 2
+			u.kind = updateAccount
+			return walkSlot(value, 2, &u.slot)
+		case 3: // SubscribeUpdateSlot slot: uint64 slot = 1
+			u.kind = updateSlot
+			return walkSlot(value, 1, &u.slot)
+		case 4: // SubscribeUpdateTransaction transaction: uint64 slot = 2
+			u.kind = updateTransaction
+			return walkSlot(value, 2, &u.slot)
+		case 6: // SubscribeUpdatePing ping
+			u.kind = updatePing
+		case 11: // google.protobuf.Timestamp created_at
+			var seconds, nanos uint64
+			err := walk(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				if typ == protowire.VarintType {
+					v, _ := protowire.ConsumeVarint(value)
+					switch num {
+					case 1:
+						seconds = v
+					case 2:
+						nanos = v
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			u.createdAt = time.Unix(int64(seconds), int64(nanos))
+		}
+		return nil
+	})
+	return u, err
+}
+
+// walkSlot reads the varint field num of message b into slot.
+func walkSlot(b []byte, num protowire.Number, slot *uint64) error {
+	return walk(b, func(n protowire.Number, typ protowire.Type, value []byte) error {
+		if n == num && typ == protowire.VarintType {
+			*slot, _ = protowire.ConsumeVarint(value)
+		}
+		return nil
+	})
+}
+
+// walk calls fn for each field of message b. value is a varint's encoding
+// or the contents of a length-delimited field.
+func walk(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+			value, n = v, m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value = b[:n]
+		}
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package mockserver
+
+const (
+	// SlotsPerEpoch is mainnet's epoch length.
+	SlotsPerEpoch = 432_000
+	// Validators is the number of validators in the mock cluster, each
+	// leading four consecutive slots in turn. The last two are delinquent.
+	Validators = 50
+
+	leaderWindow = 4
+	delinquent   = 2
+)
+
+func identity(i int) string {
+	return hashString(uint64(i), "identity", 32)
+}
+
+// leader is the index of slot's leader.
+func leader(slot uint64) int {
+	return int(slot/leaderWindow) % Validators
+}
+
+func blockHeight(slot uint64) uint64 {
+	return slot - genesisSlot/20
+}
+
+func (s *Server) epochInfo() interface{} {
+	slot := s.slot()
+	return map[string]interface{}{
+		"absoluteSlot":     slot,
+		"blockHeight":      blockHeight(slot),
+		"epoch":            slot / SlotsPerEpoch,
+		"slotIndex":        slot % SlotsPerEpoch,
+		"slotsInEpoch":     SlotsPerEpoch,
+		"transactionCount": slot * TransactionsPerBlock,
+	}
+}
+
+// blockProduction counts each validator's leader slots and blocks over
+// the epoch so far; skipped slots are the only ones without a block.
+func (s *Server) blockProduction() interface{} {
+	tip := s.slot()
+	first := tip - tip%SlotsPerEpoch
+	production := make([][2]int, Validators)
+	for slot := first; slot <= tip; slot++ {
+		p := &production[leader(slot)]
+		p[0]++
+		if slot%SkipEvery != SkipEvery-1 {
+			p[1]++
+		}
+	}
+	byIdentity := make(map[string]interface{}, Validators)
+	for i, p := range production {
+		byIdentity[identity(i)] = []int{p[0], p[1]}
+	}
+	return map[string]interface{}{
+		"context": map[string]interface{}{"slot": tip},
+		"value": map[string]interface{}{
+			"byIdentity": byIdentity,
+			"range":      map[string]interface{}{"firstSlot": first, "lastSlot": tip},
+		},
+	}
+}
+
+func (s *Server) voteAccounts() interface{} {
+	tip := s.slot()
+	epoch := tip / SlotsPerEpoch
+	current, late := []interface{}{}, []interface{}{}
+	for i := 0; i < Validators; i++ {
+		lastVote := tip - 1
+		if i >= Validators-delinquent {
+			lastVote = tip - 1000
+		}
+		account := map[string]interface{}{
+			"votePubkey":       hashString(uint64(i), "vote", 32),
+			"nodePubkey":       identity(i),
+			"activatedStake":   uint64(Validators-i) * 100_000_000_000_000,
+			"epochVoteAccount": true,
+			"commission":       i % 11,
+			"lastVote":         lastVote,
+			"rootSlot":         lastVote - FinalizedLag,
+			"epochCredits": [][3]uint64{
+				{epoch - 1, epoch * 1000, (epoch - 1) * 1000},
+				{epoch, epoch*1000 + tip%SlotsPerEpoch, epoch * 1000},
+			},
+		}
+		if i >= Validators-delinquent {
+			late = append(late, account)
+		} else {
+			current = append(current, account)
+		}
+	}
+	return map[string]interface{}{"current": current, "delinquent": late}
+}
+
+// leaderSchedule lists every slot index of the current epoch under its
+// leader, as large as a real schedule.
+func (s *Server) leaderSchedule() interface{} {
+	first := s.slot() - s.slot()%SlotsPerEpoch
+	slots := make([][]int, Validators)
+	for index := 0; index < SlotsPerEpoch; index++ {
+		i := leader(first + uint64(index))
+		slots[i] = append(slots[i], index)
+	}
+	schedule := make(map[string][]int, Validators)
+	for i, indexes := range slots {
+		schedule[identity(i)] = indexes
+	}
+	return schedule
+}
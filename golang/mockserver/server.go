@@ -0,0 +1,455 @@
+// Package mockserver is a small Solana JSON-RPC and PubSub server with canned
+// responses, artificial latency and injected errors, for developing and
+// testing the benchmark without a real endpoint.
+package mockserver
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+const (
+	genesisSlot = 300_000_000
+	slotTime    = 400 * time.Millisecond
+
+	// History is how many slots behind the tip blocks are still served.
+	History = 1_000_000
+	// SkipEvery makes every SkipEvery-th slot a skipped slot.
+	SkipEvery = 10
+	// TransactionsPerBlock is the number of signatures in each block.
+	TransactionsPerBlock = 4
+	// ConfirmedLag and FinalizedLag are how many slots the confirmed and
+	// finalized slots trail the tip.
+	ConfirmedLag = 2
+	FinalizedLag = 32
+	// GenesisHash identifies the mock's own cluster, which is none of
+	// the public ones.
+	GenesisHash = "MockGenesisHash1111111111111111111111111111"
+)
+
+// Config controls how the server misbehaves.
+type Config struct {
+	// Latency is added to every response, plus or minus a uniformly
+	// random Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the fraction of requests, 0 to 1, answered with a
+	// JSON-RPC internal error instead of a result.
+	ErrorRate float64
+	Seed      int64
+	// RateLimit, if positive, throttles the server to that many requests
+	// per second, after a burst of up to Burst. Requests over the limit
+	// are answered with HTTP 429, and every response carries
+	// x-ratelimit-limit and x-ratelimit-remaining headers.
+	RateLimit float64
+	Burst     int
+}
+
+type Server struct {
+	cfg   Config
+	start time.Time
+
+	mu  sync.Mutex
+	rng *rand.Rand
+	// tokens is what the rate limit's bucket held at refilled.
+	tokens   float64
+	refilled time.Time
+}
+
+func New(cfg Config) *Server {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if cfg.RateLimit > 0 && cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	return &Server{cfg: cfg, start: time.Now(), rng: rand.New(rand.NewSource(seed)),
+		tokens: float64(cfg.Burst), refilled: time.Now()}
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONrpc string              `json:"jsonrpc"`
+	ID      json.RawMessage     `json:"id"`
+	Result  interface{}         `json:"result,omitempty"`
+	Error   *rpcclient.RPCError `json:"error,omitempty"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWebSocket(r) {
+		s.serveWS(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.cfg.RateLimit > 0 {
+		allowed, remaining, wait := s.take()
+		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%g", s.cfg.RateLimit))
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(remaining))
+		if !allowed {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(response{JSONrpc: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcclient.RPCError{Code: http.StatusTooManyRequests, Message: "Too many requests"}})
+			return
+		}
+	}
+
+	var req request
+	resp := response{JSONrpc: "2.0"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.ID = json.RawMessage("null")
+		resp.Error = &rpcclient.RPCError{Code: -32700, Message: "Parse error"}
+	} else {
+		resp.ID = req.ID
+		delay, fail := s.roll()
+		time.Sleep(delay)
+		if fail {
+			resp.Error = &rpcclient.RPCError{Code: -32603, Message: "Internal error"}
+		} else {
+			resp.Result, resp.Error = s.handle(req)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var out io.Writer = w
+	switch accept := r.Header.Get("Accept-Encoding"); {
+	case strings.Contains(accept, "gzip"):
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	case strings.Contains(accept, "deflate"):
+		w.Header().Set("Content-Encoding", "deflate")
+		zw := zlib.NewWriter(w)
+		defer zw.Close()
+		out = zw
+	}
+	json.NewEncoder(out).Encode(resp)
+}
+
+// roll draws this request's delay and whether it fails.
+func (s *Server) roll() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delay := s.cfg.Latency
+	if s.cfg.Jitter > 0 {
+		delay += time.Duration(s.rng.Int63n(int64(2*s.cfg.Jitter))) - s.cfg.Jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, s.rng.Float64() < s.cfg.ErrorRate
+}
+
+// take takes a token from the rate limit's bucket, if it holds one, and
+// returns whether it did, how many whole tokens are left and, if none
+// was, how long until one will be.
+func (s *Server) take() (bool, int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.tokens = min(float64(s.cfg.Burst), s.tokens+now.Sub(s.refilled).Seconds()*s.cfg.RateLimit)
+	s.refilled = now
+	if s.tokens < 1 {
+		return false, 0, time.Duration((1 - s.tokens) / s.cfg.RateLimit * float64(time.Second))
+	}
+	s.tokens--
+	return true, int(s.tokens), 0
+}
+
+// slot is the current tip, advancing at mainnet's nominal slot time.
+func (s *Server) slot() uint64 {
+	return genesisSlot + uint64(time.Since(s.start)/slotTime)
+}
+
+func (s *Server) handle(req request) (interface{}, *rpcclient.RPCError) {
+	switch req.Method {
+	case "getVersion":
+		return map[string]interface{}{"solana-core": "mock", "feature-set": 0}, nil
+	case "getSlot":
+		// Commitment lags behind the tip as on a real cluster.
+		var cfg struct {
+			Commitment string `json:"commitment"`
+		}
+		if len(req.Params) > 0 {
+			if err := param(req, 0, &cfg); err != nil {
+				return nil, err
+			}
+		}
+		switch cfg.Commitment {
+		case "confirmed":
+			return s.slot() - ConfirmedLag, nil
+		case "finalized":
+			return s.slot() - FinalizedLag, nil
+		}
+		return s.slot(), nil
+	case "getHealth":
+		return "ok", nil
+	case "getGenesisHash":
+		return GenesisHash, nil
+	case "getBalance":
+		var key string
+		if err := param(req, 0, &key); err != nil {
+			return nil, err
+		}
+		pk, parseErr := rpcclient.ParsePublicKey(key)
+		if parseErr != nil {
+			return nil, invalidParams(parseErr.Error())
+		}
+		lamports := binary.LittleEndian.Uint64(pk[:8]) % 1_000_000_000_000
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": s.slot()},
+			"value":   lamports,
+		}, nil
+	case "getRecentPerformanceSamples":
+		limit := 720
+		if len(req.Params) > 0 {
+			if err := param(req, 0, &limit); err != nil {
+				return nil, err
+			}
+		}
+		return s.performanceSamples(limit), nil
+	case "getRecentPrioritizationFees":
+		// Fees for the last 150 slots, none in some and spiking in a few.
+		tip := s.slot()
+		fees := make([]interface{}, 0, 150)
+		for slot := tip - 149; slot <= tip; slot++ {
+			fee := binary.LittleEndian.Uint64([]byte(hashString(slot, "fee", 8))) % 5000
+			switch {
+			case fee < 1000:
+				fee = 0
+			case fee > 4900:
+				fee *= 20
+			}
+			fees = append(fees, map[string]interface{}{"slot": slot, "prioritizationFee": fee})
+		}
+		return fees, nil
+	case "getEpochInfo":
+		return s.epochInfo(), nil
+	case "getBlockHeight":
+		return blockHeight(s.slot()), nil
+	case "getBlockProduction":
+		return s.blockProduction(), nil
+	case "getVoteAccounts":
+		return s.voteAccounts(), nil
+	case "getLeaderSchedule":
+		return s.leaderSchedule(), nil
+	case "getSupply":
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": s.slot()},
+			"value": map[string]interface{}{
+				"total":                  590_000_000_000_000_000,
+				"circulating":            480_000_000_000_000_000,
+				"nonCirculating":         110_000_000_000_000_000,
+				"nonCirculatingAccounts": []string{},
+			},
+		}, nil
+	case "getInflationRate":
+		return map[string]interface{}{
+			"total": 0.046, "validator": 0.046, "foundation": 0, "epoch": s.slot() / SlotsPerEpoch,
+		}, nil
+	case "getInflationReward":
+		var addresses []string
+		if err := param(req, 0, &addresses); err != nil {
+			return nil, err
+		}
+		epoch := s.slot()/SlotsPerEpoch - 1
+		rewards := make([]interface{}, len(addresses))
+		for i := range addresses {
+			rewards[i] = map[string]interface{}{
+				"epoch":         epoch,
+				"effectiveSlot": (epoch + 1) * SlotsPerEpoch,
+				"amount":        2_000_000 + i,
+				"postBalance":   1_000_000_000_000 + i,
+				"commission":    nil,
+			}
+		}
+		return rewards, nil
+	case "getLargestAccounts":
+		// Switched off, as many providers have it.
+		return nil, &rpcclient.RPCError{Code: -32603, Message: "Method getLargestAccounts is disabled"}
+	case "getFirstAvailableBlock":
+		return s.slot() - History, nil
+	case "getBlock":
+		return s.block(req)
+	case "getBlocks":
+		return s.blocks(req)
+	case "getTransaction":
+		return s.getTransaction(req)
+	default:
+		return nil, &rpcclient.RPCError{Code: -32601, Message: "Method not found"}
+	}
+}
+
+func (s *Server) block(req request) (interface{}, *rpcclient.RPCError) {
+	var slot uint64
+	if err := param(req, 0, &slot); err != nil {
+		return nil, err
+	}
+	var config transactionConfig
+	if len(req.Params) > 1 {
+		if err := param(req, 1, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	tip := s.slot()
+	switch {
+	case slot > tip:
+		return nil, &rpcclient.RPCError{Code: rpcclient.ErrCodeBlockNotAvailable, Message: fmt.Sprintf("Block not available for slot %d", slot)}
+	case slot < tip-History:
+		return nil, &rpcclient.RPCError{Code: rpcclient.ErrCodeBlockCleanedUp, Message: fmt.Sprintf("Block %d cleaned up, does not exist on node. First available block: %d", slot, tip-History)}
+	case slot%SkipEvery == SkipEvery-1:
+		return nil, &rpcclient.RPCError{Code: rpcclient.ErrCodeSlotSkipped, Message: fmt.Sprintf("Slot %d was skipped, or missing due to ledger jump to recent snapshot", slot)}
+	}
+
+	parent := slot - 1
+	if parent%SkipEvery == SkipEvery-1 {
+		parent--
+	}
+	block := map[string]interface{}{
+		"blockhash":         hashString(slot, "blockhash", 32),
+		"previousBlockhash": hashString(parent, "blockhash", 32),
+		"parentSlot":        parent,
+		"blockHeight":       blockHeight(slot),
+		"blockTime":         s.start.Add(-time.Duration(tip-slot) * slotTime).Unix(),
+	}
+	if config.Rewards == nil || *config.Rewards {
+		block["rewards"] = []interface{}{map[string]interface{}{
+			"pubkey":      identity(leader(slot)),
+			"lamports":    TransactionsPerBlock * 2500,
+			"postBalance": 1_000_000_000_000 + slot,
+			"rewardType":  "Fee",
+			"commission":  nil,
+		}}
+	}
+	switch config.TransactionDetails {
+	case "none":
+	case "signatures":
+		signatures := make([]string, TransactionsPerBlock)
+		for i := range signatures {
+			signatures[i] = txSignature(slot, i)
+		}
+		block["signatures"] = signatures
+	default:
+		// Every block has version 0 transactions, which a node refuses
+		// to return to clients that don't say they can handle them.
+		if config.MaxSupportedTransactionVersion == nil {
+			return nil, errTransactionVersion
+		}
+		transactions := make([]interface{}, TransactionsPerBlock)
+		for i := range transactions {
+			entry, err := newTransaction(slot, i).blockEntry(config)
+			if err != nil {
+				return nil, err
+			}
+			transactions[i] = entry
+		}
+		block["transactions"] = transactions
+	}
+	return block, nil
+}
+
+// blocks lists the served blocks in a range of slots, which the real
+// method caps at 500,000 slots.
+func (s *Server) blocks(req request) (interface{}, *rpcclient.RPCError) {
+	var start, end uint64
+	if err := param(req, 0, &start); err != nil {
+		return nil, err
+	}
+	tip := s.slot()
+	end = tip
+	if len(req.Params) > 1 {
+		if err := param(req, 1, &end); err != nil {
+			return nil, err
+		}
+	}
+	if end > tip {
+		end = tip
+	}
+	if end >= start && end-start > 500_000 {
+		return nil, invalidParams("slot range too large; max 500000")
+	}
+	if start < tip-History {
+		start = tip - History
+	}
+	slots := []uint64{}
+	for slot := start; slot <= end; slot++ {
+		if slot%SkipEvery != SkipEvery-1 {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
+
+// performanceSamples returns limit minute-long samples, newest first, of
+// a cluster running at its nominal slot time with a steady transaction
+// rate.
+func (s *Server) performanceSamples(limit int) []interface{} {
+	const period = 60
+	slotsPerSample := uint64(period * time.Second / slotTime)
+	var samples []interface{}
+	for slot := s.slot(); len(samples) < limit; slot -= slotsPerSample {
+		samples = append(samples, map[string]interface{}{
+			"slot":                   slot,
+			"numSlots":               slotsPerSample,
+			"numTransactions":        slotsPerSample * 1000,
+			"numNonVoteTransactions": slotsPerSample * 200,
+			"samplePeriodSecs":       period,
+		})
+	}
+	return samples
+}
+
+func param(req request, i int, out interface{}) *rpcclient.RPCError {
+	if i >= len(req.Params) {
+		return invalidParams(fmt.Sprintf("missing parameter %d", i))
+	}
+	if err := json.Unmarshal(req.Params[i], out); err != nil {
+		return invalidParams(err.Error())
+	}
+	return nil
+}
+
+func invalidParams(msg string) *rpcclient.RPCError {
+	return &rpcclient.RPCError{Code: -32602, Message: "Invalid params: " + msg}
+}
+
+// hashString derives a stable base58 value of n bytes from slot and label.
+func hashString(slot uint64, label string, n int) string {
+	return rpcclient.Base58Encode(hashBytes(slot, label, n))
+}
+
+// hashBytes derives n stable bytes from slot and label.
+func hashBytes(slot uint64, label string, n int) []byte {
+	var out []byte
+	for counter := byte(0); len(out) < n; counter++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d/%s/%d", slot, label, counter)))
+		out = append(out, sum[:]...)
+	}
+	return out[:n]
+}
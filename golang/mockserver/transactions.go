@@ -0,0 +1,307 @@
+package mockserver
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// systemProgram is the program every mock transaction calls.
+const systemProgram = "11111111111111111111111111111111"
+
+// Transactions alternate between legacy and version 0, and the last of
+// each block loads an account through an address lookup table.
+func transactionVersion(i int) interface{} {
+	if i%2 == 0 {
+		return "legacy"
+	}
+	return 0
+}
+
+func usesLookupTable(i int) bool {
+	return i == TransactionsPerBlock-1
+}
+
+// signatureBytes derives the signature of a block's i-th transaction.
+// It ends with the index and slot, so getTransaction can find the
+// transaction from its signature alone.
+func signatureBytes(slot uint64, i int) []byte {
+	sig := hashBytes(slot, fmt.Sprintf("tx%d", i), 64)
+	sig[55] = byte(i)
+	binary.BigEndian.PutUint64(sig[56:], slot)
+	return sig
+}
+
+func txSignature(slot uint64, i int) string {
+	return rpcclient.Base58Encode(signatureBytes(slot, i))
+}
+
+// parseSignature returns the slot and index of a mock transaction's
+// signature.
+func parseSignature(signature string) (uint64, int, bool) {
+	sig, err := rpcclient.Base58Decode(signature)
+	if err != nil || len(sig) != 64 {
+		return 0, 0, false
+	}
+	slot, i := binary.BigEndian.Uint64(sig[56:]), int(sig[55])
+	if i >= TransactionsPerBlock || rpcclient.Base58Encode(signatureBytes(slot, i)) != signature {
+		return 0, 0, false
+	}
+	return slot, i, true
+}
+
+// errTransactionVersion is what a node answers when asked for a version 0
+// transaction without maxSupportedTransactionVersion.
+var errTransactionVersion = &rpcclient.RPCError{
+	Code:    rpcclient.ErrCodeUnsupportedTransactionVersion,
+	Message: `Transaction version (0) is not supported by the requesting client. Please try the request again with the following configuration parameter: "maxSupportedTransactionVersion": 0`,
+}
+
+// transactionConfig is the configuration getBlock and getTransaction
+// share.
+type transactionConfig struct {
+	Encoding                       string `json:"encoding"`
+	TransactionDetails             string `json:"transactionDetails"`
+	Rewards                        *bool  `json:"rewards"`
+	MaxSupportedTransactionVersion *int   `json:"maxSupportedTransactionVersion"`
+}
+
+// transaction is a mock transaction: a payer transferring to an account,
+// which the lookup table supplies when one is used.
+type transaction struct {
+	slot      uint64
+	i         int
+	payer     []byte
+	recipient []byte
+	table     []byte
+	blockhash []byte
+}
+
+func newTransaction(slot uint64, i int) transaction {
+	return transaction{
+		slot:      slot,
+		i:         i,
+		payer:     hashBytes(uint64(i), "payer", 32),
+		recipient: hashBytes(slot, fmt.Sprintf("recipient%d", i), 32),
+		table:     hashBytes(0, "lookupTable", 32),
+		blockhash: hashBytes(slot-1, "blockhash", 32),
+	}
+}
+
+// accountKeys are the keys in the form jsonParsed and transactionDetails
+// accounts give them, loaded ones included.
+func (tx transaction) accountKeys() []interface{} {
+	key := func(k []byte, writable, signer bool, source string) interface{} {
+		return map[string]interface{}{
+			"pubkey": rpcclient.Base58Encode(k), "writable": writable, "signer": signer, "source": source,
+		}
+	}
+	keys := []interface{}{key(tx.payer, true, true, "transaction")}
+	if !usesLookupTable(tx.i) {
+		keys = append(keys, key(tx.recipient, true, false, "transaction"))
+	}
+	keys = append(keys, key(make([]byte, 32), false, false, "transaction"))
+	if usesLookupTable(tx.i) {
+		keys = append(keys, key(tx.recipient, true, false, "lookupTable"))
+	}
+	return keys
+}
+
+func (tx transaction) meta() map[string]interface{} {
+	loaded := []string{}
+	if usesLookupTable(tx.i) {
+		loaded = append(loaded, rpcclient.Base58Encode(tx.recipient))
+	}
+	return map[string]interface{}{
+		"err":                  nil,
+		"status":               map[string]interface{}{"Ok": nil},
+		"fee":                  5000,
+		"preBalances":          []uint64{1_000_000_000, 0, 1},
+		"postBalances":         []uint64{998_995_000, 1_000_000, 1},
+		"innerInstructions":    []interface{}{},
+		"logMessages":          []string{"Program 11111111111111111111111111111111 invoke [1]", "Program 11111111111111111111111111111111 success"},
+		"loadedAddresses":      map[string]interface{}{"writable": loaded, "readonly": []string{}},
+		"computeUnitsConsumed": 150,
+	}
+}
+
+// lookups is the message's addressTableLookups, empty for version 0
+// transactions that use no table.
+func (tx transaction) lookups() []interface{} {
+	lookups := []interface{}{}
+	if usesLookupTable(tx.i) {
+		lookups = append(lookups, map[string]interface{}{
+			"accountKey":      rpcclient.Base58Encode(tx.table),
+			"writableIndexes": []int{0},
+			"readonlyIndexes": []int{},
+		})
+	}
+	return lookups
+}
+
+// message returns the message in the json or jsonParsed encoding.
+func (tx transaction) message(parsed bool) map[string]interface{} {
+	var message map[string]interface{}
+	if parsed {
+		recipient := rpcclient.Base58Encode(tx.recipient)
+		message = map[string]interface{}{
+			"accountKeys": tx.accountKeys(),
+			"instructions": []interface{}{map[string]interface{}{
+				"program":   "system",
+				"programId": systemProgram,
+				"parsed": map[string]interface{}{
+					"type": "transfer",
+					"info": map[string]interface{}{"source": rpcclient.Base58Encode(tx.payer), "destination": recipient, "lamports": 1_000_000},
+				},
+				"stackHeight": nil,
+			}},
+		}
+	} else {
+		// The recipient is the second static key, or the first loaded one,
+		// after the payer and the system program.
+		keys := []string{rpcclient.Base58Encode(tx.payer)}
+		programIndex, recipientIndex := 2, 1
+		if usesLookupTable(tx.i) {
+			programIndex, recipientIndex = 1, 2
+		} else {
+			keys = append(keys, rpcclient.Base58Encode(tx.recipient))
+		}
+		keys = append(keys, systemProgram)
+		message = map[string]interface{}{
+			"header": map[string]interface{}{
+				"numRequiredSignatures": 1, "numReadonlySignedAccounts": 0, "numReadonlyUnsignedAccounts": 1,
+			},
+			"accountKeys": keys,
+			"instructions": []interface{}{map[string]interface{}{
+				"programIdIndex": programIndex,
+				"accounts":       []int{0, recipientIndex},
+				"data":           "3Bxs4h24hBtQy9rw",
+				"stackHeight":    nil,
+			}},
+		}
+	}
+	message["recentBlockhash"] = rpcclient.Base58Encode(tx.blockhash)
+	if transactionVersion(tx.i) == 0 {
+		message["addressTableLookups"] = tx.lookups()
+	}
+	return message
+}
+
+// wire serializes the transaction as it goes over the wire: a signature
+// and the message, marked as version 0 by its first byte when it is one.
+// Every length is under 128, so compact-u16 lengths are single bytes.
+func (tx transaction) wire() []byte {
+	out := append([]byte{1}, signatureBytes(tx.slot, tx.i)...)
+	versioned := transactionVersion(tx.i) == 0
+	if versioned {
+		out = append(out, 0x80)
+	}
+	out = append(out, 1, 0, 1)
+	if usesLookupTable(tx.i) {
+		out = append(out, 2)
+		out = append(out, tx.payer...)
+		out = append(out, make([]byte, 32)...)
+	} else {
+		out = append(out, 3)
+		out = append(out, tx.payer...)
+		out = append(out, tx.recipient...)
+		out = append(out, make([]byte, 32)...)
+	}
+	out = append(out, tx.blockhash...)
+	programIndex, recipientIndex := byte(2), byte(1)
+	if usesLookupTable(tx.i) {
+		programIndex, recipientIndex = 1, 2
+	}
+	data := []byte{2, 0, 0, 0, 0x40, 0x42, 0x0f, 0, 0, 0, 0, 0}
+	out = append(out, 1, programIndex, 2, 0, recipientIndex, byte(len(data)))
+	out = append(out, data...)
+	if versioned {
+		if usesLookupTable(tx.i) {
+			out = append(out, 1)
+			out = append(out, tx.table...)
+			out = append(out, 1, 0, 0)
+		} else {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// encode returns the transaction, signatures and message, in encoding.
+func (tx transaction) encode(encoding string) (interface{}, *rpcclient.RPCError) {
+	switch encoding {
+	case "", "json", "jsonParsed":
+		return map[string]interface{}{
+			"signatures": []string{txSignature(tx.slot, tx.i)},
+			"message":    tx.message(encoding == "jsonParsed"),
+		}, nil
+	case "base64":
+		return []string{base64.StdEncoding.EncodeToString(tx.wire()), "base64"}, nil
+	case "base58":
+		return []string{rpcclient.Base58Encode(tx.wire()), "base58"}, nil
+	}
+	return nil, invalidParams(fmt.Sprintf("unsupported encoding %q", encoding))
+}
+
+// blockEntry is the transaction as a block lists it, with details "full"
+// or "accounts".
+func (tx transaction) blockEntry(config transactionConfig) (interface{}, *rpcclient.RPCError) {
+	entry := map[string]interface{}{"meta": tx.meta()}
+	if config.MaxSupportedTransactionVersion != nil {
+		entry["version"] = transactionVersion(tx.i)
+	}
+	if config.TransactionDetails == "accounts" {
+		entry["transaction"] = map[string]interface{}{
+			"signatures":  []string{txSignature(tx.slot, tx.i)},
+			"accountKeys": tx.accountKeys(),
+		}
+		return entry, nil
+	}
+	encoded, err := tx.encode(config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	entry["transaction"] = encoded
+	return entry, nil
+}
+
+// getTransaction answers getTransaction for the signatures of served
+// blocks, and with null for any other.
+func (s *Server) getTransaction(req request) (interface{}, *rpcclient.RPCError) {
+	var signature string
+	if err := param(req, 0, &signature); err != nil {
+		return nil, err
+	}
+	var config transactionConfig
+	if len(req.Params) > 1 {
+		if err := param(req, 1, &config); err != nil {
+			return nil, err
+		}
+	}
+	slot, i, ok := parseSignature(signature)
+	tip := s.slot()
+	if !ok || slot > tip || slot < tip-History || slot%SkipEvery == SkipEvery-1 {
+		return nil, nil
+	}
+	if transactionVersion(i) == 0 && config.MaxSupportedTransactionVersion == nil {
+		return nil, errTransactionVersion
+	}
+	tx := newTransaction(slot, i)
+	encoded, err := tx.encode(config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"slot":        slot,
+		"blockTime":   s.start.Add(-time.Duration(tip-slot) * slotTime).Unix(),
+		"transaction": encoded,
+		"meta":        tx.meta(),
+	}
+	if config.MaxSupportedTransactionVersion != nil {
+		result["version"] = transactionVersion(i)
+	}
+	return result, nil
+}
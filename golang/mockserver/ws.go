@@ -0,0 +1,151 @@
+package mockserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// isWebSocket reports whether r asks to upgrade to a PubSub connection,
+// which the server accepts on the same address as JSON-RPC.
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// serveWS answers PubSub subscriptions until the client goes away.
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		nextSub uint64
+	)
+	defer wg.Wait()
+	send := func(v interface{}) error {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return conn.Write(ctx, websocket.MessageText, body)
+	}
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			cancel()
+			return
+		}
+		var req request
+		resp := response{JSONrpc: "2.0"}
+		if err := json.Unmarshal(data, &req); err != nil {
+			resp.ID = json.RawMessage("null")
+			resp.Error = &rpcclient.RPCError{Code: -32700, Message: "Parse error"}
+			send(resp)
+			continue
+		}
+		resp.ID = req.ID
+		notifications, ok := subscriptions[req.Method]
+		if !ok {
+			resp.Error = &rpcclient.RPCError{Code: -32601, Message: "Method not found"}
+			send(resp)
+			continue
+		}
+		sub := nextSub
+		nextSub++
+		resp.Result = sub
+		if err := send(resp); err != nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.notify(ctx, sub, notifications, send)
+		}()
+	}
+}
+
+// subscription is the name of a subscription's notifications and the
+// results notified for each new slot.
+type subscription struct {
+	method  string
+	results func(slot uint64) []interface{}
+}
+
+// subscriptions are the supported subscribe methods.
+var subscriptions = map[string]subscription{
+	"slotSubscribe": {"slotNotification", func(slot uint64) []interface{} {
+		return []interface{}{map[string]interface{}{"slot": slot, "parent": slot - 1, "root": slot - FinalizedLag}}
+	}},
+	// Every block's transactions are logged, and each touches one account
+	// of the program; skipped slots have neither.
+	"logsSubscribe": {"logsNotification", func(slot uint64) []interface{} {
+		var results []interface{}
+		for i := 0; i < TransactionsPerBlock && slot%SkipEvery != SkipEvery-1; i++ {
+			results = append(results, map[string]interface{}{
+				"context": map[string]interface{}{"slot": slot},
+				"value": map[string]interface{}{
+					"signature": txSignature(slot, i),
+					"err":       nil,
+					"logs":      []string{"Program 11111111111111111111111111111111 invoke [1]", "Program 11111111111111111111111111111111 success"},
+				},
+			})
+		}
+		return results
+	}},
+	"programSubscribe": {"programNotification", func(slot uint64) []interface{} {
+		var results []interface{}
+		for i := 0; i < TransactionsPerBlock && slot%SkipEvery != SkipEvery-1; i++ {
+			results = append(results, map[string]interface{}{
+				"context": map[string]interface{}{"slot": slot},
+				"value": map[string]interface{}{
+					"pubkey": hashString(uint64(i), "account", 32),
+					"account": map[string]interface{}{
+						"data":       []string{base64.StdEncoding.EncodeToString([]byte(hashString(slot, "data", 32))), "base64"},
+						"executable": false,
+						"lamports":   1_000_000 + slot,
+						"owner":      "11111111111111111111111111111111",
+						"rentEpoch":  0,
+						"space":      44,
+					},
+				},
+			})
+		}
+		return results
+	}},
+}
+
+// notify sends a subscription's notifications as each slot starts.
+func (s *Server) notify(ctx context.Context, sub uint64, notifications subscription, send func(interface{}) error) {
+	for {
+		slot := s.slot() + 1
+		next := s.start.Add(time.Duration(slot-genesisSlot) * slotTime)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		for _, result := range notifications.results(slot) {
+			err := send(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  notifications.method,
+				"params":  map[string]interface{}{"subscription": sub, "result": result},
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+}
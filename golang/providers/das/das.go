@@ -0,0 +1,90 @@
+// Package das benchmarks the Digital Asset Standard (DAS) API that Helius,
+// Triton and other providers serve next to standard Solana RPC, the
+// endpoints NFT and compressed-asset workloads run on. Importing it
+// registers getAsset, getAssetsByOwner and searchAssets with bench, and a
+// "das" scenario running all three.
+package das
+
+import (
+	"context"
+	"fmt"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// MaxLimit is the largest page the DAS API serves.
+const MaxLimit = 1000
+
+type provider struct{}
+
+func init() { bench.RegisterProvider(provider{}) }
+
+func (provider) Name() string { return "das" }
+
+func (provider) Methods() map[string]bench.Method {
+	return map[string]bench.Method{
+		"getAsset": {Check: needsAssets, Run: func(ctx context.Context, s *bench.JSONRPCTester) (*rpcclient.Result, error) {
+			// Without asset IDs getAsset looks up the token mints,
+			// which DAS serves as fungible assets.
+			assets := s.DASAssets
+			if assets == nil {
+				assets = s.TokenMints
+			}
+			return GetAsset(ctx, s.Client, assets.Next())
+		}},
+		"getAssetsByOwner": {Check: needsOwners, Run: func(ctx context.Context, s *bench.JSONRPCTester) (*rpcclient.Result, error) {
+			return GetAssetsByOwner(ctx, s.Client, s.Accounts.Next(), 1, s.DASLimit)
+		}},
+		"searchAssets": {Check: needsOwners, Run: func(ctx context.Context, s *bench.JSONRPCTester) (*rpcclient.Result, error) {
+			return SearchAssets(ctx, s.Client, map[string]interface{}{"ownerAddress": s.Accounts.Next()}, 1, s.DASLimit)
+		}},
+	}
+}
+
+func (provider) Scenarios() map[string][]string {
+	return map[string][]string{
+		"das": {"getAsset", "getAssetsByOwner", "searchAssets"},
+	}
+}
+
+func needsAssets(s *bench.JSONRPCTester) error {
+	if s.DASAssets == nil && s.TokenMints == nil {
+		return fmt.Errorf("needs asset IDs (-das-assets or -token-mints)")
+	}
+	return nil
+}
+
+func needsOwners(s *bench.JSONRPCTester) error {
+	if s.Accounts == nil {
+		return fmt.Errorf("needs owner addresses (-accounts)")
+	}
+	return nil
+}
+
+// GetAsset fetches one asset by ID.
+func GetAsset(ctx context.Context, c *rpcclient.Client, id string) (*rpcclient.Result, error) {
+	return c.Call(ctx, "getAsset", map[string]interface{}{"id": id})
+}
+
+// GetAssetsByOwner fetches one page, counting from 1, of an owner's assets.
+func GetAssetsByOwner(ctx context.Context, c *rpcclient.Client, owner string, page, limit int) (*rpcclient.Result, error) {
+	return c.Call(ctx, "getAssetsByOwner", map[string]interface{}{
+		"ownerAddress": owner,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// SearchAssets fetches one page, counting from 1, of the assets matching
+// criteria, such as {"ownerAddress": owner} or {"grouping": ["collection",
+// id]}.
+func SearchAssets(ctx context.Context, c *rpcclient.Client, criteria map[string]interface{}, page, limit int) (*rpcclient.Result, error) {
+	params := make(map[string]interface{}, len(criteria)+2)
+	for key, value := range criteria {
+		params[key] = value
+	}
+	params["page"] = page
+	params["limit"] = limit
+	return c.Call(ctx, "searchAssets", params)
+}
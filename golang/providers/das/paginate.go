@@ -0,0 +1,119 @@
+package das
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"solana-rpc-performance-golang/bench"
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+type Page struct {
+	Page    int    `json:"page"`
+	Latency int64  `json:"latency"`
+	Size    int    `json:"size"`
+	Items   int    `json:"items"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PaginationStats covers a walk through an owner's assets.
+type PaginationStats struct {
+	Method      string             `json:"method"`
+	Owner       string             `json:"owner"`
+	PageLimit   int                `json:"pageLimit"`
+	Pages       int                `json:"pages"`
+	Items       int                `json:"items"`
+	ReachedEnd  bool               `json:"reachedEnd"`
+	TotalTime   int64              `json:"totalTime"`
+	TotalBytes  int64              `json:"totalBytes"`
+	PageLatency stats.LatencyStats `json:"pageLatency"`
+	Error       string             `json:"error,omitempty"`
+	Interrupted bool               `json:"interrupted,omitempty"`
+	PageSamples []Page             `json:"pageSamples"`
+}
+
+type assetPage struct {
+	Items []interface{} `json:"items"`
+}
+
+// RunPagination pages through owner's assets with method, getAssetsByOwner
+// or searchAssets, for up to depth pages of limit items, timing every page.
+// Deep pages are where DAS indexes slow down, since each is an offset
+// query.
+func RunPagination(ctx context.Context, s *bench.JSONRPCTester, method, owner string, depth, limit int) (*PaginationStats, error) {
+	if _, err := rpcclient.ParsePublicKey(owner); err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > MaxLimit {
+		return nil, fmt.Errorf("page limit must be between 1 and %d, got %d", MaxLimit, limit)
+	}
+	fetch := func(page int) (*rpcclient.Result, error) {
+		return GetAssetsByOwner(ctx, s.Client, owner, page, limit)
+	}
+	switch method {
+	case "getAssetsByOwner":
+	case "searchAssets":
+		fetch = func(page int) (*rpcclient.Result, error) {
+			return SearchAssets(ctx, s.Client, map[string]interface{}{"ownerAddress": owner}, page, limit)
+		}
+	default:
+		return nil, fmt.Errorf("cannot paginate %q (want getAssetsByOwner or searchAssets)", method)
+	}
+	s.Log().Info("paginating "+method, "owner", owner, "pages", depth, "limit", limit)
+
+	result := &PaginationStats{Method: method, Owner: owner, PageLimit: limit}
+	var latencies []int64
+	start := time.Now()
+
+	for page := 1; page <= depth && !stopped(ctx, s); page++ {
+		call, err := fetch(page)
+		if err != nil {
+			return nil, err
+		}
+
+		sample := Page{Page: page, Latency: call.Latency, Size: call.Size}
+		result.TotalBytes += int64(call.Size)
+
+		var assets assetPage
+		if err := rpcclient.DecodeResult(call, &assets); err != nil {
+			sample.Error = err.Error()
+			result.PageSamples = append(result.PageSamples, sample)
+			result.Error = fmt.Sprintf("page %d: %v", page, err)
+			break
+		}
+
+		latencies = append(latencies, call.Latency)
+		result.Pages++
+		result.Items += len(assets.Items)
+		sample.Items = len(assets.Items)
+		result.PageSamples = append(result.PageSamples, sample)
+
+		if len(assets.Items) < limit {
+			result.ReachedEnd = true
+			break
+		}
+		if page%10 == 0 {
+			s.Log().Info("pages fetched", "done", page, "total", depth, "items", result.Items)
+		}
+	}
+
+	result.TotalTime = time.Since(start).Milliseconds()
+	result.PageLatency = stats.Summarize(latencies, s.Percentiles...)
+	result.Interrupted = stopped(ctx, s)
+	return result, nil
+}
+
+// stopped reports whether the run was interrupted, through ctx or the
+// tester's Stop channel.
+func stopped(ctx context.Context, s *bench.JSONRPCTester) bool {
+	select {
+	case <-s.Stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,227 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+const (
+	tuiRefresh       = 250 * time.Millisecond
+	tuiSparkWidth    = 60
+	tuiRollingWindow = 500
+	tuiErrorFeed     = 6
+)
+
+// TerminalRestore shows the cursor again and leaves the alternate screen,
+// undoing what a running Dashboard changed.
+const TerminalRestore = "\x1b[?25h\x1b[?1049l"
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+type tuiMethodCounter struct {
+	requests int
+	errors   int
+	total    int64
+	last     int64
+}
+
+type tuiError struct {
+	at     time.Time
+	method string
+	msg    string
+}
+
+// Dashboard redraws a full-screen view of the running benchmark using
+// plain ANSI escapes: a latency sparkline, rolling percentiles, per-method
+// counters and the most recent errors.
+type Dashboard struct {
+	out      io.Writer
+	endpoint string
+	expected int
+
+	mu      sync.Mutex
+	start   time.Time
+	done    int
+	errors  int
+	rolling []int64
+	tick    []int64
+	spark   []float64
+	methods map[string]*tuiMethodCounter
+	errFeed []tuiError
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewDashboard(endpoint string) *Dashboard {
+	return &Dashboard{
+		out:      os.Stdout,
+		endpoint: rpcclient.RedactURL(endpoint),
+		methods:  make(map[string]*tuiMethodCounter),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (d *Dashboard) Observe(result rpcclient.Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.done++
+	counter, ok := d.methods[result.Method]
+	if !ok {
+		counter = &tuiMethodCounter{}
+		d.methods[result.Method] = counter
+	}
+	counter.requests++
+	counter.last = result.Latency
+
+	if !result.Success {
+		d.errors++
+		counter.errors++
+		d.errFeed = append(d.errFeed, tuiError{at: time.Now(), method: result.Method, msg: result.Error})
+		if len(d.errFeed) > tuiErrorFeed {
+			d.errFeed = d.errFeed[len(d.errFeed)-tuiErrorFeed:]
+		}
+		return
+	}
+
+	counter.total += result.Latency
+	d.tick = append(d.tick, result.Latency)
+	d.rolling = append(d.rolling, result.Latency)
+	if len(d.rolling) > tuiRollingWindow {
+		d.rolling = d.rolling[len(d.rolling)-tuiRollingWindow:]
+	}
+}
+
+func (d *Dashboard) Start(expected int) {
+	d.expected, d.start = expected, time.Now()
+	// Alternate screen buffer, cursor hidden.
+	fmt.Fprint(d.out, "\x1b[?1049h\x1b[?25l")
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(tuiRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				d.render()
+			}
+		}
+	}()
+}
+
+func (d *Dashboard) Stop() {
+	close(d.stop)
+	d.wg.Wait()
+	fmt.Fprint(d.out, TerminalRestore)
+}
+
+func (d *Dashboard) render() {
+	d.mu.Lock()
+	if len(d.tick) > 0 {
+		var sum int64
+		for _, latency := range d.tick {
+			sum += latency
+		}
+		d.spark = append(d.spark, float64(sum)/float64(len(d.tick)))
+		d.tick = d.tick[:0]
+	} else {
+		d.spark = append(d.spark, -1)
+	}
+	if len(d.spark) > tuiSparkWidth {
+		d.spark = d.spark[len(d.spark)-tuiSparkWidth:]
+	}
+
+	elapsed := time.Since(d.start)
+	rolling := stats.Summarize(append([]int64(nil), d.rolling...))
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "Go RPC benchmark  %s\n", d.endpoint)
+	fmt.Fprintf(&b, "elapsed %-8s  %d/%d requests  %.1f req/s  errors %d (%.1f%%)\n\n",
+		elapsed.Round(time.Second), d.done, d.expected, float64(d.done)/elapsed.Seconds(),
+		d.errors, percent(d.errors, d.done))
+
+	fmt.Fprintf(&b, "latency (avg per %s)\n  %s\n\n", tuiRefresh, sparkline(d.spark))
+	fmt.Fprintf(&b, "rolling last %d: p50 %dms  p95 %dms  p99 %dms  max %dms\n\n",
+		len(d.rolling), rolling.P50, rolling.P95, rolling.P99, rolling.Max)
+
+	fmt.Fprintf(&b, "%-28s %9s %7s %9s %9s\n", "method", "requests", "errors", "avg ms", "last ms")
+	names := make([]string, 0, len(d.methods))
+	for name := range d.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		counter := d.methods[name]
+		avg := 0.0
+		if ok := counter.requests - counter.errors; ok > 0 {
+			avg = float64(counter.total) / float64(ok)
+		}
+		fmt.Fprintf(&b, "%-28s %9d %7d %9.1f %9d\n", name, counter.requests, counter.errors, avg, counter.last)
+	}
+
+	b.WriteString("\nrecent errors\n")
+	if len(d.errFeed) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, e := range d.errFeed {
+		msg := e.msg
+		if len(msg) > 80 {
+			msg = msg[:77] + "..."
+		}
+		fmt.Fprintf(&b, "  %s %-24s %s\n", e.at.Format("15:04:05"), e.method, msg)
+	}
+	d.mu.Unlock()
+
+	fmt.Fprint(d.out, b.String())
+}
+
+func sparkline(points []float64) string {
+	lo, hi := -1.0, -1.0
+	for _, p := range points {
+		if p < 0 {
+			continue
+		}
+		if lo < 0 || p < lo {
+			lo = p
+		}
+		if p > hi {
+			hi = p
+		}
+	}
+
+	var b strings.Builder
+	for _, p := range points {
+		if p < 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		level := 0
+		if hi > lo {
+			level = int((p - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	if hi >= 0 {
+		fmt.Fprintf(&b, "  %.0f-%.0fms", lo, hi)
+	}
+	return b.String()
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
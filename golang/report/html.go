@@ -0,0 +1,134 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// htmlTemplate lays out an HTMLReport. It needs nothing from the
+// network, so an archived report opens anywhere.
+//
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlPage = template.Must(template.New("report").Parse(htmlTemplate))
+
+// HTMLReport gathers a run's results into a standalone HTML page with the
+// tables of a MarkdownReport, for archiving with -upload or opening in a
+// browser.
+type HTMLReport struct {
+	mu    sync.Mutex
+	sinks []*TallySink
+}
+
+// NewHTMLReport returns an empty report.
+func NewHTMLReport() *HTMLReport {
+	return &HTMLReport{}
+}
+
+// Sink returns a sink adding the results of a benchmark of endpoint to the
+// report as a section, titled job if it isn't empty.
+func (r *HTMLReport) Sink(job, endpoint string) *TallySink {
+	sink := newTallySink(job, endpoint)
+	r.mu.Lock()
+	r.sinks = append(r.sinks, sink)
+	r.mu.Unlock()
+	return sink
+}
+
+// WriteFile writes the report to path.
+func (r *HTMLReport) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Bytes returns the report.
+func (r *HTMLReport) Bytes() ([]byte, error) {
+	var b bytes.Buffer
+	if err := r.Write(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// htmlRow is a table row: a method, or every method of an endpoint.
+type htmlRow struct {
+	Name                             string
+	Requests                         int
+	Success, Avg, P50, P95, P99, Max string
+}
+
+// htmlSection is an endpoint's table.
+type htmlSection struct {
+	Name, Endpoint string
+	Methods        []htmlRow
+	All            *htmlRow
+	Quota          string
+}
+
+// Write writes the report to w: an overview of the endpoints when there is
+// more than one, then a table of methods per endpoint.
+func (r *HTMLReport) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	page := struct {
+		Generated string
+		Overview  []htmlRow
+		Sections  []htmlSection
+	}{Generated: time.Now().UTC().Format("2006-01-02 15:04 MST")}
+	for _, sink := range r.sinks {
+		sink.mu.Lock()
+		if len(r.sinks) > 1 {
+			page.Overview = append(page.Overview, newHTMLRow(sink.Name, sink.all))
+		}
+		section := htmlSection{Name: sink.Name, Endpoint: sink.Endpoint, Quota: quotaLine(sink)}
+		for _, method := range sink.methodNames() {
+			section.Methods = append(section.Methods, newHTMLRow(method, sink.methods[method]))
+		}
+		if len(sink.methods) > 1 {
+			all := newHTMLRow("All", sink.all)
+			section.All = &all
+		}
+		sink.mu.Unlock()
+		page.Sections = append(page.Sections, section)
+	}
+	return htmlPage.Execute(w, page)
+}
+
+// newHTMLRow returns the row of m, with dashes for the latencies of a
+// method that never succeeded.
+func newHTMLRow(name string, m *methodTally) htmlRow {
+	row := htmlRow{Name: name, Requests: m.requests, Avg: "-", P50: "-", P95: "-", P99: "-", Max: "-"}
+	switch {
+	case m.requests > 0 && m.unsupported == m.requests:
+		row.Success = "unsupported"
+	case m.requests == m.failures:
+		row.Success = fmt.Sprintf("%.2f%%", 100-m.errorRate())
+	default:
+		row.Success = fmt.Sprintf("%.2f%%", 100-m.errorRate())
+		latency := m.latency.Summary()
+		row.Avg = fmt.Sprintf("%.1f", latency.Avg)
+		row.P50 = fmt.Sprint(latency.P50)
+		row.P95 = fmt.Sprint(latency.P95)
+		row.P99 = fmt.Sprint(latency.P99)
+		row.Max = fmt.Sprint(latency.Max)
+	}
+	return row
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// influxWriteTimeout bounds a write to an InfluxDB server.
+const influxWriteTimeout = 30 * time.Second
+
+// WriteInflux delivers InfluxDB line protocol to target: a write URL,
+// such as http://localhost:8086/api/v2/write?org=o&bucket=b or a 1.x
+// server's /write?db=bench, or otherwise a file. A token, if set, is sent as "Authorization: Token <token>".
+func WriteInflux(target, token string, lines []byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, lines, 0o644)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), influxWriteTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(lines))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx write to %s: %w", rpcclient.RedactURL(target), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influx write to %s: %s: %s", rpcclient.RedactURL(target), resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
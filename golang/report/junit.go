@@ -0,0 +1,192 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/stats"
+)
+
+// JUnitReport gathers a run's results into JUnit XML, so CI systems show
+// a benchmark gate in their test report. Each benchmark is a test suite
+// with a test case per method, which fails if the method misses the SLO,
+// and a test case per SLO check over all its requests. A test case's time
+// is its p99 latency.
+type JUnitReport struct {
+	targetP99    time.Duration
+	maxErrorRate float64
+
+	mu    sync.Mutex
+	sinks []*TallySink
+}
+
+// NewJUnitReport returns a report judging results against a p99 target
+// and an error percentage ceiling.
+func NewJUnitReport(targetP99 time.Duration, maxErrorRate float64) *JUnitReport {
+	return &JUnitReport{targetP99: targetP99, maxErrorRate: maxErrorRate}
+}
+
+// Sink returns a sink adding the results of a benchmark of endpoint to the
+// report as a test suite, named job if it isn't empty.
+func (r *JUnitReport) Sink(job, endpoint string) *TallySink {
+	sink := newTallySink(job, endpoint)
+	r.mu.Lock()
+	r.sinks = append(r.sinks, sink)
+	r.mu.Unlock()
+	return sink
+}
+
+// WriteFile writes the report to path.
+func (r *JUnitReport) WriteFile(path string) error {
+	data, err := xml.MarshalIndent(r.build(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o644)
+}
+
+func (r *JUnitReport) build() *junitSuites {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	suites := &junitSuites{Name: "solana-rpc-bench"}
+	hostname, _ := os.Hostname()
+	var ms int64
+	for _, sink := range r.sinks {
+		suite := junitSuiteOf(sink, r.targetP99, r.maxErrorRate)
+		suite.Hostname = hostname
+		suites.Suites = append(suites.Suites, suite)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		ms += suite.ms
+	}
+	suites.Time = seconds(ms)
+	return suites
+}
+
+// junitSuiteOf judges the results of s, a test case per method in name
+// order, then the SLO checks.
+func junitSuiteOf(s *TallySink, targetP99 time.Duration, maxErrorRate float64) *junitSuite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	suite := &junitSuite{Name: s.Name}
+	if !s.start.IsZero() {
+		suite.ms = s.end.Sub(s.start).Milliseconds()
+		suite.Timestamp = s.start.UTC().Format("2006-01-02T15:04:05")
+		suite.Time = seconds(suite.ms)
+	}
+	for _, method := range s.methodNames() {
+		m := s.methods[method]
+		latency := m.latency.Summary()
+		testCase := junitCase{
+			Name:      method,
+			ClassName: s.Name,
+			Time:      seconds(latency.P99),
+			SystemOut: fmt.Sprintf("requests=%d failures=%d avg=%.1fms p50=%dms p95=%dms p99=%dms max=%dms",
+				m.requests, m.failures, latency.Avg, latency.P50, latency.P95, latency.P99, latency.Max),
+		}
+		switch breaches := junitBreaches(m, latency, targetP99, maxErrorRate); {
+		case m.unsupported == m.requests:
+			testCase.Skipped = &junitMessage{Message: "the endpoint does not serve " + method}
+		case len(breaches) > 0:
+			testCase.Failure = &junitMessage{
+				Message: strings.Join(breaches, "; "),
+				Type:    "SLOBreach",
+				Text:    strings.Join(m.errors, "\n"),
+			}
+		}
+		suite.add(testCase)
+	}
+
+	latency := s.all.latency.Summary()
+	if targetP99 > 0 {
+		check := junitCase{Name: fmt.Sprintf("SLO p99 <= %s", targetP99), ClassName: s.Name, Time: seconds(latency.P99)}
+		if s.all.requests == s.all.failures {
+			check.Failure = &junitMessage{Message: "no request succeeded", Type: "SLOBreach"}
+		} else if latency.P99 > targetP99.Milliseconds() {
+			check.Failure = &junitMessage{Message: fmt.Sprintf("p99 %dms above %dms", latency.P99, targetP99.Milliseconds()), Type: "SLOBreach"}
+		}
+		suite.add(check)
+	}
+	if maxErrorRate >= 0 {
+		check := junitCase{Name: fmt.Sprintf("SLO error rate <= %g%%", maxErrorRate), ClassName: s.Name, Time: seconds(0)}
+		if rate := s.all.errorRate(); rate > maxErrorRate {
+			check.Failure = &junitMessage{Message: fmt.Sprintf("error rate %.2f%% above %.2f%%", rate, maxErrorRate), Type: "SLOBreach", Text: strings.Join(s.all.errors, "\n")}
+		}
+		suite.add(check)
+	}
+	return suite
+}
+
+// junitBreaches returns how m misses the SLO, or nil if it meets it.
+func junitBreaches(m *methodTally, latency stats.LatencyStats, targetP99 time.Duration, maxErrorRate float64) []string {
+	var breaches []string
+	if rate := m.errorRate(); maxErrorRate >= 0 && rate > maxErrorRate {
+		breaches = append(breaches, fmt.Sprintf("error rate %.2f%% above %.2f%%", rate, maxErrorRate))
+	}
+	if targetP99 > 0 && m.requests > m.failures && latency.P99 > targetP99.Milliseconds() {
+		breaches = append(breaches, fmt.Sprintf("p99 %dms above %dms", latency.P99, targetP99.Milliseconds()))
+	}
+	return breaches
+}
+
+// seconds formats a duration in milliseconds as JUnit's seconds.
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Name     string        `xml:"name,attr"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Time     string        `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr,omitempty"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Hostname  string      `xml:"hostname,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+
+	ms int64
+}
+
+func (s *junitSuite) add(c junitCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+	if c.Skipped != nil {
+		s.Skipped++
+	}
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
@@ -0,0 +1,32 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// NewLogger returns a logger writing text or JSON records to w at level and
+// above. rpcclient.LevelTrace is shown as TRACE.
+func NewLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.LevelKey && len(groups) == 0 {
+				if l, ok := attr.Value.Any().(slog.Level); ok && l <= rpcclient.LevelTrace {
+					attr.Value = slog.StringValue("TRACE")
+				}
+			}
+			return attr
+		},
+	}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+}
@@ -0,0 +1,139 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// markdownColumns heads the rows MarkdownReport writes; the numbers are
+// right-aligned.
+const markdownColumns = "| Requests | Success | Avg | p50 | p95 | p99 | Max |"
+
+// MarkdownReport gathers a run's results into GitHub-flavored Markdown
+// tables, a row per method of every benchmarked endpoint, for pasting
+// into pull requests and provider evaluations.
+type MarkdownReport struct {
+	mu    sync.Mutex
+	sinks []*TallySink
+}
+
+// NewMarkdownReport returns an empty report.
+func NewMarkdownReport() *MarkdownReport {
+	return &MarkdownReport{}
+}
+
+// Sink returns a sink adding the results of a benchmark of endpoint to the
+// report as a section, titled job if it isn't empty.
+func (r *MarkdownReport) Sink(job, endpoint string) *TallySink {
+	sink := newTallySink(job, endpoint)
+	r.mu.Lock()
+	r.sinks = append(r.sinks, sink)
+	r.mu.Unlock()
+	return sink
+}
+
+// WriteFile writes the report to path.
+func (r *MarkdownReport) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Write writes the report to w: an overview of the endpoints when there is
+// more than one, then a table of methods per endpoint.
+func (r *MarkdownReport) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "# RPC Benchmark Report\n\n")
+	fmt.Fprintf(&b, "Generated %s. Latencies are in milliseconds.\n", time.Now().UTC().Format("2006-01-02 15:04 MST"))
+
+	if len(r.sinks) > 1 {
+		fmt.Fprintf(&b, "\n| Endpoint %s\n", markdownColumns)
+		fmt.Fprintf(&b, "|---%s\n", strings.Repeat("|---:", 7)+"|")
+		for _, sink := range r.sinks {
+			sink.mu.Lock()
+			fmt.Fprintf(&b, "| %s %s\n", markdownCell(sink.Name), markdownRow(sink.all))
+			sink.mu.Unlock()
+		}
+	}
+
+	for _, sink := range r.sinks {
+		sink.mu.Lock()
+		fmt.Fprintf(&b, "\n## %s\n\n", sink.Name)
+		if sink.Name != sink.Endpoint {
+			fmt.Fprintf(&b, "`%s`\n\n", sink.Endpoint)
+		}
+		fmt.Fprintf(&b, "| Method %s\n", markdownColumns)
+		fmt.Fprintf(&b, "|---%s\n", strings.Repeat("|---:", 7)+"|")
+		for _, method := range sink.methodNames() {
+			fmt.Fprintf(&b, "| `%s` %s\n", method, markdownRow(sink.methods[method]))
+		}
+		if len(sink.methods) > 1 {
+			fmt.Fprintf(&b, "| **All** %s\n", markdownRow(sink.all))
+		}
+		if line := quotaLine(sink); line != "" {
+			fmt.Fprintf(&b, "\n%s\n", line)
+		}
+		sink.mu.Unlock()
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownRow returns the cells of m after the first, with the closing
+// pipe.
+func markdownRow(m *methodTally) string {
+	if m.requests > 0 && m.unsupported == m.requests {
+		return fmt.Sprintf("| %d | unsupported | - | - | - | - | - |", m.requests)
+	}
+	success := fmt.Sprintf("%.2f%%", 100-m.errorRate())
+	if m.requests == m.failures {
+		return fmt.Sprintf("| %d | %s | - | - | - | - | - |", m.requests, success)
+	}
+	latency := m.latency.Summary()
+	return fmt.Sprintf("| %d | %s | %.1f | %d | %d | %d | %d |",
+		m.requests, success, latency.Avg, latency.P50, latency.P95, latency.P99, latency.Max)
+}
+
+// quotaLine sums up the rate-limit headers and refusals of sink's
+// endpoint, or returns "" if there were none. The caller holds sink.mu.
+func quotaLine(sink *TallySink) string {
+	var parts []string
+	if q := sink.quota; q != nil {
+		left := fmt.Sprintf("%d", q.Remaining)
+		if q.Limit > 0 {
+			left += fmt.Sprintf(" of %d", q.Limit)
+		}
+		if q.Window != "" {
+			left += " per " + q.Window
+		}
+		parts = append(parts, fmt.Sprintf("%s left at the end, %d at the lowest", left, sink.minRemaining))
+	}
+	if sink.rateLimited > 0 {
+		parts = append(parts, fmt.Sprintf("%d requests rate limited", sink.rateLimited))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Rate-limit quota: " + strings.Join(parts, "; ") + "."
+}
+
+// markdownCell escapes the pipes in s, which would end a table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
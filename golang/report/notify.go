@@ -0,0 +1,172 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// notifyTimeout bounds the post of a notification.
+const notifyTimeout = 15 * time.Second
+
+// Colors of the notification's sidebar, for a run that met its SLO and
+// one that breached it.
+const (
+	notifyColorOK     = 0x2eb67d
+	notifyColorBreach = 0xe01e5a
+)
+
+// RunSummary is what a notification says about a finished run.
+type RunSummary struct {
+	Title       string
+	Endpoint    string
+	Requests    int
+	SuccessRate float64
+	Latency     stats.LatencyStats
+	// Throughput is the achieved request rate, if known.
+	Throughput float64
+	// Baseline, if set, compares the run, as A, with the baseline file
+	// BaselinePath, as B.
+	Baseline     *stats.Significance
+	BaselinePath string
+	// Breaches lists how the run missed its SLO.
+	Breaches []string
+	// Regressions lists how the run did worse than the run before it,
+	// when it is one of a series.
+	Regressions []string
+}
+
+// fields returns the summary as name and value pairs, in display order.
+func (s *RunSummary) fields() [][2]string {
+	var fields [][2]string
+	if s.Endpoint != "" {
+		fields = append(fields, [2]string{"Endpoint", rpcclient.RedactURL(s.Endpoint)})
+	}
+	fields = append(fields, [][2]string{
+		{"Requests", fmt.Sprint(s.Requests)},
+		{"Success rate", fmt.Sprintf("%.2f%%", s.SuccessRate)},
+		{"Latency p50 / p95 / p99", fmt.Sprintf("%d / %d / %d ms", s.Latency.P50, s.Latency.P95, s.Latency.P99)},
+	}...)
+	if s.Throughput > 0 {
+		fields = append(fields, [2]string{"Throughput", fmt.Sprintf("%.1f req/s", s.Throughput)})
+	}
+	if b := s.Baseline; b != nil {
+		verdict := "no significant change"
+		if b.Significant && b.CliffsDelta > 0 {
+			verdict = fmt.Sprintf("slower (%s effect)", b.Effect)
+		} else if b.Significant {
+			verdict = fmt.Sprintf("faster (%s effect)", b.Effect)
+		}
+		fields = append(fields, [2]string{"vs. " + s.BaselinePath,
+			fmt.Sprintf("median %d ms vs. %d ms: %s, p=%.3g", b.MedianA, b.MedianB, verdict, b.PValue)})
+	}
+	if len(s.Breaches) > 0 {
+		fields = append(fields, [2]string{"SLO breached", strings.Join(s.Breaches, "; ")})
+	}
+	if len(s.Regressions) > 0 {
+		fields = append(fields, [2]string{"Regressed", strings.Join(s.Regressions, "; ")})
+	}
+	return fields
+}
+
+func (s *RunSummary) color() int {
+	if len(s.Breaches) > 0 || len(s.Regressions) > 0 {
+		return notifyColorBreach
+	}
+	return notifyColorOK
+}
+
+// Notify posts summary to webhook, a Slack or Discord incoming webhook
+// URL. Discord URLs get a Discord embed; any other URL gets a Slack
+// message, which Mattermost and most chat tools also accept.
+func Notify(webhook string, summary *RunSummary) error {
+	var payload interface{}
+	if u, err := url.Parse(webhook); err == nil && isDiscord(u.Hostname()) {
+		payload = discordMessage(summary)
+	} else {
+		payload = slackMessage(summary)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The webhook's path is its secret, so errors name only the host.
+	host := req.URL.Host
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify %s: %w", host, errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notify %s: %s: %s", host, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+func isDiscord(host string) bool {
+	for _, domain := range []string{"discord.com", "discordapp.com"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// slackMessage lays summary out as an attachment, whose color marks a
+// breach, with a plain-text fallback for notifications.
+func slackMessage(summary *RunSummary) map[string]interface{} {
+	var text strings.Builder
+	for _, field := range summary.fields() {
+		fmt.Fprintf(&text, "*%s:* %s\n", field[0], field[1])
+	}
+	return map[string]interface{}{
+		"text": summary.Title,
+		"attachments": []map[string]interface{}{{
+			"color":    fmt.Sprintf("#%06x", summary.color()),
+			"fallback": summary.Title,
+			"blocks": []map[string]interface{}{{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": strings.TrimSuffix(text.String(), "\n")},
+			}},
+		}},
+	}
+}
+
+// discordMessage lays summary out as an embed, whose color marks a
+// breach.
+func discordMessage(summary *RunSummary) map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, field := range summary.fields() {
+		fields = append(fields, map[string]interface{}{
+			"name":   field[0],
+			"value":  field[1],
+			"inline": len(field[1]) < 40,
+		})
+	}
+	return map[string]interface{}{
+		"embeds": []map[string]interface{}{{
+			"title":  summary.Title,
+			"color":  summary.color(),
+			"fields": fields,
+		}},
+	}
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// ProgressReporter prints interim stats for the results seen since its last
+// report, so degradation during a long run shows up as it happens.
+type ProgressReporter struct {
+	logger   *slog.Logger
+	interval time.Duration
+	expected int
+
+	mu       sync.Mutex
+	start    time.Time
+	last     time.Time
+	done     int
+	window   []int64
+	requests int
+	errors   int
+	// quota is the least allowance the rate-limit headers reported since
+	// the last report, if any did.
+	quota *rpcclient.Quota
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewProgressReporter(interval time.Duration, logger *slog.Logger) *ProgressReporter {
+	return &ProgressReporter{
+		logger:   logger,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (p *ProgressReporter) Observe(result rpcclient.Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.requests++
+	if result.Success {
+		p.window = append(p.window, result.Latency)
+	} else {
+		p.errors++
+	}
+	if quota := rpcclient.ParseQuota(result.LimitHeaders, time.Now()); quota != nil && quota.HasRemaining && (p.quota == nil || quota.Remaining < p.quota.Remaining) {
+		p.quota = quota
+	}
+}
+
+func (p *ProgressReporter) Start(expected int) {
+	now := time.Now()
+	p.expected, p.start, p.last = expected, now, now
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.report()
+			}
+		}
+	}()
+}
+
+func (p *ProgressReporter) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+func (p *ProgressReporter) report() {
+	p.mu.Lock()
+	now := time.Now()
+	window, requests, errors, done, quota := p.window, p.requests, p.errors, p.done, p.quota
+	elapsedWindow := now.Sub(p.last)
+	p.window, p.requests, p.errors, p.last, p.quota = nil, 0, 0, now, nil
+	p.mu.Unlock()
+
+	latency := stats.Summarize(window)
+	errorRate := 0.0
+	if requests > 0 {
+		errorRate = float64(errors) / float64(requests) * 100
+	}
+	args := []interface{}{
+		"elapsed", now.Sub(p.start).Round(time.Second).String(),
+		"done", done,
+		"expected", p.expected,
+		"rps", math.Round(float64(requests)/elapsedWindow.Seconds()*10) / 10,
+		"error_pct", math.Round(errorRate*10) / 10,
+		"p50_ms", latency.P50,
+		"p95_ms", latency.P95,
+		"p99_ms", latency.P99,
+	}
+	if quota != nil {
+		args = append(args, "quota_remaining", quota.Remaining)
+		if quota.Limit > 0 {
+			args = append(args, "quota_limit", quota.Limit)
+		}
+	}
+	p.logger.Info("progress", args...)
+}
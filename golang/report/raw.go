@@ -0,0 +1,186 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// RawRow is one request in a raw results file. Latency is in
+// milliseconds, StartedAt in Unix microseconds. Response bodies are left
+// out.
+type RawRow struct {
+	Job          string `json:"job,omitempty" parquet:"name=job, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Endpoint     string `json:"endpoint" parquet:"name=endpoint, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Method       string `json:"method" parquet:"name=method, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	StartedAt    int64  `json:"startedAt" parquet:"name=started_at, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	OffsetMicros int64  `json:"offsetMicros" parquet:"name=offset_micros, type=INT64"`
+	Success      bool   `json:"success" parquet:"name=success, type=BOOLEAN"`
+	Latency      int64  `json:"latency" parquet:"name=latency, type=INT64"`
+	Size         int64  `json:"size" parquet:"name=size, type=INT64"`
+	WireSize     int64  `json:"wireSize,omitempty" parquet:"name=wire_size, type=INT64"`
+	Error        string `json:"error,omitempty" parquet:"name=error, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ErrorCode    int64  `json:"errorCode,omitempty" parquet:"name=error_code, type=INT64"`
+	StatusCode   int32  `json:"statusCode,omitempty" parquet:"name=status_code, type=INT32"`
+	TimedOut     bool   `json:"timedOut,omitempty" parquet:"name=timed_out, type=BOOLEAN"`
+	RateLimited  bool   `json:"rateLimited,omitempty" parquet:"name=rate_limited, type=BOOLEAN"`
+	Unsupported  bool   `json:"unsupported,omitempty" parquet:"name=unsupported, type=BOOLEAN"`
+	Connection   string `json:"connection,omitempty" parquet:"name=connection, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Protocol     string `json:"protocol,omitempty" parquet:"name=protocol, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	IPFamily     string `json:"ipFamily,omitempty" parquet:"name=ip_family, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+}
+
+// Result returns the request the row records, as far as the row goes.
+func (r *RawRow) Result() rpcclient.Result {
+	result := rpcclient.Result{
+		Method:       r.Method,
+		Success:      r.Success,
+		Latency:      r.Latency,
+		Size:         int(r.Size),
+		WireSize:     int(r.WireSize),
+		Error:        r.Error,
+		ErrorCode:    int(r.ErrorCode),
+		StatusCode:   int(r.StatusCode),
+		TimedOut:     r.TimedOut,
+		RateLimited:  r.RateLimited,
+		Unsupported:  r.Unsupported,
+		Connection:   r.Connection,
+		Protocol:     r.Protocol,
+		IPFamily:     r.IPFamily,
+		OffsetMicros: r.OffsetMicros,
+	}
+	if r.StartedAt != 0 {
+		result.StartedAt = time.UnixMicro(r.StartedAt)
+	}
+	return result
+}
+
+// rawRowGroupSize bounds how much a Parquet file buffers before writing
+// a row group, about a million requests.
+const rawRowGroupSize = 64 << 20
+
+// RawResults is a file receiving every request's result as a row: Parquet
+// if its name ends in .parquet, otherwise JSON lines. Parquet suits runs
+// of millions of requests, read with DuckDB or Spark.
+type RawResults struct {
+	file *os.File
+
+	mu      sync.Mutex
+	lines   *bufio.Writer
+	parquet *writer.ParquetWriter
+	err     error
+}
+
+// CreateRawResults creates the raw results file at path.
+func CreateRawResults(path string) (*RawResults, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := &RawResults{file: file}
+	if !strings.EqualFold(filepath.Ext(path), ".parquet") {
+		raw.lines = bufio.NewWriter(file)
+		return raw, nil
+	}
+	if raw.parquet, err = writer.NewParquetWriterFromWriter(file, new(RawRow), 1); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("create parquet file %s: %w", path, err)
+	}
+	raw.parquet.RowGroupSize = rawRowGroupSize
+	raw.parquet.CompressionType = parquet.CompressionCodec_ZSTD
+	return raw, nil
+}
+
+// Sink returns a sink adding the results of a benchmark of endpoint to
+// the file, labelled job if it isn't empty. The endpoint is redacted.
+func (r *RawResults) Sink(job, endpoint string) *RawSink {
+	return &RawSink{raw: r, job: job, endpoint: rpcclient.RedactURL(endpoint)}
+}
+
+func (r *RawResults) write(row *RawRow) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if r.parquet != nil {
+		r.err = r.parquet.Write(row)
+		return
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.lines.Write(data)
+	r.err = r.lines.WriteByte('\n')
+}
+
+// Close finishes and closes the file, returning the first error writing
+// it.
+func (r *RawResults) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	if err == nil && r.parquet != nil {
+		err = r.parquet.WriteStop()
+	}
+	if err == nil && r.lines != nil {
+		err = r.lines.Flush()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// RawSink adds the results of one benchmark to a RawResults file. It is a
+// bench.ResultSink.
+type RawSink struct {
+	raw      *RawResults
+	job      string
+	endpoint string
+}
+
+func (s *RawSink) Start(expected int) {}
+
+func (s *RawSink) Observe(result rpcclient.Result) {
+	row := &RawRow{
+		Job:          s.job,
+		Endpoint:     s.endpoint,
+		Method:       result.Method,
+		OffsetMicros: result.OffsetMicros,
+		Success:      result.Success,
+		Latency:      result.Latency,
+		Size:         int64(result.Size),
+		WireSize:     int64(result.WireSize),
+		Error:        result.Error,
+		ErrorCode:    int64(result.ErrorCode),
+		StatusCode:   int32(result.StatusCode),
+		TimedOut:     result.TimedOut,
+		RateLimited:  result.RateLimited,
+		Unsupported:  result.Unsupported,
+		Connection:   result.Connection,
+		Protocol:     result.Protocol,
+		IPFamily:     result.IPFamily,
+	}
+	if !result.StartedAt.IsZero() {
+		row.StartedAt = result.StartedAt.UnixMicro()
+	}
+	s.raw.write(row)
+}
+
+func (s *RawSink) Stop() {}
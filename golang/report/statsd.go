@@ -0,0 +1,189 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+const (
+	// statsdPacketSize keeps packets under a typical 1500-byte MTU.
+	statsdPacketSize = 1432
+	// statsdFlushInterval bounds how long a metric waits in the buffer,
+	// so dashboards stay live during slow runs.
+	statsdFlushInterval = time.Second
+)
+
+// statsdNameEscaper and statsdTagEscaper replace the characters StatsD
+// and DogStatsD treat as delimiters in metric names and tag values.
+var (
+	statsdNameEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", ",", "_", "#", "_", " ", "_")
+	statsdTagEscaper  = strings.NewReplacer("|", "_", ",", "_", " ", "_")
+)
+
+// StatsD streams request metrics over UDP to a StatsD server, or to a
+// Datadog agent's DogStatsD port, while a run is in progress. Metrics are
+// batched into packets and sent at least once a second.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+	tagged bool
+	tags   []string
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	err  error
+	stop chan struct{}
+	done chan struct{}
+}
+
+// DialStatsD returns a client sending to addr, a host:port. Metric names
+// begin with prefix. If tagged, metrics carry DogStatsD tags, tags among
+// them; otherwise the method and outcome are folded into plain StatsD
+// metric names, and tags are dropped.
+func DialStatsD(addr, prefix string, tagged bool, tags []string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd %s: %w", addr, err)
+	}
+	s := &StatsD{
+		conn:   conn,
+		prefix: strings.TrimSuffix(prefix, "."),
+		tagged: tagged,
+		tags:   tags,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.flushEvery(statsdFlushInterval)
+	return s, nil
+}
+
+func (s *StatsD) flushEvery(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flush()
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// send adds a metric line to the buffer, sending the buffer first if the
+// line would not fit in the packet.
+func (s *StatsD) send(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buf.Len() > 0 && s.buf.Len()+1+len(line) > statsdPacketSize {
+		s.flush()
+	}
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line)
+}
+
+func (s *StatsD) flush() {
+	if s.buf.Len() == 0 {
+		return
+	}
+	// A missing agent shows up as refused writes. The run goes on, and
+	// Close reports the first error.
+	if _, err := s.conn.Write(s.buf.Bytes()); err != nil && s.err == nil {
+		s.err = err
+	}
+	s.buf.Reset()
+}
+
+// Close sends what is buffered and closes the connection, returning the
+// first error sending.
+func (s *StatsD) Close() error {
+	close(s.stop)
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush()
+	err := s.err
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Sink returns a sink emitting the results of a benchmark of endpoint,
+// tagged with job if it isn't empty. The endpoint is redacted.
+func (s *StatsD) Sink(job, endpoint string) *StatsDSink {
+	sink := &StatsDSink{statsd: s, job: statsdNameEscaper.Replace(job)}
+	if s.tagged {
+		tags := append([]string(nil), s.tags...)
+		tags = append(tags, "endpoint:"+statsdTagEscaper.Replace(rpcclient.RedactURL(endpoint)))
+		if job != "" {
+			tags = append(tags, "job:"+statsdTagEscaper.Replace(job))
+		}
+		sink.tags = strings.Join(tags, ",")
+	}
+	return sink
+}
+
+// StatsDSink emits a latency timer and request and error counters for
+// every result of one benchmark. It is a bench.ResultSink.
+type StatsDSink struct {
+	statsd *StatsD
+	job    string
+	tags   string
+}
+
+func (s *StatsDSink) Start(expected int) {}
+
+func (s *StatsDSink) Observe(result rpcclient.Result) {
+	outcome := resultOutcome(result)
+	latency := strconv.FormatInt(result.Latency, 10)
+	if s.statsd.tagged {
+		tags := "|#" + s.tags + ",method:" + statsdTagEscaper.Replace(result.Method) + ",outcome:" + outcome
+		s.statsd.send(s.statsd.prefix + ".request.latency:" + latency + "|ms" + tags)
+		s.statsd.send(s.statsd.prefix + ".request.count:1|c" + tags)
+		if !result.Success {
+			s.statsd.send(s.statsd.prefix + ".request.errors:1|c" + tags)
+		}
+		return
+	}
+	name := s.statsd.prefix
+	if s.job != "" {
+		name += "." + s.job
+	}
+	name += "." + statsdNameEscaper.Replace(result.Method)
+	s.statsd.send(name + ".latency:" + latency + "|ms")
+	s.statsd.send(name + ".requests:1|c")
+	if !result.Success {
+		s.statsd.send(name + ".errors." + outcome + ":1|c")
+	}
+}
+
+func (s *StatsDSink) Stop() {}
+
+// resultOutcome names how a request ended: ok, timeout, rate_limited,
+// unsupported or error.
+func resultOutcome(result rpcclient.Result) string {
+	switch {
+	case result.Success:
+		return "ok"
+	case result.TimedOut:
+		return "timeout"
+	case result.RateLimited:
+		return "rate_limited"
+	case result.Unsupported:
+		return "unsupported"
+	}
+	return "error"
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"solana-rpc-performance-golang/rpcclient"
+	"solana-rpc-performance-golang/stats"
+)
+
+// tallyMaxErrors is how many distinct errors a tally keeps as examples.
+const tallyMaxErrors = 5
+
+// TallySink gathers the results of one benchmark by method, for the
+// reports that break a run down that way. It is a bench.ResultSink.
+type TallySink struct {
+	// Name is the job, or the endpoint's host; Endpoint is redacted.
+	Name     string
+	Endpoint string
+
+	mu      sync.Mutex
+	start   time.Time
+	end     time.Time
+	all     *methodTally
+	methods map[string]*methodTally
+	// quota is the allowance the latest rate-limit headers reported and
+	// minRemaining the least any did; rateLimited counts the requests
+	// refused for going too fast.
+	quota        *rpcclient.Quota
+	minRemaining int64
+	rateLimited  int
+}
+
+func newTallySink(job, endpoint string) *TallySink {
+	name := job
+	if name == "" {
+		name = rpcclient.RedactURL(endpoint)
+		if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+			name = u.Host
+		}
+	}
+	return &TallySink{
+		Name:     name,
+		Endpoint: rpcclient.RedactURL(endpoint),
+		all:      &methodTally{},
+		methods:  map[string]*methodTally{},
+	}
+}
+
+// methodTally is the results of one method, or of every method.
+type methodTally struct {
+	requests    int
+	failures    int
+	unsupported int
+	latency     stats.Histogram
+	errors      []string
+}
+
+func (m *methodTally) observe(result rpcclient.Result) {
+	m.requests++
+	if result.Success {
+		m.latency.Record(result.Latency)
+		return
+	}
+	m.failures++
+	if result.Unsupported {
+		m.unsupported++
+	}
+	if len(m.errors) < tallyMaxErrors {
+		for _, seen := range m.errors {
+			if seen == result.Error {
+				return
+			}
+		}
+		m.errors = append(m.errors, result.Error)
+	}
+}
+
+// errorRate returns the percentage of m's requests that failed.
+func (m *methodTally) errorRate() float64 {
+	if m.requests == 0 {
+		return 0
+	}
+	return 100 * float64(m.failures) / float64(m.requests)
+}
+
+func (s *TallySink) Start(expected int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.start.IsZero() {
+		s.start = time.Now()
+	}
+}
+
+func (s *TallySink) Observe(result rpcclient.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	method := s.methods[result.Method]
+	if method == nil {
+		method = &methodTally{}
+		s.methods[result.Method] = method
+	}
+	method.observe(result)
+	s.all.observe(result)
+	if result.RateLimited {
+		s.rateLimited++
+	}
+	if quota := rpcclient.ParseQuota(result.LimitHeaders, time.Now()); quota != nil && quota.HasRemaining {
+		if s.quota == nil || quota.Remaining < s.minRemaining {
+			s.minRemaining = quota.Remaining
+		}
+		s.quota = quota
+	}
+}
+
+func (s *TallySink) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end = time.Now()
+}
+
+// methodNames returns the methods seen, in name order. The caller holds
+// s.mu.
+func (s *TallySink) methodNames() []string {
+	methods := make([]string, 0, len(s.methods))
+	for method := range s.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,42 @@
+package rpcclient
+
+import "context"
+
+func (c *Client) GetVersion(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getVersion", nil)
+}
+
+func (c *Client) GetSlot(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getSlot", nil)
+}
+
+// GetSlotWithCommitment returns the highest slot that has reached
+// commitment.
+func (c *Client) GetSlotWithCommitment(ctx context.Context, commitment string) (*Result, error) {
+	return c.Call(ctx, "getSlot", []interface{}{map[string]interface{}{"commitment": commitment}})
+}
+
+// GetHealth returns "ok", or fails with the reason the node considers
+// itself unhealthy, such as falling behind the cluster.
+func (c *Client) GetHealth(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getHealth", nil)
+}
+
+func (c *Client) GetBalance(ctx context.Context, publicKey string) (*Result, error) {
+	params := []interface{}{publicKey}
+	return c.Call(ctx, "getBalance", params)
+}
+
+func (c *Client) GetAccountInfo(ctx context.Context, publicKey string) (*Result, error) {
+	return c.GetAccountInfoEncoded(ctx, publicKey, "base64")
+}
+
+func (c *Client) GetAccountInfoEncoded(ctx context.Context, publicKey, encoding string) (*Result, error) {
+	params := []interface{}{publicKey, map[string]interface{}{"encoding": encoding}}
+	return c.Call(ctx, "getAccountInfo", params)
+}
+
+func (c *Client) GetMultipleAccounts(ctx context.Context, publicKeys []string) (*Result, error) {
+	params := []interface{}{publicKeys, map[string]interface{}{"encoding": "base64"}}
+	return c.Call(ctx, "getMultipleAccounts", params)
+}
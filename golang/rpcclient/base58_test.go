@@ -0,0 +1,73 @@
+package rpcclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBase58(t *testing.T) {
+	tests := []struct {
+		hex     string
+		encoded string
+	}{
+		{"", ""},
+		{"00", "1"},
+		{"0000", "11"},
+		{"61", "2g"},
+		{"626262", "a3gV"},
+		{"636363", "aPEr"},
+		{"48656c6c6f20576f726c6421", "2NEpo7TZRRrLZSi2U"},
+		{"0000287fb4cd", "11233QC4"},
+		{"00eb15231dfceb60925886b67d065299925915aeb172c06647", "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+		{"0000000000000000000000000000000000000000000000000000000000000000", "11111111111111111111111111111111"},
+	}
+	for _, tt := range tests {
+		data, err := hex.DecodeString(tt.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Base58Encode(data); got != tt.encoded {
+			t.Errorf("Base58Encode(%s) = %q, want %q", tt.hex, got, tt.encoded)
+		}
+		decoded, err := Base58Decode(tt.encoded)
+		if err != nil {
+			t.Errorf("Base58Decode(%q): %v", tt.encoded, err)
+			continue
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("Base58Decode(%q) = %x, want %s", tt.encoded, decoded, tt.hex)
+		}
+	}
+}
+
+func TestBase58DecodeInvalid(t *testing.T) {
+	for _, s := range []string{"0", "O", "I", "l", "abc+", "1 1"} {
+		if _, err := Base58Decode(s); err == nil {
+			t.Errorf("Base58Decode(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: "11111111111111111111111111111111"},
+		{in: "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"},
+		{in: "1111", wantErr: true},
+		{in: "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA1", wantErr: true},
+		{in: "0okenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, err := ParsePublicKey(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePublicKey(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && key.String() != tt.in {
+			t.Errorf("ParsePublicKey(%q).String() = %q", tt.in, key.String())
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package rpcclient
+
+import "context"
+
+// Solana JSON-RPC server error codes for ledger lookups.
+const (
+	ErrCodeBlockCleanedUp             = -32001
+	ErrCodeBlockNotAvailable          = -32004
+	ErrCodeSlotSkipped                = -32007
+	ErrCodeLongTermStorageSlotSkipped = -32009
+	ErrCodeTransactionHistoryMissing  = -32011
+	// ErrCodeUnsupportedTransactionVersion is returned for a versioned
+	// transaction when the request lacks maxSupportedTransactionVersion.
+	ErrCodeUnsupportedTransactionVersion = -32015
+)
+
+func (c *Client) GetFirstAvailableBlock(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getFirstAvailableBlock", nil)
+}
+
+// GetBlock fetches a block; config is passed through as the getBlock
+// configuration object.
+func (c *Client) GetBlock(ctx context.Context, slot uint64, config map[string]interface{}) (*Result, error) {
+	params := []interface{}{slot}
+	if config != nil {
+		params = append(params, config)
+	}
+	return c.Call(ctx, "getBlock", params)
+}
+
+// GetBlocks lists the confirmed blocks from start to end inclusive, leaving
+// out skipped slots.
+func (c *Client) GetBlocks(ctx context.Context, start, end uint64) (*Result, error) {
+	return c.Call(ctx, "getBlocks", []interface{}{start, end})
+}
+
+func (c *Client) GetTransaction(ctx context.Context, signature string, config map[string]interface{}) (*Result, error) {
+	params := []interface{}{signature}
+	if config != nil {
+		params = append(params, config)
+	}
+	return c.Call(ctx, "getTransaction", params)
+}
+
+// IsSkippedSlot reports whether a ledger lookup failed because the slot
+// was skipped rather than because the block is unavailable.
+func IsSkippedSlot(result *Result) bool {
+	return result.ErrorCode == ErrCodeSlotSkipped || result.ErrorCode == ErrCodeLongTermStorageSlotSkipped
+}
+
+func (c *Client) GetSignaturesForAddress(ctx context.Context, address string, limit int, before string) (*Result, error) {
+	config := map[string]interface{}{"limit": limit}
+	if before != "" {
+		config["before"] = before
+	}
+	params := []interface{}{address, config}
+	return c.Call(ctx, "getSignaturesForAddress", params)
+}
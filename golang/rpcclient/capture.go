@@ -0,0 +1,116 @@
+package rpcclient
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BodyCapture saves the raw response body of every call to a directory,
+// gzip-compressed, for auditing what an endpoint actually returned. Each
+// body is stored as <request ID>.json.gz and listed in index.jsonl with
+// the call's method, timing and outcome.
+type BodyCapture struct {
+	dir      string
+	maxBytes int
+
+	mu     sync.Mutex
+	index  *os.File
+	next   uint64
+	failed bool
+}
+
+// CaptureEntry is one line of a BodyCapture's index.jsonl.
+type CaptureEntry struct {
+	RequestID string    `json:"requestId"`
+	File      string    `json:"file"`
+	Endpoint  string    `json:"endpoint"`
+	Method    string    `json:"method"`
+	StartedAt time.Time `json:"startedAt"`
+	Latency   int64     `json:"latency"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Size      int       `json:"size"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// NewBodyCapture creates dir if needed and starts a new index there,
+// replacing any earlier capture's. Bodies longer than maxBytes are cut
+// short; zero or less keeps them whole.
+func NewBodyCapture(dir string, maxBytes int) (*BodyCapture, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, "index.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &BodyCapture{dir: dir, maxBytes: maxBytes, index: index}, nil
+}
+
+// Close closes the index.
+func (b *BodyCapture) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.index.Close()
+}
+
+// fail records a failure to save, and reports whether it is the first.
+func (b *BodyCapture) fail() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	first := !b.failed
+	b.failed = true
+	return first
+}
+
+// save stores body under a new request ID, which it records in result.
+func (b *BodyCapture) save(endpoint string, result *Result, body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next++
+	result.RequestID = fmt.Sprintf("%08d", b.next)
+
+	entry := CaptureEntry{
+		RequestID: result.RequestID,
+		File:      result.RequestID + ".json.gz",
+		Endpoint:  RedactURL(endpoint),
+		Method:    result.Method,
+		StartedAt: result.StartedAt,
+		Latency:   result.Latency,
+		Success:   result.Success,
+		Error:     result.Error,
+		Size:      len(body),
+	}
+	if b.maxBytes > 0 && len(body) > b.maxBytes {
+		body, entry.Truncated = body[:b.maxBytes], true
+	}
+
+	file, err := os.Create(filepath.Join(b.dir, entry.File))
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(file)
+	if _, err := zw.Write(body); err != nil {
+		file.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = b.index.Write(append(line, '\n'))
+	return err
+}
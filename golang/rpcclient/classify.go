@@ -0,0 +1,80 @@
+package rpcclient
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ErrCodeMethodNotFound is JSON-RPC's code for a method the server does
+// not implement.
+const ErrCodeMethodNotFound = -32601
+
+// unsupportedPhrases are what providers say, in a variety of error codes,
+// when they have switched a method off.
+var unsupportedPhrases = []string{
+	"method not found", "not supported", "unsupported", "disabled", "not allowed", "not available", "not enabled",
+}
+
+// isUnsupported reports whether an RPC error means the endpoint doesn't
+// serve the method at all, rather than that this call failed: the
+// standard method-not-found code, or an error about the method that says
+// it is unsupported or disabled.
+func isUnsupported(err *RPCError) bool {
+	if err.Code == ErrCodeMethodNotFound {
+		return true
+	}
+	message := strings.ToLower(err.Message)
+	if !strings.Contains(message, "method") {
+		return false
+	}
+	for _, phrase := range unsupportedPhrases {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitPhrases are what providers say when throttling, whatever the
+// HTTP status and error code.
+var rateLimitPhrases = []string{"rate limit", "rate-limit", "ratelimit", "too many requests", "quota"}
+
+// isRateLimited reports whether a failed call was refused for going too
+// fast: an HTTP 429, an error code of 429, or an error that says so.
+func isRateLimited(status int, result *Result) bool {
+	if status == http.StatusTooManyRequests || result.ErrorCode == http.StatusTooManyRequests {
+		return true
+	}
+	message := strings.ToLower(result.Error)
+	for _, phrase := range rateLimitPhrases {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitHeaderPhrases mark the response headers in which providers
+// announce their rate limits and quotas, such as X-RateLimit-Remaining,
+// RateLimit-Reset or X-Quota-Used.
+var limitHeaderPhrases = []string{"ratelimit", "rate-limit", "quota"}
+
+// limitHeaders returns the rate-limit headers of a response, by lower-case
+// name, with Retry-After among them, or nil if it has none.
+func limitHeaders(header http.Header) map[string]string {
+	var found map[string]string
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		match := lower == "retry-after"
+		for _, phrase := range limitHeaderPhrases {
+			match = match || strings.Contains(lower, phrase)
+		}
+		if match && len(values) > 0 {
+			if found == nil {
+				found = make(map[string]string)
+			}
+			found[lower] = values[0]
+		}
+	}
+	return found
+}
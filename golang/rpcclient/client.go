@@ -0,0 +1,556 @@
+// Package rpcclient is a minimal JSON-RPC client that times every call and
+// records failures as results rather than errors, so benchmarks can count
+// them. Call speaks any JSON-RPC 2.0 endpoint; the typed methods are
+// Solana's.
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RPCRequest struct {
+	JSONrpc string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type RPCResponse struct {
+	JSONrpc string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *RPCError   `json:"error,omitempty"`
+}
+
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Result is the outcome of one RPC call. Latency is in milliseconds and
+// Size is the response body length in bytes.
+type Result struct {
+	Method  string      `json:"method"`
+	Success bool        `json:"success"`
+	Latency int64       `json:"latency"`
+	Size    int         `json:"size,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+
+	ErrorCode int `json:"errorCode,omitempty"`
+	// Unsupported marks a failure because the endpoint doesn't serve the
+	// method, or has it disabled.
+	Unsupported bool `json:"unsupported,omitempty"`
+	// RateLimited marks a failure because the endpoint is throttling the
+	// client. StatusCode is the HTTP status, when a response arrived.
+	RateLimited bool `json:"rateLimited,omitempty"`
+	StatusCode  int  `json:"statusCode,omitempty"`
+	// LimitHeaders holds the response's rate-limit headers, such as
+	// x-ratelimit-remaining and retry-after, by lower-case name.
+	LimitHeaders map[string]string `json:"limitHeaders,omitempty"`
+	// SchemaViolation marks a failure because the result, though the
+	// endpoint returned one, didn't match the method's schema.
+	SchemaViolation bool `json:"schemaViolation,omitempty"`
+	// TimedOut marks a failure caused by the call's timeout rather than
+	// an error from the endpoint.
+	TimedOut bool `json:"timedOut,omitempty"`
+	// Connection is ConnNew or ConnReused, or empty if the request never
+	// got a connection.
+	Connection string `json:"connection,omitempty"`
+	// Protocol is the HTTP version the response came back over, such as
+	// "HTTP/1.1" or "HTTP/2.0".
+	Protocol string `json:"protocol,omitempty"`
+	// WireSize and DecompressMicros are set when the client asked for a
+	// compressed response itself (AcceptEncoding): the body length as
+	// transferred, and the time spent decompressing it.
+	WireSize         int   `json:"wireSize,omitempty"`
+	DecompressMicros int64 `json:"decompressMicros,omitempty"`
+	// IPFamily is "ipv4" or "ipv6", the family of the connection used.
+	IPFamily string `json:"ipFamily,omitempty"`
+	// DNSMicros is the time spent resolving the endpoint's hostname, for
+	// requests that had to look it up.
+	DNSMicros int64 `json:"dnsMicros,omitempty"`
+	// TLSVersion and TLSCipher describe the connection of an https://
+	// request, e.g. "TLS 1.3" and "TLS_AES_128_GCM_SHA256".
+	TLSVersion string `json:"tlsVersion,omitempty"`
+	TLSCipher  string `json:"tlsCipher,omitempty"`
+	// StartedAt is when the request was sent. It keeps Go's monotonic
+	// clock reading, so in-process offsets between results are immune to
+	// wall clock adjustments. OffsetMicros is its offset from the start of
+	// the benchmark run, set by the runners that time a run as a whole.
+	StartedAt    time.Time `json:"startedAt,omitzero"`
+	OffsetMicros int64     `json:"offsetMicros,omitempty"`
+	// RequestID names the captured response body when the client has a
+	// BodyCapture.
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Result.Connection values.
+const (
+	ConnNew    = "new"
+	ConnReused = "reused"
+)
+
+// LevelTrace is below slog.LevelDebug; at this level Call also logs request
+// headers and a truncated response body.
+const LevelTrace = slog.LevelDebug - 4
+
+// maxLoggedBody caps how much of a response body is logged at LevelTrace.
+const maxLoggedBody = 512
+
+// DefaultTimeout bounds each call unless the client overrides it.
+const DefaultTimeout = 30 * time.Second
+
+// Doer sends an HTTP request. *http.Client satisfies it; wrap one to
+// instrument, record or replay requests, or supply a fake in tests.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Client sends JSON-RPC requests to a single endpoint through HTTP.
+type Client struct {
+	Endpoint string
+	HTTP     Doer
+	Headers  map[string]string
+
+	// Timeout bounds each call, including reading the response; zero
+	// means no limit. Timeouts overrides it per RPC method.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+
+	// AcceptEncoding, if set, is sent as the Accept-Encoding header and
+	// the client decompresses gzip or deflate responses itself, recording
+	// wire size and decompression time. Left empty, net/http negotiates
+	// gzip transparently and only decompressed sizes are visible.
+	AcceptEncoding string
+
+	// Logger, if set, receives a debug record for every call. API keys in
+	// the endpoint and credential headers are redacted.
+	Logger *slog.Logger
+
+	// Capture, if set, saves every response body. A failure to save is
+	// logged once and does not fail the call.
+	Capture *BodyCapture
+	// Recorder, if set, records every request for later replay.
+	Recorder *Recorder
+	// HAR, if set, collects every call's HTTP metadata.
+	HAR *HARLog
+	// Params, if set, rewrites every call's params before the request is
+	// sent. An error fails the call without sending it.
+	Params func(method string, params interface{}) (interface{}, error)
+	// Tracer, if set, records a span for every call and sends its trace
+	// context to the endpoint in a traceparent header.
+	Tracer trace.Tracer
+
+	limiter *rateLimiter
+}
+
+func NewClient(endpoint string) *Client {
+	return &Client{
+		Endpoint: endpoint,
+		HTTP:     &http.Client{},
+		Timeout:  DefaultTimeout,
+	}
+}
+
+// Clone returns a client for endpoint that sends through the same kind of
+// transport. An *http.Client is replaced by a copy with the same settings
+// that doesn't share its connections; any other Doer is shared.
+// Headers and rate limit are not copied; Logger, timeouts,
+// AcceptEncoding, Capture, Recorder, HAR, Params and Tracer are.
+func (c *Client) Clone(endpoint string) *Client {
+	clone := NewClient(endpoint)
+	clone.Logger = c.Logger
+	clone.Capture = c.Capture
+	clone.Recorder = c.Recorder
+	clone.HAR = c.HAR
+	clone.Params = c.Params
+	clone.Tracer = c.Tracer
+	clone.Timeout = c.Timeout
+	clone.Timeouts = c.Timeouts
+	clone.AcceptEncoding = c.AcceptEncoding
+	if hc, ok := c.HTTP.(*http.Client); ok {
+		clone.HTTP = cloneHTTPClient(hc)
+	} else {
+		clone.HTTP = c.HTTP
+	}
+	return clone
+}
+
+// TimeoutFor returns the timeout that applies to calls of method.
+func (c *Client) TimeoutFor(method string) time.Duration {
+	if timeout, ok := c.Timeouts[method]; ok {
+		return timeout
+	}
+	return c.Timeout
+}
+
+// SetRateLimit caps the client at rps requests per second; zero or less
+// removes the cap.
+func (c *Client) SetRateLimit(rps float64) {
+	c.limiter = newRateLimiter(rps)
+}
+
+// Call sends one JSON-RPC request. Transport and RPC failures are recorded
+// in the returned Result; the error is only set when ctx ends before the
+// call completes.
+func (c *Client) Call(ctx context.Context, method string, params interface{}) (*Result, error) {
+	result, body, err := c.call(ctx, method, params)
+	if c.Capture != nil && result != nil {
+		if captureErr := c.Capture.save(c.Endpoint, result, body); captureErr != nil && c.Capture.fail() {
+			logger := c.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger.Warn("could not capture response body", "error", captureErr)
+		}
+	}
+	if c.Logger != nil && result != nil {
+		c.logCall(ctx, result, body)
+	}
+	return result, err
+}
+
+func (c *Client) logCall(ctx context.Context, result *Result, body []byte) {
+	attrs := []slog.Attr{
+		slog.String("method", result.Method),
+		slog.String("started_at", result.StartedAt.Format(time.RFC3339Nano)),
+		slog.Int64("latency_ms", result.Latency),
+		slog.Int("size", result.Size),
+		slog.Bool("success", result.Success),
+	}
+	if result.Protocol != "" {
+		attrs = append(attrs, slog.String("protocol", result.Protocol))
+	}
+	if result.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", result.RequestID))
+	}
+	if result.TLSVersion != "" {
+		attrs = append(attrs, slog.String("tls_version", result.TLSVersion), slog.String("tls_cipher", result.TLSCipher))
+	}
+	if !result.Success {
+		attrs = append(attrs, slog.String("error", result.Error))
+	}
+	if quota := ParseQuota(result.LimitHeaders, time.Now()); quota != nil {
+		if quota.HasRemaining {
+			attrs = append(attrs, slog.Int64("quota_remaining", quota.Remaining))
+		}
+		if quota.Limit > 0 {
+			attrs = append(attrs, slog.Int64("quota_limit", quota.Limit))
+		}
+		if quota.Window != "" {
+			attrs = append(attrs, slog.String("quota_window", quota.Window))
+		}
+		if quota.RetryAfter > 0 {
+			attrs = append(attrs, slog.Duration("retry_after", quota.RetryAfter))
+		}
+	}
+	c.Logger.LogAttrs(ctx, slog.LevelDebug, "rpc call", attrs...)
+
+	if c.Logger.Enabled(ctx, LevelTrace) {
+		if len(body) > maxLoggedBody {
+			body = append(body[:maxLoggedBody:maxLoggedBody], "..."...)
+		}
+		c.Logger.LogAttrs(ctx, LevelTrace, "rpc response",
+			slog.String("method", result.Method),
+			slog.String("endpoint", RedactURL(c.Endpoint)),
+			slog.Any("headers", RedactHeaders(c.Headers)),
+			slog.String("body", string(body)))
+	}
+}
+
+// call does the work of Call and also returns the raw response body.
+func (c *Client) call(ctx context.Context, method string, params interface{}) (result *Result, body []byte, err error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, nil, err
+	}
+	if c.Params != nil {
+		if params, err = c.Params(method, params); err != nil {
+			return &Result{Method: method, Success: false, Error: fmt.Sprintf("params: %v", err)}, nil, nil
+		}
+	}
+	start := time.Now()
+	if c.Recorder != nil {
+		c.Recorder.record(method, params, start)
+	}
+
+	callCtx := ctx
+	if timeout := c.TimeoutFor(method); timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// timedOut returns a timeout Result if err came from the call's own
+	// deadline or the transport's, and nil otherwise.
+	timedOut := func(err error) *Result {
+		var netErr net.Error
+		if !errors.Is(callCtx.Err(), context.DeadlineExceeded) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			return nil
+		}
+		elapsed := time.Since(start)
+		return &Result{
+			Method:   method,
+			Success:  false,
+			Latency:  elapsed.Milliseconds(),
+			Error:    fmt.Sprintf("timed out after %s", elapsed.Round(time.Millisecond)),
+			TimedOut: true,
+		}
+	}
+
+	if c.Tracer != nil {
+		var span trace.Span
+		callCtx, span = c.startSpan(callCtx, method, start)
+		defer func() {
+			endSpan(span, result, err)
+		}()
+	}
+
+	var har *harCall
+	if c.HAR != nil {
+		har = &harCall{start: start}
+		callCtx = httptrace.WithClientTrace(callCtx, har.trace())
+		defer func() {
+			if result != nil {
+				c.HAR.add(har.entry(c.Endpoint, result, body))
+			}
+		}()
+	}
+
+	// The DNS hooks run on the transport's dial goroutine, which can
+	// outlive a timed-out call.
+	var connection, family string
+	var dnsStart, dns atomic.Int64
+	callCtx = httptrace.WithClientTrace(callCtx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			dnsStart.Store(time.Now().UnixNano())
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			dns.Store(time.Now().UnixNano() - dnsStart.Load())
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			connection = ConnNew
+			if info.Reused {
+				connection = ConnReused
+			}
+			if info.Conn != nil {
+				family = addrFamily(info.Conn.RemoteAddr())
+			}
+		},
+	})
+	defer func() {
+		if result != nil {
+			result.StartedAt = start
+			result.Connection = connection
+			result.IPFamily = family
+			result.DNSMicros = time.Duration(dns.Load()).Microseconds()
+		}
+	}()
+
+	request := RPCRequest{
+		JSONrpc: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return &Result{
+			Method:  method,
+			Success: false,
+			Latency: time.Since(start).Milliseconds(),
+			Error:   err.Error(),
+		}, nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(callCtx, http.MethodPost, c.Endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return &Result{
+			Method:  method,
+			Success: false,
+			Latency: time.Since(start).Milliseconds(),
+			Error:   err.Error(),
+		}, nil, nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	if c.AcceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", c.AcceptEncoding)
+	}
+	if c.Tracer != nil {
+		injectSpan(callCtx, req.Header)
+	}
+	if har != nil {
+		har.request, har.requestBody = req, jsonData
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		// A cancelled or expired ctx ends the caller's run; that is not a
+		// failure of the endpoint.
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if result := timedOut(err); result != nil {
+			return result, nil, nil
+		}
+		return &Result{
+			Method:  method,
+			Success: false,
+			Latency: time.Since(start).Milliseconds(),
+			Error:   err.Error(),
+		}, nil, nil
+	}
+	defer resp.Body.Close()
+	if har != nil {
+		har.response = resp
+	}
+	defer func() {
+		if result != nil {
+			result.Protocol = resp.Proto
+			result.StatusCode = resp.StatusCode
+			result.RateLimited = !result.Success && isRateLimited(resp.StatusCode, result)
+			result.LimitHeaders = limitHeaders(resp.Header)
+			if resp.TLS != nil {
+				result.TLSVersion = tls.VersionName(resp.TLS.Version)
+				result.TLSCipher = tls.CipherSuiteName(resp.TLS.CipherSuite)
+			}
+		}
+	}()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if result := timedOut(err); result != nil {
+			return result, body, nil
+		}
+		return &Result{
+			Method:  method,
+			Success: false,
+			Latency: time.Since(start).Milliseconds(),
+			Error:   err.Error(),
+		}, nil, nil
+	}
+
+	if har != nil {
+		har.wireSize = len(body)
+	}
+
+	var wireSize int
+	var decompress time.Duration
+	if c.AcceptEncoding != "" {
+		wireSize = len(body)
+		decodeStart := time.Now()
+		decoded, err := decodeBody(resp.Header.Get("Content-Encoding"), body)
+		if err != nil {
+			return &Result{
+				Method:   method,
+				Success:  false,
+				Latency:  time.Since(start).Milliseconds(),
+				WireSize: wireSize,
+				Error:    err.Error(),
+			}, body, nil
+		}
+		body = decoded
+		decompress = time.Since(decodeStart)
+	}
+	defer func() {
+		if result != nil && wireSize > 0 {
+			result.WireSize = wireSize
+			result.DecompressMicros = decompress.Microseconds()
+		}
+	}()
+
+	var rpcResponse RPCResponse
+	err = json.Unmarshal(body, &rpcResponse)
+	if err != nil {
+		return &Result{
+			Method:  method,
+			Success: false,
+			Latency: time.Since(start).Milliseconds(),
+			Size:    len(body),
+			Error:   err.Error(),
+		}, body, nil
+	}
+
+	latency := time.Since(start).Milliseconds()
+
+	if rpcResponse.Error != nil {
+		return &Result{
+			Method:      method,
+			Success:     false,
+			Latency:     latency,
+			Size:        len(body),
+			Error:       rpcResponse.Error.Error(),
+			ErrorCode:   rpcResponse.Error.Code,
+			Unsupported: isUnsupported(rpcResponse.Error),
+		}, body, nil
+	}
+
+	return &Result{
+		Method:  method,
+		Success: true,
+		Latency: latency,
+		Size:    len(body),
+		Result:  rpcResponse.Result,
+	}, body, nil
+}
+
+// CallResult performs an RPC call and decodes its result into out. RPC and
+// transport failures are returned as errors alongside the raw Result.
+func (c *Client) CallResult(ctx context.Context, method string, params interface{}, out interface{}) (*Result, error) {
+	result, err := c.Call(ctx, method, params)
+	if err != nil {
+		return result, err
+	}
+	return result, DecodeResult(result, out)
+}
+
+// DecodeResult unmarshals a successful call's result into out, or reports
+// the call's error.
+func DecodeResult(result *Result, out interface{}) error {
+	if !result.Success {
+		return fmt.Errorf("%s: %s", result.Method, result.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	raw, err := json.Marshal(result.Result)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%s: decode result: %w", result.Method, err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package rpcclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// respond returns a Doer that answers every request with status, header and
+// body.
+func respond(status int, header http.Header, body []byte) DoerFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			StatusCode: status,
+			Proto:      "HTTP/1.1",
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func deflated(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const okBody = `{"jsonrpc":"2.0","id":1,"result":42}`
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name           string
+		doer           DoerFunc
+		timeout        time.Duration
+		acceptEncoding string
+		check          func(t *testing.T, result *Result)
+	}{
+		{
+			name: "success",
+			doer: respond(http.StatusOK, nil, []byte(okBody)),
+			check: func(t *testing.T, result *Result) {
+				if !result.Success || result.Result != float64(42) || result.Size != len(okBody) {
+					t.Errorf("got success %v, result %v, size %d", result.Success, result.Result, result.Size)
+				}
+				if result.StatusCode != http.StatusOK || result.Protocol != "HTTP/1.1" || result.StartedAt.IsZero() {
+					t.Errorf("got status %d, protocol %q, started %v", result.StatusCode, result.Protocol, result.StartedAt)
+				}
+			},
+		},
+		{
+			name: "transport error",
+			doer: func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+			check: func(t *testing.T, result *Result) {
+				if result.Success || result.TimedOut || !strings.Contains(result.Error, "connection refused") {
+					t.Errorf("got success %v, timed out %v, error %q", result.Success, result.TimedOut, result.Error)
+				}
+			},
+		},
+		{
+			name:    "timeout",
+			timeout: 20 * time.Millisecond,
+			doer: func(req *http.Request) (*http.Response, error) {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			},
+			check: func(t *testing.T, result *Result) {
+				if result.Success || !result.TimedOut || !strings.HasPrefix(result.Error, "timed out after") {
+					t.Errorf("got success %v, timed out %v, error %q", result.Success, result.TimedOut, result.Error)
+				}
+			},
+		},
+		{
+			name: "malformed response",
+			doer: respond(http.StatusBadGateway, nil, []byte("<html>bad gateway</html>")),
+			check: func(t *testing.T, result *Result) {
+				if result.Success || result.RateLimited || result.StatusCode != http.StatusBadGateway {
+					t.Errorf("got success %v, rate limited %v, status %d", result.Success, result.RateLimited, result.StatusCode)
+				}
+			},
+		},
+		{
+			name: "rate limited by status",
+			doer: respond(http.StatusTooManyRequests, http.Header{
+				"X-Ratelimit-Remaining": {"0"},
+				"Retry-After":           {"2"},
+				"Content-Type":          {"application/json"},
+			}, []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"slow down"}}`)),
+			check: func(t *testing.T, result *Result) {
+				if result.Success || !result.RateLimited || result.StatusCode != http.StatusTooManyRequests {
+					t.Errorf("got success %v, rate limited %v, status %d", result.Success, result.RateLimited, result.StatusCode)
+				}
+				want := map[string]string{"x-ratelimit-remaining": "0", "retry-after": "2"}
+				if len(result.LimitHeaders) != len(want) {
+					t.Errorf("got limit headers %v, want %v", result.LimitHeaders, want)
+				}
+				for name, value := range want {
+					if result.LimitHeaders[name] != value {
+						t.Errorf("limit header %s = %q, want %q", name, result.LimitHeaders[name], value)
+					}
+				}
+			},
+		},
+		{
+			name: "rate limited by message",
+			doer: respond(http.StatusOK, nil, []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32429,"message":"Too many requests for this key"}}`)),
+			check: func(t *testing.T, result *Result) {
+				if result.Success || !result.RateLimited || result.ErrorCode != -32429 {
+					t.Errorf("got success %v, rate limited %v, code %d", result.Success, result.RateLimited, result.ErrorCode)
+				}
+			},
+		},
+		{
+			name: "unsupported method",
+			doer: respond(http.StatusOK, nil, []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`)),
+			check: func(t *testing.T, result *Result) {
+				if result.Success || !result.Unsupported || result.RateLimited {
+					t.Errorf("got success %v, unsupported %v, rate limited %v", result.Success, result.Unsupported, result.RateLimited)
+				}
+			},
+		},
+		{
+			name:           "gzip",
+			acceptEncoding: "gzip",
+			doer:           respond(http.StatusOK, http.Header{"Content-Encoding": {"gzip"}}, gzipped(t, okBody)),
+			check: func(t *testing.T, result *Result) {
+				if !result.Success || result.Size != len(okBody) {
+					t.Errorf("got success %v, size %d, want %d", result.Success, result.Size, len(okBody))
+				}
+				if want := len(gzipped(t, okBody)); result.WireSize != want {
+					t.Errorf("got wire size %d, want %d", result.WireSize, want)
+				}
+			},
+		},
+		{
+			name:           "deflate",
+			acceptEncoding: "gzip, deflate",
+			doer:           respond(http.StatusOK, http.Header{"Content-Encoding": {"deflate"}}, deflated(t, okBody)),
+			check: func(t *testing.T, result *Result) {
+				if !result.Success || result.Size != len(okBody) || result.WireSize == 0 {
+					t.Errorf("got success %v, size %d, wire size %d", result.Success, result.Size, result.WireSize)
+				}
+			},
+		},
+		{
+			name:           "identity with accept encoding",
+			acceptEncoding: "gzip",
+			doer:           respond(http.StatusOK, nil, []byte(okBody)),
+			check: func(t *testing.T, result *Result) {
+				if !result.Success || result.WireSize != len(okBody) {
+					t.Errorf("got success %v, wire size %d", result.Success, result.WireSize)
+				}
+			},
+		},
+		{
+			name:           "corrupt gzip",
+			acceptEncoding: "gzip",
+			doer:           respond(http.StatusOK, http.Header{"Content-Encoding": {"gzip"}}, []byte("not gzip")),
+			check: func(t *testing.T, result *Result) {
+				if result.Success || !strings.Contains(result.Error, "decode gzip body") || result.WireSize != len("not gzip") {
+					t.Errorf("got success %v, error %q, wire size %d", result.Success, result.Error, result.WireSize)
+				}
+			},
+		},
+		{
+			name:           "unsupported encoding",
+			acceptEncoding: "gzip",
+			doer:           respond(http.StatusOK, http.Header{"Content-Encoding": {"br"}}, []byte(okBody)),
+			check: func(t *testing.T, result *Result) {
+				if result.Success || !strings.Contains(result.Error, "unsupported Content-Encoding") {
+					t.Errorf("got success %v, error %q", result.Success, result.Error)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("http://rpc.invalid")
+			client.HTTP = tt.doer
+			client.Timeout = tt.timeout
+			client.AcceptEncoding = tt.acceptEncoding
+			result, err := client.Call(context.Background(), "getSlot", nil)
+			if err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			if result.Method != "getSlot" {
+				t.Errorf("got method %q", result.Method)
+			}
+			tt.check(t, result)
+		})
+	}
+}
+
+func TestCallSendsRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	client := NewClient("http://rpc.invalid/path")
+	client.Headers = map[string]string{"Authorization": "Bearer key"}
+	client.AcceptEncoding = "gzip"
+	client.HTTP = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		return respond(http.StatusOK, nil, []byte(okBody))(req)
+	})
+	if _, err := client.Call(context.Background(), "getBalance", []interface{}{"addr"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPost || got.URL.String() != "http://rpc.invalid/path" {
+		t.Errorf("got %s %s", got.Method, got.URL)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":    "application/json",
+		"Authorization":   "Bearer key",
+		"Accept-Encoding": "gzip",
+	} {
+		if value := got.Header.Get(name); value != want {
+			t.Errorf("header %s = %q, want %q", name, value, want)
+		}
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"method":"getBalance","params":["addr"]}`; string(body) != want {
+		t.Errorf("got body %s, want %s", body, want)
+	}
+}
+
+func TestCallCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient("http://rpc.invalid")
+	client.HTTP = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, req.Context().Err()
+	})
+	result, err := client.Call(ctx, "getSlot", nil)
+	if !errors.Is(err, context.Canceled) || result != nil {
+		t.Errorf("got result %v, error %v; want a nil result and context.Canceled", result, err)
+	}
+}
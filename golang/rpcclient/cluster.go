@@ -0,0 +1,68 @@
+package rpcclient
+
+import "context"
+
+// GetRecentPerformanceSamples returns up to limit samples of the cluster's
+// transaction and slot counts, newest first, each covering about a minute.
+func (c *Client) GetRecentPerformanceSamples(ctx context.Context, limit int) (*Result, error) {
+	return c.Call(ctx, "getRecentPerformanceSamples", []interface{}{limit})
+}
+
+// GetRecentPrioritizationFees returns the lowest priority fee paid in each
+// recent slot by transactions that lock all of accounts as writable, or by
+// any transaction if accounts is empty.
+func (c *Client) GetRecentPrioritizationFees(ctx context.Context, accounts []string) (*Result, error) {
+	if len(accounts) == 0 {
+		return c.Call(ctx, "getRecentPrioritizationFees", nil)
+	}
+	return c.Call(ctx, "getRecentPrioritizationFees", []interface{}{accounts})
+}
+
+func (c *Client) GetEpochInfo(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getEpochInfo", nil)
+}
+
+func (c *Client) GetBlockHeight(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getBlockHeight", nil)
+}
+
+// GetBlockProduction returns how many leader slots each validator had and
+// how many blocks it produced, over the current epoch so far.
+func (c *Client) GetBlockProduction(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getBlockProduction", nil)
+}
+
+// GetVoteAccounts returns every current and delinquent vote account, a
+// response of a megabyte or more on mainnet.
+func (c *Client) GetVoteAccounts(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getVoteAccounts", nil)
+}
+
+// GetLeaderSchedule returns the current epoch's leader schedule, every
+// slot of it, a response of several megabytes on mainnet.
+func (c *Client) GetLeaderSchedule(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getLeaderSchedule", nil)
+}
+
+// GetSupply returns the circulating and non-circulating supply, leaving
+// out the list of non-circulating accounts, which few callers want.
+func (c *Client) GetSupply(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getSupply", []interface{}{map[string]interface{}{"excludeNonCirculatingAccountsList": true}})
+}
+
+func (c *Client) GetInflationRate(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getInflationRate", nil)
+}
+
+// GetInflationReward returns the staking rewards addresses earned in the
+// last completed epoch.
+func (c *Client) GetInflationReward(ctx context.Context, addresses []string) (*Result, error) {
+	return c.Call(ctx, "getInflationReward", []interface{}{addresses})
+}
+
+// GetLargestAccounts returns the 20 largest accounts by lamports. Nodes
+// compute it by scanning every account, so providers often cache it or
+// switch it off.
+func (c *Client) GetLargestAccounts(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getLargestAccounts", nil)
+}
@@ -0,0 +1,37 @@
+package rpcclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeBody undoes a response's Content-Encoding. Encodings other than
+// gzip and deflate (and identity) are reported as errors.
+func decodeBody(contentEncoding string, body []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// HTTP's deflate is the zlib format, not raw DEFLATE.
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", contentEncoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s body: %w", contentEncoding, err)
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s body: %w", contentEncoding, err)
+	}
+	return decoded, nil
+}
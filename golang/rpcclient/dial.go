@@ -0,0 +1,306 @@
+package rpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// resolveSourceIP turns a local IP address or interface name into the
+// address outgoing connections are bound to. An interface's first IPv4
+// address is preferred over its IPv6 ones.
+func resolveSourceIP(source string) (net.IP, error) {
+	if ip := net.ParseIP(source); ip != nil {
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, fmt.Errorf("source %q is neither an IP address nor an interface: %w", source, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", source, err)
+	}
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+		if fallback == nil {
+			fallback = ipNet.IP
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("interface %s has no usable address", source)
+	}
+	return fallback, nil
+}
+
+// dialer opens the TCP connections and QUIC sessions of the transports
+// built by NewHTTPClient, applying the source address and DNS strategy.
+type dialer struct {
+	net.Dialer
+	dns string
+	// ipVersion is "4" or "6" to restrict dialing to that family, or
+	// empty for either.
+	ipVersion string
+
+	// quic sends from the source address when one is set.
+	quic *quic.Transport
+
+	mu     sync.Mutex
+	pinned map[string]net.IP
+}
+
+// newDialer returns the dialer for opts, with the same timeouts as
+// http.DefaultTransport.
+func newDialer(opts TransportOptions) (*dialer, error) {
+	d := &dialer{
+		Dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		dns:    opts.DNS,
+		pinned: make(map[string]net.IP),
+	}
+	switch opts.DNS {
+	case "":
+		d.dns = DNSSystem
+	case DNSSystem, DNSPin, DNSFresh:
+	default:
+		return nil, fmt.Errorf("unknown DNS strategy %q (want %s, %s or %s)", opts.DNS, DNSSystem, DNSPin, DNSFresh)
+	}
+	switch opts.IPVersion {
+	case "", IPAuto:
+	case IPv4, IPv6:
+		d.ipVersion = opts.IPVersion
+	default:
+		return nil, fmt.Errorf("unknown IP version %q (want %s, %s or %s)", opts.IPVersion, IPAuto, IPv4, IPv6)
+	}
+	if opts.SourceIP != "" {
+		ip, err := resolveSourceIP(opts.SourceIP)
+		if err != nil {
+			return nil, err
+		}
+		if !d.allows(ip) {
+			return nil, fmt.Errorf("source address %s is not IPv%s", ip, d.ipVersion)
+		}
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+		if opts.Protocol == ProtoH3 {
+			conn, err := net.ListenUDP("udp"+d.ipVersion, &net.UDPAddr{IP: ip})
+			if err != nil {
+				return nil, fmt.Errorf("bind %s: %w", ip, err)
+			}
+			d.quic = &quic.Transport{Conn: conn}
+		}
+	}
+	return d, nil
+}
+
+// allows reports whether ip belongs to the family the dialer is limited to.
+func (d *dialer) allows(ip net.IP) bool {
+	switch d.ipVersion {
+	case IPv4:
+		return ip.To4() != nil
+	case IPv6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// resolve looks up host; the resolver reports the lookup to any
+// httptrace.ClientTrace in ctx. With DNSPin only the first lookup of each
+// host is made.
+func (d *dialer) resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !d.allows(ip) {
+			return nil, fmt.Errorf("%s is not IPv%s", ip, d.ipVersion)
+		}
+		return ip, nil
+	}
+	if d.dns == DNSPin {
+		d.mu.Lock()
+		ip, ok := d.pinned[host]
+		d.mu.Unlock()
+		if ok {
+			return ip, nil
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var ip net.IP
+	for _, addr := range addrs {
+		if d.allows(addr.IP) {
+			ip = addr.IP
+			break
+		}
+	}
+	if ip == nil {
+		if d.ipVersion != "" {
+			return nil, fmt.Errorf("%s has no IPv%s address", host, d.ipVersion)
+		}
+		return nil, fmt.Errorf("%s has no addresses", host)
+	}
+
+	if d.dns == DNSPin {
+		d.mu.Lock()
+		if pinned, ok := d.pinned[host]; ok {
+			ip = pinned
+		} else {
+			d.pinned[host] = ip
+		}
+		d.mu.Unlock()
+	}
+	return ip, nil
+}
+
+// resolveAddr resolves the host part of a host:port address.
+func (d *dialer) resolveAddr(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	ip, err := d.resolve(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network == "tcp" {
+		network += d.ipVersion
+	}
+	if d.dns == DNSSystem {
+		return d.Dialer.DialContext(ctx, network, addr)
+	}
+	resolved, err := d.resolveAddr(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return d.Dialer.DialContext(ctx, network, resolved)
+}
+
+// quicDialFunc is the signature of http3.Transport.Dial.
+type quicDialFunc func(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error)
+
+// quicDial returns a Dial function for http3.Transport, or nil when
+// quic-go's default does the same thing.
+func (d *dialer) quicDial() quicDialFunc {
+	if d.dns == DNSSystem && d.ipVersion == "" && d.quic == nil {
+		return nil
+	}
+	return func(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+		resolved, err := d.resolveAddr(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		if d.quic == nil {
+			return quic.DialAddrEarly(ctx, resolved, tlsConfig, config)
+		}
+		udpAddr, err := net.ResolveUDPAddr("udp", resolved)
+		if err != nil {
+			return nil, err
+		}
+		return d.quic.DialEarly(ctx, udpAddr, tlsConfig, config)
+	}
+}
+
+// ResolveEndpoint looks up every address the endpoint's hostname resolves
+// to. An endpoint that already names an IP resolves to just that IP.
+func ResolveEndpoint(ctx context.Context, endpoint string) (host string, ips []net.IP, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", nil, err
+	}
+	host = u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return host, []net.IP{ip}, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return host, nil, err
+	}
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return host, ips, nil
+}
+
+// Pinned returns a copy of c whose connections go to ip rather than to
+// whatever the endpoint's hostname resolves to. The request URL, Host
+// header and TLS server name are unchanged, so the certificate is still
+// checked against the hostname. Pinned connections bypass any proxy. The
+// copy shares c's headers and rate limit.
+func (c *Client) Pinned(ip net.IP) (*Client, error) {
+	hc, ok := c.HTTP.(*http.Client)
+	if !ok {
+		return nil, fmt.Errorf("pinning an address needs an *http.Client transport, not %T", c.HTTP)
+	}
+	pinned := *c
+	clone := cloneHTTPClient(hc)
+	pinAddr := func(addr string) string {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return addr
+		}
+		return net.JoinHostPort(ip.String(), port)
+	}
+
+	if clone.Transport == nil {
+		clone.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	switch transport := clone.Transport.(type) {
+	case *http.Transport:
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dial(ctx, network, pinAddr(addr))
+		}
+		transport.Proxy = nil
+	case *http3.Transport:
+		dial := transport.Dial
+		if dial == nil {
+			dial = quic.DialAddrEarly
+		}
+		transport.Dial = func(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+			return dial(ctx, pinAddr(addr), tlsConfig, config)
+		}
+	default:
+		return nil, fmt.Errorf("pinning an address is not supported for %T", transport)
+	}
+	pinned.HTTP = clone
+	return &pinned, nil
+}
+
+// addrFamily returns "ipv4" or "ipv6" for a TCP or UDP address, or "" if
+// it is neither.
+func addrFamily(addr net.Addr) string {
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	default:
+		return ""
+	}
+	if ip.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
@@ -0,0 +1,268 @@
+package rpcclient
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HARLog collects the request and response metadata of every call in HTTP
+// Archive 1.2 form, for inspection in browser devtools or attaching to a
+// provider support ticket. Bodies are left out, apart from the JSON-RPC
+// request; credentials in URLs and headers are redacted.
+type HARLog struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+func NewHARLog() *HARLog {
+	return &HARLog{}
+}
+
+// WriteFile writes the archive collected so far to path.
+func (h *HARLog) WriteFile(path string) error {
+	h.mu.Lock()
+	entries := append([]harEntry(nil), h.entries...)
+	h.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedDateTime.Before(entries[j].StartedDateTime) })
+
+	var archive struct {
+		Log struct {
+			Version string      `json:"version"`
+			Creator harNameSpec `json:"creator"`
+			Entries []harEntry  `json:"entries"`
+		} `json:"log"`
+	}
+	archive.Log.Version = "1.2"
+	archive.Log.Creator = harNameSpec{Name: "solana-rpc-bench", Version: "1"}
+	archive.Log.Entries = entries
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (h *HARLog) add(entry harEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+}
+
+type harNameSpec struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []struct{}  `json:"cookies"`
+	Headers     []harHeader `json:"headers"`
+	QueryString []harHeader `json:"queryString"`
+	PostData    struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	} `json:"postData"`
+	HeadersSize int `json:"headersSize"`
+	BodySize    int `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []struct{}  `json:"cookies"`
+	Headers     []harHeader `json:"headers"`
+	Content     struct {
+		Size        int    `json:"size"`
+		Compression int    `json:"compression,omitempty"`
+		MimeType    string `json:"mimeType"`
+	} `json:"content"`
+	RedirectURL string `json:"redirectURL"`
+	HeadersSize int    `json:"headersSize"`
+	BodySize    int    `json:"bodySize"`
+}
+
+// harTimings are in milliseconds; -1 marks a phase that did not happen,
+// such as DNS and connect on a reused connection.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harCall gathers one call's HAR entry as it happens. The trace hooks can
+// run on the transport's dial goroutine, hence the mutex; the remaining
+// fields are set by the calling goroutine before entry.
+type harCall struct {
+	start time.Time
+
+	mu                               sync.Mutex
+	dnsStart, dnsDone                time.Time
+	connectStart, connectDone        time.Time
+	tlsStart, tlsDone                time.Time
+	gotConn, wroteRequest, firstByte time.Time
+	remote, local                    string
+	request                          *http.Request
+	requestBody                      []byte
+	response                         *http.Response
+	wireSize                         int
+}
+
+func (h *harCall) trace() *httptrace.ClientTrace {
+	at := func(field *time.Time) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { at(&h.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { at(&h.dnsDone) },
+		ConnectStart:      func(string, string) { at(&h.connectStart) },
+		ConnectDone:       func(string, string, error) { at(&h.connectDone) },
+		TLSHandshakeStart: func() { at(&h.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { at(&h.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			at(&h.gotConn)
+			if info.Conn != nil {
+				h.mu.Lock()
+				h.remote, h.local = info.Conn.RemoteAddr().String(), info.Conn.LocalAddr().String()
+				h.mu.Unlock()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { at(&h.wroteRequest) },
+		GotFirstResponseByte: func() { at(&h.firstByte) },
+	}
+}
+
+// entry builds the HAR entry once the call has ended with result.
+func (h *harCall) entry(endpoint string, result *Result, body []byte) harEntry {
+	end := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := harEntry{
+		StartedDateTime: h.start,
+		Time:            millis(end.Sub(h.start)),
+		Comment:         result.Method,
+		Connection:      h.local,
+	}
+	if host, _, err := net.SplitHostPort(h.remote); err == nil {
+		entry.ServerIPAddress = host
+	}
+	if !result.Success {
+		entry.Comment += ": " + result.Error
+	}
+
+	entry.Request = harRequest{
+		Method:      http.MethodPost,
+		URL:         RedactURL(endpoint),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []struct{}{},
+		Headers:     []harHeader{},
+		QueryString: []harHeader{},
+		HeadersSize: -1,
+		BodySize:    len(h.requestBody),
+	}
+	entry.Request.PostData.MimeType = "application/json"
+	entry.Request.PostData.Text = string(h.requestBody)
+	if h.request != nil {
+		entry.Request.Headers = harHeaders(h.request.Header)
+		for key := range h.request.URL.Query() {
+			entry.Request.QueryString = append(entry.Request.QueryString, harHeader{Name: key, Value: "REDACTED"})
+		}
+	}
+
+	entry.Response = harResponse{
+		Cookies:     []struct{}{},
+		Headers:     []harHeader{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if h.response != nil {
+		entry.Request.HTTPVersion = h.response.Proto
+		entry.Response.Status = h.response.StatusCode
+		entry.Response.StatusText = http.StatusText(h.response.StatusCode)
+		entry.Response.HTTPVersion = h.response.Proto
+		entry.Response.Headers = harHeaders(h.response.Header)
+		entry.Response.Content.MimeType = h.response.Header.Get("Content-Type")
+		entry.Response.Content.Size = len(body)
+		entry.Response.BodySize = h.wireSize
+		if h.wireSize > 0 && h.wireSize < len(body) {
+			entry.Response.Content.Compression = len(body) - h.wireSize
+		}
+	}
+
+	span := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return millis(to.Sub(from))
+	}
+	connectEnd := h.connectDone
+	if !h.tlsDone.IsZero() {
+		connectEnd = h.tlsDone
+	}
+	entry.Timings = harTimings{
+		DNS:     span(h.dnsStart, h.dnsDone),
+		Connect: span(h.connectStart, connectEnd),
+		SSL:     span(h.tlsStart, h.tlsDone),
+		Send:    max(span(h.gotConn, h.wroteRequest), 0),
+		Wait:    max(span(h.wroteRequest, h.firstByte), 0),
+		Receive: max(span(h.firstByte, end), 0),
+	}
+	// Whatever time before the connection was not spent resolving or
+	// connecting was spent waiting for one.
+	entry.Timings.Blocked = max(span(h.start, h.gotConn)-max(entry.Timings.DNS, 0)-max(entry.Timings.Connect, 0), 0)
+	return entry
+}
+
+func harHeaders(header http.Header) []harHeader {
+	flat := make(map[string]string, len(header))
+	for key, values := range header {
+		for _, value := range values {
+			flat[key] = value
+		}
+	}
+	headers := []harHeader{}
+	for key, value := range RedactHeaders(flat) {
+		headers = append(headers, harHeader{Name: key, Value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
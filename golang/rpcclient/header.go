@@ -0,0 +1,24 @@
+package rpcclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// CallWithHeader is Call, also returning the HTTP response headers, or nil
+// if no response arrived. It is meant for probes, so the call is not
+// recorded, captured or rewritten by Params.
+func (c *Client) CallWithHeader(ctx context.Context, method string, params interface{}) (*Result, http.Header, error) {
+	var header http.Header
+	probe := *c
+	probe.Recorder, probe.HAR, probe.Capture, probe.Params = nil, nil, nil, nil
+	probe.HTTP = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := c.HTTP.Do(req)
+		if err == nil {
+			header = resp.Header.Clone()
+		}
+		return resp, err
+	})
+	result, err := probe.Call(ctx, method, params)
+	return result, header, err
+}
@@ -0,0 +1,51 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
+// JitoBundlesURL returns the bundle API endpoint of a Jito block engine
+// given its base URL, such as https://mainnet.block-engine.jito.wtf.
+func JitoBundlesURL(blockEngine string) string {
+	return strings.TrimSuffix(blockEngine, "/") + "/api/v1/bundles"
+}
+
+// GetTipAccounts lists the accounts a bundle may tip; c must point at a
+// block engine's bundle API.
+func (c *Client) GetTipAccounts(ctx context.Context) (*Result, error) {
+	return c.Call(ctx, "getTipAccounts", []interface{}{})
+}
+
+// SendBundle submits signed transactions as one bundle to a block
+// engine's bundle API. A successful result is the bundle ID.
+func (c *Client) SendBundle(ctx context.Context, txs [][]byte) (*Result, error) {
+	params := []interface{}{encodeTransactions(txs), map[string]interface{}{"encoding": "base64"}}
+	return c.Call(ctx, "sendBundle", params)
+}
+
+// SimulateBundle simulates transactions as one bundle on a Jito-Solana
+// RPC node, without signature verification and with a recent blockhash
+// swapped in.
+func (c *Client) SimulateBundle(ctx context.Context, txs [][]byte) (*Result, error) {
+	none := make([]interface{}, len(txs))
+	params := []interface{}{
+		map[string]interface{}{"encodedTransactions": encodeTransactions(txs)},
+		map[string]interface{}{
+			"preExecutionAccountsConfigs":  none,
+			"postExecutionAccountsConfigs": none,
+			"skipSigVerify":                true,
+			"replaceRecentBlockhash":       true,
+		},
+	}
+	return c.Call(ctx, "simulateBundle", params)
+}
+
+func encodeTransactions(txs [][]byte) []string {
+	encoded := make([]string, len(txs))
+	for i, tx := range txs {
+		encoded[i] = base64.StdEncoding.EncodeToString(tx)
+	}
+	return encoded
+}
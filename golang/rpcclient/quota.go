@@ -0,0 +1,182 @@
+package rpcclient
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota is what a response's rate-limit headers say about the client's
+// allowance. Limit and Remaining are the requests, or credits, allowed
+// and left in the window that resets after Reset. Window names it when
+// the provider reports several, such as "second" and "minute"; the one
+// with the smallest share left is chosen. HasRemaining reports whether
+// the headers gave an allowance at all; without it the other fields are
+// unset, save RetryAfter, how long a refused client was asked to wait.
+type Quota struct {
+	Limit        int64
+	Remaining    int64
+	HasRemaining bool
+	Window       string
+	Reset        time.Duration
+	RetryAfter   time.Duration
+}
+
+// quotaWindow gathers the headers of one window.
+type quotaWindow struct {
+	Quota
+	used    int64
+	hasUsed bool
+}
+
+// ParseQuota reads the rate-limit headers of a response, as kept in
+// Result.LimitHeaders, that arrived at now. It knows the X-RateLimit-*
+// family, with or without a window suffix such as -Second or -Minute,
+// its X-Rate-Limit-* spelling, the IETF RateLimit-* headers and combined
+// RateLimit field, X-Quota-* and Retry-After. It returns nil if they give
+// neither an allowance left nor a Retry-After.
+func ParseQuota(headers map[string]string, now time.Time) *Quota {
+	if len(headers) == 0 {
+		return nil
+	}
+	windows := map[string]*quotaWindow{}
+	window := func(name string) *quotaWindow {
+		if windows[name] == nil {
+			windows[name] = &quotaWindow{Quota: Quota{Window: name}}
+		}
+		return windows[name]
+	}
+	var retryAfter time.Duration
+	for name, value := range headers {
+		name = strings.Replace(strings.TrimPrefix(strings.ToLower(name), "x-"), "rate-limit", "ratelimit", 1)
+		value = strings.TrimSpace(value)
+		switch {
+		case name == "retry-after":
+			retryAfter = parseRetryAfter(value, now)
+		case name == "ratelimit":
+			parseRateLimitField(value, window(""), now)
+		case strings.HasPrefix(name, "ratelimit-"), strings.HasPrefix(name, "quota-"):
+			_, rest, _ := strings.Cut(name, "-")
+			field, suffix, _ := strings.Cut(rest, "-")
+			setQuotaField(window(suffix), field, value, now)
+		}
+	}
+
+	var chosen *Quota
+	names := make([]string, 0, len(windows))
+	for name := range windows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w := windows[name]
+		if !w.HasRemaining && w.hasUsed && w.Limit > 0 {
+			w.Remaining, w.HasRemaining = max(0, w.Limit-w.used), true
+		}
+		if w.HasRemaining && (chosen == nil || share(&w.Quota) < share(chosen)) {
+			chosen = &w.Quota
+		}
+	}
+	if chosen == nil {
+		if retryAfter == 0 {
+			return nil
+		}
+		chosen = &Quota{}
+	}
+	chosen.RetryAfter = retryAfter
+	return chosen
+}
+
+// share is the fraction of q's limit left, or its remaining allowance
+// when the limit is unknown.
+func share(q *Quota) float64 {
+	if q.Limit > 0 {
+		return float64(q.Remaining) / float64(q.Limit)
+	}
+	return float64(q.Remaining)
+}
+
+// setQuotaField sets the field of w a header names: limit, remaining,
+// used, reset or policy.
+func setQuotaField(w *quotaWindow, field, value string, now time.Time) {
+	switch field {
+	case "reset":
+		w.Reset = parseReset(value, now)
+		return
+	case "policy":
+		// A policy such as "100;w=60" gives the limit, if nothing else
+		// did.
+		if w.Limit == 0 {
+			setQuotaField(w, "limit", value, now)
+		}
+		return
+	case "limit", "remaining", "used":
+	default:
+		return
+	}
+	// Limits such as "100, 100;w=60" lead with the number.
+	if i := strings.IndexAny(value, ",;"); i >= 0 {
+		value = value[:i]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return
+	}
+	switch field {
+	case "limit":
+		w.Limit = int64(n)
+	case "remaining":
+		w.Remaining, w.HasRemaining = int64(n), true
+	case "used":
+		w.used, w.hasUsed = int64(n), true
+	}
+}
+
+// parseRateLimitField reads the IETF RateLimit field, in its early form,
+// "limit=100, remaining=50, reset=5", or its later structured one,
+// "default";r=50;t=30.
+func parseRateLimitField(value string, w *quotaWindow, now time.Time) {
+	for _, param := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "limit", "l", "q":
+			setQuotaField(w, "limit", v, now)
+		case "remaining", "r":
+			setQuotaField(w, "remaining", v, now)
+		case "reset", "t":
+			setQuotaField(w, "reset", v, now)
+		}
+	}
+}
+
+// parseReset reads a reset header: seconds until the window resets, or
+// the Unix time, in seconds or milliseconds, at which it does.
+func parseReset(value string, now time.Time) time.Duration {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	switch {
+	case n > 1e12:
+		return max(0, time.UnixMilli(int64(n)).Sub(now))
+	case n > 1e9:
+		return max(0, time.Unix(int64(n), 0).Sub(now))
+	}
+	return time.Duration(n * float64(time.Second))
+}
+
+// parseRetryAfter reads Retry-After: a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(max(0, seconds) * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, at.Sub(now))
+	}
+	return 0
+}
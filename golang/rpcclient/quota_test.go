@@ -0,0 +1,125 @@
+package rpcclient
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseQuota(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	unix := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    *Quota
+	}{
+		{name: "no headers"},
+		{name: "nothing known", headers: map[string]string{"X-Request-Id": "7"}},
+		{
+			name:    "x-ratelimit",
+			headers: map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "40", "X-RateLimit-Reset": "30"},
+			want:    &Quota{Limit: 100, Remaining: 40, HasRemaining: true, Reset: 30 * time.Second},
+		},
+		{
+			name:    "x-rate-limit spelling, lower case",
+			headers: map[string]string{"x-rate-limit-limit": "50", "x-rate-limit-remaining": " 5 "},
+			want:    &Quota{Limit: 50, Remaining: 5, HasRemaining: true},
+		},
+		{
+			name: "the window with the smallest share left",
+			headers: map[string]string{
+				"X-RateLimit-Limit-Second": "10", "X-RateLimit-Remaining-Second": "9",
+				"X-RateLimit-Limit-Minute": "100", "X-RateLimit-Remaining-Minute": "20",
+			},
+			want: &Quota{Limit: 100, Remaining: 20, HasRemaining: true, Window: "minute"},
+		},
+		{
+			name:    "remaining from used",
+			headers: map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Used": "30"},
+			want:    &Quota{Limit: 100, Remaining: 70, HasRemaining: true},
+		},
+		{
+			name:    "used beyond the limit",
+			headers: map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Used": "120"},
+			want:    &Quota{Limit: 100, Remaining: 0, HasRemaining: true},
+		},
+		{
+			name:    "used without a limit",
+			headers: map[string]string{"X-RateLimit-Used": "30"},
+		},
+		{
+			name:    "ietf headers with a policy list",
+			headers: map[string]string{"RateLimit-Limit": "100, 100;w=60", "RateLimit-Remaining": "7", "RateLimit-Reset": "12"},
+			want:    &Quota{Limit: 100, Remaining: 7, HasRemaining: true, Reset: 12 * time.Second},
+		},
+		{
+			name:    "limit from the policy",
+			headers: map[string]string{"RateLimit-Policy": "100;w=60", "RateLimit-Remaining": "3"},
+			want:    &Quota{Limit: 100, Remaining: 3, HasRemaining: true},
+		},
+		{
+			name:    "combined field, early form",
+			headers: map[string]string{"RateLimit": "limit=100, remaining=50, reset=5"},
+			want:    &Quota{Limit: 100, Remaining: 50, HasRemaining: true, Reset: 5 * time.Second},
+		},
+		{
+			name:    "combined field, structured form",
+			headers: map[string]string{"RateLimit": `"default";r=50;t=30`},
+			want:    &Quota{Remaining: 50, HasRemaining: true, Reset: 30 * time.Second},
+		},
+		{
+			name:    "x-quota",
+			headers: map[string]string{"X-Quota-Limit": "1000", "X-Quota-Remaining": "250.0"},
+			want:    &Quota{Limit: 1000, Remaining: 250, HasRemaining: true},
+		},
+		{
+			name:    "reset as a unix time",
+			headers: map[string]string{"X-RateLimit-Remaining": "1", "X-RateLimit-Reset": unix(45 * time.Second)},
+			want:    &Quota{Remaining: 1, HasRemaining: true, Reset: 45 * time.Second},
+		},
+		{
+			name:    "reset as a unix time in milliseconds",
+			headers: map[string]string{"X-RateLimit-Remaining": "1", "X-RateLimit-Reset": strconv.FormatInt(now.Add(1500*time.Millisecond).UnixMilli(), 10)},
+			want:    &Quota{Remaining: 1, HasRemaining: true, Reset: 1500 * time.Millisecond},
+		},
+		{
+			name:    "reset in the past",
+			headers: map[string]string{"X-RateLimit-Remaining": "1", "X-RateLimit-Reset": unix(-time.Minute)},
+			want:    &Quota{Remaining: 1, HasRemaining: true},
+		},
+		{
+			name:    "retry-after alone",
+			headers: map[string]string{"Retry-After": "1.5"},
+			want:    &Quota{RetryAfter: 1500 * time.Millisecond},
+		},
+		{
+			name:    "retry-after as a date",
+			headers: map[string]string{"Retry-After": now.Add(90 * time.Second).UTC().Format(http.TimeFormat), "X-RateLimit-Remaining": "0"},
+			want:    &Quota{Remaining: 0, HasRemaining: true, RetryAfter: 90 * time.Second},
+		},
+		{
+			name:    "a 429 with only retry-after",
+			headers: map[string]string{"Retry-After": "30", "Content-Type": "application/json"},
+			want:    &Quota{RetryAfter: 30 * time.Second},
+		},
+		{
+			name:    "a 429 with retry-after and no allowance left",
+			headers: map[string]string{"Retry-After": "30", "X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "0"},
+			want:    &Quota{Limit: 100, Remaining: 0, HasRemaining: true, RetryAfter: 30 * time.Second},
+		},
+		{
+			name:    "unreadable values",
+			headers: map[string]string{"X-RateLimit-Remaining": "many", "Retry-After": "later"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseQuota(tt.headers, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package rpcclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecordedCall is one request of a recording: what was sent and when,
+// as an offset from the recording's first request.
+type RecordedCall struct {
+	OffsetMicros int64           `json:"offsetMicros"`
+	Method       string          `json:"method"`
+	Params       json.RawMessage `json:"params,omitempty"`
+}
+
+// Recorder writes every request a client sends to a file, one
+// RecordedCall per line, so the same sequence can be replayed later.
+type Recorder struct {
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	start time.Time
+	err   error
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Close flushes the recording and returns the first error met while
+// writing it.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+func (r *Recorder) record(method string, params interface{}, sent time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if r.start.IsZero() {
+		r.start = sent
+	}
+	call := RecordedCall{OffsetMicros: sent.Sub(r.start).Microseconds(), Method: method}
+	if params != nil {
+		if call.Params, r.err = json.Marshal(params); r.err != nil {
+			return
+		}
+	}
+	line, err := json.Marshal(call)
+	if err != nil {
+		r.err = err
+		return
+	}
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		r.err = err
+	}
+}
+
+// ReadRecording loads the calls of a file written by a Recorder.
+func ReadRecording(path string) ([]RecordedCall, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var calls []RecordedCall
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var call RecordedCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if call.Method == "" {
+			return nil, fmt.Errorf("%s:%d: no method", path, line)
+		}
+		calls = append(calls, call)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("recording %s is empty", path)
+	}
+	return calls, nil
+}
@@ -0,0 +1,75 @@
+package rpcclient
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RedactURL masks query parameter values, which is where most providers
+// carry API keys, user info, and path segments that look like keys, as in
+// QuickNode's https://name.quiknode.pro/<token>/ or Alchemy's /v2/<key>.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if looksLikeKey(segment) {
+			segments[i] = "REDACTED"
+		}
+	}
+	u.Path, u.RawPath = strings.Join(segments, "/"), ""
+	query := u.Query()
+	for key := range query {
+		query.Set(key, "REDACTED")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// minPathKeyLength is the shortest path segment taken for a key. Provider
+// keys run to 32 characters or more; names such as solana-mainnet are
+// shorter, or have no digits.
+const minPathKeyLength = 16
+
+// looksLikeKey reports whether a path segment is a long run of letters,
+// digits, - and _ with a digit among them.
+func looksLikeKey(segment string) bool {
+	if len(segment) < minPathKeyLength {
+		return false
+	}
+	digit := false
+	for _, c := range segment {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return digit
+}
+
+var secretHeaderWords = []string{"auth", "key", "token", "secret", "cookie", "password"}
+
+// RedactHeaders returns a copy of headers with the values of anything that
+// looks like a credential masked.
+func RedactHeaders(headers map[string]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for key, value := range headers {
+		lower := strings.ToLower(key)
+		for _, word := range secretHeaderWords {
+			if strings.Contains(lower, word) {
+				value = "REDACTED"
+				break
+			}
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
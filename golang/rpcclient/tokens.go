@@ -0,0 +1,26 @@
+package rpcclient
+
+import "context"
+
+const (
+	TokenProgramID     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	Token2022ProgramID = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+	USDCMint           = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+func (c *Client) GetTokenAccountsByOwner(ctx context.Context, owner, programID string) (*Result, error) {
+	if programID == "" {
+		programID = TokenProgramID
+	}
+	params := []interface{}{
+		owner,
+		map[string]interface{}{"programId": programID},
+		map[string]interface{}{"encoding": "jsonParsed"},
+	}
+	return c.Call(ctx, "getTokenAccountsByOwner", params)
+}
+
+func (c *Client) GetTokenSupply(ctx context.Context, mint string) (*Result, error) {
+	params := []interface{}{mint}
+	return c.Call(ctx, "getTokenSupply", params)
+}
@@ -0,0 +1,69 @@
+package rpcclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts the client span of a call of method at start. Its
+// attributes follow the OpenTelemetry conventions for JSON-RPC.
+func (c *Client) startSpan(ctx context.Context, method string, start time.Time) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.jsonrpc.version", "2.0"),
+		attribute.String("rpc.method", method),
+		attribute.String("url.full", RedactURL(c.Endpoint)),
+	}
+	if u, err := url.Parse(c.Endpoint); err == nil {
+		attrs = append(attrs, attribute.String("server.address", u.Hostname()))
+	}
+	return c.Tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...))
+}
+
+// injectSpan adds the W3C traceparent header of the span in ctx to
+// header, so the endpoint's own traces can be joined to the benchmark's.
+func injectSpan(ctx context.Context, header http.Header) {
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// endSpan records how the call ended on span and ends it. err is set
+// when the caller's context ended the call.
+func endSpan(span trace.Span, result *Result, err error) {
+	switch {
+	case err != nil:
+		span.SetStatus(codes.Error, err.Error())
+	case result != nil:
+		span.SetAttributes(
+			attribute.Bool("solana_rpc_bench.success", result.Success),
+			attribute.Int64("solana_rpc_bench.latency_ms", result.Latency),
+			attribute.Int("solana_rpc_bench.size", result.Size),
+		)
+		if result.StatusCode != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+		}
+		if result.Protocol != "" {
+			span.SetAttributes(attribute.String("network.protocol.version", result.Protocol))
+		}
+		if !result.Success {
+			if result.ErrorCode != 0 {
+				span.SetAttributes(attribute.Int("rpc.jsonrpc.error_code", result.ErrorCode))
+			}
+			span.SetAttributes(
+				attribute.Bool("solana_rpc_bench.timed_out", result.TimedOut),
+				attribute.Bool("solana_rpc_bench.rate_limited", result.RateLimited),
+			)
+			span.SetStatus(codes.Error, result.Error)
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,201 @@
+package rpcclient
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type PublicKey [32]byte
+
+// SystemProgramID is the all-zero key 11111111111111111111111111111111.
+var SystemProgramID PublicKey
+
+func (k PublicKey) String() string {
+	return Base58Encode(k[:])
+}
+
+func ParsePublicKey(s string) (PublicKey, error) {
+	var key PublicKey
+	raw, err := Base58Decode(s)
+	if err != nil {
+		return key, err
+	}
+	if len(raw) != len(key) {
+		return key, fmt.Errorf("public key %q decodes to %d bytes, want %d", s, len(raw), len(key))
+	}
+	copy(key[:], raw)
+	return key, nil
+}
+
+// LoadKeypair reads a Solana CLI keypair file: a JSON array of the 64
+// secret key bytes, which is also the layout of ed25519.PrivateKey.
+func LoadKeypair(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ints []int
+	if err := json.Unmarshal(data, &ints); err != nil {
+		return nil, fmt.Errorf("parse keypair %s: %w", path, err)
+	}
+	if len(ints) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("keypair %s has %d bytes, want %d", path, len(ints), ed25519.PrivateKeySize)
+	}
+	raw := make([]byte, 0, len(ints))
+	for _, v := range ints {
+		if v < 0 || v > 255 {
+			return nil, fmt.Errorf("keypair %s contains non-byte value %d", path, v)
+		}
+		raw = append(raw, byte(v))
+	}
+	return ed25519.PrivateKey(raw), nil
+}
+
+func PublicKeyOf(key ed25519.PrivateKey) PublicKey {
+	var pub PublicKey
+	copy(pub[:], key.Public().(ed25519.PublicKey))
+	return pub
+}
+
+type AccountMeta struct {
+	PublicKey  PublicKey
+	IsSigner   bool
+	IsWritable bool
+}
+
+type Instruction struct {
+	ProgramID PublicKey
+	Accounts  []AccountMeta
+	Data      []byte
+}
+
+func TransferInstruction(from, to PublicKey, lamports uint64) Instruction {
+	data := make([]byte, 12)
+	binary.LittleEndian.PutUint32(data[0:4], 2)
+	binary.LittleEndian.PutUint64(data[4:12], lamports)
+	return Instruction{
+		ProgramID: SystemProgramID,
+		Accounts: []AccountMeta{
+			{PublicKey: from, IsSigner: true, IsWritable: true},
+			{PublicKey: to, IsWritable: true},
+		},
+		Data: data,
+	}
+}
+
+func appendCompactU16(buf []byte, n int) []byte {
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(buf, b)
+		}
+		buf = append(buf, b|0x80)
+	}
+}
+
+// compileMessage serializes a legacy transaction message with payer as the
+// first (fee-paying) signer and returns it with the number of signatures
+// the transaction needs.
+func compileMessage(payer PublicKey, instructions []Instruction, blockhash PublicKey) ([]byte, int) {
+	metas := []AccountMeta{{PublicKey: payer, IsSigner: true, IsWritable: true}}
+	position := map[PublicKey]int{payer: 0}
+	add := func(meta AccountMeta) {
+		if i, ok := position[meta.PublicKey]; ok {
+			metas[i].IsSigner = metas[i].IsSigner || meta.IsSigner
+			metas[i].IsWritable = metas[i].IsWritable || meta.IsWritable
+			return
+		}
+		position[meta.PublicKey] = len(metas)
+		metas = append(metas, meta)
+	}
+	for _, ix := range instructions {
+		for _, meta := range ix.Accounts {
+			add(meta)
+		}
+		add(AccountMeta{PublicKey: ix.ProgramID})
+	}
+
+	// Signed-writable, signed-readonly, unsigned-writable, unsigned-readonly;
+	// the payer stays first.
+	rank := func(m AccountMeta) int {
+		switch {
+		case m.IsSigner && m.IsWritable:
+			return 0
+		case m.IsSigner:
+			return 1
+		case m.IsWritable:
+			return 2
+		default:
+			return 3
+		}
+	}
+	ordered := []AccountMeta{metas[0]}
+	for r := 0; r < 4; r++ {
+		for _, m := range metas[1:] {
+			if rank(m) == r {
+				ordered = append(ordered, m)
+			}
+		}
+	}
+
+	var numSigners, readonlySigned, readonlyUnsigned int
+	index := make(map[PublicKey]byte, len(ordered))
+	for i, m := range ordered {
+		index[m.PublicKey] = byte(i)
+		switch rank(m) {
+		case 0:
+			numSigners++
+		case 1:
+			numSigners++
+			readonlySigned++
+		case 3:
+			readonlyUnsigned++
+		}
+	}
+
+	msg := []byte{byte(numSigners), byte(readonlySigned), byte(readonlyUnsigned)}
+	msg = appendCompactU16(msg, len(ordered))
+	for _, m := range ordered {
+		msg = append(msg, m.PublicKey[:]...)
+	}
+	msg = append(msg, blockhash[:]...)
+	msg = appendCompactU16(msg, len(instructions))
+	for _, ix := range instructions {
+		msg = append(msg, index[ix.ProgramID])
+		msg = appendCompactU16(msg, len(ix.Accounts))
+		for _, meta := range ix.Accounts {
+			msg = append(msg, index[meta.PublicKey])
+		}
+		msg = appendCompactU16(msg, len(ix.Data))
+		msg = append(msg, ix.Data...)
+	}
+	return msg, numSigners
+}
+
+// BuildTransaction returns a wire-format transaction signed by payer and
+// its signature, which doubles as the transaction id.
+func BuildTransaction(payer ed25519.PrivateKey, instructions []Instruction, blockhash PublicKey) ([]byte, string, error) {
+	msg, numSigners := compileMessage(PublicKeyOf(payer), instructions, blockhash)
+	if numSigners != 1 {
+		return nil, "", fmt.Errorf("transaction needs %d signers, only the payer can sign", numSigners)
+	}
+	sig := ed25519.Sign(payer, msg)
+
+	tx := appendCompactU16(nil, 1)
+	tx = append(tx, sig...)
+	tx = append(tx, msg...)
+	return tx, Base58Encode(sig), nil
+}
+
+// BuildUnsignedTransaction fills the signature slots with zeros, which is
+// enough for simulateTransaction with sigVerify disabled.
+func BuildUnsignedTransaction(payer PublicKey, instructions []Instruction, blockhash PublicKey) []byte {
+	msg, numSigners := compileMessage(payer, instructions, blockhash)
+	tx := appendCompactU16(nil, numSigners)
+	tx = append(tx, make([]byte, numSigners*ed25519.SignatureSize)...)
+	return append(tx, msg...)
+}
@@ -0,0 +1,122 @@
+package rpcclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"testing"
+)
+
+func TestAppendCompactU16(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0x80, 0x01}},
+		{0xff, []byte{0xff, 0x01}},
+		{0x100, []byte{0x80, 0x02}},
+		{0x3fff, []byte{0xff, 0x7f}},
+		{0x4000, []byte{0x80, 0x80, 0x01}},
+		{0xffff, []byte{0xff, 0xff, 0x03}},
+	}
+	for _, tt := range tests {
+		if got := appendCompactU16([]byte{0xaa}, tt.n); !bytes.Equal(got, append([]byte{0xaa}, tt.want...)) {
+			t.Errorf("appendCompactU16(%d) = %x, want aa%x", tt.n, got, tt.want)
+		}
+	}
+}
+
+func testKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func TestBuildTransaction(t *testing.T) {
+	payer := testKey(1)
+	from := PublicKeyOf(payer)
+	to := PublicKeyOf(testKey(2))
+	var blockhash PublicKey
+	blockhash[0] = 7
+
+	tx, signature, err := BuildTransaction(payer, []Instruction{TransferInstruction(from, to, 5000)}, blockhash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx[0] != 1 {
+		t.Fatalf("signature count = %d, want 1", tx[0])
+	}
+	sig, msg := tx[1:1+ed25519.SignatureSize], tx[1+ed25519.SignatureSize:]
+	if signature != Base58Encode(sig) {
+		t.Errorf("signature %q does not match the transaction's", signature)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(from[:]), msg, sig) {
+		t.Error("signature does not verify against the message")
+	}
+
+	// Header, then payer, recipient and System Program, then the blockhash
+	// and one instruction.
+	want := []byte{1, 0, 1, 3}
+	want = append(want, from[:]...)
+	want = append(want, to[:]...)
+	want = append(want, SystemProgramID[:]...)
+	want = append(want, blockhash[:]...)
+	want = append(want, 1, 2, 2, 0, 1, 12)
+	data := binary.LittleEndian.AppendUint32(nil, 2)
+	want = append(want, binary.LittleEndian.AppendUint64(data, 5000)...)
+	if !bytes.Equal(msg, want) {
+		t.Errorf("message =\n%x\nwant\n%x", msg, want)
+	}
+}
+
+func TestCompileMessageOrdersAccounts(t *testing.T) {
+	payer := PublicKeyOf(testKey(1))
+	signer := PublicKeyOf(testKey(2))
+	writable := PublicKeyOf(testKey(3))
+	readonly := PublicKeyOf(testKey(4))
+	program := PublicKeyOf(testKey(5))
+	ix := Instruction{
+		ProgramID: program,
+		Accounts: []AccountMeta{
+			{PublicKey: readonly},
+			{PublicKey: writable, IsWritable: true},
+			{PublicKey: signer, IsSigner: true},
+			// A repeated key takes the widest permissions.
+			{PublicKey: readonly, IsWritable: false},
+			{PublicKey: payer},
+		},
+	}
+	msg, numSigners := compileMessage(payer, []Instruction{ix}, PublicKey{})
+	if numSigners != 2 {
+		t.Fatalf("numSigners = %d, want 2", numSigners)
+	}
+	if header := msg[:4]; !bytes.Equal(header, []byte{2, 1, 2, 5}) {
+		t.Fatalf("header = %v, want [2 1 2 5]", header)
+	}
+	order := []PublicKey{payer, signer, writable, readonly, program}
+	for i, key := range order {
+		var got PublicKey
+		copy(got[:], msg[4+32*i:])
+		if got != key {
+			t.Errorf("account %d = %s, want %s", i, got, key)
+		}
+	}
+	// Program index, then the accounts by their new positions.
+	ixStart := 4 + 32*len(order) + 32 + 1
+	if got, want := msg[ixStart:ixStart+7], []byte{4, 5, 3, 2, 1, 3, 0}; !bytes.Equal(got, want) {
+		t.Errorf("instruction = %v, want %v", got, want)
+	}
+}
+
+func TestBuildTransactionNeedsOnlyThePayer(t *testing.T) {
+	payer := testKey(1)
+	ix := Instruction{ProgramID: SystemProgramID, Accounts: []AccountMeta{{PublicKey: PublicKeyOf(testKey(2)), IsSigner: true}}}
+	if _, _, err := BuildTransaction(payer, []Instruction{ix}, PublicKey{}); err == nil {
+		t.Error("BuildTransaction succeeded with a second signer, want an error")
+	}
+	tx := BuildUnsignedTransaction(PublicKeyOf(payer), []Instruction{ix}, PublicKey{})
+	if tx[0] != 2 || !bytes.Equal(tx[1:1+2*ed25519.SignatureSize], make([]byte, 2*ed25519.SignatureSize)) {
+		t.Errorf("unsigned transaction starts %x, want two zero signatures", tx[:8])
+	}
+}
@@ -0,0 +1,43 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/base64"
+)
+
+func (c *Client) GetLatestBlockhash(ctx context.Context, commitment string) (*Result, error) {
+	params := []interface{}{map[string]interface{}{"commitment": commitment}}
+	return c.Call(ctx, "getLatestBlockhash", params)
+}
+
+func (c *Client) IsBlockhashValid(ctx context.Context, blockhash, commitment string) (*Result, error) {
+	params := []interface{}{blockhash, map[string]interface{}{"commitment": commitment}}
+	return c.Call(ctx, "isBlockhashValid", params)
+}
+
+func (c *Client) SendTransaction(ctx context.Context, tx []byte, skipPreflight bool, commitment string) (*Result, error) {
+	params := []interface{}{
+		base64.StdEncoding.EncodeToString(tx),
+		map[string]interface{}{
+			"encoding":            "base64",
+			"skipPreflight":       skipPreflight,
+			"preflightCommitment": commitment,
+		},
+	}
+	return c.Call(ctx, "sendTransaction", params)
+}
+
+// SimulateTransaction simulates tx without signature verification, letting
+// the node substitute a recent blockhash.
+func (c *Client) SimulateTransaction(ctx context.Context, tx []byte) (*Result, error) {
+	params := []interface{}{
+		base64.StdEncoding.EncodeToString(tx),
+		map[string]interface{}{
+			"encoding":               "base64",
+			"sigVerify":              false,
+			"replaceRecentBlockhash": true,
+			"commitment":             "processed",
+		},
+	}
+	return c.Call(ctx, "simulateTransaction", params)
+}
@@ -0,0 +1,176 @@
+package rpcclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Protocol modes for TransportOptions.Protocol.
+const (
+	// ProtoAuto negotiates HTTP/2 over TLS when the server offers it and
+	// falls back to HTTP/1.1.
+	ProtoAuto = "auto"
+	// ProtoHTTP1 never uses HTTP/2.
+	ProtoHTTP1 = "http1"
+	// ProtoH2 requires HTTP/2, using prior-knowledge h2c for http://
+	// endpoints; requests to servers without it fail.
+	ProtoH2 = "h2"
+	// ProtoH3 sends HTTP/3 over QUIC. It needs an https:// endpoint that
+	// accepts QUIC on UDP; the pool options don't apply.
+	ProtoH3 = "h3"
+)
+
+// DNS strategies for TransportOptions.DNS.
+const (
+	// DNSSystem leaves lookups to the system resolver, once per new
+	// connection. It is the default.
+	DNSSystem = "system"
+	// DNSPin resolves each host once and keeps using that address for
+	// the life of the client.
+	DNSPin = "pin"
+	// DNSFresh looks the host up again for every request; it implies
+	// DisableKeepAlives, since lookups only happen on connect.
+	DNSFresh = "fresh"
+)
+
+// Address families for TransportOptions.IPVersion.
+const (
+	IPAuto = "auto"
+	IPv4   = "4"
+	IPv6   = "6"
+)
+
+// TransportOptions tune the connection pool of the HTTP client built by
+// NewHTTPClient. Zero values keep net/http's defaults, except that
+// MaxConnsPerHost zero means no limit, as in http.Transport.
+type TransportOptions struct {
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	// DisableKeepAlives opens a new connection for every request, so each
+	// one pays for DNS, TCP and TLS setup.
+	DisableKeepAlives bool
+	// Protocol is one of the Proto constants; empty means ProtoAuto.
+	Protocol string
+	// DisableCompression stops the transport from asking for gzip on its
+	// own, so responses arrive uncompressed unless Client.AcceptEncoding
+	// asks otherwise.
+	DisableCompression bool
+	// Proxy is an http://, https://, socks5:// or socks5h:// URL that all
+	// requests go through. Empty falls back to HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY from the environment.
+	Proxy string
+	TLS   TLSOptions
+	// SourceIP binds outgoing connections to a local IP address, or to
+	// the first address of a named interface, on multi-homed hosts.
+	SourceIP string
+	// DNS is one of the DNS strategy constants; empty means DNSSystem.
+	DNS string
+	// IPVersion restricts connections to IPv4 or IPv6; empty means IPAuto.
+	IPVersion string
+}
+
+// NewHTTPClient returns an *http.Client with its own transport configured
+// from opts. Its Timeout is left at zero; Client applies timeouts per call.
+func NewHTTPClient(opts TransportOptions) (*http.Client, error) {
+	tlsConfig, err := opts.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer, err := newDialer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Protocol == ProtoH3 {
+		if opts.Proxy != "" {
+			return nil, fmt.Errorf("a proxy cannot be used with HTTP/3")
+		}
+		if opts.DNS == DNSFresh {
+			return nil, fmt.Errorf("fresh DNS lookups per request are not supported with HTTP/3")
+		}
+		return &http.Client{Transport: &http3.Transport{
+			TLSClientConfig:    tlsConfig,
+			Dial:               dialer.quicDial(),
+			DisableCompression: opts.DisableCompression,
+		}}, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSClientConfig = tlsConfig
+	protocols := new(http.Protocols)
+	switch opts.Protocol {
+	case "", ProtoAuto:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	case ProtoHTTP1:
+		protocols.SetHTTP1(true)
+	case ProtoH2:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unknown protocol %q (want %s, %s, %s or %s)", opts.Protocol, ProtoAuto, ProtoHTTP1, ProtoH2, ProtoH3)
+	}
+	transport.Protocols = protocols
+
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		if transport.MaxIdleConns < opts.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = opts.MaxIdleConnsPerHost
+		}
+	}
+	transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives || opts.DNS == DNSFresh
+	transport.DisableCompression = opts.DisableCompression
+	if opts.Proxy != "" {
+		proxy, err := parseProxy(opts.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+func parseProxy(raw string) (*url.URL, error) {
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", RedactURL(raw), err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy %s: scheme must be http, https, socks5 or socks5h", RedactURL(raw))
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("proxy %s has no host", RedactURL(raw))
+	}
+	return proxy, nil
+}
+
+// cloneHTTPClient copies hc with a transport of the same configuration but
+// no shared connections.
+func cloneHTTPClient(hc *http.Client) *http.Client {
+	clone := *hc
+	switch transport := hc.Transport.(type) {
+	case *http.Transport:
+		clone.Transport = transport.Clone()
+	case *http3.Transport:
+		clone.Transport = &http3.Transport{
+			TLSClientConfig:    transport.TLSClientConfig,
+			QUICConfig:         transport.QUICConfig,
+			Dial:               transport.Dial,
+			DisableCompression: transport.DisableCompression,
+		}
+	}
+	return &clone
+}
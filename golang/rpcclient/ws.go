@@ -0,0 +1,173 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// maxWSMessageSize bounds a single PubSub message; program and block
+// notifications can run to megabytes.
+const maxWSMessageSize = 64 << 20
+
+// WSURL derives the PubSub WebSocket URL of an HTTP endpoint: http becomes
+// ws and https wss, and 8899, the port validators serve JSON-RPC on,
+// becomes 8900, where they serve PubSub. The path and query, where
+// providers carry API keys, are kept.
+func WSURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("cannot derive a WebSocket URL from %q", RedactURL(endpoint))
+	}
+	if u.Port() == "8899" {
+		u.Host = u.Hostname() + ":8900"
+	}
+	return u.String(), nil
+}
+
+// Notification is one message of a subscription.
+type Notification struct {
+	// Method is the notification's method, e.g. slotNotification.
+	Method       string
+	Subscription uint64
+	// Result is the notification's params.result, undecoded.
+	Result     json.RawMessage
+	Size       int
+	ReceivedAt time.Time
+}
+
+// WSConn is a JSON-RPC PubSub connection. It is not safe for concurrent
+// use.
+type WSConn struct {
+	conn   *websocket.Conn
+	nextID int
+	// pending holds notifications read while waiting for a subscription
+	// to be confirmed.
+	pending []Notification
+}
+
+// wsMessage is either a call's response or a notification.
+type wsMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	Params struct {
+		Result       json.RawMessage `json:"result"`
+		Subscription uint64          `json:"subscription"`
+	} `json:"params"`
+}
+
+// DialWS opens a PubSub connection to wsURL, sending c's headers with the
+// handshake.
+func (c *Client) DialWS(ctx context.Context, wsURL string) (*WSConn, error) {
+	header := http.Header{}
+	for key, value := range c.Headers {
+		header.Set(key, value)
+	}
+	conn, _, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", RedactURL(wsURL), err)
+	}
+	conn.SetReadLimit(maxWSMessageSize)
+	return &WSConn{conn: conn}, nil
+}
+
+// Subscribe calls method, e.g. slotSubscribe, and returns the subscription
+// ID once the server confirms it.
+func (w *WSConn) Subscribe(ctx context.Context, method string, params interface{}) (uint64, error) {
+	w.nextID++
+	id := w.nextID
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.conn.Write(ctx, websocket.MessageText, body); err != nil {
+		return 0, fmt.Errorf("%s: %w", method, err)
+	}
+	for {
+		msg, size, err := w.read(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", method, err)
+		}
+		if msg.ID == nil {
+			w.pending = append(w.pending, notification(msg, size))
+			continue
+		}
+		if *msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return 0, fmt.Errorf("%s: %w", method, msg.Error)
+		}
+		var sub uint64
+		if err := json.Unmarshal(msg.Result, &sub); err != nil {
+			return 0, fmt.Errorf("%s: decode subscription id: %w", method, err)
+		}
+		return sub, nil
+	}
+}
+
+// Next returns the next notification of any subscription on the
+// connection, waiting for one to arrive.
+func (w *WSConn) Next(ctx context.Context) (Notification, error) {
+	if len(w.pending) > 0 {
+		n := w.pending[0]
+		w.pending = w.pending[1:]
+		return n, nil
+	}
+	for {
+		msg, size, err := w.read(ctx)
+		if err != nil {
+			return Notification{}, err
+		}
+		if msg.ID == nil {
+			return notification(msg, size), nil
+		}
+	}
+}
+
+// Close closes the connection.
+func (w *WSConn) Close() error {
+	return w.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func (w *WSConn) read(ctx context.Context) (wsMessage, int, error) {
+	var msg wsMessage
+	_, data, err := w.conn.Read(ctx)
+	if err != nil {
+		return msg, 0, err
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, 0, fmt.Errorf("decode message: %w", err)
+	}
+	return msg, len(data), nil
+}
+
+func notification(msg wsMessage, size int) Notification {
+	return Notification{
+		Method:       msg.Method,
+		Subscription: msg.Params.Subscription,
+		Result:       msg.Params.Result,
+		Size:         size,
+		ReceivedAt:   time.Now(),
+	}
+}
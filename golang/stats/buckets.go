@@ -0,0 +1,34 @@
+package stats
+
+import "sort"
+
+// Bucket counts the samples in [From, To). To is zero for the last,
+// open-ended bucket.
+type Bucket struct {
+	From  int64 `json:"from"`
+	To    int64 `json:"to,omitempty"`
+	Count int   `json:"count"`
+}
+
+// Bucketize counts samples into the buckets split at bounds, which must be
+// ascending and positive: [0, bounds[0]), [bounds[0], bounds[1]) and so on,
+// ending with [bounds[len(bounds)-1], ∞).
+func Bucketize(samples []int64, bounds []int64) []Bucket {
+	if len(bounds) == 0 {
+		return nil
+	}
+	buckets := make([]Bucket, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].From = bounds[i-1]
+		}
+		if i < len(bounds) {
+			buckets[i].To = bounds[i]
+		}
+	}
+	for _, sample := range samples {
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] > sample })
+		buckets[i].Count++
+	}
+	return buckets
+}
@@ -0,0 +1,213 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// histogramSubBuckets is how many buckets each power of two is split into
+// once values outgrow the exact range, which bounds the relative error of
+// a reported percentile to under 1%.
+const histogramSubBuckets = 64
+
+// Histogram is a streaming alternative to Summarize for runs too long to
+// keep every sample. Values below 2×histogramSubBuckets are counted
+// exactly; larger ones fall into log-linear buckets, so memory stays a
+// few kilobytes however many values are recorded. Min, Max, Avg and the
+// variance are exact.
+type Histogram struct {
+	counts  []int64
+	total   int64
+	sum     int64
+	squares float64
+	min     int64
+	max     int64
+}
+
+// Record adds v; negative values count as zero.
+func (h *Histogram) Record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	i := histogramIndex(v)
+	if i >= len(h.counts) {
+		counts := make([]int64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.total++
+	h.sum += v
+	h.squares += float64(v) * float64(v)
+}
+
+// Merge adds every value recorded in other.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.total == 0 {
+		return
+	}
+	if len(other.counts) > len(h.counts) {
+		counts := make([]int64, len(other.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	if h.total == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.total += other.total
+	h.sum += other.sum
+	h.squares += other.squares
+}
+
+// Count returns the number of values recorded.
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+// Summary returns the same summary Summarize would, with percentiles
+// rounded to their bucket rather than interpolated.
+func (h *Histogram) Summary(percentiles ...float64) LatencyStats {
+	var stats LatencyStats
+	if h.total == 0 {
+		return stats
+	}
+	stats.Avg = float64(h.sum) / float64(h.total)
+	stats.Min = h.min
+	stats.Max = h.max
+	stats.P50 = h.quantile(0.5)
+	stats.P95 = h.quantile(0.95)
+	stats.P99 = h.quantile(0.99)
+	stats.IQR = h.quantile(0.75) - h.quantile(0.25)
+	stats.Percentiles = percentileValues(percentiles, func(q float64) float64 { return float64(h.quantile(q)) })
+	stats.Variance = max(h.squares/float64(h.total)-stats.Avg*stats.Avg, 0)
+	stats.StdDev = math.Sqrt(stats.Variance)
+	stats.TrimmedMean = h.trimmedMean()
+	return stats
+}
+
+// trimmedMean averages the bucket values between the ranks Summarize
+// would trim.
+func (h *Histogram) trimmedMean() float64 {
+	trim := int64(float64(h.total) * trimFraction)
+	low, high := trim, h.total-trim
+	var seen, kept int64
+	var sum float64
+	for i, n := range h.counts {
+		// The part of this bucket's ranks [seen, seen+n) inside [low, high).
+		if in := min(seen+n, high) - max(seen, low); in > 0 {
+			sum += float64(in) * float64(min(max(histogramValue(i), h.min), h.max))
+			kept += in
+		}
+		seen += n
+	}
+	return sum / float64(kept)
+}
+
+// quantile returns the value of the bucket holding the sample nearest to
+// rank q×(n-1).
+func (h *Histogram) quantile(q float64) int64 {
+	rank := int64(math.Round(q * float64(h.total-1)))
+	var seen int64
+	for i, n := range h.counts {
+		seen += n
+		if seen > rank {
+			return min(max(histogramValue(i), h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// histogramJSON is how a Histogram is saved. Buckets lists the nonempty
+// buckets as "index:count" pairs, on one line so indented results files
+// stay readable.
+type histogramJSON struct {
+	Count   int64   `json:"count"`
+	Sum     int64   `json:"sum"`
+	Squares float64 `json:"squares"`
+	Min     int64   `json:"min"`
+	Max     int64   `json:"max"`
+	Buckets string  `json:"buckets"`
+}
+
+// MarshalJSON saves every count, so that a histogram read back merges as
+// if its values had been recorded again.
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	var buckets []string
+	for i, n := range h.counts {
+		if n > 0 {
+			buckets = append(buckets, strconv.Itoa(i)+":"+strconv.FormatInt(n, 10))
+		}
+	}
+	return json.Marshal(histogramJSON{
+		Count:   h.total,
+		Sum:     h.sum,
+		Squares: h.squares,
+		Min:     h.min,
+		Max:     h.max,
+		Buckets: strings.Join(buckets, " "),
+	})
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var saved histogramJSON
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	loaded := Histogram{total: saved.Count, sum: saved.Sum, squares: saved.Squares, min: saved.Min, max: saved.Max}
+	var total int64
+	for _, bucket := range strings.Fields(saved.Buckets) {
+		index, count, ok := strings.Cut(bucket, ":")
+		i, err := strconv.Atoi(index)
+		n, countErr := strconv.ParseInt(count, 10, 64)
+		if !ok || err != nil || countErr != nil || i < 0 || i > histogramIndex(math.MaxInt64) || n < 0 {
+			return fmt.Errorf("histogram bucket %q is not of the form index:count", bucket)
+		}
+		if i >= len(loaded.counts) {
+			counts := make([]int64, i+1)
+			copy(counts, loaded.counts)
+			loaded.counts = counts
+		}
+		loaded.counts[i] += n
+		total += n
+	}
+	if total != loaded.total {
+		return fmt.Errorf("histogram buckets hold %d values, not %d", total, loaded.total)
+	}
+	*h = loaded
+	return nil
+}
+
+func histogramIndex(v int64) int {
+	if v < 2*histogramSubBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - 7
+	return 2*histogramSubBuckets + (shift-1)*histogramSubBuckets + int(v>>shift) - histogramSubBuckets
+}
+
+// histogramValue returns the middle of bucket i.
+func histogramValue(i int) int64 {
+	if i < 2*histogramSubBuckets {
+		return int64(i)
+	}
+	i -= 2 * histogramSubBuckets
+	shift := i/histogramSubBuckets + 1
+	low := int64(i%histogramSubBuckets+histogramSubBuckets) << shift
+	return low + int64(1)<<shift/2
+}
@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func record(values ...int64) *Histogram {
+	var h Histogram
+	for _, v := range values {
+		h.Record(v)
+	}
+	return &h
+}
+
+func TestHistogramSummary(t *testing.T) {
+	got := record(sequence(0, 99)...).Summary(99.9)
+	want := LatencyStats{
+		Avg: 49.5, Min: 0, Max: 99, P50: 50, P95: 94, P99: 98,
+		Variance: 833.25, StdDev: got.StdDev, TrimmedMean: 49.5, IQR: 49,
+		Percentiles: map[string]float64{"p99.9": 99},
+	}
+	if !near(got.StdDev, 28.86607004772212, 1e-9) {
+		t.Errorf("stddev = %v", got.StdDev)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := new(Histogram).Summary(99); !reflect.DeepEqual(got, LatencyStats{}) {
+		t.Errorf("empty histogram summary = %+v", got)
+	}
+}
+
+func TestHistogramQuantileError(t *testing.T) {
+	for _, v := range []int64{127, 128, 1000, 4095, 123456, 9876543210} {
+		h := record(0, v, v+v/10)
+		if got := h.quantile(0.5); math.Abs(float64(got-v)) > float64(v)/100 {
+			t.Errorf("median of 0, %d, %d = %d, more than 1%% off", v, v+v/10, got)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []int64
+	}{
+		{name: "both empty"},
+		{name: "into empty", b: []int64{3, 5, 8}},
+		{name: "from empty", a: []int64{3, 5, 8}},
+		{name: "overlapping", a: []int64{1, 2, 2, 3}, b: []int64{2, 3, 4}},
+		{name: "wider", a: []int64{5, 10}, b: []int64{1, 1000, 50000}},
+		{name: "narrower", a: []int64{1, 1000, 50000}, b: []int64{5, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := record(tt.a...)
+			merged.Merge(record(tt.b...))
+			want := record(append(append([]int64(nil), tt.a...), tt.b...)...)
+			if !reflect.DeepEqual(merged, want) {
+				t.Errorf("merged %+v, want %+v", *merged, *want)
+			}
+		})
+	}
+}
+
+func TestHistogramJSONRoundTrip(t *testing.T) {
+	h := record(1, 1, 250, 70000)
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Histogram
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&loaded, h) {
+		t.Errorf("loaded %+v, want %+v", loaded, *h)
+	}
+	if err := json.Unmarshal([]byte(`{"count":2,"buckets":"1:1"}`), &loaded); err == nil {
+		t.Error("loaded a histogram whose buckets don't add up to its count")
+	}
+}
@@ -0,0 +1,110 @@
+// Package stats summarizes latency and other int64 distributions.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// LatencyStats summarizes a distribution of milliseconds. It is also used
+// for other int64 samples such as response sizes or compute units.
+//
+// Variance and StdDev are of the whole population. TrimmedMean drops the
+// lowest and highest 5% of samples before averaging, and IQR is p75 minus
+// p25; unlike Avg, neither is dragged around by a few extreme samples, so a
+// large gap between them and Avg hints at a long tail or a bimodal
+// distribution such as cache hits and misses.
+//
+// Percentiles are interpolated linearly between the two nearest samples.
+// P50, P95 and P99 are rounded to whole units; Percentiles holds the
+// unrounded values of the extra percentiles the summary was asked for.
+type LatencyStats struct {
+	Avg         float64 `json:"avg"`
+	Min         int64   `json:"min"`
+	Max         int64   `json:"max"`
+	P50         int64   `json:"p50"`
+	P95         int64   `json:"p95"`
+	P99         int64   `json:"p99"`
+	StdDev      float64 `json:"stddev"`
+	Variance    float64 `json:"variance"`
+	TrimmedMean float64 `json:"trimmedMean"`
+	IQR         int64   `json:"iqr"`
+
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+}
+
+// PercentileKey names percentile p in LatencyStats.Percentiles, e.g.
+// "p99.9".
+func PercentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// trimFraction is the share of samples TrimmedMean drops at each end.
+const trimFraction = 0.05
+
+// Summarize sorts samples in place and returns their summary, with each of
+// percentiles, between 0 and 100, in LatencyStats.Percentiles.
+func Summarize(samples []int64, percentiles ...float64) LatencyStats {
+	var stats LatencyStats
+	if len(samples) == 0 {
+		return stats
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	var sum int64
+	for _, sample := range samples {
+		sum += sample
+	}
+
+	stats.Avg = float64(sum) / float64(len(samples))
+	stats.Min = samples[0]
+	stats.Max = samples[len(samples)-1]
+	stats.P50 = int64(math.Round(quantile(samples, 0.5)))
+	stats.P95 = int64(math.Round(quantile(samples, 0.95)))
+	stats.P99 = int64(math.Round(quantile(samples, 0.99)))
+	stats.IQR = int64(math.Round(quantile(samples, 0.75) - quantile(samples, 0.25)))
+	stats.Percentiles = percentileValues(percentiles, func(q float64) float64 { return quantile(samples, q) })
+
+	var squares float64
+	for _, sample := range samples {
+		d := float64(sample) - stats.Avg
+		squares += d * d
+	}
+	stats.Variance = squares / float64(len(samples))
+	stats.StdDev = math.Sqrt(stats.Variance)
+
+	trim := int(float64(len(samples)) * trimFraction)
+	var trimmed int64
+	for _, sample := range samples[trim : len(samples)-trim] {
+		trimmed += sample
+	}
+	stats.TrimmedMean = float64(trimmed) / float64(len(samples)-2*trim)
+
+	return stats
+}
+
+// quantile interpolates the q-quantile of sorted samples between the two
+// samples closest to rank q×(n-1).
+func quantile(sorted []int64, q float64) float64 {
+	rank := q * float64(len(sorted)-1)
+	low := int(rank)
+	if low >= len(sorted)-1 {
+		return float64(sorted[len(sorted)-1])
+	}
+	return float64(sorted[low]) + (rank-float64(low))*float64(sorted[low+1]-sorted[low])
+}
+
+func percentileValues(percentiles []float64, quantile func(q float64) float64) map[string]float64 {
+	if len(percentiles) == 0 {
+		return nil
+	}
+	values := make(map[string]float64, len(percentiles))
+	for _, p := range percentiles {
+		values[PercentileKey(p)] = quantile(p / 100)
+	}
+	return values
+}
@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// sequence returns from, from+1, ... to, shuffled.
+func sequence(from, to int64) []int64 {
+	var samples []int64
+	for v := from; v <= to; v++ {
+		samples = append(samples, v)
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(samples), func(i, j int) {
+		samples[i], samples[j] = samples[j], samples[i]
+	})
+	return samples
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []int64{10, 20, 30, 40}
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{q: 0, want: 10},
+		{q: 0.25, want: 17.5},
+		{q: 0.5, want: 25},
+		{q: 0.9, want: 37},
+		{q: 1, want: 40},
+	}
+	for _, tt := range tests {
+		if got := quantile(sorted, tt.q); !near(got, tt.want, 1e-9) {
+			t.Errorf("quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := quantile([]int64{7}, 0.99); got != 7 {
+		t.Errorf("quantile of one sample = %v, want 7", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name        string
+		samples     []int64
+		percentiles []float64
+		want        LatencyStats
+	}{
+		{
+			name: "no samples",
+		},
+		{
+			name:        "one sample",
+			samples:     []int64{7},
+			percentiles: []float64{99.9},
+			want: LatencyStats{
+				Avg: 7, Min: 7, Max: 7, P50: 7, P95: 7, P99: 7, TrimmedMean: 7,
+				Percentiles: map[string]float64{"p99.9": 7},
+			},
+		},
+		{
+			name:        "interpolated",
+			samples:     sequence(1, 10),
+			percentiles: []float64{90, 99.9},
+			want: LatencyStats{
+				Avg: 5.5, Min: 1, Max: 10, P50: 6, P95: 10, P99: 10,
+				Variance: 8.25, StdDev: 2.8722813232690143, TrimmedMean: 5.5, IQR: 5,
+				Percentiles: map[string]float64{"p90": 9.1, "p99.9": 9.991},
+			},
+		},
+		{
+			name:    "trimmed",
+			samples: sequence(0, 99),
+			want: LatencyStats{
+				Avg: 49.5, Min: 0, Max: 99, P50: 50, P95: 94, P99: 98,
+				Variance: 833.25, StdDev: 28.86607004772212, TrimmedMean: 49.5, IQR: 50,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Summarize(tt.samples, tt.percentiles...)
+			if !slices.IsSorted(tt.samples) {
+				t.Error("samples were not sorted in place")
+			}
+			for key, value := range got.Percentiles {
+				if !near(value, tt.want.Percentiles[key], 1e-9) {
+					t.Errorf("%s = %v, want %v", key, value, tt.want.Percentiles[key])
+				}
+				got.Percentiles[key] = tt.want.Percentiles[key]
+			}
+			if !near(got.StdDev, tt.want.StdDev, 1e-9) {
+				t.Errorf("stddev = %v, want %v", got.StdDev, tt.want.StdDev)
+			}
+			got.StdDev = tt.want.StdDev
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentileKey(t *testing.T) {
+	for p, want := range map[float64]string{50: "p50", 99.9: "p99.9", 99.99: "p99.99"} {
+		if got := PercentileKey(p); got != want {
+			t.Errorf("PercentileKey(%v) = %q, want %q", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	separatedA := make([]int64, 25)
+	separatedB := make([]int64, 25)
+	for i := range separatedA {
+		separatedA[i] = int64(10 + i)
+		separatedB[i] = int64(100 + i)
+	}
+	tests := []struct {
+		name        string
+		a, b        []int64
+		u, z, p     float64
+		delta       float64
+		effect      string
+		significant bool
+	}{
+		{
+			name: "no samples",
+			a:    nil, b: []int64{1, 2},
+			p: 1, effect: "negligible",
+		},
+		{
+			name: "all equal",
+			a:    []int64{5, 5, 5}, b: []int64{5, 5, 5, 5},
+			u: 6, p: 1, effect: "negligible",
+		},
+		{
+			name: "separated, small",
+			a:    []int64{1, 2, 3}, b: []int64{4, 5, 6},
+			u: 0, z: -1.745743, p: 0.080856, delta: -1, effect: "large",
+		},
+		{
+			name: "interleaved with ties",
+			a:    []int64{10, 12, 12, 15, 18, 20, 21, 25}, b: []int64{11, 14, 16, 19, 22, 24, 30, 31, 35},
+			u: 21, z: -1.396119, p: 0.162679, delta: -0.416667, effect: "medium",
+		},
+		{
+			name: "separated, large",
+			a:    separatedB, b: separatedA,
+			u: 625, z: 6.053689, p: 1.415656e-9, delta: 1, effect: "large", significant: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MannWhitneyU(tt.a, tt.b, 0.05)
+			if got.SamplesA != len(tt.a) || got.SamplesB != len(tt.b) {
+				t.Errorf("got %d and %d samples", got.SamplesA, got.SamplesB)
+			}
+			if !near(got.U, tt.u, 1e-9) || !near(got.Z, tt.z, 1e-3) || !near(got.PValue, tt.p, 1e-3*tt.p+1e-12) {
+				t.Errorf("got U %v, z %v, p %v; want %v, %v, %v", got.U, got.Z, got.PValue, tt.u, tt.z, tt.p)
+			}
+			if !near(got.CliffsDelta, tt.delta, 1e-6) || got.Effect != tt.effect {
+				t.Errorf("got delta %v (%s), want %v (%s)", got.CliffsDelta, got.Effect, tt.delta, tt.effect)
+			}
+			if got.Significant != tt.significant {
+				t.Errorf("got significant %v, want %v", got.Significant, tt.significant)
+			}
+
+			// Swapping the samples flips the direction, not the p-value.
+			swapped := MannWhitneyU(tt.b, tt.a, 0.05)
+			if !near(swapped.Z, -got.Z, 1e-9) || !near(swapped.PValue, got.PValue, 1e-12) || !near(swapped.CliffsDelta, -got.CliffsDelta, 1e-9) {
+				t.Errorf("swapped: got z %v, p %v, delta %v", swapped.Z, swapped.PValue, swapped.CliffsDelta)
+			}
+		})
+	}
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"solana-rpc-performance-golang/stats"
+)
+
+// Filter selects runs. Empty fields match everything; Endpoint matches
+// any endpoint containing it.
+type Filter struct {
+	Endpoint string
+	Command  string
+	Since    time.Time
+	Limit    int
+}
+
+// where returns the SQL condition on the runs table, aliased r, and its
+// arguments.
+func (f Filter) where() (string, []interface{}) {
+	conds, args := []string{"1 = 1"}, []interface{}{}
+	if f.Endpoint != "" {
+		conds = append(conds, "r.endpoint LIKE ?")
+		args = append(args, "%"+f.Endpoint+"%")
+	}
+	if f.Command != "" {
+		conds = append(conds, "r.command = ?")
+		args = append(args, f.Command)
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "r.started_at >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// RunRecord is a recorded run. The headline numbers are those of its
+// summary, and zero if it had none.
+type RunRecord struct {
+	ID            int64           `json:"id"`
+	StartedAt     time.Time       `json:"startedAt"`
+	FinishedAt    *time.Time      `json:"finishedAt,omitempty"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	Command       string          `json:"command"`
+	Endpoint      string          `json:"endpoint"`
+	Chain         string          `json:"chain"`
+	Title         string          `json:"title,omitempty"`
+	TotalRequests int             `json:"totalRequests"`
+	SuccessRate   float64         `json:"successRate"`
+	P50           int64           `json:"p50"`
+	P95           int64           `json:"p95"`
+	P99           int64           `json:"p99"`
+	Config        json.RawMessage `json:"config,omitempty"`
+	Summary       json.RawMessage `json:"summary,omitempty"`
+}
+
+const runColumns = `r.id, r.started_at, r.finished_at, r.status, r.error, r.command, r.endpoint, r.chain, r.title,
+	r.total_requests, r.success_rate, r.p50, r.p95, r.p99`
+
+// scanRun reads the runColumns of a row.
+func scanRun(row interface{ Scan(...interface{}) error }) (RunRecord, error) {
+	var (
+		run                  RunRecord
+		started              int64
+		finished             sql.NullInt64
+		message, title       sql.NullString
+		total, p50, p95, p99 sql.NullInt64
+		successRate          sql.NullFloat64
+	)
+	err := row.Scan(&run.ID, &started, &finished, &run.Status, &message, &run.Command, &run.Endpoint, &run.Chain, &title,
+		&total, &successRate, &p50, &p95, &p99)
+	if err != nil {
+		return run, err
+	}
+	run.StartedAt = time.UnixMilli(started)
+	if finished.Valid {
+		at := time.UnixMilli(finished.Int64)
+		run.FinishedAt = &at
+	}
+	run.Error, run.Title = message.String, title.String
+	run.TotalRequests, run.SuccessRate = int(total.Int64), successRate.Float64
+	run.P50, run.P95, run.P99 = p50.Int64, p95.Int64, p99.Int64
+	return run, nil
+}
+
+// Runs returns the runs f selects, newest first.
+func (st *Store) Runs(f Filter) ([]RunRecord, error) {
+	where, args := f.where()
+	query := `SELECT ` + runColumns + ` FROM runs r WHERE ` + where + ` ORDER BY r.started_at DESC, r.id DESC`
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	rows, err := st.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var runs []RunRecord
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Run returns the run id with its config and summary.
+func (st *Store) Run(id int64) (*RunRecord, error) {
+	row := st.queryRow(`SELECT `+runColumns+`, r.config, r.summary FROM runs r WHERE r.id = ?`, id)
+	var config, summary sql.NullString
+	run, err := scanRun(scanFunc(func(dest ...interface{}) error {
+		return row.Scan(append(dest, &config, &summary)...)
+	}))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no run %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Valid {
+		run.Config = json.RawMessage(config.String)
+	}
+	if summary.Valid {
+		run.Summary = json.RawMessage(summary.String)
+	}
+	return &run, nil
+}
+
+// scanFunc adapts a function to the Scan method scanRun reads rows with.
+type scanFunc func(dest ...interface{}) error
+
+func (f scanFunc) Scan(dest ...interface{}) error { return f(dest...) }
+
+// TrendPoint summarizes the requests of the selected runs that were sent
+// during one period.
+type TrendPoint struct {
+	Start       time.Time          `json:"start"`
+	Runs        int                `json:"runs"`
+	Requests    int                `json:"requests"`
+	SuccessRate float64            `json:"successRate"`
+	Latency     stats.LatencyStats `json:"latency"`
+}
+
+// Trend breaks the recorded requests of the runs f selects, of method if
+// it isn't empty, into periods of length period, oldest first. f.Limit is
+// ignored. Latencies are of successful requests.
+func (st *Store) Trend(f Filter, method string, period time.Duration) ([]TrendPoint, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("trend period must be positive")
+	}
+	where, args := f.where()
+	if method != "" {
+		where += " AND q.method = ?"
+		args = append(args, method)
+	}
+	rows, err := st.query(`SELECT q.run_id, q.at, q.success, q.latency FROM requests q JOIN runs r ON r.id = q.run_id
+		WHERE `+where+` ORDER BY q.at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []TrendPoint
+	var latencies []int64
+	var successes int
+	runs := map[int64]bool{}
+	finish := func() {
+		if len(points) == 0 {
+			return
+		}
+		point := &points[len(points)-1]
+		point.Runs = len(runs)
+		point.SuccessRate = float64(successes) / float64(point.Requests) * 100
+		point.Latency = stats.Summarize(latencies)
+	}
+	for rows.Next() {
+		var runID, at, latency int64
+		var success bool
+		if err := rows.Scan(&runID, &at, &success, &latency); err != nil {
+			return nil, err
+		}
+		start := time.UnixMilli(at).Truncate(period)
+		if len(points) == 0 || !points[len(points)-1].Start.Equal(start) {
+			finish()
+			points = append(points, TrendPoint{Start: start})
+			latencies, successes, runs = nil, 0, map[int64]bool{}
+		}
+		point := &points[len(points)-1]
+		point.Requests++
+		runs[runID] = true
+		if success {
+			successes++
+			latencies = append(latencies, latency)
+		}
+	}
+	finish()
+	return points, rows.Err()
+}
+
+// SeriesPoint is a completed run's headline numbers, for charting runs
+// over time. Requests is zero for runs whose summary has no request
+// counts, such as -finality probes. ConfirmedLag and FinalizedLag are how
+// many slots, on average, the confirmed and finalized commitments trailed
+// the processed one, for -finality runs.
+type SeriesPoint struct {
+	Run          int64     `json:"run"`
+	StartedAt    time.Time `json:"startedAt"`
+	Command      string    `json:"command"`
+	Endpoint     string    `json:"endpoint"`
+	Requests     int       `json:"requests"`
+	SuccessRate  float64   `json:"successRate"`
+	P50          int64     `json:"p50"`
+	P95          int64     `json:"p95"`
+	P99          int64     `json:"p99"`
+	ConfirmedLag *float64  `json:"confirmedLag,omitempty"`
+	FinalizedLag *float64  `json:"finalizedLag,omitempty"`
+}
+
+// Series returns the completed runs f selects, oldest first. With
+// f.Limit, it returns the newest f.Limit.
+func (st *Store) Series(f Filter) ([]SeriesPoint, error) {
+	where, args := f.where()
+	query := `SELECT ` + runColumns + `, r.summary FROM runs r WHERE ` + where + ` AND r.status = ?
+		ORDER BY r.started_at DESC, r.id DESC`
+	args = append(args, StatusCompleted)
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	rows, err := st.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var points []SeriesPoint
+	for rows.Next() {
+		var summary sql.NullString
+		run, err := scanRun(scanFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &summary)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		point := SeriesPoint{
+			Run:         run.ID,
+			StartedAt:   run.StartedAt,
+			Command:     run.Command,
+			Endpoint:    run.Endpoint,
+			Requests:    run.TotalRequests,
+			SuccessRate: run.SuccessRate,
+			P50:         run.P50,
+			P95:         run.P95,
+			P99:         run.P99,
+		}
+		if summary.Valid {
+			var lags struct {
+				ConfirmedLag *struct {
+					Avg float64 `json:"avg"`
+				} `json:"confirmedLag"`
+				FinalizedLag *struct {
+					Avg float64 `json:"avg"`
+				} `json:"finalizedLag"`
+			}
+			json.Unmarshal([]byte(summary.String), &lags)
+			if lags.ConfirmedLag != nil {
+				point.ConfirmedLag = &lags.ConfirmedLag.Avg
+			}
+			if lags.FinalizedLag != nil {
+				point.FinalizedLag = &lags.FinalizedLag.Avg
+			}
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(points)
+	return points, nil
+}
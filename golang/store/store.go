@@ -0,0 +1,423 @@
+// Package store keeps benchmark runs in a SQLite or PostgreSQL database:
+// each run's configuration, every request it sent and the summary it
+// printed, so runs can be listed and compared over weeks, or, in a shared
+// PostgreSQL or TimescaleDB database, across a team's machines.
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	// Register the pgx and sqlite3 drivers.
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+
+	"solana-rpc-performance-golang/rpcclient"
+)
+
+// Run statuses. A run that never finished, killed rather than failed,
+// stays running.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id             INTEGER PRIMARY KEY,
+	started_at     INTEGER NOT NULL,
+	finished_at    INTEGER,
+	status         TEXT    NOT NULL,
+	error          TEXT,
+	command        TEXT    NOT NULL,
+	endpoint       TEXT    NOT NULL,
+	chain          TEXT    NOT NULL,
+	config         TEXT    NOT NULL,
+	title          TEXT,
+	summary        TEXT,
+	total_requests INTEGER,
+	success_rate   REAL,
+	p50            INTEGER,
+	p95            INTEGER,
+	p99            INTEGER
+);
+CREATE INDEX IF NOT EXISTS runs_started_at ON runs (started_at);
+CREATE TABLE IF NOT EXISTS requests (
+	run_id     INTEGER NOT NULL REFERENCES runs (id),
+	seq        INTEGER NOT NULL,
+	at         INTEGER NOT NULL,
+	job        TEXT,
+	method     TEXT    NOT NULL,
+	success    INTEGER NOT NULL,
+	latency    INTEGER NOT NULL,
+	size       INTEGER NOT NULL,
+	error      TEXT,
+	error_code INTEGER,
+	timed_out  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS requests_run_id ON requests (run_id);
+`
+
+// postgresSchema is sqliteSchema with PostgreSQL's types, plus the host
+// each run was recorded from and the run's endpoint on every request, so
+// dashboards can group requests without a join.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id             BIGSERIAL PRIMARY KEY,
+	started_at     BIGINT           NOT NULL,
+	finished_at    BIGINT,
+	status         TEXT             NOT NULL,
+	error          TEXT,
+	host           TEXT             NOT NULL,
+	command        TEXT             NOT NULL,
+	endpoint       TEXT             NOT NULL,
+	chain          TEXT             NOT NULL,
+	config         JSONB            NOT NULL,
+	title          TEXT,
+	summary        JSONB,
+	total_requests INTEGER,
+	success_rate   DOUBLE PRECISION,
+	p50            BIGINT,
+	p95            BIGINT,
+	p99            BIGINT
+);
+CREATE INDEX IF NOT EXISTS runs_started_at ON runs (started_at);
+CREATE TABLE IF NOT EXISTS requests (
+	run_id     BIGINT  NOT NULL REFERENCES runs (id),
+	seq        BIGINT  NOT NULL,
+	at         BIGINT  NOT NULL,
+	host       TEXT    NOT NULL,
+	endpoint   TEXT    NOT NULL,
+	job        TEXT,
+	method     TEXT    NOT NULL,
+	success    BOOLEAN NOT NULL,
+	latency    BIGINT  NOT NULL,
+	size       BIGINT  NOT NULL,
+	error      TEXT,
+	error_code INTEGER,
+	timed_out  BOOLEAN NOT NULL
+);
+CREATE INDEX IF NOT EXISTS requests_run_id ON requests (run_id);
+CREATE INDEX IF NOT EXISTS requests_at ON requests (at);
+`
+
+// timescaleHypertable turns requests into a hypertable chunked by day.
+const timescaleHypertable = `SELECT create_hypertable('requests', 'at', chunk_time_interval => 86400000, if_not_exists => TRUE, migrate_data => TRUE)`
+
+// Store is a database of runs. Times are stored as Unix milliseconds.
+type Store struct {
+	db       *sql.DB
+	postgres bool
+	// host is the machine runs are recorded from, kept with each run and
+	// request in PostgreSQL.
+	host string
+}
+
+// IsPostgres reports whether target is a PostgreSQL connection URL rather
+// than a SQLite file.
+func IsPostgres(target string) bool {
+	return strings.HasPrefix(target, "postgres://") || strings.HasPrefix(target, "postgresql://")
+}
+
+// Open opens the database target, a SQLite file or a postgres:// URL,
+// creating the file and its tables if need be.
+func Open(target string) (*Store, error) {
+	if IsPostgres(target) {
+		return openPostgres(target)
+	}
+	db, err := sql.Open("sqlite3", "file:"+target+"?_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open store %s: %w", target, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// openPostgres connects to the PostgreSQL database at url and creates the
+// tables if need be. On TimescaleDB, requests becomes a hypertable.
+func openPostgres(url string) (*Store, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, err
+	}
+	if err := createPostgresSchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open store %s: %w", rpcclient.RedactURL(url), err)
+	}
+	host, _ := os.Hostname()
+	return &Store{db: db, postgres: true, host: host}, nil
+}
+
+func createPostgresSchema(db *sql.DB) error {
+	if _, err := db.Exec(postgresSchema); err != nil {
+		return err
+	}
+	var timescale bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`).Scan(&timescale); err != nil {
+		return err
+	}
+	if timescale {
+		if _, err := db.Exec(timescaleHypertable); err != nil {
+			return fmt.Errorf("create hypertable: %w", err)
+		}
+	}
+	return nil
+}
+
+// rebind rewrites the ? placeholders of query as PostgreSQL's $1, $2 and
+// so on if need be.
+func (st *Store) rebind(query string) string {
+	if !st.postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (st *Store) exec(query string, args ...interface{}) (sql.Result, error) {
+	return st.db.Exec(st.rebind(query), args...)
+}
+
+func (st *Store) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return st.db.Query(st.rebind(query), args...)
+}
+
+func (st *Store) queryRow(query string, args ...interface{}) *sql.Row {
+	return st.db.QueryRow(st.rebind(query), args...)
+}
+
+func (st *Store) Close() error {
+	return st.db.Close()
+}
+
+// RunConfig is what a run was started with. Config is stored as JSON and
+// should hold no secrets; Endpoint is redacted.
+type RunConfig struct {
+	Command  string
+	Endpoint string
+	Chain    string
+	Config   interface{}
+}
+
+// Run is a run being recorded.
+type Run struct {
+	ID int64
+
+	st       *Store
+	endpoint string
+	mu       sync.Mutex
+	seq      int64
+	// summarized is set once the run's summary is recorded; later
+	// output, such as a baseline comparison, is not its summary.
+	summarized bool
+	// writeErr is the first error writing requests.
+	writeErr error
+}
+
+// BeginRun records the start of a run.
+func (st *Store) BeginRun(cfg RunConfig) (*Run, error) {
+	config, err := json.Marshal(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := rpcclient.RedactURL(cfg.Endpoint)
+	columns, args := "started_at, status, command, endpoint, chain, config", []interface{}{
+		time.Now().UnixMilli(), StatusRunning, cfg.Command, endpoint, cfg.Chain, string(config),
+	}
+	if st.postgres {
+		columns, args = columns+", host", append(args, st.host)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	var id int64
+	err = st.queryRow(`INSERT INTO runs (`+columns+`) VALUES (`+placeholders+`) RETURNING id`, args...).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("record run: %w", err)
+	}
+	return &Run{ID: id, st: st, endpoint: endpoint}, nil
+}
+
+// Summarize records v, the results the run printed under title, as its
+// summary, unless one is already recorded. The headline numbers are
+// taken from v's totalRequests, successRate and latency fields, if it has
+// them.
+func (r *Run) Summarize(title string, v interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.summarized {
+		return nil
+	}
+	r.summarized = true
+	summary, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var headline struct {
+		TotalRequests *int     `json:"totalRequests"`
+		SuccessRate   *float64 `json:"successRate"`
+		Latency       *struct {
+			P50 int64 `json:"p50"`
+			P95 int64 `json:"p95"`
+			P99 int64 `json:"p99"`
+		} `json:"latency"`
+	}
+	json.Unmarshal(summary, &headline)
+	var p50, p95, p99 interface{}
+	if headline.Latency != nil {
+		p50, p95, p99 = headline.Latency.P50, headline.Latency.P95, headline.Latency.P99
+	}
+	_, err = r.st.exec(`UPDATE runs SET title = ?, summary = ?, total_requests = ?, success_rate = ?, p50 = ?, p95 = ?, p99 = ? WHERE id = ?`,
+		title, string(summary), headline.TotalRequests, headline.SuccessRate, p50, p95, p99, r.ID)
+	return err
+}
+
+// Err returns the first error any of the run's sinks had writing
+// requests. The requests of a failed write are dropped rather than
+// failing the run.
+func (r *Run) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writeErr
+}
+
+// Finish records the end of the run, failed if err is not nil.
+func (r *Run) Finish(err error) error {
+	status, message := StatusCompleted, sql.NullString{}
+	if err != nil {
+		status, message = StatusFailed, sql.NullString{String: err.Error(), Valid: true}
+	}
+	_, execErr := r.st.exec(`UPDATE runs SET finished_at = ?, status = ?, error = ? WHERE id = ?`,
+		time.Now().UnixMilli(), status, message, r.ID)
+	return execErr
+}
+
+// sinkBatch is how many requests a Sink buffers before writing them, in
+// one statement.
+const sinkBatch = 1000
+
+// Sink records the requests of one benchmark of the run; job labels them
+// within a batch. It is a bench.ResultSink. Requests are written in the
+// background, so a slow or remote database doesn't hold up the benchmark.
+type Sink struct {
+	run *Run
+	job sql.NullString
+
+	mu      sync.Mutex
+	pending []request
+	batches chan []request
+	done    chan struct{}
+}
+
+type request struct {
+	seq, at int64
+	result  rpcclient.Result
+}
+
+// Sink returns a sink recording requests into the run, labelled job if it
+// isn't empty.
+func (r *Run) Sink(job string) *Sink {
+	return &Sink{run: r, job: sql.NullString{String: job, Valid: job != ""}}
+}
+
+func (s *Sink) Start(expected int) {
+	s.batches = make(chan []request, 16)
+	s.done = make(chan struct{})
+	go s.writeBatches()
+}
+
+func (s *Sink) Observe(result rpcclient.Result) {
+	s.run.mu.Lock()
+	s.run.seq++
+	seq := s.run.seq
+	s.run.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, request{seq: seq, at: time.Now().UnixMilli(), result: result})
+	if len(s.pending) >= sinkBatch {
+		s.batches <- s.pending
+		s.pending = nil
+	}
+}
+
+// Stop writes the requests still buffered and waits for every write to
+// finish.
+func (s *Sink) Stop() {
+	s.mu.Lock()
+	if len(s.pending) > 0 {
+		s.batches <- s.pending
+		s.pending = nil
+	}
+	close(s.batches)
+	s.mu.Unlock()
+	<-s.done
+}
+
+// writeBatches writes batches until they are closed. After a failed write
+// the rest are dropped, the error kept for Run.Err.
+func (s *Sink) writeBatches() {
+	defer close(s.done)
+	var failed bool
+	for batch := range s.batches {
+		if failed {
+			continue
+		}
+		if err := s.write(batch); err != nil {
+			failed = true
+			s.run.mu.Lock()
+			if s.run.writeErr == nil {
+				s.run.writeErr = err
+			}
+			s.run.mu.Unlock()
+		}
+	}
+}
+
+// write inserts batch in one statement. PostgreSQL rows also carry the
+// host and endpoint.
+func (s *Sink) write(batch []request) error {
+	st := s.run.st
+	columns := "run_id, seq, at, job, method, success, latency, size, error, error_code, timed_out"
+	if st.postgres {
+		columns += ", host, endpoint"
+	}
+	var query strings.Builder
+	query.WriteString("INSERT INTO requests (" + columns + ") VALUES ")
+	args := make([]interface{}, 0, len(batch)*13)
+	for i, req := range batch {
+		r := req.result
+		message, code := sql.NullString{}, sql.NullInt64{}
+		if !r.Success {
+			message = sql.NullString{String: r.Error, Valid: true}
+			code = sql.NullInt64{Int64: int64(r.ErrorCode), Valid: r.ErrorCode != 0}
+		}
+		row := []interface{}{s.run.ID, req.seq, req.at, s.job, r.Method, r.Success, r.Latency, r.Size, message, code, r.TimedOut}
+		if st.postgres {
+			row = append(row, st.host, s.run.endpoint)
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(" + strings.TrimSuffix(strings.Repeat("?, ", len(row)), ", ") + ")")
+		args = append(args, row...)
+	}
+	_, err := st.exec(query.String(), args...)
+	return err
+}
@@ -0,0 +1,49 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// defaultS3Region is used when neither the environment nor the shared
+// config names a region.
+const defaultS3Region = "us-east-1"
+
+// s3Bucket uploads with the AWS SDK.
+type s3Bucket struct {
+	name   string
+	client *s3.Client
+}
+
+// newS3Bucket loads the AWS SDK's default config. An endpoint set with
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL, such as a MinIO server, is
+// addressed path-style.
+func newS3Bucket(ctx context.Context, name string) (*s3Bucket, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("aws config: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = o.BaseEndpoint != nil
+	})
+	return &s3Bucket{name: name, client: client}, nil
+}
+
+func (b *s3Bucket) put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(b.name),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	})
+	return err
+}
@@ -0,0 +1,120 @@
+// Package upload archives a run's artifacts, its summary, config and the
+// files it wrote, to S3 or Google Cloud Storage under a run ID.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// bucket stores objects in one bucket of an object store.
+type bucket interface {
+	put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+}
+
+// Destination is where a run's artifacts go: a bucket and a key prefix,
+// under which each run gets a folder named by its run ID.
+type Destination struct {
+	URL    string
+	RunID  string
+	bucket bucket
+	prefix string
+}
+
+// Open returns the destination rawURL names, s3://bucket/prefix or
+// gs://bucket/prefix, with a new run ID. Credentials come from each
+// store's usual chain: the AWS environment variables, shared config and
+// instance roles for S3, and Application Default Credentials for GCS.
+func Open(ctx context.Context, rawURL string) (*Destination, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("upload URL: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("upload URL %q names no bucket", rawURL)
+	}
+	d := &Destination{URL: rawURL, RunID: NewRunID(time.Now()), prefix: strings.Trim(u.Path, "/")}
+	switch u.Scheme {
+	case "s3":
+		d.bucket, err = newS3Bucket(ctx, u.Host)
+	case "gs":
+		d.bucket, err = newGCSBucket(ctx, u.Host)
+	default:
+		return nil, fmt.Errorf("upload URL %q: want s3://bucket/prefix or gs://bucket/prefix", rawURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewRunID returns a run ID that sorts by start time, such as
+// 20260314T090000Z-3f9a1c.
+func NewRunID(start time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// Key returns the object key of the artifact name.
+func (d *Destination) Key(name string) string {
+	return path.Join(d.prefix, d.RunID, name)
+}
+
+// Location returns the URL of the run's folder.
+func (d *Destination) Location() string {
+	return strings.TrimSuffix(d.URL, "/") + "/" + d.RunID + "/"
+}
+
+// PutJSON uploads v as the indented JSON artifact name.
+func (d *Destination) PutJSON(ctx context.Context, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return d.PutBytes(ctx, name, "application/json", data)
+}
+
+// PutBytes uploads data as the artifact name, of type contentType.
+func (d *Destination) PutBytes(ctx context.Context, name, contentType string, data []byte) error {
+	return d.put(ctx, name, contentType, bytes.NewReader(data), int64(len(data)))
+}
+
+// PutFile uploads the file at file as the artifact named after its base
+// name.
+func (d *Destination) PutFile(ctx context.Context, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(file)
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return d.put(ctx, name, contentType, f, info.Size())
+}
+
+func (d *Destination) put(ctx context.Context, name, contentType string, body io.Reader, size int64) error {
+	if err := d.bucket.put(ctx, d.Key(name), contentType, body, size); err != nil {
+		return fmt.Errorf("upload %s: %w", name, err)
+	}
+	return nil
+}
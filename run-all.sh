@@ -55,7 +55,7 @@ else
         echo "Downloading Go dependencies..."
         go mod tidy > /dev/null 2>&1
     fi
-    go run main.go "$ENDPOINT" "$ITERATIONS"
+    go run ./cmd/solana-rpc-bench "$ENDPOINT" "$ITERATIONS"
     cd ..
 
     echo ""